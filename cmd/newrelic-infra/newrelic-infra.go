@@ -54,6 +54,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/backend/identityapi"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/disk"
+	"github.com/newrelic/infrastructure-agent/pkg/entity/register"
 	"github.com/newrelic/infrastructure-agent/pkg/fs/systemd"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers/recover"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/legacy"
@@ -362,7 +363,15 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	// queues config entries requests
 	configEntryQ := make(chan configrequest.Entry, 100)
 
-	dmEmitter := dm.NewEmitter(agt.GetContext(), dmSender, registerClient, instruments.Measure)
+	var entityLifecycle *register.Lifecycle
+	if c.EntityDecommissionTTL != "" {
+		// the format is checked during NormalizeConfig
+		ttl, _ := time.ParseDuration(c.EntityDecommissionTTL)
+		stateFile := filepath.Join(agt.InventoryStore().DataDir, register.LifecycleStateFile)
+		entityLifecycle = register.NewLifecycle(agt.Context.Identity, registerClient, ttl, stateFile)
+	}
+
+	dmEmitter := dm.NewEmitter(agt.GetContext(), dmSender, registerClient, entityLifecycle, instruments.Measure)
 
 	// track stoppable integrations
 	tracker := track.NewTracker(dmEmitter)
@@ -426,6 +435,12 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 
 			if c.StatusServerEnabled {
 				apiSrv.Status.Enable("localhost", c.StatusServerPort)
+				if c.InventoryHistoryEnabled {
+					apiSrv.EnableInventoryHistory(agt.InventoryStore())
+				}
+				if entityLifecycle != nil {
+					apiSrv.EnableEntityLifecycle(entityLifecycle)
+				}
 			}
 
 			if err != nil {
@@ -736,6 +751,7 @@ func executeIntegrationsDryRunMode(configPath string, ac *config.Config) {
 
 	// Initialize the cloudDetector.
 	cloudHarvester := cloud.NewDetector(ac.DisableCloudMetadata, ac.CloudMaxRetryCount, ac.CloudRetryBackOffSec, ac.CloudMetadataExpiryInSec, ac.CloudMetadataDisableKeepAlive)
+	cloudHarvester.SetDetectionOrder(ac.CloudDetectionOrder)
 	cloudHarvester.Initialize()
 
 	agentIDLookup := agent.NewIdLookup(hostnameResolver, cloudHarvester, ac.DisplayName)
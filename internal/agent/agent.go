@@ -61,13 +61,14 @@ type registerableSender interface {
 
 type Agent struct {
 	inv                 inventoryState
-	plugins             []Plugin              // Slice of registered plugins
-	oldPlugins          []ids.PluginID        // Deprecated plugins whose cached data must be removed, if existing
-	agentDir            string                // Base data directory for the agent
-	extDir              string                // Location of external data input
-	userAgent           string                // User-Agent making requests to warlock
-	inventories         map[string]*inventory // Inventory reaper and sender instances (key: entity ID)
-	Context             *context              // Agent context data that is passed around the place
+	plugins             []Plugin                   // Slice of registered plugins
+	oldPlugins          []ids.PluginID             // Deprecated plugins whose cached data must be removed, if existing
+	agentDir            string                     // Base data directory for the agent
+	extDir              string                     // Location of external data input
+	userAgent           string                     // User-Agent making requests to warlock
+	inventories         map[string]*inventory      // Inventory reaper and sender instances (key: entity ID)
+	instanceEntities    map[string]map[string]bool // Entities reported by discovered integration instances (key: instance ID)
+	Context             *context                   // Agent context data that is passed around the place
 	metricsSender       registerableSender
 	store               *delta.Store
 	debugProvide        debug.Provide
@@ -77,6 +78,7 @@ type Agent struct {
 	entityMap           entity.KnownIDs
 	fpHarvester         fingerprint.Harvester
 	cloudHarvester      cloud.Harvester                          // If it's the case returns information about the cloud where instance is running.
+	cloudTags           *cloud.TagsAttributes                    // The cloud tags reported as attributes, nil unless enabled.
 	agentID             *entity.ID                               // pointer as it's referred from several points
 	mtx                 sync.Mutex                               // Protect plugins
 	notificationHandler *ctl.NotificationHandlerWithCancellation // Handle ipc messaging.
@@ -93,6 +95,8 @@ type inventory struct {
 	sender       patchSender
 	needsReaping bool
 	needsCleanup bool
+	// removed is set when the entity is gone, so its inventory is unregistered once its deletion is sent
+	removed bool
 }
 
 var (
@@ -122,6 +126,9 @@ type AgentContext interface {
 	CacheServicePids(source string, pidMap map[int]string)
 	GetServiceForPid(pid int) (service string, ok bool)
 	ActiveEntitiesChannel() chan string
+	// InstanceGone tells the agent that a discovered integration instance is gone, so the inventory
+	// of the entities only reported by it is removed.
+	InstanceGone(instanceID string)
 	// HostnameResolver returns the host name resolver associated to the agent context
 	HostnameResolver() hostname.Resolver
 	IDLookup() host.IDLookup
@@ -142,6 +149,7 @@ type context struct {
 	reconnecting   *sync.Map         // Plugins that must be re-executed after a long disconnection
 	ch             chan PluginOutput // Channel of inbound plugin data payloads
 	activeEntities chan string       // Channel will be reported about the local/remote entities that are active
+	goneInstances  chan string       // Channel will be reported about the discovered integration instances that are gone
 	version        string
 	eventSender    eventSender
 
@@ -268,6 +276,7 @@ func NewAgent(
 
 	// Initialize the cloudDetector.
 	cloudHarvester := cloud.NewDetector(cfg.DisableCloudMetadata, cfg.CloudMaxRetryCount, cfg.CloudRetryBackOffSec, cfg.CloudMetadataExpiryInSec, cfg.CloudMetadataDisableKeepAlive)
+	cloudHarvester.SetDetectionOrder(cfg.CloudDetectionOrder)
 	cloudHarvester.Initialize()
 
 	idLookupTable := NewIdLookup(hostnameResolver, cloudHarvester, cfg.DisplayName)
@@ -292,7 +301,21 @@ func NewAgent(
 		maxInventorySize = delta.DisableInventorySplit
 	}
 
-	s := delta.NewStore(dataDir, ctx.EntityKey(), maxInventorySize)
+	var s *delta.Store
+	if cfg.InventoryStoreBackend == config.InventoryStoreBackendBolt {
+		// no fallback to the files store, as its cache may have been migrated to the database
+		if s, err = delta.NewBoltStore(dataDir, ctx.EntityKey(), maxInventorySize); err != nil {
+			return nil, err
+		}
+	} else {
+		s = delta.NewStore(dataDir, ctx.EntityKey(), maxInventorySize)
+	}
+	if cfg.InventoryHistoryEnabled {
+		maxAge, _ := time.ParseDuration(cfg.InventoryHistoryMaxAge)
+		if err = s.EnableHistory(cfg.InventoryHistoryMaxSnapshots, maxAge); err != nil {
+			alog.WithError(err).Warn("inventory history disabled")
+		}
+	}
 
 	transport := backendhttp.BuildTransport(cfg, backendhttp.ClientTimeout)
 
@@ -386,6 +409,14 @@ func New(
 	a.oldPlugins = make([]ids.PluginID, 0)
 
 	a.Context.cfg = cfg
+
+	if cfg.CloudTagsAsAttributes && !cfg.DisableCloudMetadata {
+		if tagsHarvester, ok := cloudHarvester.(cloud.TagsHarvester); ok {
+			interval := time.Duration(cfg.CloudMetadataExpiryInSec) * time.Second
+			a.cloudTags = cloud.NewTagsAttributes(tagsHarvester, cfg.CloudTagsInclude, cfg.CloudTagsExclude, cfg.CloudTagsPrefix, interval)
+		}
+	}
+
 	a.agentDir = cfg.AgentDir
 	if cfg.AppDataDir != "" {
 		a.extDir = filepath.Join(cfg.AppDataDir, "user_data")
@@ -401,6 +432,7 @@ func New(
 
 	// Instantiate reaper and sender
 	a.inventories = map[string]*inventory{}
+	a.instanceEntities = map[string]map[string]bool{}
 
 	// Make sure the network is working before continuing with identity
 	if err := checkCollectorConnectivity(ctx.Ctx, cfg, backoff.NewRetrier(), a.userAgent, a.Context.getAgentKey(), transport); err != nil {
@@ -424,6 +456,7 @@ func New(
 	llog.Tracef("inventory parallelize queue: %v", a.Context.cfg.InventoryQueueLen)
 	a.Context.ch = make(chan PluginOutput, a.Context.cfg.InventoryQueueLen)
 	a.Context.activeEntities = make(chan string, activeEntitiesBufferLength)
+	a.Context.goneInstances = make(chan string, activeEntitiesBufferLength)
 
 	if cfg.RegisterEnabled {
 		localEntityMap := entity.NewKnownIDs()
@@ -505,6 +538,38 @@ func (a *Agent) unregisterEntityInventory(entityKey string) error {
 	return a.store.RemoveEntity(entityKey)
 }
 
+// removeInstanceEntities reaps the removal of the inventory of the entities reported by a gone
+// integration instance, which are unregistered once the removal is sent, unless they are the local entity or they are still reported by another instance
+// (e.g. several instances monitoring the same cluster).
+func (a *Agent) removeInstanceEntities(instanceID string) {
+	entities := a.instanceEntities[instanceID]
+	delete(a.instanceEntities, instanceID)
+
+	for _, others := range a.instanceEntities {
+		for entityKey := range others {
+			delete(entities, entityKey)
+		}
+	}
+	delete(entities, a.Context.EntityKey())
+
+	for entityKey := range entities {
+		elog := alog.WithField("entityKey", entityKey).WithField("instanceID", instanceID)
+		elog.Debug("Removing inventory for entity of gone integration instance.")
+		inv, ok := a.inventories[entityKey]
+		if !ok {
+			if err := a.store.RemoveEntity(entityKey); err != nil {
+				elog.WithError(err).Warn("removing inventory for entity")
+			}
+			continue
+		}
+		if err := inv.reaper.RemoveEntity(); err != nil {
+			elog.WithError(err).Warn("reaping inventory removal for entity")
+		}
+		inv.needsReaping = false
+		inv.removed = true
+	}
+}
+
 func (a *Agent) Plugins() []Plugin {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
@@ -547,11 +612,21 @@ func (a *Agent) GetContext() AgentContext {
 	return a.Context
 }
 
+// InventoryStore returns the store holding the inventory deltas and history.
+func (a *Agent) InventoryStore() *delta.Store {
+	return a.store
+}
+
 // GetCloudHarvester will return the CloudHarvester service.
 func (a *Agent) GetCloudHarvester() cloud.Harvester {
 	return a.cloudHarvester
 }
 
+// GetCloudTags returns the cloud tags reported as attributes, or nil when cloud_tags_as_attributes is disabled.
+func (a *Agent) GetCloudTags() *cloud.TagsAttributes {
+	return a.cloudTags
+}
+
 // DeprecatePlugin builds the list of deprecated plugins
 func (a *Agent) DeprecatePlugin(plugin ids.PluginID) {
 	a.oldPlugins = append(a.oldPlugins, plugin)
@@ -754,10 +829,17 @@ func (a *Agent) Run() (err error) {
 	for {
 		select {
 		case <-exit:
+			if a.store != nil {
+				if err := a.store.Close(); err != nil {
+					alog.WithError(err).Warn("cannot close the inventory store")
+				}
+			}
 			return nil
 			// agent gets notified about active entities
 		case ent := <-a.Context.activeEntities:
 			reportedEntities[ent] = true
+		case instanceID := <-a.Context.goneInstances:
+			a.removeInstanceEntities(instanceID)
 			// read data from plugin and write json
 		case data := <-a.Context.ch:
 			{
@@ -773,10 +855,18 @@ func (a *Agent) Run() (err error) {
 						_ = a.registerEntityInventory(data.Entity)
 					}
 
+					if data.InstanceID != "" {
+						if _, ok := a.instanceEntities[data.InstanceID]; !ok {
+							a.instanceEntities[data.InstanceID] = map[string]bool{}
+						}
+						a.instanceEntities[data.InstanceID][entityKey] = true
+					}
+
 					if err := a.storePluginOutput(data); err != nil {
 						alog.WithError(err).Error("problem storing plugin output")
 					}
 					a.inventories[entityKey].needsReaping = true
+					a.inventories[entityKey].removed = false
 				}
 			}
 		case <-reapInventoryTimer.C:
@@ -941,7 +1031,7 @@ func (a *Agent) exitGracefully(sendTimer *time.Timer, reapTimer, removeEntitiesT
 
 func (a *Agent) sendInventory(sendTimer *time.Timer) {
 	backoffMax := config.MAX_BACKOFF
-	for _, i := range a.inventories {
+	for entityKey, i := range a.inventories {
 		err := i.sender.Process()
 		if err != nil {
 			if ingestError, ok := err.(*inventoryapi.IngestError); ok &&
@@ -959,6 +1049,11 @@ func (a *Agent) sendInventory(sendTimer *time.Timer) {
 		} else {
 			a.inv.sendErrorCount = 0
 		}
+		if i.removed {
+			if err := a.unregisterEntityInventory(entityKey); err != nil {
+				alog.WithField("entityKey", entityKey).WithError(err).Warn("unregistering inventory for entity")
+			}
+		}
 	}
 	sendTimerVal := helpers.ExpBackoff(a.Context.cfg.SendInterval,
 		time.Duration(backoffMax)*time.Second,
@@ -1015,6 +1110,12 @@ func (c *context) ActiveEntitiesChannel() chan string {
 	return c.activeEntities
 }
 
+func (c *context) InstanceGone(instanceID string) {
+	if c.goneInstances != nil {
+		c.goneInstances <- instanceID
+	}
+}
+
 func (c *context) SendEvent(event sample.Event, entityKey entity.Key) {
 	_, txn := instrumentation.SelfInstrumentation.StartTransaction(context2.Background(), "agent.queue_event")
 	defer txn.End()
@@ -37,6 +37,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
 	"github.com/newrelic/infrastructure-agent/internal/testhelpers"
 	http2 "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
 	"github.com/newrelic/infrastructure-agent/pkg/backend/state"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers/fingerprint"
@@ -371,6 +372,52 @@ func TestRemoveOutdatedEntities(t *testing.T) {
 	}
 }
 
+func TestRemoveInstanceEntities(t *testing.T) {
+	const aPlugin = "aPlugin"
+
+	// Given an agent
+	agent := newTesting(nil)
+	defer os.RemoveAll(agent.store.DataDir)
+	agent.inventories = map[string]*inventory{}
+	dataDir := agent.store.DataDir
+
+	// With entities reported by two discovered integration instances, sharing one of them
+	agent.instanceEntities = map[string]map[string]bool{
+		"container-1": {"redis:1": true, "cluster:1": true, agent.Context.EntityKey(): true},
+		"container-2": {"redis:2": true, "cluster:1": true},
+	}
+	senders := map[string]*deltasRecorder{}
+	for _, id := range []string{"redis:1", "redis:2", "cluster:1"} {
+		require.NoError(t, agent.registerEntityInventory(entity.NewFromNameWithoutID(id)))
+		require.NoError(t, agent.store.SavePluginSource(id, aPlugin, "config", map[string]interface{}{
+			"port": map[string]interface{}{"value": 6379},
+		}))
+		agent.inventories[id].reaper.Reap()
+		senders[id] = &deltasRecorder{entityKey: id, store: agent.store}
+		agent.inventories[id].sender = senders[id]
+	}
+	agent.sendInventory(time.NewTimer(time.Hour))
+
+	// When one of the instances is gone
+	agent.removeInstanceEntities("container-1")
+	agent.sendInventory(time.NewTimer(time.Hour))
+
+	// Then the removal of the inventory of the entities that it reported alone is sent
+	require.Len(t, senders["redis:1"].deltas, 1)
+	assert.Equal(t, map[string]interface{}{"port": nil}, senders["redis:1"].deltas[0].Diff)
+	assert.Empty(t, senders["redis:2"].deltas)
+	assert.Empty(t, senders["cluster:1"].deltas)
+
+	// And only their inventory is removed
+	for id, shouldBeRegistered := range map[string]bool{"redis:1": false, "redis:2": true, "cluster:1": true} {
+		_, ok := agent.inventories[id]
+		assert.Equal(t, shouldBeRegistered, ok, id)
+		_, err := os.Stat(filepath.Join(dataDir, aPlugin, helpers.SanitizeFileName(id)))
+		assert.Equal(t, shouldBeRegistered, err == nil, id)
+	}
+	assert.NotContains(t, agent.instanceEntities, "container-1")
+}
+
 func TestReconnectablePlugins(t *testing.T) {
 	// Given an agent
 	a := newTesting(nil)
@@ -539,6 +586,26 @@ func (p *patchSenderCallRecorder) getCalls() int {
 	return p.calls
 }
 
+// deltasRecorder patchSender implementation for tests. It records the deltas of the entity sent by Process()
+type deltasRecorder struct {
+	entityKey string
+	store     *delta.Store
+	deltas    []*inventoryapi.RawDelta
+}
+
+func (d *deltasRecorder) Process() error {
+	blocks, err := d.store.ReadDeltas(d.entityKey)
+	if err != nil {
+		return err
+	}
+	d.deltas = nil
+	for _, block := range blocks {
+		d.deltas = append(d.deltas, block...)
+		d.store.UpdateState(d.entityKey, block, &inventoryapi.DeltaStateMap{})
+	}
+	return nil
+}
+
 func TestAgent_Run_DontSendInventoryIfFwdOnly(t *testing.T) {
 	tests := []struct {
 		name              string
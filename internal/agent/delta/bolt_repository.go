@@ -0,0 +1,323 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/newrelic/infrastructure-agent/pkg/disk"
+)
+
+const (
+	// BOLT_DB_FILE is the file of the bolt backed delta store, inside the data directory.
+	BOLT_DB_FILE    = "delta_repo.db"
+	boltOpenTimeout = 5 * time.Second
+)
+
+var (
+	entitiesBucket = []byte("entities")
+	metaBucket     = []byte("meta")
+	pluginIDMapKey = []byte("plugin_id_map")
+	recordKeys     = map[RecordKind][]byte{
+		CacheRecord:   []byte("cache"),
+		PendingRecord: []byte("pending"),
+		ArchiveRecord: []byte("sent"),
+	}
+)
+
+// NewBoltStore creates a Store that keeps the inventory cache and deltas in a bolt database
+// instead of a tree of files, so each update is a single crash-safe transaction. The existing
+// cache files are migrated to the database the first time. If maxInventorySize <= 0, the
+// inventory splitting is disabled.
+func NewBoltStore(dataDir string, defaultEntityKey string, maxInventorySize int) (*Store, error) {
+	if defaultEntityKey == "" {
+		return nil, errors.New("default entity ID can't be empty")
+	}
+
+	if err := disk.MkdirAll(dataDir, DATA_DIR_MODE); err != nil {
+		return nil, fmt.Errorf("can't create data directory: %s err: %s", dataDir, err)
+	}
+
+	repo, err := openBoltRepository(filepath.Join(dataDir, BOLT_DB_FILE))
+	if err != nil {
+		return nil, err
+	}
+	if err = repo.migrate(filepath.Join(dataDir, CACHE_DIR)); err != nil {
+		_ = repo.Close()
+		return nil, fmt.Errorf("can't migrate delta store files: %s", err)
+	}
+
+	s, err := NewStoreWithBackend(dataDir, defaultEntityKey, maxInventorySize, repo)
+	if err != nil {
+		_ = repo.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// boltRepository stores the records in a bolt database, with a bucket per entity folder
+// holding a bucket per plugin source, with a key per record kind.
+type boltRepository struct {
+	db *bolt.DB
+}
+
+func openBoltRepository(path string) (*boltRepository, error) {
+	db, err := bolt.Open(path, DATA_FILE_MODE, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("can't open delta store database %s: %s", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(entitiesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(metaBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &boltRepository{db: db}, nil
+}
+
+func (r *boltRepository) Update(fn func(tx StorageTx) error) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+func (r *boltRepository) View(fn func(tx StorageTx) error) error {
+	return r.db.View(func(tx *bolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+// Size returns the bytes of the stored records. The database file size isn't used, as it never
+// shrinks: the pages freed by removed records are reused for the new ones.
+func (r *boltRepository) Size() (size uint64, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, b *bolt.Bucket) error {
+			return bucketSize(b, &size)
+		})
+	})
+	return
+}
+
+// bucketSize adds the size of the values of a bucket and its nested buckets to size.
+func bucketSize(b *bolt.Bucket, size *uint64) error {
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return bucketSize(b.Bucket(k), size)
+		}
+		*size += uint64(len(v))
+		return nil
+	})
+}
+
+func (r *boltRepository) Close() error {
+	return r.db.Close()
+}
+
+// migrate moves the records of a files repository into the database, and removes the files.
+func (r *boltRepository) migrate(cacheDir string) error {
+	files := &fileRepository{dir: cacheDir}
+	entities, err := files.Entities()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	idMap, err := files.PluginIDMap()
+	if err != nil {
+		return err
+	}
+	if len(entities) == 0 && idMap == nil {
+		return nil
+	}
+
+	var migrated int
+	err = r.Update(func(tx StorageTx) error {
+		for entityFolder := range entities {
+			plugins, err := pluginRecords(cacheDir, entityFolder)
+			if err != nil {
+				return err
+			}
+			for _, pi := range plugins {
+				for kind := range recordKeys {
+					value, err := files.Get(kind, pi, entityFolder)
+					if err != nil {
+						return err
+					}
+					if value == nil {
+						continue
+					}
+					if err = tx.Put(kind, pi, entityFolder, value); err != nil {
+						return err
+					}
+					migrated++
+				}
+			}
+		}
+		if idMap != nil {
+			return tx.PutPluginIDMap(idMap)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.WithFields(logrus.Fields{"path": cacheDir, "records": migrated}).Info("Delta store files migrated.")
+	if err = os.RemoveAll(cacheDir); err != nil {
+		return err
+	}
+	return disk.MkdirAll(cacheDir, DATA_DIR_MODE)
+}
+
+// pluginRecords returns the plugins with any kind of record for the entity in a cache folder.
+func pluginRecords(cacheDir, entityFolder string) ([]*PluginInfo, error) {
+	categories, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	var plugins []*PluginInfo
+	for _, category := range categories {
+		if !category.IsDir() || nonEntityFolders[category.Name()] {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(cacheDir, category.Name(), entityFolder))
+		if err != nil {
+			continue
+		}
+		terms := map[string]bool{}
+		for _, f := range files {
+			term := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+			if !f.IsDir() && !terms[term] {
+				terms[term] = true
+				plugins = append(plugins, newPluginInfo(category.Name(), term+".json"))
+			}
+		}
+	}
+	return plugins, nil
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+// pluginBucket returns the bucket of the plugin records of an entity, creating it if required.
+func (b *boltTx) pluginBucket(pi *PluginInfo, entityFolder string, create bool) (*bolt.Bucket, error) {
+	entities := b.tx.Bucket(entitiesBucket)
+	if !create {
+		entity := entities.Bucket([]byte(entityFolder))
+		if entity == nil {
+			return nil, nil
+		}
+		return entity.Bucket([]byte(pi.Source)), nil
+	}
+	entity, err := entities.CreateBucketIfNotExists([]byte(entityFolder))
+	if err != nil {
+		return nil, err
+	}
+	return entity.CreateBucketIfNotExists([]byte(pi.Source))
+}
+
+func (b *boltTx) Get(kind RecordKind, pi *PluginInfo, entityFolder string) ([]byte, error) {
+	bucket, err := b.pluginBucket(pi, entityFolder, false)
+	if bucket == nil || err != nil {
+		return nil, err
+	}
+	value := bucket.Get(recordKeys[kind])
+	if value == nil {
+		return nil, nil
+	}
+	// values are only valid during the transaction
+	return append([]byte{}, value...), nil
+}
+
+func (b *boltTx) Put(kind RecordKind, pi *PluginInfo, entityFolder string, value []byte) error {
+	bucket, err := b.pluginBucket(pi, entityFolder, true)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return bucket.Put(recordKeys[kind], value)
+}
+
+func (b *boltTx) Append(kind RecordKind, pi *PluginInfo, entityFolder string, value []byte) error {
+	bucket, err := b.pluginBucket(pi, entityFolder, true)
+	if err != nil {
+		return err
+	}
+	previous := bucket.Get(recordKeys[kind])
+	return bucket.Put(recordKeys[kind], append(append([]byte{}, previous...), value...))
+}
+
+func (b *boltTx) Remove(kind RecordKind, pi *PluginInfo, entityFolder string) error {
+	bucket, err := b.pluginBucket(pi, entityFolder, false)
+	if bucket == nil || err != nil {
+		return err
+	}
+	return bucket.Delete(recordKeys[kind])
+}
+
+func (b *boltTx) Plugins(entityFolder string) ([]*PluginInfo, error) {
+	entity := b.tx.Bucket(entitiesBucket).Bucket([]byte(entityFolder))
+	if entity == nil {
+		return nil, nil
+	}
+	var plugins []*PluginInfo
+	err := entity.ForEach(func(source, _ []byte) error {
+		// as the files repository, only the plugins with cached inventory are returned
+		if entity.Bucket(source).Get(recordKeys[CacheRecord]) == nil {
+			return nil
+		}
+		parts := strings.SplitN(string(source), "/", 2)
+		if len(parts) == 2 {
+			plugins = append(plugins, newPluginInfo(parts[0], parts[1]+".json"))
+		}
+		return nil
+	})
+	return plugins, err
+}
+
+func (b *boltTx) Entities() (map[string]interface{}, error) {
+	entities := make(map[string]interface{})
+	err := b.tx.Bucket(entitiesBucket).ForEach(func(entityFolder, _ []byte) error {
+		entities[string(entityFolder)] = true
+		return nil
+	})
+	return entities, err
+}
+
+func (b *boltTx) RemoveEntity(entityFolder string) error {
+	err := b.tx.Bucket(entitiesBucket).DeleteBucket([]byte(entityFolder))
+	if err == bolt.ErrBucketNotFound {
+		return nil
+	}
+	return err
+}
+
+func (b *boltTx) PluginIDMap() ([]byte, error) {
+	value := b.tx.Bucket(metaBucket).Get(pluginIDMapKey)
+	if value == nil {
+		return nil, nil
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (b *boltTx) PutPluginIDMap(value []byte) error {
+	return b.tx.Bucket(metaBucket).Put(pluginIDMapKey, value)
+}
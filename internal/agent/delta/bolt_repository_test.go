@@ -0,0 +1,150 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package delta
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
+)
+
+func newTestBoltStore(t *testing.T, dataDir string) *Store {
+	s, err := NewBoltStore(dataDir, "localhost", maxInventorySize)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestBoltStore_ReadAndArchiveDeltas(t *testing.T) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	s := newTestBoltStore(t, dataDir)
+
+	require.NoError(t, s.SavePluginSource("entity:1", "config", "sysctl", map[string]interface{}{"a": map[string]interface{}{"value": "1"}}))
+	require.NoError(t, s.UpdatePluginsInventoryCache("entity:1"))
+	require.NoError(t, s.SavePluginSource("entity:1", "config", "sysctl", map[string]interface{}{"a": map[string]interface{}{"value": "2"}}))
+	require.NoError(t, s.UpdatePluginsInventoryCache("entity:1"))
+
+	deltas, err := s.ReadDeltas("entity:1")
+	require.NoError(t, err)
+	require.Len(t, deltas, 1)
+	require.Len(t, deltas[0], 2)
+	assert.True(t, deltas[0][0].FullDiff)
+	assert.Equal(t, int64(2), deltas[0][1].ID)
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"value": "2"}}, deltas[0][1].Diff)
+
+	// first delta accepted by the backend
+	s.UpdateState("entity:1", deltas[0][:1], &inventoryapi.DeltaStateMap{
+		"config/sysctl": &inventoryapi.DeltaState{SendNextID: 2},
+	})
+	deltas, err = s.ReadDeltas("entity:1")
+	require.NoError(t, err)
+	require.Len(t, deltas, 1)
+	require.Len(t, deltas[0], 1)
+	assert.Equal(t, int64(2), deltas[0][0].ID)
+
+	// state persisted across restarts
+	require.NoError(t, s.Close())
+	s = newTestBoltStore(t, dataDir)
+	assert.Equal(t, int64(1), s.plugins["config/sysctl"].lastSentID("entity:1"))
+	assert.Equal(t, int64(2), s.plugins["config/sysctl"].deltaID("entity:1"))
+	deltas, err = s.ReadDeltas("entity:1")
+	require.NoError(t, err)
+	require.Len(t, deltas[0], 1)
+}
+
+func TestBoltStore_RemoveEntity(t *testing.T) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	s := newTestBoltStore(t, dataDir)
+
+	for _, entityKey := range []string{"entity:1", "entity:2"} {
+		require.NoError(t, s.SavePluginSource(entityKey, "config", "sysctl", map[string]interface{}{"a": map[string]interface{}{"value": "1"}}))
+		require.NoError(t, s.UpdatePluginsInventoryCache(entityKey))
+	}
+	entities, err := s.ScanEntityFolders()
+	require.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	require.NoError(t, s.RemoveEntity("entity:1"))
+
+	entities, err = s.ScanEntityFolders()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"entity2": true}, entities)
+	deltas, err := s.ReadDeltas("entity:1")
+	require.NoError(t, err)
+	assert.Empty(t, deltas)
+}
+
+func TestBoltStore_SizeOfLiveRecords(t *testing.T) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	s := newTestBoltStore(t, dataDir)
+
+	empty, err := s.repo.Size()
+	require.NoError(t, err)
+
+	for _, entityKey := range []string{"entity:1", "entity:2"} {
+		require.NoError(t, s.SavePluginSource(entityKey, "config", "sysctl", map[string]interface{}{"a": map[string]interface{}{"value": "1"}}))
+		require.NoError(t, s.UpdatePluginsInventoryCache(entityKey))
+	}
+	full, err := s.repo.Size()
+	require.NoError(t, err)
+	assert.Greater(t, full, empty)
+
+	// the database file doesn't shrink, but the reported size does, down to the plugins ID map
+	require.NoError(t, s.RemoveEntity("entity:1"))
+	require.NoError(t, s.RemoveEntity("entity:2"))
+	size, err := s.repo.Size()
+	require.NoError(t, err)
+	assert.Less(t, size, full)
+	assert.Less(t, size, uint64(256))
+}
+
+func TestBoltStore_MigratesFiles(t *testing.T) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	// Given a files store with sent and pending deltas
+	files := NewStore(dataDir, "localhost", maxInventorySize)
+	require.NoError(t, files.SavePluginSource("entity:1", "config", "sysctl", map[string]interface{}{"a": map[string]interface{}{"value": "1"}}))
+	require.NoError(t, files.UpdatePluginsInventoryCache("entity:1"))
+	deltas, err := files.ReadDeltas("entity:1")
+	require.NoError(t, err)
+	files.UpdateState("entity:1", deltas[0], &inventoryapi.DeltaStateMap{
+		"config/sysctl": &inventoryapi.DeltaState{SendNextID: 2},
+	})
+	require.NoError(t, files.SavePluginSource("entity:1", "config", "sysctl", map[string]interface{}{"a": map[string]interface{}{"value": "2"}}))
+	require.NoError(t, files.UpdatePluginsInventoryCache("entity:1"))
+
+	// When the bolt store is opened
+	s := newTestBoltStore(t, dataDir)
+
+	// Then the deltas state is kept
+	assert.Equal(t, int64(1), s.plugins["config/sysctl"].lastSentID("entity:1"))
+	deltas, err = s.ReadDeltas("entity:1")
+	require.NoError(t, err)
+	require.Len(t, deltas, 1)
+	require.Len(t, deltas[0], 1)
+	assert.Equal(t, int64(2), deltas[0][0].ID)
+
+	// And the unchanged inventory doesn't generate a new delta
+	require.NoError(t, s.UpdatePluginsInventoryCache("entity:1"))
+	deltas, err = s.ReadDeltas("entity:1")
+	require.NoError(t, err)
+	require.Len(t, deltas[0], 1)
+
+	// And the files are removed
+	cached, err := os.ReadDir(filepath.Join(dataDir, CACHE_DIR))
+	require.NoError(t, err)
+	assert.Empty(t, cached)
+}
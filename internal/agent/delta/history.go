@@ -0,0 +1,264 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package delta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/newrelic/infrastructure-agent/pkg/disk"
+)
+
+const (
+	HISTORY_DIR          = ".inventory_history"
+	historySnapshotExt   = ".json"
+	historyTemporaryExt  = ".tmp"
+	historyTimeFormatLen = 19 // length of a unix nanoseconds timestamp, so snapshot names sort by time
+)
+
+// ErrNoHistory is returned when there is no inventory snapshot for the requested time.
+var ErrNoHistory = errors.New("no inventory history for the requested time")
+
+// ErrInvalidPlugin is returned when the requested category or term can't name a plugin folder.
+var ErrInvalidPlugin = errors.New("invalid inventory category or term")
+
+// ValidatePlugin returns ErrInvalidPlugin if the category or term aren't a single folder name, as
+// they are provided by the status API users and become part of the history path.
+func ValidatePlugin(category, term string) error {
+	for _, name := range []string{category, term} {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return ErrInvalidPlugin
+		}
+	}
+	return nil
+}
+
+// Snapshot is the inventory state of a plugin for an entity, since the time it was taken.
+type Snapshot struct {
+	Time      time.Time              `json:"time"`
+	Inventory map[string]interface{} `json:"inventory"`
+}
+
+// Diff holds the inventory items changed between two times, by item ID. From is the time of
+// the snapshot the changes are computed from, which is zero if the history doesn't go that far.
+type Diff struct {
+	From    time.Time              `json:"from"`
+	To      time.Time              `json:"to"`
+	Added   map[string]interface{} `json:"added"`
+	Removed map[string]interface{} `json:"removed"`
+	Changed map[string]Change      `json:"changed"`
+}
+
+// Change holds the old and new values of a modified inventory item.
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// history stores timestamped snapshots of the plugins inventory, under a folder tree with the same
+// layout as the data directory: <category>/<entity folder>/<term>/<unix nanoseconds>.json
+type history struct {
+	dir          string
+	maxSnapshots int
+	maxAge       time.Duration
+	now          func() time.Time
+	lock         sync.Mutex
+	// seeded holds the snapshot folders known to have, at least, one snapshot
+	seeded map[string]bool
+}
+
+// EnableHistory makes the store keep a snapshot of the inventory of each plugin and entity every
+// time it changes. Snapshots are removed when there are more than maxSnapshots or when they are
+// older than maxAge, but the one holding the current state.
+func (s *Store) EnableHistory(maxSnapshots int, maxAge time.Duration) error {
+	dir := filepath.Join(s.DataDir, HISTORY_DIR)
+	if err := disk.MkdirAll(dir, DATA_DIR_MODE); err != nil {
+		return fmt.Errorf("can't create inventory history directory: %s, err: %s", dir, err)
+	}
+	s.history = &history{
+		dir:          dir,
+		maxSnapshots: maxSnapshots,
+		maxAge:       maxAge,
+		now:          time.Now,
+		seeded:       map[string]bool{},
+	}
+	return nil
+}
+
+// InventoryAt returns the inventory of a plugin for an entity as it was at the given time.
+func (s *Store) InventoryAt(entityKey, category, term string, at time.Time) (Snapshot, error) {
+	if s.history == nil {
+		return Snapshot{}, ErrNoHistory
+	}
+	if err := ValidatePlugin(category, term); err != nil {
+		return Snapshot{}, err
+	}
+	return s.history.at(s.historyDirPath(category, term, entityKey), at)
+}
+
+// InventoryDiff returns the inventory items of a plugin for an entity that changed between the
+// from and to times. If the history doesn't reach the from time, all the items are reported as added.
+func (s *Store) InventoryDiff(entityKey, category, term string, from, to time.Time) (Diff, error) {
+	if s.history == nil {
+		return Diff{}, ErrNoHistory
+	}
+	if err := ValidatePlugin(category, term); err != nil {
+		return Diff{}, err
+	}
+	dir := s.historyDirPath(category, term, entityKey)
+	newer, err := s.history.at(dir, to)
+	if err != nil {
+		return Diff{}, err
+	}
+	older, err := s.history.at(dir, from)
+	if err == ErrNoHistory {
+		older = Snapshot{Inventory: map[string]interface{}{}}
+	} else if err != nil {
+		return Diff{}, err
+	}
+
+	d := Diff{
+		From:    older.Time,
+		To:      newer.Time,
+		Added:   map[string]interface{}{},
+		Removed: map[string]interface{}{},
+		Changed: map[string]Change{},
+	}
+	for id, item := range newer.Inventory {
+		old, ok := older.Inventory[id]
+		if !ok {
+			d.Added[id] = item
+		} else if !reflect.DeepEqual(old, item) {
+			d.Changed[id] = Change{Old: old, New: item}
+		}
+	}
+	for id, item := range older.Inventory {
+		if _, ok := newer.Inventory[id]; !ok {
+			d.Removed[id] = item
+		}
+	}
+	return d, nil
+}
+
+func (s *Store) historyDirPath(category, term, entityKey string) string {
+	return filepath.Join(s.history.dir, category, s.EntityFolder(entityKey), term)
+}
+
+// recordHistory stores the inventory of the plugin as a new snapshot when it has been updated, or
+// when there is no snapshot yet for it.
+func (s *Store) recordHistory(pi *PluginInfo, entityKey string, inventory []byte, updated bool) error {
+	term := strings.TrimSuffix(pi.FileName, filepath.Ext(pi.FileName))
+	dir := s.historyDirPath(pi.Plugin, term, entityKey)
+	return s.history.record(dir, inventory, updated)
+}
+
+func (h *history) record(dir string, content []byte, updated bool) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !updated && h.seeded[dir] {
+		return nil
+	}
+	names, err := snapshotNames(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !updated && len(names) > 0 {
+		h.seeded[dir] = true
+		return nil
+	}
+
+	if err = disk.MkdirAll(dir, DATA_DIR_MODE); err != nil {
+		return err
+	}
+	now := h.now()
+	name := fmt.Sprintf("%0*d%s", historyTimeFormatLen, now.UnixNano(), historySnapshotExt)
+	// snapshots are written atomically, as they may be concurrently read from the status API
+	tmpPath := filepath.Join(dir, name+historyTemporaryExt)
+	if err = disk.WriteFile(tmpPath, content, DATA_FILE_MODE); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, filepath.Join(dir, name)); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	h.seeded[dir] = true
+
+	h.prune(dir, append(names, name), now)
+	return nil
+}
+
+// prune removes the snapshots exceeding the retention limits. The latest one is always kept.
+func (h *history) prune(dir string, names []string, now time.Time) {
+	for i, name := range names[:len(names)-1] {
+		exceeding := len(names)-i > h.maxSnapshots
+		if !exceeding && (h.maxAge <= 0 || now.Sub(snapshotTime(name)) <= h.maxAge) {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			slog.WithFields(logrus.Fields{"path": dir, "snapshot": name}).
+				WithError(err).Warn("can't remove inventory history snapshot")
+		}
+	}
+}
+
+// at returns the latest snapshot of the folder taken at or before the given time.
+func (h *history) at(dir string, at time.Time) (Snapshot, error) {
+	names, err := snapshotNames(dir)
+	if os.IsNotExist(err) {
+		return Snapshot{}, ErrNoHistory
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	idx := sort.Search(len(names), func(i int) bool {
+		return snapshotTime(names[i]).After(at)
+	}) - 1
+	if idx < 0 {
+		return Snapshot{}, ErrNoHistory
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, names[idx]))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot := Snapshot{Time: snapshotTime(names[idx])}
+	if err = json.Unmarshal(content, &snapshot.Inventory); err != nil {
+		return Snapshot{}, fmt.Errorf("corrupted inventory history snapshot %s: %s", names[idx], err)
+	}
+	return snapshot, nil
+}
+
+// snapshotNames returns the snapshot file names of a folder, sorted from older to newer.
+func snapshotNames(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && filepath.Ext(f.Name()) == historySnapshotExt {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func snapshotTime(name string) time.Time {
+	nanos, _ := strconv.ParseInt(strings.TrimSuffix(name, historySnapshotExt), 10, 64)
+	return time.Unix(0, nanos)
+}
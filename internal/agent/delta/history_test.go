@@ -0,0 +1,141 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package delta
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHistoryStore(t *testing.T, maxSnapshots int, maxAge time.Duration) (*Store, *time.Time) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dataDir) })
+
+	s := NewStore(dataDir, "localhost", maxInventorySize)
+	require.NoError(t, s.EnableHistory(maxSnapshots, maxAge))
+	now := time.Unix(1650000000, 0)
+	s.history.now = func() time.Time { return now }
+	return s, &now
+}
+
+func saveInventory(t *testing.T, s *Store, now *time.Time, at time.Time, source map[string]interface{}) {
+	*now = at
+	require.NoError(t, s.SavePluginSource("localhost", "config", "sysctl", source))
+	require.NoError(t, s.UpdatePluginsInventoryCache("localhost"))
+}
+
+func TestStore_InventoryAt(t *testing.T) {
+	s, now := newHistoryStore(t, 10, time.Hour)
+	t0 := *now
+
+	saveInventory(t, s, now, t0, map[string]interface{}{"vm.swappiness": map[string]interface{}{"value": "60"}})
+	// unchanged inventory is not recorded
+	saveInventory(t, s, now, t0.Add(time.Minute), map[string]interface{}{"vm.swappiness": map[string]interface{}{"value": "60"}})
+	saveInventory(t, s, now, t0.Add(2*time.Minute), map[string]interface{}{"vm.swappiness": map[string]interface{}{"value": "10"}})
+
+	snapshot, err := s.InventoryAt("", "config", "sysctl", t0.Add(90*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, t0, snapshot.Time)
+	assert.Equal(t, map[string]interface{}{"vm.swappiness": map[string]interface{}{"value": "60"}}, snapshot.Inventory)
+
+	snapshot, err = s.InventoryAt("localhost", "config", "sysctl", t0.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, t0.Add(2*time.Minute), snapshot.Time)
+	assert.Equal(t, map[string]interface{}{"vm.swappiness": map[string]interface{}{"value": "10"}}, snapshot.Inventory)
+
+	_, err = s.InventoryAt("", "config", "sysctl", t0.Add(-time.Second))
+	assert.Equal(t, ErrNoHistory, err)
+	_, err = s.InventoryAt("", "packages", "dpkg", t0)
+	assert.Equal(t, ErrNoHistory, err)
+}
+
+func TestStore_InventoryDiff(t *testing.T) {
+	s, now := newHistoryStore(t, 10, time.Hour)
+	t0 := *now
+
+	saveInventory(t, s, now, t0, map[string]interface{}{
+		"vm.swappiness":  map[string]interface{}{"value": "60"},
+		"net.ipv4.ip_fw": map[string]interface{}{"value": "0"},
+	})
+	saveInventory(t, s, now, t0.Add(time.Minute), map[string]interface{}{
+		"vm.swappiness":  map[string]interface{}{"value": "10"},
+		"net.ipv4.ip_fw": map[string]interface{}{"value": "0"},
+		"fs.file-max":    map[string]interface{}{"value": "1000"},
+	})
+	saveInventory(t, s, now, t0.Add(2*time.Minute), map[string]interface{}{
+		"vm.swappiness": map[string]interface{}{"value": "10"},
+		"kernel.panic":  map[string]interface{}{"value": "1"},
+	})
+
+	d, err := s.InventoryDiff("", "config", "sysctl", t0, t0.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, t0, d.From)
+	assert.Equal(t, t0.Add(2*time.Minute), d.To)
+	assert.Equal(t, map[string]interface{}{"kernel.panic": map[string]interface{}{"value": "1"}}, d.Added)
+	assert.Equal(t, map[string]interface{}{"net.ipv4.ip_fw": map[string]interface{}{"value": "0"}}, d.Removed)
+	assert.Equal(t, map[string]Change{"vm.swappiness": {
+		Old: map[string]interface{}{"value": "60"},
+		New: map[string]interface{}{"value": "10"},
+	}}, d.Changed)
+
+	// history not reaching the from time
+	d, err = s.InventoryDiff("", "config", "sysctl", t0.Add(-time.Hour), t0)
+	require.NoError(t, err)
+	assert.True(t, d.From.IsZero())
+	assert.Len(t, d.Added, 2)
+	assert.Empty(t, d.Removed)
+}
+
+func TestStore_InventoryInvalidPlugin(t *testing.T) {
+	s, now := newHistoryStore(t, 10, time.Hour)
+
+	for _, plugin := range [][2]string{{"..", "sysctl"}, {"config", ".."}, {"config", "../../etc"}, {"config", `..\sysctl`}, {".", "sysctl"}, {"", "sysctl"}} {
+		_, err := s.InventoryAt("", plugin[0], plugin[1], *now)
+		assert.Equal(t, ErrInvalidPlugin, err, plugin)
+		_, err = s.InventoryDiff("", plugin[0], plugin[1], now.Add(-time.Minute), *now)
+		assert.Equal(t, ErrInvalidPlugin, err, plugin)
+	}
+
+	// dots are valid within names, as in integration terms
+	assert.NoError(t, ValidatePlugin("integration", "com.newrelic.nginx"))
+}
+
+func TestStore_HistoryRetention(t *testing.T) {
+	s, now := newHistoryStore(t, 3, 10*time.Minute)
+	t0 := *now
+
+	for i := 0; i < 5; i++ {
+		saveInventory(t, s, now, t0.Add(time.Duration(i)*time.Minute), map[string]interface{}{"n": map[string]interface{}{"value": i}})
+	}
+	names, err := snapshotNames(s.historyDirPath("config", "sysctl", ""))
+	require.NoError(t, err)
+	assert.Len(t, names, 3, "limited by the number of snapshots")
+
+	saveInventory(t, s, now, t0.Add(time.Hour), map[string]interface{}{"n": map[string]interface{}{"value": 5}})
+	names, err = snapshotNames(s.historyDirPath("config", "sysctl", ""))
+	require.NoError(t, err)
+	assert.Len(t, names, 1, "older snapshots expired, the current one is kept")
+}
+
+func TestStore_HistorySeededWithCurrentInventory(t *testing.T) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	s := NewStore(dataDir, "localhost", maxInventorySize)
+	require.NoError(t, s.SavePluginSource("localhost", "config", "sysctl", map[string]interface{}{"a": map[string]interface{}{"value": "1"}}))
+	require.NoError(t, s.UpdatePluginsInventoryCache("localhost"))
+
+	// history enabled when the inventory was already cached
+	require.NoError(t, s.EnableHistory(10, time.Hour))
+	require.NoError(t, s.UpdatePluginsInventoryCache("localhost"))
+
+	snapshot, err := s.InventoryAt("", "config", "sysctl", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"value": "1"}}, snapshot.Inventory)
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package delta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/disk"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// RecordKind is the kind of data stored by the backend for each plugin and entity.
+type RecordKind int
+
+const (
+	// CacheRecord is the last inventory of the plugin, the one the next delta is computed from.
+	CacheRecord RecordKind = iota
+	// PendingRecord holds the deltas not sent yet.
+	PendingRecord
+	// ArchiveRecord holds the deltas already accepted by the backend.
+	ArchiveRecord
+)
+
+// StorageBackend persists the delta store cache: the inventory cache and deltas of each plugin
+// and entity, plus the plugins ID map. The Store implements Storage on top of it.
+type StorageBackend interface {
+	// Update runs fn in a read-write transaction. Implementations that support it discard all
+	// the changes made by fn when it returns an error.
+	Update(fn func(tx StorageTx) error) error
+	// View runs fn in a read-only transaction.
+	View(fn func(tx StorageTx) error) error
+	// Size returns the bytes used by the stored records.
+	Size() (uint64, error)
+	Close() error
+}
+
+// StorageTx accesses the backend records. Reading a missing record returns nil.
+type StorageTx interface {
+	Get(kind RecordKind, pi *PluginInfo, entityFolder string) ([]byte, error)
+	Put(kind RecordKind, pi *PluginInfo, entityFolder string, value []byte) error
+	Append(kind RecordKind, pi *PluginInfo, entityFolder string, value []byte) error
+	Remove(kind RecordKind, pi *PluginInfo, entityFolder string) error
+	// Plugins returns the plugins with cached inventory for the entity.
+	Plugins(entityFolder string) ([]*PluginInfo, error)
+	// Entities returns the folders of the entities with cached data.
+	Entities() (map[string]interface{}, error)
+	RemoveEntity(entityFolder string) error
+	PluginIDMap() ([]byte, error)
+	PutPluginIDMap(value []byte) error
+}
+
+// fileRepository stores each record in a file of the CACHE_DIR tree:
+// <plugin category>/<entity folder>/<plugin term>.{json,pending,sent}
+type fileRepository struct {
+	dir string
+}
+
+func (r *fileRepository) Update(fn func(tx StorageTx) error) error {
+	return fn(r)
+}
+
+func (r *fileRepository) View(fn func(tx StorageTx) error) error {
+	return fn(r)
+}
+
+func (r *fileRepository) Size() (uint64, error) {
+	var size int64
+	err := filepath.Walk(r.dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return err
+	})
+	return uint64(size), err
+}
+
+func (r *fileRepository) Close() error {
+	return nil
+}
+
+func (r *fileRepository) path(kind RecordKind, pi *PluginInfo, entityFolder string) string {
+	file := filepath.Join(r.dir, pi.Plugin, entityFolder, pi.FileName)
+	switch kind {
+	case PendingRecord:
+		return fmt.Sprintf("%s%s", strings.TrimSuffix(file, filepath.Ext(file)), UNSENT_DELTA_JOURNAL_EXT)
+	case ArchiveRecord:
+		return fmt.Sprintf("%s%s", strings.TrimSuffix(file, filepath.Ext(file)), ARCHIVE_DELTA_JOURNAL_EXT)
+	}
+	return file
+}
+
+func (r *fileRepository) Get(kind RecordKind, pi *PluginInfo, entityFolder string) ([]byte, error) {
+	buf, err := ioutil.ReadFile(r.path(kind, pi, entityFolder))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return buf, err
+}
+
+func (r *fileRepository) Put(kind RecordKind, pi *PluginInfo, entityFolder string, value []byte) error {
+	path := r.path(kind, pi, entityFolder)
+	if err := disk.MkdirAll(filepath.Dir(path), DATA_DIR_MODE); err != nil {
+		return err
+	}
+	return disk.WriteFile(path, value, DATA_FILE_MODE)
+}
+
+func (r *fileRepository) Append(kind RecordKind, pi *PluginInfo, entityFolder string, value []byte) error {
+	path := r.path(kind, pi, entityFolder)
+	if err := disk.MkdirAll(filepath.Dir(path), DATA_DIR_MODE); err != nil {
+		return err
+	}
+	f, err := disk.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, DATA_FILE_MODE)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(value)
+	return err
+}
+
+func (r *fileRepository) Remove(kind RecordKind, pi *PluginInfo, entityFolder string) error {
+	err := os.Remove(r.path(kind, pi, entityFolder))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *fileRepository) Plugins(entityFolder string) ([]*PluginInfo, error) {
+	pluginsFileInfo, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	return globPlugins(r.dir, pluginsFileInfo, entityFolder), nil
+}
+
+func (r *fileRepository) Entities() (map[string]interface{}, error) {
+	return fetchEntities(r.dir)
+}
+
+func (r *fileRepository) RemoveEntity(entityFolder string) error {
+	errStrings := removeEntityEntries(r.dir, entityFolder)
+	if len(errStrings) > 0 {
+		return fmt.Errorf("%s", strings.Join(errStrings, ", "))
+	}
+	return nil
+}
+
+func (r *fileRepository) PluginIDMap() ([]byte, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(r.dir, CACHE_ID_FILE))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return buf, err
+}
+
+func (r *fileRepository) PutPluginIDMap(value []byte) error {
+	// the cache folder may have been removed on purpose, so it isn't created again
+	if _, err := os.Stat(r.dir); err != nil {
+		return err
+	}
+	return disk.WriteFile(filepath.Join(r.dir, CACHE_ID_FILE), value, DATA_FILE_MODE)
+}
+
+// globPlugins returns the plugins with JSON files for the entity, inside the plugin folders.
+func globPlugins(dir string, pluginsFileInfo []os.FileInfo, entityFolder string) []*PluginInfo {
+	pluginList := make([]*PluginInfo, 0, len(pluginsFileInfo))
+	for _, dirInfo := range pluginsFileInfo {
+		if dirInfo != nil && dirInfo.IsDir() && !nonEntityFolders[dirInfo.Name()] {
+			// Look inside each "plugin" directory to find the plugin's data files
+			join := filepath.Join(dir, dirInfo.Name(), entityFolder, "*.json")
+			filesInfo, err := filepath.Glob(join)
+			if err != nil {
+				// There is no such entity for the given plugin, so continuing
+				continue
+			}
+
+			for _, fInfo := range filesInfo {
+				pluginList = append(pluginList, newPluginInfo(dirInfo.Name(), filepath.Base(fInfo)))
+			}
+		}
+	}
+	return pluginList
+}
+
+func removeEntityEntries(dir, entityFolder string) (errStrings []string) {
+	errStrings = make([]string, 0)
+	// For all the plugins in the given directory
+	plugins, err := ioutil.ReadDir(dir)
+	if err != nil {
+		errStrings = append(errStrings, err.Error())
+		return errStrings
+	}
+	for _, plugin := range plugins {
+		if plugin.IsDir() && !nonEntityFolders[plugin.Name()] {
+			// For all the entities under the plugin folder, remove those whose directory name is planned for removal
+			entityPath := filepath.Join(dir, plugin.Name(), entityFolder)
+			if _, err := os.Stat(entityPath); err == nil {
+				helpers.DebugStackf("removing: %s", entityPath)
+				if err = os.RemoveAll(entityPath); err != nil {
+					errStrings = append(errStrings, err.Error())
+				}
+			}
+		}
+	}
+	return errStrings
+}
+
+func fetchEntities(dir string) (map[string]interface{}, error) {
+	entities := make(map[string]interface{})
+
+	// For all the plugins in the given directory
+	plugins, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, plugin := range plugins {
+		if plugin.IsDir() && !nonEntityFolders[plugin.Name()] {
+			// For all the entities under the plugin folder, adds them to the map
+			entityFolders, err := ioutil.ReadDir(filepath.Join(dir, plugin.Name()))
+			if err != nil {
+				return entities, err
+			}
+			for _, folder := range entityFolders {
+				if folder.IsDir() {
+					entities[folder.Name()] = true
+				}
+			}
+		}
+	}
+	return entities, nil
+}
@@ -6,6 +6,7 @@ package delta
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -51,6 +52,7 @@ var nonEntityFolders = map[string]bool{
 	SAMPLING_REPO:               true,
 	lastSuccessSubmissionFolder: true,
 	lastEntityIDFolder:          true,
+	HISTORY_DIR:                 true,
 }
 
 type delta struct {
@@ -101,6 +103,10 @@ type Store struct {
 	plugins pluginSource2Info
 	// stores time of last success submission of inventory to backend
 	lastSuccessSubmission time.Time
+	// history keeps the inventory snapshots, if enabled
+	history *history
+	// repo persists the inventory cache and deltas
+	repo StorageBackend
 }
 
 // NewStore creates a new Store and returns a pointer to it. If maxInventorySize <= 0, the inventory splitting is disabled
@@ -117,6 +123,7 @@ func NewStore(dataDir string, defaultEntityKey string, maxInventorySize int) *St
 		defaultEntityKey: defaultEntityKey,
 		plugins:          make(pluginSource2Info),
 	}
+	d.repo = &fileRepository{dir: d.CacheDir}
 
 	// Nice2Have: remove side effects from constructor
 	if err := d.createDataStore(); err != nil {
@@ -137,6 +144,46 @@ func NewStore(dataDir string, defaultEntityKey string, maxInventorySize int) *St
 	return d
 }
 
+// NewStoreWithBackend creates a Store that keeps the inventory cache and deltas in the provided
+// backend. If maxInventorySize <= 0, the inventory splitting is disabled.
+func NewStoreWithBackend(dataDir string, defaultEntityKey string, maxInventorySize int, backend StorageBackend) (*Store, error) {
+	if defaultEntityKey == "" {
+		return nil, errors.New("default entity ID can't be empty")
+	}
+
+	s := &Store{
+		DataDir:          dataDir,
+		CacheDir:         filepath.Join(dataDir, CACHE_DIR),
+		maxInventorySize: maxInventorySize,
+		defaultEntityKey: defaultEntityKey,
+		plugins:          make(pluginSource2Info),
+		repo:             backend,
+	}
+	if err := s.createDataStore(); err != nil {
+		return nil, err
+	}
+
+	var idMap []byte
+	err := backend.View(func(tx StorageTx) (err error) {
+		idMap, err = tx.PluginIDMap()
+		return
+	})
+	if err == nil {
+		err = s.loadPluginIDMap(idMap)
+	}
+	if err != nil {
+		slog.WithError(err).Error("can't initialize plugin-id map")
+		s.plugins = make(pluginSource2Info)
+	}
+
+	return s, nil
+}
+
+// Close releases the resources held by the store.
+func (s *Store) Close() error {
+	return s.repo.Close()
+}
+
 func (s *Store) createDataStore() (err error) {
 	if err = disk.MkdirAll(s.DataDir, DATA_DIR_MODE); err != nil {
 		return fmt.Errorf("can't create data directory: %s err: %s", s.DataDir, err)
@@ -192,16 +239,13 @@ func (s *Store) PluginDirPath(pluginCategory, entityKey string) string {
 	return filepath.Join(s.DataDir, pluginCategory, s.EntityFolder(entityKey))
 }
 
-func (s *Store) clearPluginDeltaStore(pluginItem *PluginInfo, entityKey string) (err error) {
+func (s *Store) clearPluginDeltaStore(tx StorageTx, pluginItem *PluginInfo, entityKey string) (err error) {
 	// Clear the cachedFile and deltas
-	cachedFilePath := s.cachedFilePath(pluginItem, entityKey)
-	deltaFilePath := s.DeltaFilePath(pluginItem, entityKey)
-	archiveFilePath := s.archiveFilePath(pluginItem, entityKey)
-	helpers.DebugStackf("Clearing delta store for plugin %s and entity %s: %s, %s, %s",
-		pluginItem.Source, entityKey, cachedFilePath, deltaFilePath, archiveFilePath)
-	_ = os.Remove(cachedFilePath)
-	_ = os.Remove(deltaFilePath)
-	_ = os.Remove(archiveFilePath)
+	entityFolder := s.EntityFolder(entityKey)
+	helpers.DebugStackf("Clearing delta store for plugin %s and entity %s", pluginItem.Source, entityKey)
+	_ = tx.Remove(CacheRecord, pluginItem, entityFolder)
+	_ = tx.Remove(PendingRecord, pluginItem, entityFolder)
+	_ = tx.Remove(ArchiveRecord, pluginItem, entityFolder)
 	return
 }
 
@@ -210,36 +254,41 @@ func (s *Store) compactCacheStorage(entityKey string, _ uint64) (err error) {
 	// For any plugins that don't exist anymore, we can complete clean those out
 	// For plugins that do exist with N generations of data, remove all sent generations
 
-	if activePlugins, err := s.collectPluginFiles(s.DataDir, entityKey, helpers.JsonFilesRegexp); err == nil {
-		if reapedPlugins, err := s.collectPluginFiles(s.CacheDir, entityKey, helpers.JsonFilesRegexp); err == nil {
-			// clear out unused plugins
-			removedPlugins := make(map[string]*PluginInfo)
-			for _, plugin := range reapedPlugins {
-				removedPlugins[plugin.Source] = plugin
-			}
-			for _, plugin := range activePlugins {
-				delete(removedPlugins, plugin.Source)
-			}
-			for _, p := range removedPlugins {
-				_ = s.clearPluginDeltaStore(p, entityKey)
-				delete(s.plugins, p.Source)
-			}
-
-			// Now for the active ones, remove their archives
-			for _, p := range activePlugins {
-				archiveFilePath := s.archiveFilePath(p, entityKey)
-				_ = os.Remove(archiveFilePath)
-			}
+	activePlugins, err := s.collectPluginFiles(s.DataDir, entityKey, helpers.JsonFilesRegexp)
+	if err != nil {
+		return nil
+	}
+	entityFolder := s.EntityFolder(entityKey)
+	return s.repo.Update(func(tx StorageTx) error {
+		reapedPlugins, err := tx.Plugins(entityFolder)
+		if err != nil {
+			return nil
+		}
+		// clear out unused plugins
+		removedPlugins := make(map[string]*PluginInfo)
+		for _, plugin := range reapedPlugins {
+			removedPlugins[plugin.Source] = plugin
+		}
+		for _, plugin := range activePlugins {
+			delete(removedPlugins, plugin.Source)
+		}
+		for _, p := range removedPlugins {
+			_ = s.clearPluginDeltaStore(tx, p, entityKey)
+			delete(s.plugins, p.Source)
+		}
 
+		// Now for the active ones, remove their archives
+		for _, p := range activePlugins {
+			_ = tx.Remove(ArchiveRecord, p, entityFolder)
 		}
-	}
-	return
+		return nil
+	})
 }
 
 // CompactStorage reduces the size of the Delta Storage
 func (s *Store) CompactStorage(entityKey string, threshold uint64) (err error) {
 	var repoSize, newRepoSize uint64
-	repoSize, err = s.StorageSize(s.CacheDir)
+	repoSize, err = s.repo.Size()
 	if err == nil && repoSize > 0 && repoSize > threshold {
 		cslog := slog.WithFieldsF(func() logrus.Fields {
 			return logrus.Fields{"repoSize": repoSize, "threshold": threshold, "entityKey": entityKey}
@@ -249,7 +298,7 @@ func (s *Store) CompactStorage(entityKey string, threshold uint64) (err error) {
 		if err = s.compactCacheStorage(entityKey, threshold); err != nil {
 			return
 		}
-		newRepoSize, err = s.StorageSize(s.CacheDir)
+		newRepoSize, err = s.repo.Size()
 		if nil != err {
 			return
 		}
@@ -280,10 +329,11 @@ func (s *Store) StorageSize(path string) (uint64, error) {
 	return uint64(size), err
 }
 
-func (s *Store) archivePlugin(pluginItem *PluginInfo, entityKey string) (err error) {
+func (s *Store) archivePlugin(tx StorageTx, pluginItem *PluginInfo, entityKey string) (err error) {
+	entityFolder := s.EntityFolder(entityKey)
 	var buf []byte
-	buf, err = s.readIndividualPluginDeltas(pluginItem, entityKey)
-	if err != nil {
+	buf, err = tx.Get(PendingRecord, pluginItem, entityFolder)
+	if err != nil || buf == nil {
 		return
 	}
 
@@ -307,53 +357,69 @@ func (s *Store) archivePlugin(pluginItem *PluginInfo, entityKey string) (err err
 		}
 	}
 
-	err = s.rewriteDeltas(s.archiveFilePath(pluginItem, entityKey), os.O_CREATE|os.O_APPEND|os.O_WRONLY, archiveDeltas)
-	if err != nil {
+	var archiveBuf, keepBuf []byte
+	if archiveBuf, err = marshalDeltas(archiveDeltas); err != nil {
 		return
 	}
-
-	return s.rewriteDeltas(s.DeltaFilePath(pluginItem, entityKey), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, keepDeltas)
+	if keepBuf, err = marshalDeltas(keepDeltas); err != nil {
+		return
+	}
+	if err = tx.Append(ArchiveRecord, pluginItem, entityFolder, archiveBuf); err != nil {
+		return
+	}
+	return tx.Put(PendingRecord, pluginItem, entityFolder, keepBuf)
 }
 
 // ResetAllDeltas clears the plugin delta store for all the existing plugins
 func (s *Store) ResetAllDeltas(entityKey string) {
 	if s.plugins != nil {
-		for _, plugin := range s.plugins {
-			_ = s.clearPluginDeltaStore(plugin, entityKey)
-		}
+		_ = s.repo.Update(func(tx StorageTx) error {
+			for _, plugin := range s.plugins {
+				_ = s.clearPluginDeltaStore(tx, plugin, entityKey)
+			}
+			return nil
+		})
 	}
 }
 
 // UpdateState updates in disk the state of the deltas according to the passed PostDeltaBody, whose their ExternalKeys
 // field may be empty.
 func (s *Store) UpdateState(entityKey string, deltas []*inventoryapi.RawDelta, deltaStateResults *inventoryapi.DeltaStateMap) {
-	sentPlugins := make([]string, len(deltas))
-
-	// record what was sent and archive
-	for _, d := range deltas {
-		var dResult *inventoryapi.DeltaState
-		if deltaStateResults != nil {
-			dResult, _ = (*deltaStateResults)[d.Source]
+	// the deltas archive and the plugin ID maps are updated in a single transaction, so a crash
+	// doesn't leave them inconsistent on transactional repositories
+	err := s.repo.Update(func(tx StorageTx) error {
+		sentPlugins := make([]string, len(deltas))
+
+		// record what was sent and archive
+		for _, d := range deltas {
+			var dResult *inventoryapi.DeltaState
+			if deltaStateResults != nil {
+				dResult, _ = (*deltaStateResults)[d.Source]
+			}
+			s.updateLastDeltaSent(tx, entityKey, d, dResult)
+			sentPlugins = append(sentPlugins, d.Source)
 		}
-		s.updateLastDeltaSent(entityKey, d, dResult)
-		sentPlugins = append(sentPlugins, d.Source)
-	}
-
-	// Clean up delta files in bulk for each plugin
-	for _, source := range sentPlugins {
-		plugin := s.plugins[source]
-		if plugin != nil {
-			ierr := s.archivePlugin(plugin, entityKey)
-			if ierr != nil {
-				slog.WithFields(logrus.Fields{"source": source, "entity": entityKey}).
-					Debug("UpdateState: Plugin delta does not exist.")
+
+		// Clean up delta files in bulk for each plugin
+		for _, source := range sentPlugins {
+			plugin := s.plugins[source]
+			if plugin != nil {
+				ierr := s.archivePlugin(tx, plugin, entityKey)
+				if ierr != nil {
+					slog.WithFields(logrus.Fields{"source": source, "entity": entityKey}).
+						WithError(ierr).Debug("UpdateState: Plugin delta can't be archived.")
+				}
 			}
 		}
+		return s.writePluginIDMap(tx)
+	})
+	if err != nil {
+		slog.WithField("entity", entityKey).WithError(err).Error("can't update deltas state")
 	}
 	return
 }
 
-func (s *Store) updateLastDeltaSent(entityKey string, dRaw *inventoryapi.RawDelta, resultHint *inventoryapi.DeltaState) {
+func (s *Store) updateLastDeltaSent(tx StorageTx, entityKey string, dRaw *inventoryapi.RawDelta, resultHint *inventoryapi.DeltaState) {
 	if s.plugins == nil {
 		return
 	}
@@ -390,7 +456,7 @@ func (s *Store) updateLastDeltaSent(entityKey string, dRaw *inventoryapi.RawDelt
 			// Fixes the situation where agent sent N but backend expected N+1. In this situation,
 			// when backend sends back N+1 as the SendNextID, the agent could not tell if its delta
 			// was problematic.
-			s.reconciliateWithBackend(p, entityKey, resultHint)
+			s.reconciliateWithBackend(tx, p, entityKey, resultHint)
 
 		case resultHint.SendNextID == id+1:
 			// Normal case.
@@ -398,12 +464,12 @@ func (s *Store) updateLastDeltaSent(entityKey string, dRaw *inventoryapi.RawDelt
 
 		case resultHint.SendNextID == 0:
 			// Send full. Leave delta ID values as is.
-			_ = s.clearPluginDeltaStore(p, entityKey)
+			_ = s.clearPluginDeltaStore(tx, p, entityKey)
 
 		case resultHint.SendNextID != id:
 			// If not present, send current full Reset delta ids to use SendNextID for the numbering
 			// of the next delta ids so we can fill in the gaps in the correct sequence.
-			s.reconciliateWithBackend(p, entityKey, resultHint)
+			s.reconciliateWithBackend(tx, p, entityKey, resultHint)
 
 		case resultHint.SendNextID == id:
 			// Send again? This is a no-op, set last sent id to one previous.
@@ -418,15 +484,15 @@ func (s *Store) updateLastDeltaSent(entityKey string, dRaw *inventoryapi.RawDelt
 	dslog.WithField("plugin", source).Debug("Updating deltas.")
 }
 
-func (s *Store) reconciliateWithBackend(pi *PluginInfo, entityKey string, resultHint *inventoryapi.DeltaState) {
-	_ = s.clearPluginDeltaStore(pi, entityKey)
+func (s *Store) reconciliateWithBackend(tx StorageTx, pi *PluginInfo, entityKey string, resultHint *inventoryapi.DeltaState) {
+	_ = s.clearPluginDeltaStore(tx, pi, entityKey)
 	pi.setLastSentID(entityKey, resultHint.SendNextID-1)
 	pi.setDeltaID(entityKey, resultHint.LastStoredID)
 }
 
 // SaveState writes on disk the plugin ID maps
 func (s *Store) SaveState() (err error) {
-	if err = s.writePluginIDMap(); err != nil {
+	if err = s.repo.Update(s.writePluginIDMap); err != nil {
 		slog.WithError(err).Error("can't write plugin id maps")
 	}
 	return
@@ -462,19 +528,12 @@ func (s *Store) loadPluginIDMap(deltaIDBytes []byte) (err error) {
 	return nil
 }
 
-func (s *Store) writePluginIDMap() (err error) {
-	if _, err = os.Stat(s.CacheDir); err != nil {
-		return
-	}
-
+func (s *Store) writePluginIDMap(tx StorageTx) (err error) {
 	var buf []byte
 	if buf, err = json.Marshal(s.plugins); err != nil {
 		slog.WithError(err).Error("can't marshal id map?")
-	} else {
-		cachedDeltaPath := filepath.Join(s.CacheDir, CACHE_ID_FILE)
-		if err = disk.WriteFile(cachedDeltaPath, buf, DATA_FILE_MODE); err != nil {
-			slog.WithError(err).WithField("path", cachedDeltaPath).Error("unable to write delta cache")
-		}
+	} else if err = tx.PutPluginIDMap(buf); err != nil {
+		slog.WithError(err).Error("unable to write delta cache")
 	}
 	return
 }
@@ -485,26 +544,7 @@ func (s *Store) collectPluginFiles(dir string, entityKey string, fileFilterRE *r
 		return
 	}
 
-	pluginList = make([]*PluginInfo, 0, len(pluginsFileInfo))
-	entityFolder := s.EntityFolder(entityKey)
-
-	for _, dirInfo := range pluginsFileInfo {
-		if dirInfo != nil && dirInfo.IsDir() && !nonEntityFolders[dirInfo.Name()] {
-			// Look inside each "plugin" directory to find the plugin's data files
-			join := filepath.Join(dir, dirInfo.Name(), entityFolder, "*.json")
-			filesInfo, err := filepath.Glob(join)
-			if err != nil {
-				// There is no such entity for the given plugin, so continuing
-				continue
-			}
-
-			for _, fInfo := range filesInfo {
-				pluginList = append(pluginList, newPluginInfo(dirInfo.Name(), filepath.Base(fInfo)))
-			}
-		}
-	}
-
-	return
+	return globPlugins(dir, pluginsFileInfo, s.EntityFolder(entityKey)), nil
 }
 
 func removeNilsFromMarshaledJSON(buf []byte) (cleanBuf []byte, err error) {
@@ -534,62 +574,26 @@ func (s *Store) getDeltaFromJSON(previous, current []byte) (delta []byte, err er
 	return jsonpatch.CreateMergePatch(previous, current)
 }
 
-func (s *Store) rewriteDeltas(deltaFilePath string, flag int, deltas []*inventoryapi.RawDelta) (err error) {
-	f, err := disk.OpenFile(deltaFilePath, flag, DATA_FILE_MODE)
-	if err != nil {
-		slog.WithField("path", deltaFilePath).WithError(err).Error("can't open delta journal file")
-		return
-	}
-	defer f.Close()
-
-	if len(deltas) > 0 {
-		var deltaBuf []byte
-		if deltaBuf, err = json.Marshal(deltas); err == nil {
-			// strip the square brackets, write as one blob
-			deltaBuf = bytes.Trim(deltaBuf, "[]")
-			err = s.writeDelta(f, deltaBuf)
-		}
+// marshalDeltas formats deltas as they are stored: a list of JSON objects, each followed by a
+// comma, without the surrounding square brackets.
+func marshalDeltas(deltas []*inventoryapi.RawDelta) ([]byte, error) {
+	if len(deltas) == 0 {
+		return []byte{}, nil
 	}
-	return
-}
-
-func (s *Store) writeDelta(f *os.File, deltaBuf []byte) (err error) {
-	if _, err = f.Write(deltaBuf); err != nil {
-		slog.WithError(err).Error("can't write journal entry")
-		return
-	}
-	if _, err = f.Write([]byte{','}); err != nil {
-		slog.WithError(err).Error("can't write journal entry terminator")
-	}
-	return
-}
-
-func (s *Store) storeDelta(pluginItem *PluginInfo, entityKey string, d delta) (err error) {
-	// FS mgmt
-	dir := s.cachedDirPath(pluginItem, entityKey)
-	if err = disk.MkdirAll(dir, DATA_DIR_MODE); err != nil {
-		slog.WithFields(logrus.Fields{
-			"path":   dir,
-			"plugin": pluginItem.ID(),
-		}).WithError(err).Error("could not create cache directory")
-		return
-	}
-
-	file := s.DeltaFilePath(pluginItem, entityKey)
-	f, err := disk.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, DATA_FILE_MODE)
+	deltaBuf, err := json.Marshal(deltas)
 	if err != nil {
-		slog.WithFields(logrus.Fields{
-			"path":   file,
-			"plugin": pluginItem.ID(),
-		}).WithError(err).Error("can't open delta journal file")
-		return
+		return nil, err
 	}
-	defer f.Close()
+	// strip the square brackets, write as one blob
+	deltaBuf = bytes.Trim(deltaBuf, "[]")
+	return append(deltaBuf, ','), nil
+}
 
+func (s *Store) storeDelta(tx StorageTx, pluginItem *PluginInfo, entityKey string, d delta) (err error) {
 	// format raw diff
 	var diff map[string]interface{}
 	if err = json.Unmarshal(d.value, &diff); err != nil {
-		return fmt.Errorf("error unmarshaling file %s: %s", file, err)
+		return fmt.Errorf("error unmarshaling delta of plugin %s: %s", pluginItem.ID(), err)
 	}
 
 	// increase ID
@@ -608,7 +612,13 @@ func (s *Store) storeDelta(pluginItem *PluginInfo, entityKey string, d delta) (e
 	}
 	var deltaBuf []byte
 	if deltaBuf, err = json.Marshal(dRaw); err == nil {
-		err = s.writeDelta(f, deltaBuf)
+		err = tx.Append(PendingRecord, pluginItem, s.EntityFolder(entityKey), append(deltaBuf, ','))
+		if err != nil {
+			slog.WithFields(logrus.Fields{
+				"entityKey": entityKey,
+				"plugin":    pluginItem.ID(),
+			}).WithError(err).Error("can't write delta journal entry")
+		}
 	}
 
 	return
@@ -624,23 +634,20 @@ func (s *Store) wrapBuffer(buf []byte, openWrapChar, closeWrapChar byte, strip s
 }
 
 // The deltas are a list of json hashes WITHOUT the surrounding square brackets
-func (s *Store) readIndividualPluginDeltas(plugin *PluginInfo, entityKey string) (buf []byte, err error) {
-	deltaFilePath := s.DeltaFilePath(plugin, entityKey)
-	if _, err = os.Stat(deltaFilePath); err == nil {
-		if buf, err = ioutil.ReadFile(deltaFilePath); err != nil {
-			slog.WithField("path", deltaFilePath).WithError(err).Error("can't read delta file")
-		}
+func (s *Store) readIndividualPluginDeltas(tx StorageTx, plugin *PluginInfo, entityKey string) (buf []byte, err error) {
+	if buf, err = tx.Get(PendingRecord, plugin, s.EntityFolder(entityKey)); err != nil {
+		slog.WithField("plugin", plugin.ID()).WithError(err).Error("can't read deltas")
 	}
 	return
 }
 
 // Returns deltas grouped in buffers of size <= maxGroupSize
-func (s *Store) readAllPluginDeltas(plugins []*PluginInfo, entityKey string) ([][]byte, error) {
+func (s *Store) readAllPluginDeltas(tx StorageTx, plugins []*PluginInfo, entityKey string) ([][]byte, error) {
 	allDeltas := make([][]byte, 0)
 	buf := make([]byte, 0)
 	bufferSize := 0
 	for _, plugin := range plugins {
-		diff, err := s.readIndividualPluginDeltas(plugin, entityKey)
+		diff, err := s.readIndividualPluginDeltas(tx, plugin, entityKey)
 		diffLen := len(diff)
 		if err == nil && diffLen > 0 {
 			if bufferSize+diffLen > s.maxInventorySize {
@@ -659,10 +666,10 @@ func (s *Store) readAllPluginDeltas(plugins []*PluginInfo, entityKey string) ([]
 }
 
 // Legacy method that implemented delta reading before the splitting mechanism was added
-func (s *Store) readAllPluginDeltasWithoutSplitting(plugins []*PluginInfo, entityKey string) (buf []byte, err error) {
+func (s *Store) readAllPluginDeltasWithoutSplitting(tx StorageTx, plugins []*PluginInfo, entityKey string) (buf []byte, err error) {
 	buf = []byte{}
 	for _, plugin := range plugins {
-		diff, err := s.readIndividualPluginDeltas(plugin, entityKey)
+		diff, err := s.readIndividualPluginDeltas(tx, plugin, entityKey)
 		if err == nil && len(diff) > 0 {
 			buf = append(buf, diff...)
 		}
@@ -670,21 +677,19 @@ func (s *Store) readAllPluginDeltasWithoutSplitting(plugins []*PluginInfo, entit
 	return buf, nil
 }
 
-func (s *Store) cleanPluginDeltas(plugins []*PluginInfo, entityKey string) (err error) {
+func (s *Store) cleanPluginDeltas(tx StorageTx, plugins []*PluginInfo, entityKey string) (err error) {
 	for _, plugin := range plugins {
 		var delta inventoryapi.RawDelta
-		if buf, err := s.readIndividualPluginDeltas(plugin, entityKey); err == nil {
+		if buf, err := s.readIndividualPluginDeltas(tx, plugin, entityKey); err == nil {
 			if err = json.Unmarshal(buf, &delta); err != nil {
-				deltaFilePath := s.DeltaFilePath(plugin, entityKey)
 				cslog := slog.WithFieldsF(func() logrus.Fields {
 					return logrus.Fields{
 						"entity": entityKey,
 						"plugin": plugin.ID(),
-						"path":   deltaFilePath,
 					}
 				})
 
-				if err = disk.WriteFile(deltaFilePath, []byte(``), DATA_FILE_MODE); err != nil {
+				if err = tx.Put(PendingRecord, plugin, s.EntityFolder(entityKey), []byte(``)); err != nil {
 					cslog.WithError(err).Error("can't clean delta file")
 					return err
 				}
@@ -700,24 +705,28 @@ func (s *Store) ReadDeltas(entityKey string) ([]inventoryapi.RawDeltaBlock, erro
 	// Walk through all active plugins and see if each has any deltas,
 	// and collect them if so
 	llog := slog.WithField("entity", entityKey)
-	reapedPlugins, err := s.collectPluginFiles(s.CacheDir, entityKey, helpers.JsonFilesRegexp)
-	if err != nil {
-		llog.WithError(err).WithField("path", s.CacheDir).Error("can't get plugins in cache directory")
-		return nil, err
-	}
-
+	var reapedPlugins []*PluginInfo
 	var buffers [][]byte
-	if s.maxInventorySize <= DisableInventorySplit {
-		buffer, err := s.readAllPluginDeltasWithoutSplitting(reapedPlugins, entityKey)
+	err := s.repo.View(func(tx StorageTx) (err error) {
+		reapedPlugins, err = tx.Plugins(s.EntityFolder(entityKey))
 		if err != nil {
-			return nil, err
+			llog.WithError(err).Error("can't get plugins in cache")
+			return err
 		}
-		buffers = [][]byte{buffer}
-	} else {
-		buffers, err = s.readAllPluginDeltas(reapedPlugins, entityKey)
-		if err != nil {
-			return nil, err
+
+		if s.maxInventorySize <= DisableInventorySplit {
+			buffer, err := s.readAllPluginDeltasWithoutSplitting(tx, reapedPlugins, entityKey)
+			if err != nil {
+				return err
+			}
+			buffers = [][]byte{buffer}
+		} else {
+			buffers, err = s.readAllPluginDeltas(tx, reapedPlugins, entityKey)
 		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	deltas := make([]inventoryapi.RawDeltaBlock, 0)
@@ -726,7 +735,10 @@ func (s *Store) ReadDeltas(entityKey string) ([]inventoryapi.RawDeltaBlock, erro
 		buf = s.wrapBuffer(buf, '[', ']', ",")
 		if err = json.Unmarshal(buf, &deltasGroup); err != nil {
 			llog.WithError(err).Error("ReadDeltas can't unmarshal raw deltas, cleaning out file")
-			if err2 := s.cleanPluginDeltas(reapedPlugins, entityKey); err2 != nil {
+			err2 := s.repo.Update(func(tx StorageTx) error {
+				return s.cleanPluginDeltas(tx, reapedPlugins, entityKey)
+			})
+			if err2 != nil {
 				llog.WithError(err2).Error("can't clean plugin deltas")
 			}
 			return nil, err
@@ -756,44 +768,33 @@ func (s *Store) RemoveEntity(entityKey string) error {
 
 // RemoveEntityFolders removes the entity cached storage from the entities whose folder is equal to the argument.
 func (s *Store) RemoveEntityFolders(entityFolder string) error {
-	errStrings := s.removeEntityEntries(s.DataDir, entityFolder)
-	errStrings = append(errStrings, s.removeEntityEntries(s.CacheDir, entityFolder)...)
-	if len(errStrings) > 0 {
-		return fmt.Errorf("errors happened while removing entity folders: %s", strings.Join(errStrings, ", "))
-	}
-	return nil
-}
-
-func (s *Store) removeEntityEntries(dir, entityFolder string) (errStrings []string) {
-	errStrings = make([]string, 0)
-	// For all the plugins in the given directory
-	plugins, err := ioutil.ReadDir(dir)
+	errStrings := removeEntityEntries(s.DataDir, entityFolder)
+	err := s.repo.Update(func(tx StorageTx) error {
+		return tx.RemoveEntity(entityFolder)
+	})
 	if err != nil {
 		errStrings = append(errStrings, err.Error())
-		return errStrings
 	}
-	for _, plugin := range plugins {
-		if plugin.IsDir() && !nonEntityFolders[plugin.Name()] {
-			// For all the entities under the plugin folder, remove those whose directory name is planned for removal
-			entityPath := filepath.Join(dir, plugin.Name(), entityFolder)
-			if _, err := os.Stat(entityPath); err == nil {
-				helpers.DebugStackf("removing: %s", entityPath)
-				if err = os.RemoveAll(entityPath); err != nil {
-					errStrings = append(errStrings, err.Error())
-				}
-			}
-		}
+	if s.history != nil {
+		errStrings = append(errStrings, removeEntityEntries(s.history.dir, entityFolder)...)
+	}
+	if len(errStrings) > 0 {
+		return fmt.Errorf("errors happened while removing entity folders: %s", strings.Join(errStrings, ", "))
 	}
-	return errStrings
+	return nil
 }
 
 // ScanEntityFolders returns a set of those entities that have been found in the different plugin folders.
 func (s *Store) ScanEntityFolders() (map[string]interface{}, error) {
-	entities, err := s.fetchEntities(s.DataDir)
+	entities, err := fetchEntities(s.DataDir)
 	if err != nil {
 		return nil, err
 	}
-	cacheEntities, err := s.fetchEntities(s.CacheDir)
+	var cacheEntities map[string]interface{}
+	err = s.repo.View(func(tx StorageTx) (err error) {
+		cacheEntities, err = tx.Entities()
+		return
+	})
 	if err != nil {
 		return entities, err
 	}
@@ -803,36 +804,11 @@ func (s *Store) ScanEntityFolders() (map[string]interface{}, error) {
 	return entities, nil
 }
 
-func (s *Store) fetchEntities(dir string) (map[string]interface{}, error) {
-	entities := make(map[string]interface{})
-
-	// For all the plugins in the given directory
-	plugins, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-	for _, plugin := range plugins {
-		if plugin.IsDir() && !nonEntityFolders[plugin.Name()] {
-			// For all the entities under the plugin folder, adds them to the map
-			entityFolders, err := ioutil.ReadDir(filepath.Join(dir, plugin.Name()))
-			if err != nil {
-				return entities, err
-			}
-			for _, folder := range entityFolders {
-				if folder.IsDir() {
-					entities[folder.Name()] = true
-				}
-			}
-		}
-	}
-	return entities, nil
-}
-
 // getPluginDelta returns the difference between the source inventory
 // json and the cache inventory json of the given plugin from the given
 // entity. If there is no difference, then an empty JSON object is
 // retured `{}`.
-func (s *Store) newPluginDelta(pluginItem *PluginInfo, entityKey string) (delta, error) {
+func (s *Store) newPluginDelta(tx StorageTx, pluginItem *PluginInfo, entityKey string) ([]byte, delta, error) {
 	sourceFilePath := s.SourceFilePath(pluginItem, entityKey)
 	sourceB, err := ioutil.ReadFile(sourceFilePath)
 	if err != nil {
@@ -840,23 +816,20 @@ func (s *Store) newPluginDelta(pluginItem *PluginInfo, entityKey string) (delta,
 			"entityKey": entityKey,
 			"plugin":    pluginItem.ID(),
 		}).WithError(err).Error("can't read inventory source")
-		return delta{}, err
+		return nil, delta{}, err
 	}
 
-	cacheFilePath := s.cachedFilePath(pluginItem, entityKey)
-	_, err = os.Stat(cacheFilePath)
-	if os.IsNotExist(err) {
-		return delta{value: sourceB, full: true}, nil
-	}
-
-	cacheB, err := ioutil.ReadFile(cacheFilePath)
+	cacheB, err := tx.Get(CacheRecord, pluginItem, s.EntityFolder(entityKey))
 	if err != nil {
 		slog.WithError(err).Error("can't read inventory cache")
-		return delta{}, err
+		return nil, delta{}, err
+	}
+	if cacheB == nil {
+		return sourceB, delta{value: sourceB, full: true}, nil
 	}
 
 	del, err := s.getDeltaFromJSON(cacheB, sourceB)
-	return delta{value: del, full: false}, err
+	return sourceB, delta{value: del, full: false}, err
 }
 
 // updatePluginInventoryCache updates the inventory cache file of the
@@ -874,44 +847,58 @@ func (s *Store) updatePluginInventoryCache(pi *PluginInfo, entityKey string) (up
 		return logrus.Fields{"entityKey": entityKey, "plugin": pi.ID()}
 	})
 
-	del, err := s.newPluginDelta(pi, entityKey)
-	if err != nil {
-		llog.WithError(err).Error("can't calculate delta from JSON files")
-		// Corrupted JSON. Removing plugin folder and deltas
-		if err := s.clearPluginDeltaStore(pi, entityKey); err != nil {
-			llog.WithError(err).Warn("can't clear plugin delta store")
+	var sourceB []byte
+	txErr := s.repo.Update(func(tx StorageTx) error {
+		var del delta
+		sourceB, del, err = s.newPluginDelta(tx, pi, entityKey)
+		if err != nil {
+			llog.WithError(err).Error("can't calculate delta from JSON files")
+			// Corrupted JSON. Removing plugin folder and deltas
+			if err := s.clearPluginDeltaStore(tx, pi, entityKey); err != nil {
+				llog.WithError(err).Warn("can't clear plugin delta store")
+			}
+			if err := os.RemoveAll(s.SourceFilePath(pi, entityKey)); err != nil {
+				llog.WithError(err).Warn("can't remove source file path")
+			}
+			return nil
 		}
-		if err := os.RemoveAll(s.SourceFilePath(pi, entityKey)); err != nil {
-			llog.WithError(err).Warn("can't remove source file path")
+
+		if bytes.Equal(EMPTY_DELTA, del.value) {
+			updated = false
+			return nil
 		}
-		return
-	}
 
-	if bytes.Equal(EMPTY_DELTA, del.value) {
-		updated = false
-		return
-	}
+		err = s.storeDelta(tx, pi, entityKey, del)
+		if err != nil {
+			llog.WithError(err).Error("can't commit inventory")
+		}
 
-	err = s.storeDelta(pi, entityKey, del)
-	if err != nil {
-		llog.WithError(err).Error("can't commit inventory")
+		err = tx.Put(CacheRecord, pi, s.EntityFolder(entityKey), sourceB)
+		if err != nil {
+			llog.WithError(err).Error("replacing plugin cache file failed")
+		}
+		return nil
+	})
+	if txErr != nil {
+		llog.WithError(txErr).Error("can't update inventory cache")
+		return updated, txErr
 	}
-
-	err = s.replacePluginCacheFileWithSource(pi, entityKey)
 	if err != nil {
-		llog.WithError(err).Error("replacing plugin cache file failed")
+		return
 	}
 
+	s.updateHistory(pi, entityKey, sourceB, updated, llog)
 	return
 }
 
-// replacePluginCacheFileWithSource replaces the given entity plugin
-// inventory cache file with the source file.  It deletes the cache file
-// if it already exists.
-func (s *Store) replacePluginCacheFileWithSource(pluginItem *PluginInfo, entityKey string) error {
-	sourceFilePath := s.SourceFilePath(pluginItem, entityKey)
-	cachedFilePath := s.cachedFilePath(pluginItem, entityKey)
-	return helpers.CopyFile(sourceFilePath, cachedFilePath)
+// updateHistory records the plugin inventory in the history, when enabled.
+func (s *Store) updateHistory(pi *PluginInfo, entityKey string, inventory []byte, updated bool, llog log.Entry) {
+	if s.history == nil {
+		return
+	}
+	if err := s.recordHistory(pi, entityKey, inventory, updated); err != nil {
+		llog.WithError(err).Warn("can't record inventory history snapshot")
+	}
 }
 
 // UpdatePluginsInventoryCache looks for all the plugins of the given
@@ -960,6 +947,21 @@ func (s *Store) UpdatePluginsInventoryCache(entityKey string) (err error) {
 	return
 }
 
+// ClearPluginsSources empties the inventory json source of all the plugins of the given entityKey, so
+// the next update of the inventory cache generates the deltas that delete all their items.
+func (s *Store) ClearPluginsSources(entityKey string) error {
+	plugins, err := s.collectPluginFiles(s.DataDir, entityKey, helpers.JsonFilesRegexp)
+	if err != nil {
+		return err
+	}
+	for _, pluginItem := range plugins {
+		if err := disk.WriteFile(s.SourceFilePath(pluginItem, entityKey), []byte("{}"), DATA_FILE_MODE); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // StorePluginOutput will take a PluginOutput blob and write it to the
 // data directory in JSON format
 func (s *Store) SavePluginSource(entityKey, category, term string, source map[string]interface{}) (err error) {
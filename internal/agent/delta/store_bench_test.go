@@ -0,0 +1,65 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package delta
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
+)
+
+const (
+	benchEntities = 200
+	benchPlugins  = 5
+)
+
+// benchmarkStore measures a full inventory cycle of many entities: storing the changed
+// inventory, reading the deltas and archiving them once sent.
+func benchmarkStore(b *testing.B, newStore func(dataDir string) *Store) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(b, err)
+	defer os.RemoveAll(dataDir)
+	s := newStore(dataDir)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for e := 0; e < benchEntities; e++ {
+			entityKey := fmt.Sprintf("entity:%d", e)
+			for p := 0; p < benchPlugins; p++ {
+				source := map[string]interface{}{
+					"item": map[string]interface{}{"value": i},
+				}
+				require.NoError(b, s.SavePluginSource(entityKey, "bench", fmt.Sprintf("plugin%d", p), source))
+			}
+			require.NoError(b, s.UpdatePluginsInventoryCache(entityKey))
+
+			deltas, err := s.ReadDeltas(entityKey)
+			require.NoError(b, err)
+			results := inventoryapi.DeltaStateMap{}
+			for _, d := range deltas[0] {
+				results[d.Source] = &inventoryapi.DeltaState{SendNextID: d.ID + 1}
+			}
+			s.UpdateState(entityKey, deltas[0], &results)
+			require.NoError(b, s.SaveState())
+		}
+	}
+}
+
+func BenchmarkStore_Files(b *testing.B) {
+	benchmarkStore(b, func(dataDir string) *Store {
+		return NewStore(dataDir, "localhost", maxInventorySize)
+	})
+}
+
+func BenchmarkStore_Bolt(b *testing.B) {
+	benchmarkStore(b, func(dataDir string) *Store {
+		s, err := NewBoltStore(dataDir, "localhost", maxInventorySize)
+		require.NoError(b, err)
+		b.Cleanup(func() { _ = s.Close() })
+		return s
+	})
+}
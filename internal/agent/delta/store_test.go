@@ -787,7 +787,9 @@ func TestCompactStoreTrimSentDelta(t *testing.T) {
 
 	ds := s.SetupSavedState(t)
 	ds.plugins["metadata/plugin"].setLastSentID(eKey, 2)
-	err := ds.archivePlugin(ds.plugins["metadata/plugin"], eKey)
+	err := ds.repo.Update(func(tx StorageTx) error {
+		return ds.archivePlugin(tx, ds.plugins["metadata/plugin"], eKey)
+	})
 	require.NoError(t, err)
 	size, err := ds.StorageSize(ds.CacheDir)
 	require.NoError(t, err)
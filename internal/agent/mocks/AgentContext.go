@@ -155,6 +155,11 @@ func (_m *AgentContext) IDLookup() host.IDLookup {
 	return r0
 }
 
+// InstanceGone provides a mock function with given fields: instanceID
+func (_m *AgentContext) InstanceGone(instanceID string) {
+	_m.Called(instanceID)
+}
+
 // Reconnect provides a mock function with given fields:
 func (_m *AgentContext) Reconnect() {
 	_m.Called()
@@ -64,3 +64,13 @@ func (p *patchReaper) Reap() {
 		return
 	}
 }
+
+// RemoveEntity reaps the deletion of all the inventory items of the entity, so they are removed from
+// the backend with the next submission. The entity storage has to be removed once the deltas are sent.
+func (p *patchReaper) RemoveEntity() error {
+	if err := p.store.ClearPluginsSources(p.entityKey); err != nil {
+		return err
+	}
+	p.Reap()
+	return nil
+}
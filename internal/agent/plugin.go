@@ -40,6 +40,7 @@ type PluginCommon struct {
 	Context            AgentContext // a reference to the calling agent context
 	External           bool         // If the plugin is an external plugin
 	ExternalPluginName string       // The external plugin name. Ex: com.newrelic.nginx
+	InstanceID         string       // The discovered integration instance, if any. Ex: a container ID
 	// Returns all the information related to the plugin, including
 	// runtime environment variables
 	DetailedLogFields func() logrus.Fields
@@ -79,6 +80,7 @@ type PluginOutput struct {
 	Entity        entity.Entity
 	Data          PluginInventoryDataset
 	NotApplicable bool
+	InstanceID    string // not empty: discovered integration instance reporting the data
 }
 
 func NewPluginOutput(id ids.PluginID, entity entity.Entity, data PluginInventoryDataset) PluginOutput {
@@ -124,7 +126,9 @@ func (pc *PluginCommon) EmitInventory(data PluginInventoryDataset, entity entity
 	_, txn := instrumentation.SelfInstrumentation.StartTransaction(goContext.Background(), "plugin.emit_inventory")
 	txn.AddAttribute("plugin_id", fmt.Sprintf("%s:%s", pc.ID.Category, pc.ID.Term))
 	defer txn.End()
-	pc.Context.SendData(NewPluginOutput(pc.ID, entity, data))
+	output := NewPluginOutput(pc.ID, entity, data)
+	output.InstanceID = pc.InstanceID
+	pc.Context.SendData(output)
 }
 
 func (pc *PluginCommon) EmitEvent(eventData map[string]interface{}, entityKey entity.Key) {
@@ -68,6 +68,8 @@ func (c *fakeContext) ActiveEntitiesChannel() chan string {
 	return make(chan string, 100)
 }
 
+func (c *fakeContext) InstanceGone(string) {}
+
 func (c *fakeContext) EntityKey() string {
 	return ""
 }
@@ -48,6 +48,8 @@ type Server struct {
 	logger     log.Entry
 	definition integration.Definition
 	emitter    emitter.Emitter
+	inventory  InventoryHistory
+	lifecycle  EntityLifecycle
 	readyCh    chan struct{}
 }
 
@@ -119,6 +121,12 @@ func (s *Server) Serve(ctx context.Context) {
 			router.GET(statusEntityAPIPath, s.handleEntity)
 			router.GET(statusAPIPath, s.handle(false))
 			router.GET(statusOnlyErrorsAPIPath, s.handle(true))
+			if s.inventory != nil {
+				router.GET(inventoryAPIPath, s.handleInventory)
+			}
+			if s.lifecycle != nil {
+				router.GET(statusLifecycleAPIPath, s.handleLifecycle)
+			}
 			// local only API
 			err := http.ListenAndServe(s.Status.address, router)
 			if err != nil {
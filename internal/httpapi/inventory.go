@@ -0,0 +1,111 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
+)
+
+const (
+	inventoryAPIPath = "/v1/inventory/:entity/:category/:term"
+	// localEntity addresses the agent entity in the inventory API path.
+	localEntity = "local"
+)
+
+// InventoryHistory provides the state of the inventory in the past.
+type InventoryHistory interface {
+	InventoryAt(entityKey, category, term string, at time.Time) (delta.Snapshot, error)
+	InventoryDiff(entityKey, category, term string, from, to time.Time) (delta.Diff, error)
+}
+
+// EnableInventoryHistory serves the inventory history from the status API.
+func (s *Server) EnableInventoryHistory(h InventoryHistory) {
+	s.inventory = h
+}
+
+// handleInventory returns the inventory of an entity plugin at the time in the "at" query
+// parameter, now by default. When the "diff" parameter is provided, the changes from that time
+// are returned instead.
+func (s *Server) handleInventory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	now := time.Now()
+	at, err := parseQueryTime(r.URL.Query().Get("at"), now)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid 'at' parameter: %s", err))
+		return
+	}
+
+	entityKey := ps.ByName("entity")
+	if entityKey == localEntity {
+		entityKey = ""
+	}
+	category, term := ps.ByName("category"), ps.ByName("term")
+	if err = delta.ValidatePlugin(category, term); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var res interface{}
+	if diff := r.URL.Query().Get("diff"); diff != "" {
+		var from time.Time
+		if from, err = parseQueryTime(diff, now); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid 'diff' parameter: %s", err))
+			return
+		}
+		res, err = s.inventory.InventoryDiff(entityKey, category, term, from, at)
+	} else {
+		res, err = s.inventory.InventoryAt(entityKey, category, term, at)
+	}
+	if err == delta.ErrNoHistory {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("fetching inventory history: %s", err))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		s.logger.WithError(err).Warn("couldn't encode inventory history")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if _, err = w.Write(b); err != nil {
+		s.logger.WithError(err).Warn("cannot write inventory history response")
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(responseError{Error: msg}); err != nil {
+		s.logger.WithError(err).Warn("couldn't encode a failed response")
+	}
+}
+
+// parseQueryTime accepts RFC3339 times, unix timestamps in seconds and durations, which are
+// subtracted from now (e.g. "2h" is two hours ago). An empty value means now.
+func parseQueryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	ago, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC3339 time, unix timestamp or duration: %q", value)
+	}
+	return now.Add(-ago), nil
+}
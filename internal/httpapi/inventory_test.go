@@ -0,0 +1,117 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp/testemit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHistory struct {
+	entityKey, category, term string
+	from, to                  time.Time
+}
+
+func (f *fakeHistory) InventoryAt(entityKey, category, term string, at time.Time) (delta.Snapshot, error) {
+	f.entityKey, f.category, f.term, f.to = entityKey, category, term, at
+	if category == "missing" {
+		return delta.Snapshot{}, delta.ErrNoHistory
+	}
+	return delta.Snapshot{Time: at, Inventory: map[string]interface{}{"vm.swappiness": map[string]interface{}{"value": "60"}}}, nil
+}
+
+func (f *fakeHistory) InventoryDiff(entityKey, category, term string, from, to time.Time) (delta.Diff, error) {
+	f.entityKey, f.category, f.term, f.from, f.to = entityKey, category, term, from, to
+	return delta.Diff{From: from, To: to, Added: map[string]interface{}{"kernel.panic": map[string]interface{}{"value": "1"}}}, nil
+}
+
+func serveInventory(t *testing.T, h InventoryHistory, url string) *httptest.ResponseRecorder {
+	s, err := NewServer(nil, &testemit.RecordEmitter{})
+	require.NoError(t, err)
+	s.EnableInventoryHistory(h)
+
+	router := httprouter.New()
+	router.GET(inventoryAPIPath, s.handleInventory)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	return rec
+}
+
+func TestServer_handleInventory_At(t *testing.T) {
+	h := &fakeHistory{}
+	rec := serveInventory(t, h, "/v1/inventory/local/config/sysctl?at=2022-04-15T05:20:00Z")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "", h.entityKey)
+	assert.Equal(t, "config", h.category)
+	assert.Equal(t, "sysctl", h.term)
+	assert.True(t, h.to.Equal(time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)))
+
+	var snapshot delta.Snapshot
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&snapshot))
+	assert.Equal(t, map[string]interface{}{"vm.swappiness": map[string]interface{}{"value": "60"}}, snapshot.Inventory)
+}
+
+func TestServer_handleInventory_Diff(t *testing.T) {
+	h := &fakeHistory{}
+	before := time.Now()
+	rec := serveInventory(t, h, "/v1/inventory/my-entity/packages/dpkg?diff=2h")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "my-entity", h.entityKey)
+	assert.False(t, h.to.Before(before), "defaults to now")
+	assert.Equal(t, 2*time.Hour, h.to.Sub(h.from))
+
+	var d delta.Diff
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+	assert.Contains(t, d.Added, "kernel.panic")
+}
+
+func TestServer_handleInventory_Errors(t *testing.T) {
+	rec := serveInventory(t, &fakeHistory{}, "/v1/inventory/local/config/sysctl?at=yesterday")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serveInventory(t, &fakeHistory{}, "/v1/inventory/local/missing/sysctl")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_handleInventory_PathTraversal(t *testing.T) {
+	for _, url := range []string{
+		"/v1/inventory/local/%2E%2E/sysctl",
+		"/v1/inventory/local/config/%2E%2E",
+		"/v1/inventory/local/config/..%5C..%5Csecrets",
+		"/v1/inventory/local/./sysctl",
+	} {
+		h := &fakeHistory{}
+		rec := serveInventory(t, h, url)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, url)
+		assert.Empty(t, h.category, url)
+	}
+}
+
+func TestParseQueryTime(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+
+	for value, expected := range map[string]time.Time{
+		"":                     now,
+		"1649990000":           time.Unix(1649990000, 0),
+		"90m":                  now.Add(-90 * time.Minute),
+		"2022-04-15T05:20:00Z": time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC),
+	} {
+		got, err := parseQueryTime(value, now)
+		require.NoError(t, err, value)
+		assert.True(t, expected.Equal(got), value)
+	}
+
+	_, err := parseQueryTime("yesterday", now)
+	assert.Error(t, err)
+}
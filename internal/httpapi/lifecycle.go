@@ -0,0 +1,37 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/newrelic/infrastructure-agent/pkg/entity/register"
+)
+
+const statusLifecycleAPIPath = "/v1/status/entities/lifecycle"
+
+// EntityLifecycle provides the state of the lifecycle of the entities registered by the agent.
+type EntityLifecycle interface {
+	Status() register.LifecycleStatus
+}
+
+// EnableEntityLifecycle serves the entities lifecycle state from the status API.
+func (s *Server) EnableEntityLifecycle(l EntityLifecycle) {
+	s.lifecycle = l
+}
+
+// handleLifecycle returns the tracked entities count and the latest decommissioned entities.
+func (s *Server) handleLifecycle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	b, err := json.Marshal(s.lifecycle.Status())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		s.logger.WithError(err).Warn("couldn't encode entities lifecycle")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if _, err = w.Write(b); err != nil {
+		s.logger.WithError(err).Warn("cannot write entities lifecycle response")
+	}
+}
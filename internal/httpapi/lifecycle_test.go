@@ -0,0 +1,48 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp/testemit"
+	"github.com/newrelic/infrastructure-agent/pkg/entity/register"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLifecycle struct {
+	status register.LifecycleStatus
+}
+
+func (f *fakeLifecycle) Status() register.LifecycleStatus {
+	return f.status
+}
+
+func TestServer_handleLifecycle(t *testing.T) {
+	s, err := NewServer(nil, &testemit.RecordEmitter{})
+	require.NoError(t, err)
+	s.EnableEntityLifecycle(&fakeLifecycle{status: register.LifecycleStatus{
+		TTL:     "24h0m0s",
+		Tracked: 3,
+		Decommissioned: []register.DecommissionedEntity{
+			{TrackedEntity: register.TrackedEntity{ID: 10, Key: "container:a"}},
+		},
+	}})
+
+	router := httprouter.New()
+	router.GET(statusLifecycleAPIPath, s.handleLifecycle)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, statusLifecycleAPIPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status register.LifecycleStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.Equal(t, 3, status.Tracked)
+	require.Len(t, status.Decommissioned, 1)
+	assert.Equal(t, "container:a", string(status.Decommissioned[0].Key))
+}
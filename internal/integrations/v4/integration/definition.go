@@ -11,6 +11,7 @@ import (
 
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/executor"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/when"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
 	cfgreq "github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/track/ctx"
@@ -43,6 +44,7 @@ type Definition struct {
 	WhenConditions  []when.Condition
 	CmdChanReq      *ctx.CmdChannelRequest // not empty: command-channel run/stop integration requests
 	CfgProtocol     *cfgreq.Context
+	InstanceID      string // not empty: discovered instance whose data is being emitted
 	runnable        executor.Executor
 	newTempFile     func(template []byte) (string, error)
 }
@@ -100,14 +102,25 @@ func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, discove
 	}
 
 	// apply discovered data to run multiple instances
-	var tasksOutput []Output
+	instances, err := d.Instances(bindVals, discoveryInfo)
+	if err != nil {
+		return nil, err
+	}
 
-	// merges both runnable configuration and config template (if any) to avoid having different
-	// discoverable
-	type discoveredConfig struct {
-		Executor       executor.Executor
-		ConfigTemplate []byte
+	var tasksOutput []Output
+	for _, instance := range instances {
+		output, err := instance.Run(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tasksOutput = append(tasksOutput, output)
 	}
+	return tasksOutput, nil
+}
+
+// Instances applies the discovered data to the definition, returning an instance per discovery match.
+func (d *Definition) Instances(bindVals *databind.Values, discoveryInfo databind.DiscovererInfo) ([]Instance, error) {
+	logger := elog.WithField("integration_name", d.Name)
 
 	// used to post-process "${config.path}" appearances only if we have found it previously
 	foundConfigPath := false
@@ -128,6 +141,7 @@ func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, discove
 		WithField("discovery_matchers", discoveryInfo.Matchers).
 		Debug("Running through all discovery matches.")
 
+	instances := make([]Instance, 0, len(matches))
 	for _, ir := range matches {
 		dc, ok := ir.Variables.(discoveredConfig)
 		if !ok { // should never happen, but left here for type safety
@@ -135,48 +149,85 @@ func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, discove
 				Warn("can't execute integration due to an unexpected Executor type")
 			continue
 		}
+		instances = append(instances, Instance{
+			ID:              ir.InstanceID,
+			ExtraLabels:     ir.MetricAnnotations,
+			EntityRewrite:   ir.EntityRewrites,
+			config:          dc,
+			foundConfigPath: foundConfigPath,
+			newTempFile:     d.newTempFile,
+			logger:          logger,
+		})
+	}
+	return instances, nil
+}
 
-		var removeFile func(<-chan struct{})
-		if dc.ConfigTemplate != nil {
-			templateFile, err := d.newTempFile(dc.ConfigTemplate)
-			if err != nil {
-				return nil, err
-			}
-			// Setting to remove this file after the integration has finished
-			removeFile = removeTempFile(templateFile)
-
-			// If we previously detected some "${config.path}" placeholder in the arguments
-			// or the environment, we look again for it and replace it by the
-			// template file. Otherwise, we set the default "CONFIG_PATH"
-			// environment variable
-			if foundConfigPath {
-				// replacing on the environment
-				for key, value := range dc.Executor.Cfg.Environment {
-					if strings.Contains(value, configPathHolder) {
-						dc.Executor.Cfg.Environment[key] = strings.Replace(value, configPathHolder, templateFile, -1)
-					}
+// merges both runnable configuration and config template (if any) to avoid having different
+// discoverable
+type discoveredConfig struct {
+	Executor       executor.Executor
+	ConfigTemplate []byte
+}
+
+// Instance is a definition bound to the data of a discovery match.
+type Instance struct {
+	// ID identifies the discovered item across discovery cycles (e.g. its container ID). It is
+	// empty when the discovery source can't tell the items apart.
+	ID            string
+	ExtraLabels   data.Map
+	EntityRewrite []data.EntityRewrite
+
+	config          discoveredConfig
+	foundConfigPath bool
+	newTempFile     func(template []byte) (string, error)
+	logger          log.Entry
+}
+
+// Run executes the instance, writing its config template (if any) into a temporary file that is
+// removed when the execution finishes.
+func (i *Instance) Run(ctx context.Context) (Output, error) {
+	dc := i.config
+	dc.Executor = dc.Executor.DeepClone()
+
+	var removeFile func(<-chan struct{})
+	if dc.ConfigTemplate != nil {
+		templateFile, err := i.newTempFile(dc.ConfigTemplate)
+		if err != nil {
+			return Output{}, err
+		}
+		// Setting to remove this file after the integration has finished
+		removeFile = removeTempFile(templateFile)
+
+		// If we previously detected some "${config.path}" placeholder in the arguments
+		// or the environment, we look again for it and replace it by the
+		// template file. Otherwise, we set the default "CONFIG_PATH"
+		// environment variable
+		if i.foundConfigPath {
+			// replacing on the environment
+			for key, value := range dc.Executor.Cfg.Environment {
+				if strings.Contains(value, configPathHolder) {
+					dc.Executor.Cfg.Environment[key] = strings.Replace(value, configPathHolder, templateFile, -1)
 				}
-				// replacing on the command line arguments
-				for i, value := range dc.Executor.Args {
-					if strings.Contains(value, configPathHolder) {
-						dc.Executor.Args[i] = strings.Replace(value, configPathHolder, templateFile, -1)
-					}
+			}
+			// replacing on the command line arguments
+			for idx, value := range dc.Executor.Args {
+				if strings.Contains(value, configPathHolder) {
+					dc.Executor.Args[idx] = strings.Replace(value, configPathHolder, templateFile, -1)
 				}
-			} else {
-				dc.Executor.Cfg.Environment[configPathEnv] = templateFile
 			}
 		} else {
-			logger.Debug("Found a nil ConfigTemplate.")
+			dc.Executor.Cfg.Environment[configPathEnv] = templateFile
 		}
+	} else {
+		i.logger.Debug("Found a nil ConfigTemplate.")
+	}
 
-		logger.Debug("Executing task.")
-		taskOutput := dc.Executor.Execute(ctx, nil, nil)
-		if removeFile != nil {
-			go removeFile(taskOutput.Done)
-		}
-		tasksOutput = append(tasksOutput, Output{Receive: taskOutput, ExtraLabels: ir.MetricAnnotations, EntityRewrite: ir.EntityRewrites})
+	i.logger.Debug("Executing task.")
+	taskOutput := dc.Executor.Execute(ctx, nil, nil)
+	if removeFile != nil {
+		go removeFile(taskOutput.Done)
 	}
-	return tasksOutput, nil
+	return Output{Receive: taskOutput, ExtraLabels: i.ExtraLabels, EntityRewrite: i.EntityRewrite, InstanceID: i.ID}, nil
 }
 
 // remoteTempFile returns a function that removes the file corresponding to the passed path when the provided channel
@@ -45,6 +45,7 @@ type Output struct {
 	Receive       executor.OutputReceive
 	ExtraLabels   data.Map
 	EntityRewrite []data.EntityRewrite
+	InstanceID    string
 }
 
 // InstancesLookup helps looking for integration executables that are not explicitly
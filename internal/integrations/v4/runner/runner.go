@@ -64,6 +64,23 @@ type runner struct {
 	cache          cache.Cache
 	terminateQueue chan<- string
 	idLookup       host.IDLookup
+	instances      map[string]*discoveredInstance // key: instance ID
+}
+
+// discoveredInstance is the execution of an integration instance identified by discovery (e.g. a
+// container), which is kept across discovery cycles.
+type discoveredInstance struct {
+	cancel context.CancelFunc // stops the instance process
+	done   chan struct{}      // closed when the instance process has finished
+}
+
+func (i *discoveredInstance) finished() bool {
+	select {
+	case <-i.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // NewRunner creates an integration runner instance.
@@ -89,6 +106,7 @@ func NewRunner(
 		terminateQueue: terminateQ,
 		cache:          cache.CreateCache(),
 		idLookup:       idLookup,
+		instances:      map[string]*discoveredInstance{},
 	}
 	if handleErrorsProvide != nil {
 		r.handleErrors = handleErrorsProvide()
@@ -208,7 +226,8 @@ func (r *runner) heartBeat() {
 // execute the integration and wait for all the possible instances (resulting of multiple dSources matches)
 // to finish
 // For long-time running integrations, avoids starting the next
-// discover-execute cycle until all the parallel processes have ended
+// discover-execute cycle until all the parallel processes have ended.
+// Discovered instances with a stable ID are not waited for, but tracked by executeInstances.
 func (r *runner) execute(ctx context.Context, matches *databind.Values, discoveryInfo databind.DiscovererInfo, pidWCh, exitCodeCh chan<- int) {
 	ctx, txn := instrumentation.SelfInstrumentation.StartTransaction(ctx, "integration.v4."+r.definition.Name)
 	if hostname, ok := r.definition.ExecutorConfig.Environment["HOSTNAME"]; ok {
@@ -221,14 +240,6 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 	defer txn.End()
 	def := r.definition
 
-	// If timeout configuration is set, wraps current context in a heartbeat-enabled timeout context
-	if def.TimeoutEnabled() {
-		var act contexts.Actuator
-		ctx, act = contexts.WithHeartBeat(ctx, def.Timeout, r.log)
-		r.setHeartBeat(act.HeartBeat)
-		defer act.HeartBeatStop()
-	}
-
 	// add hostID in the context to fetch and set in executor
 	hostID, err := r.idLookup.AgentShortEntityName()
 
@@ -239,6 +250,32 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 		r.log.WithError(err).Error("can't fetch host ID")
 	}
 
+	// Discovered instances that can be told apart are tracked, so their processes outlive the
+	// discovery cycles
+	if matches != nil && !def.SingleRun() {
+		instances, err := def.Instances(matches, discoveryInfo)
+		if err != nil {
+			txn.NoticeError(err)
+			r.log.WithError(err).Error("can't start integration")
+			return
+		}
+		if identified(instances) {
+			r.executeInstances(ctx, instances)
+			return
+		}
+		// the instances can't be told apart, so the tracked ones are stopped and reported as gone
+		// before all the instances are run as a whole
+		r.executeInstances(ctx, nil)
+	}
+
+	// If timeout configuration is set, wraps current context in a heartbeat-enabled timeout context
+	if def.TimeoutEnabled() {
+		var act contexts.Actuator
+		ctx, act = contexts.WithHeartBeat(ctx, def.Timeout, r.log)
+		r.setHeartBeat(act.HeartBeat)
+		defer act.HeartBeatStop()
+	}
+
 	// Runs all the matching integration instances
 	outputs, err := r.definition.Run(ctx, matches, discoveryInfo, pidWCh, exitCodeCh)
 	if err != nil {
@@ -255,7 +292,7 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 		o := out
 		go func(txn instrumentation.Transaction) {
 			defer wg.Done()
-			r.handleLines(ctx, o.Receive.Stdout, o.ExtraLabels, o.EntityRewrite)
+			r.handleLines(ctx, r.definition, o.Receive.Stdout, o.ExtraLabels, o.EntityRewrite, r.heartBeat)
 		}(txn)
 
 		go func(txn instrumentation.Transaction) {
@@ -286,6 +323,118 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 	return
 }
 
+// identified returns true if all the instances have a distinct ID, which is also the case when
+// nothing is discovered, so the tracked instances are reconciled with an empty discovery.
+func identified(instances []integration.Instance) bool {
+	ids := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		if instance.ID == "" || ids[instance.ID] {
+			return false
+		}
+		ids[instance.ID] = true
+	}
+	return true
+}
+
+// executeInstances keeps a process running for every discovered instance. The instances still
+// running from a previous discovery cycle, as long-running integrations do, are not started again,
+// while the ones that aren't discovered anymore are stopped and reported as gone.
+func (r *runner) executeInstances(ctx context.Context, instances []integration.Instance) {
+	discovered := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		discovered[instance.ID] = true
+	}
+	for id, running := range r.instances {
+		if !discovered[id] {
+			running.cancel()
+			delete(r.instances, id)
+			r.instanceGone(id)
+		}
+	}
+
+	for _, instance := range instances {
+		running, known := r.instances[instance.ID]
+		if known && !running.finished() {
+			continue
+		}
+		if !known {
+			r.instanceStarted(instance.ID)
+		}
+		r.instances[instance.ID] = r.startInstance(ctx, instance)
+	}
+}
+
+// startInstance executes a discovered instance, with its own timeout, if enabled.
+func (r *runner) startInstance(ctx context.Context, instance integration.Instance) *discoveredInstance {
+	ctx, cancel := context.WithCancel(ctx)
+	running := &discoveredInstance{cancel: cancel, done: make(chan struct{})}
+
+	ctx, txn := instrumentation.SelfInstrumentation.StartTransaction(ctx, "integration.v4."+r.definition.Name)
+	def := r.definition
+	def.InstanceID = instance.ID
+	ilog := r.log.WithField("instance_id", instance.ID)
+
+	heartBeat, heartBeatStop := func() {}, func() {}
+	if def.TimeoutEnabled() {
+		var act contexts.Actuator
+		ctx, act = contexts.WithHeartBeat(ctx, def.Timeout, ilog)
+		heartBeat, heartBeatStop = act.HeartBeat, act.HeartBeatStop
+	}
+
+	out, err := instance.Run(ctx)
+	if err != nil {
+		txn.NoticeError(err)
+		ilog.WithError(err).Error("can't start integration instance")
+		txn.End()
+		heartBeatStop()
+		cancel()
+		close(running.done)
+		return running
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		r.handleLines(ctx, def, out.Receive.Stdout, out.ExtraLabels, out.EntityRewrite, heartBeat)
+	}()
+	go func() {
+		defer wg.Done()
+		r.handleStderr(out.Receive.Stderr)
+	}()
+	go func() {
+		defer wg.Done()
+		r.handleErrors(ctx, out.Receive.Errors)
+	}()
+	go func() {
+		wg.Wait()
+		txn.End()
+		heartBeatStop()
+		cancel()
+		close(running.done)
+		ilog.Debug("Integration instance finished its execution.")
+	}()
+	return running
+}
+
+func (r *runner) instanceStarted(instanceID string) {
+	r.log.WithField("instance_id", instanceID).Debug("Discovered new integration instance.")
+	if lifecycle, ok := r.emitter.(emitter.InstanceLifecycle); ok {
+		def := r.definition
+		def.InstanceID = instanceID
+		lifecycle.InstanceStarted(def)
+	}
+}
+
+func (r *runner) instanceGone(instanceID string) {
+	r.log.WithField("instance_id", instanceID).Debug("Integration instance is gone.")
+	if lifecycle, ok := r.emitter.(emitter.InstanceLifecycle); ok {
+		def := r.definition
+		def.InstanceID = instanceID
+		lifecycle.InstanceGone(def)
+	}
+}
+
 func (r *runner) handleStderr(stderr <-chan []byte) {
 	for line := range stderr {
 		r.lastStderr.Add(line)
@@ -326,7 +475,7 @@ func (r *runner) logErrors(ctx context.Context, errs <-chan error) {
 	}
 }
 
-func (r *runner) handleLines(ctx context.Context, stdout <-chan []byte, extraLabels data.Map, entityRewrite []data.EntityRewrite) {
+func (r *runner) handleLines(ctx context.Context, def integration.Definition, stdout <-chan []byte, extraLabels data.Map, entityRewrite []data.EntityRewrite, heartBeat func()) {
 	txn := instrumentation.TransactionFromContext(ctx)
 	payloadSize := 0
 	for line := range stdout {
@@ -336,7 +485,7 @@ func (r *runner) handleLines(ctx context.Context, stdout <-chan []byte, extraLab
 
 		if isHeartBeat(line) {
 			llog.Debug("Received heartbeat.")
-			r.heartBeat()
+			heartBeat()
 			continue
 		}
 
@@ -376,11 +525,11 @@ func (r *runner) handleLines(ctx context.Context, stdout <-chan []byte, extraLab
 		}
 
 		payloadSize += len(line)
-		err := r.emitter.Emit(r.definition, extraLabels, entityRewrite, line)
+		err := r.emitter.Emit(def, extraLabels, entityRewrite, line)
 		if err != nil {
 			llog.WithError(err).Warn("Cannot emit integration payload")
 		} else {
-			r.heartBeat()
+			heartBeat()
 		}
 
 		r.healthCheck.Do(func() {
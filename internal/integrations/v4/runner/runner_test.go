@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -15,6 +16,8 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/integration"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp/testemit"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
@@ -182,3 +185,114 @@ func Test_runner_Run_handlesCfgProtocol(t *testing.T) {
 		return false
 	}, time.Second, 10*time.Millisecond)
 }
+
+// lifecycleEmitter records the lifecycle events of the integration instances
+type lifecycleEmitter struct {
+	testemit.RecordEmitter
+	mutex   sync.Mutex
+	started []string
+	gone    []string
+}
+
+func (l *lifecycleEmitter) InstanceStarted(definition integration.Definition) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.started = append(l.started, definition.InstanceID)
+}
+
+func (l *lifecycleEmitter) InstanceGone(definition integration.Definition) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.gone = append(l.gone, definition.InstanceID)
+}
+
+func containers(first, second string) *databind.Values {
+	values := databind.NewValues(nil,
+		databind.NewDiscovery(data.Map{"discovery.containerId": first}, nil, nil),
+		databind.NewDiscovery(data.Map{"discovery.containerId": second}, nil, nil))
+	return &values
+}
+
+func Test_runner_executeInstances(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	// GIVEN a long-running integration bound to container discovery
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "long-running",
+		Exec:         testhelp.Command(fixtures.BlockedCmd, "${discovery.containerId}"),
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	e := &lifecycleEmitter{}
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{})
+	r.log = illog.WithFields(LogFields(def))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// WHEN two containers are discovered
+	instances, err := def.Instances(containers("aaa", "bbb"), databind.DiscovererInfo{})
+	require.NoError(t, err)
+	r.executeInstances(ctx, instances)
+
+	// THEN a process is started for each of them
+	assert.ElementsMatch(t, []string{"aaa", "bbb"}, e.started)
+	require.Len(t, r.instances, 2)
+	first, gone := r.instances["aaa"], r.instances["bbb"]
+
+	// AND WHEN a container stops and another one starts
+	instances, err = def.Instances(containers("aaa", "ccc"), databind.DiscovererInfo{})
+	require.NoError(t, err)
+	r.executeInstances(ctx, instances)
+
+	// THEN the still running instance is kept, the new one is started and the gone one is stopped
+	assert.ElementsMatch(t, []string{"aaa", "bbb", "ccc"}, e.started)
+	assert.Equal(t, []string{"bbb"}, e.gone)
+	assert.Same(t, first, r.instances["aaa"])
+	assert.Contains(t, r.instances, "ccc")
+	assert.NotContains(t, r.instances, "bbb")
+	assert.Eventually(t, gone.finished, time.Second, 10*time.Millisecond)
+	assert.False(t, first.finished())
+
+	// AND the instances are stopped with the runner
+	cancel()
+	assert.Eventually(t, func() bool {
+		return r.instances["aaa"].finished() && r.instances["ccc"].finished()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_runner_execute_allInstancesGone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	// GIVEN a long-running integration bound to container discovery
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "long-running",
+		Exec:         testhelp.Command(fixtures.BlockedCmd, "${discovery.containerId}"),
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	e := &lifecycleEmitter{}
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{})
+	r.log = illog.WithFields(LogFields(def))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// WHEN two containers are discovered
+	r.execute(ctx, containers("aaa", "bbb"), databind.DiscovererInfo{}, nil, nil)
+	require.Len(t, r.instances, 2)
+	first, second := r.instances["aaa"], r.instances["bbb"]
+
+	// AND WHEN the discovery doesn't find any container
+	empty := databind.NewValues(nil)
+	r.execute(ctx, &empty, databind.DiscovererInfo{}, nil, nil)
+
+	// THEN all the instances are stopped and reported as gone
+	assert.ElementsMatch(t, []string{"aaa", "bbb"}, e.gone)
+	assert.Empty(t, r.instances)
+	assert.Eventually(t, func() bool {
+		return first.finished() && second.finished()
+	}, time.Second, 10*time.Millisecond)
+}
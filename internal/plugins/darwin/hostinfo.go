@@ -62,6 +62,9 @@ type HostInfoData struct {
 	AWSAccountID        string `json:"aws_account_id,omitempty"`
 	AWSAvailabilityZone string `json:"aws_availability_zone,omitempty"`
 	AWSImageID          string `json:"aws_image_id,omitempty"`
+	// region and availability zone of the clouds without specific attributes
+	CloudRegion           string `json:"cloud_region,omitempty"`
+	CloudAvailabilityZone string `json:"cloud_availability_zone,omitempty"`
 }
 
 func (hip *HostInfoData) SortKey() string {
@@ -200,7 +203,7 @@ func (hip *HostinfoPlugin) setCloudRegion(data *HostInfoData) (err error) {
 	}
 
 	region, err := hip.cloudHarvester.GetRegion()
-	if err != nil {
+	if err != nil && err != cloud.ErrMethodNotImplemented {
 		return fmt.Errorf("couldn't retrieve cloud region: %v", err)
 	}
 
@@ -214,8 +217,12 @@ func (hip *HostinfoPlugin) setCloudRegion(data *HostInfoData) (err error) {
 	case cloud.TypeAlibaba:
 		data.RegionAlibaba = region
 	default:
+		data.CloudRegion = region
+		if zone, zoneErr := hip.cloudHarvester.GetZone(); zoneErr == nil {
+			data.CloudAvailabilityZone = zone
+		}
 	}
-	return
+	return nil
 }
 
 // Only for AWS cloud instances
@@ -0,0 +1,177 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+)
+
+// Account risks
+const (
+	riskUIDZero       = "uid_zero"
+	riskEmptyPassword = "empty_password"
+)
+
+// nonLoginShells don't allow interactive logins.
+var nonLoginShells = map[string]bool{
+	"/bin/false":        true,
+	"/usr/bin/false":    true,
+	"/sbin/nologin":     true,
+	"/usr/sbin/nologin": true,
+	"/bin/sync":         true,
+}
+
+type Account struct {
+	Name               string `json:"id"`
+	UID                string `json:"uid"`
+	GID                string `json:"gid"`
+	Home               string `json:"home"`
+	Shell              string `json:"shell"`
+	LoginShell         bool   `json:"login_shell"`
+	LastPasswordChange string `json:"last_password_change,omitempty"`
+	Locked             bool   `json:"locked"`
+	Risky              bool   `json:"risky,omitempty"`
+	Risks              string `json:"risks,omitempty"`
+}
+
+func (a Account) SortKey() string {
+	return a.Name
+}
+
+type Group struct {
+	Name    string `json:"id"`
+	GID     string `json:"gid"`
+	Members string `json:"members,omitempty"`
+}
+
+func (g Group) SortKey() string {
+	return g.Name
+}
+
+// shadowEntry holds the /etc/shadow fields of an account. Password hashes are never stored.
+type shadowEntry struct {
+	emptyPassword bool
+	locked        bool
+	lastChange    string
+}
+
+// parseColonFile returns the fields of each line of a colon separated file (passwd, group, shadow).
+func parseColonFile(r io.Reader, minFields int) (entries [][]string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < minFields {
+			continue
+		}
+		entries = append(entries, fields)
+	}
+	return entries
+}
+
+func readColonFile(path string, minFields int) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseColonFile(f, minFields), nil
+}
+
+// readShadow parses /etc/shadow, which is only readable in root mode.
+func readShadow(etcPath string) map[string]shadowEntry {
+	shadow := make(map[string]shadowEntry)
+	entries, err := readColonFile(filepath.Join(etcPath, "shadow"), 3)
+	if err != nil {
+		seclog.WithError(err).Debug("Can't read shadow file.")
+		return shadow
+	}
+	for _, fields := range entries {
+		entry := shadowEntry{
+			emptyPassword: fields[1] == "",
+			locked:        strings.HasPrefix(fields[1], "!") || fields[1] == "*",
+		}
+		// the last change is expressed as days since the epoch
+		if days, err := strconv.ParseInt(fields[2], 10, 64); err == nil && days > 0 {
+			entry.lastChange = time.Unix(days*24*60*60, 0).UTC().Format("2006-01-02")
+		}
+		shadow[fields[0]] = entry
+	}
+	return shadow
+}
+
+// readAccounts returns the /etc/passwd accounts.
+func readAccounts(etcPath string) ([]Account, error) {
+	entries, err := readColonFile(filepath.Join(etcPath, "passwd"), 7)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]Account, 0, len(entries))
+	for _, fields := range entries {
+		accounts = append(accounts, Account{
+			Name:       fields[0],
+			UID:        fields[2],
+			GID:        fields[3],
+			Home:       fields[5],
+			Shell:      fields[6],
+			LoginShell: !nonLoginShells[fields[6]],
+		})
+	}
+	return accounts, nil
+}
+
+func accountsDataset(etcPath string) (dataset agent.PluginInventoryDataset, err error) {
+	accounts, err := readAccounts(etcPath)
+	if err != nil {
+		return nil, err
+	}
+	shadow := readShadow(etcPath)
+
+	for _, account := range accounts {
+		var risks []string
+		if account.UID == "0" && account.Name != "root" {
+			risks = append(risks, riskUIDZero)
+		}
+		if entry, ok := shadow[account.Name]; ok {
+			account.LastPasswordChange = entry.lastChange
+			account.Locked = entry.locked
+			if entry.emptyPassword && account.LoginShell {
+				risks = append(risks, riskEmptyPassword)
+			}
+		}
+		account.Risky = len(risks) > 0
+		account.Risks = strings.Join(risks, ",")
+		dataset = append(dataset, account)
+	}
+	return dataset, nil
+}
+
+func groupsDataset(etcPath string) (dataset agent.PluginInventoryDataset, err error) {
+	entries, err := readColonFile(filepath.Join(etcPath, "group"), 4)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range entries {
+		dataset = append(dataset, Group{
+			Name:    fields[0],
+			GID:     fields[2],
+			Members: fields[3],
+		})
+	}
+	return dataset, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Apk Plugin
+// Reads the Alpine package database and reports the installed packages
+package linux
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+const (
+	ApkInstalledDB = "/lib/apk/db/installed"
+)
+
+var apklog = log.WithPlugin("Apk")
+
+var apkPluginID = ids.PluginID{Category: "packages", Term: "apk"}
+
+type apkPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	dbPath    string
+}
+
+type ApkItem struct {
+	Name         string `json:"id"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture"`
+	Origin       string `json:"origin"`
+	License      string `json:"license"`
+	InstallSize  string `json:"installed_size"`
+	BuildTime    string `json:"build_epoch"`
+}
+
+func (p ApkItem) SortKey() string {
+	return p.Name
+}
+
+func NewApkPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &apkPlugin{
+		PluginCommon: agent.PluginCommon{ID: apkPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.ApkRefreshSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_PACKAGE_MGRS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		dbPath: ApkInstalledDB,
+	}
+}
+
+func (p *apkPlugin) fetchPackageInfo() (agent.PluginInventoryDataset, error) {
+	f, err := os.Open(p.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseApkInstalled(f)
+}
+
+// parseApkInstalled parses the apk installed database, which is composed of blank line separated
+// package stanzas whose lines are formatted as single letter keys followed by a colon and the value.
+func parseApkInstalled(r io.Reader) (packages agent.PluginInventoryDataset, err error) {
+	var item ApkItem
+	flush := func() {
+		if item.Name != "" {
+			packages = append(packages, item)
+		}
+		item = ApkItem{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		value := strings.TrimSpace(line[2:])
+		switch line[0] {
+		case 'P':
+			item.Name = value
+		case 'V':
+			item.Version = value
+		case 'A':
+			item.Architecture = value
+		case 'o':
+			item.Origin = value
+		case 'L':
+			item.License = value
+		case 'I':
+			item.InstallSize = value
+		case 't':
+			item.BuildTime = value
+		}
+	}
+	flush()
+
+	return packages, scanner.Err()
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *apkPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		apklog.Debug("Disabled.")
+		return
+	}
+
+	// apk rewrites the database through a rename, so the parent directory is watched instead
+	runPackageWatcher(&p.PluginCommon, apklog, p.frequency, []string{filepath.Dir(p.dbPath)}, p.fetchPackageInfo)
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+)
+
+func TestParseApkInstalled(t *testing.T) {
+	f, err := os.Open("testdata/apk_installed")
+	require.NoError(t, err)
+	defer f.Close()
+
+	packages, err := parseApkInstalled(f)
+	require.NoError(t, err)
+	require.Len(t, packages, 2)
+
+	assert.Equal(t, ApkItem{
+		Name:         "musl",
+		Version:      "1.2.3-r0",
+		Architecture: "x86_64",
+		Origin:       "musl",
+		License:      "MIT",
+		InstallSize:  "622592",
+		BuildTime:    "1649396308",
+	}, packages[0])
+	assert.Equal(t, "busybox", packages[1].SortKey())
+	assert.Equal(t, "GPL-2.0-only", packages[1].(ApkItem).License)
+}
+
+func TestParseApkInstalled_IgnoresMalformedStanzas(t *testing.T) {
+	packages, err := parseApkInstalled(strings.NewReader("V:1.0\nA:x86_64\n\nP:zlib\nV:1.2.12-r3\ngarbage\n"))
+	require.NoError(t, err)
+	require.Len(t, packages, 1)
+	assert.Equal(t, "zlib", packages[0].SortKey())
+	assert.Equal(t, "1.2.12-r3", packages[0].(ApkItem).Version)
+}
+
+func TestApkPlugin_FetchPackageInfo(t *testing.T) {
+	p := NewApkPlugin(testing2.NewMockAgent()).(*apkPlugin)
+	p.dbPath = "testdata/apk_installed"
+
+	packages, err := p.fetchPackageInfo()
+	require.NoError(t, err)
+	assert.Len(t, packages, 2)
+}
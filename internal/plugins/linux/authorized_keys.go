@@ -0,0 +1,207 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+)
+
+// Authorized key risks
+const (
+	riskDSAKey      = "dsa_key"
+	riskWeakRSAKey  = "weak_rsa_key"
+	riskRootAccount = "root_account"
+)
+
+// minRSAKeyBits is the minimum RSA key size not considered weak.
+const minRSAKeyBits = 2048
+
+var errInvalidSSHKey = errors.New("invalid ssh public key")
+
+type AuthorizedKey struct {
+	ID          string `json:"id"`
+	User        string `json:"user"`
+	File        string `json:"file"`
+	Type        string `json:"type"`
+	Bits        int    `json:"bits,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment,omitempty"`
+	Options     string `json:"options,omitempty"`
+	Risky       bool   `json:"risky,omitempty"`
+	Risks       string `json:"risks,omitempty"`
+}
+
+func (k AuthorizedKey) SortKey() string {
+	return k.ID
+}
+
+// isSSHKeyType tells whether a token of an authorized_keys line is a key type rather than options.
+func isSSHKeyType(token string) bool {
+	return strings.HasPrefix(token, "ssh-") || strings.HasPrefix(token, "ecdsa-") || strings.HasPrefix(token, "sk-")
+}
+
+// splitKeyOptions separates the leading options of an authorized_keys line, which may contain
+// quoted spaces such as `command="echo hi",from="10.0.0.1"`, from the rest of the line.
+func splitKeyOptions(line string) (options, rest string) {
+	if isSSHKeyType(strings.Fields(line)[0]) {
+		return "", line
+	}
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			return line[:i], strings.TrimSpace(line[i:])
+		}
+	}
+	return line, ""
+}
+
+// sshString reads an SSH wire format string (uint32 length followed by the bytes).
+func sshString(data []byte) (value, rest []byte, err error) {
+	if len(data) < 4 {
+		return nil, nil, errInvalidSSHKey
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint32(len(data)-4) < length {
+		return nil, nil, errInvalidSSHKey
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
+
+// rsaKeyBits returns the modulus size of an ssh-rsa public key blob: string type, mpint e, mpint n.
+func rsaKeyBits(blob []byte) (int, error) {
+	_, rest, err := sshString(blob)
+	if err != nil {
+		return 0, err
+	}
+	_, rest, err = sshString(rest)
+	if err != nil {
+		return 0, err
+	}
+	n, _, err := sshString(rest)
+	if err != nil {
+		return 0, err
+	}
+	return new(big.Int).SetBytes(n).BitLen(), nil
+}
+
+// parseAuthorizedKeys returns the keys of an authorized_keys file, fingerprinted as ssh-keygen -l does.
+func parseAuthorizedKeys(r io.Reader, user, file string) (keys []AuthorizedKey) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		options, rest := splitKeyOptions(line)
+		fields := strings.Fields(rest)
+		if len(fields) < 2 {
+			continue
+		}
+		blob, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			seclog.WithField("file", file).Debug("Skipping invalid authorized key.")
+			continue
+		}
+		sum := sha256.Sum256(blob)
+		key := AuthorizedKey{
+			User:        user,
+			File:        file,
+			Type:        fields[0],
+			Fingerprint: "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]),
+			Comment:     strings.Join(fields[2:], " "),
+			Options:     options,
+		}
+		key.ID = user + ":" + key.Fingerprint
+
+		var risks []string
+		switch key.Type {
+		case "ssh-rsa":
+			if bits, err := rsaKeyBits(blob); err == nil {
+				key.Bits = bits
+				if bits < minRSAKeyBits {
+					risks = append(risks, riskWeakRSAKey)
+				}
+			}
+		case "ssh-dss":
+			risks = append(risks, riskDSAKey)
+		}
+		if user == "root" {
+			risks = append(risks, riskRootAccount)
+		}
+		key.Risky = len(risks) > 0
+		key.Risks = strings.Join(risks, ",")
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// expandAuthorizedKeysFile resolves the AuthorizedKeysFile tokens for an account. Relative paths
+// are taken from the user's home directory. The returned path is the one of the host, to be
+// resolved with hostPath.
+func expandAuthorizedKeysFile(pattern string, account Account) string {
+	replacer := strings.NewReplacer("%%", "%", "%h", account.Home, "%u", account.Name, "%U", account.UID)
+	path := replacer.Replace(pattern)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(account.Home, path)
+	}
+	return path
+}
+
+func authorizedKeysDataset(etcPath string) (dataset agent.PluginInventoryDataset, err error) {
+	accounts, err := readAccounts(etcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	if sshdCfg, err := loadSshdConfig(etcPath); err == nil {
+		patterns = sshdAuthorizedKeysFiles(sshdCfg)
+	} else {
+		patterns = sshdAuthorizedKeysFiles(sshdConfig{})
+	}
+
+	seen := make(map[string]bool)
+	for _, account := range accounts {
+		if account.Home == "" {
+			continue
+		}
+		for _, pattern := range patterns {
+			if strings.EqualFold(pattern, "none") {
+				continue
+			}
+			file := expandAuthorizedKeysFile(pattern, account)
+			f, err := os.Open(hostPath(etcPath, file))
+			if err != nil {
+				continue
+			}
+			for _, key := range parseAuthorizedKeys(f, account.Name, file) {
+				// same key authorized for the same user in several files
+				if seen[key.ID] {
+					continue
+				}
+				seen[key.ID] = true
+				dataset = append(dataset, key)
+			}
+			f.Close()
+		}
+	}
+	return dataset, nil
+}
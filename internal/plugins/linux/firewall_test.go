@@ -0,0 +1,128 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNftRuleset(t *testing.T) {
+	output, err := ioutil.ReadFile("testdata/nft_ruleset.json")
+	require.NoError(t, err)
+
+	items, err := parseNftRuleset(output)
+	require.NoError(t, err)
+	require.Len(t, items, 6)
+
+	assert.Equal(t, FirewallItem{
+		Backend:  firewallNftables,
+		Kind:     firewallChain,
+		Family:   "inet",
+		Table:    "filter",
+		Chain:    "input",
+		Type:     "filter",
+		Hook:     "input",
+		Priority: "0",
+		Policy:   "drop",
+	}, items[0])
+	assert.Equal(t, FirewallItem{Backend: firewallNftables, Kind: firewallChain, Family: "inet", Table: "filter", Chain: "ssh"}, items[1])
+	assert.Equal(t, FirewallItem{
+		Backend: firewallNftables,
+		Kind:    firewallRule,
+		Family:  "inet",
+		Table:   "filter",
+		Chain:   "input",
+		Rule:    `[{"match":{"op":"==","left":{"payload":{"protocol":"tcp","field":"dport"}},"right":22}},{"counter":null},{"jump":{"target":"ssh"}}]`,
+		Comment: "ssh access",
+	}, items[3])
+
+	dataset := withFirewallIDs(items)
+	assert.Equal(t, "inet/filter/input", dataset[0].SortKey())
+	assert.True(t, strings.HasPrefix(dataset[2].SortKey(), "inet/filter/input#"))
+	// identical rules in the same chain
+	assert.Equal(t, dataset[4].SortKey()+"-1", dataset[5].SortKey())
+	// the rules reference the previous one of their chain
+	assert.Empty(t, dataset[2].(FirewallItem).After)
+	assert.Equal(t, dataset[2].SortKey(), dataset[3].(FirewallItem).After)
+	assert.Empty(t, dataset[4].(FirewallItem).After)
+	assert.Equal(t, dataset[4].SortKey(), dataset[5].(FirewallItem).After)
+}
+
+func TestParseNftRuleset_Invalid(t *testing.T) {
+	_, err := parseNftRuleset([]byte("Error: syntax error"))
+	assert.Error(t, err)
+}
+
+func TestParseIptablesSave(t *testing.T) {
+	output := `# Generated by iptables-save v1.8.7 on Mon Nov  7 10:00:00 2022
+*nat
+:PREROUTING ACCEPT [0:0]
+:DOCKER - [0:0]
+-A PREROUTING -m addrtype --dst-type LOCAL -j DOCKER
+COMMIT
+*filter
+:INPUT DROP [120:9000]
+:FORWARD ACCEPT [0:0]
+-A INPUT -i lo -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -m comment --comment "ssh access" -j ACCEPT
+COMMIT
+# Completed on Mon Nov  7 10:00:00 2022
+`
+	items := parseIptablesSave(output, "ip")
+	require.Len(t, items, 7)
+
+	assert.Equal(t, FirewallItem{Backend: firewallIptables, Kind: firewallChain, Family: "ip", Table: "nat", Chain: "PREROUTING", Hook: "prerouting", Policy: "accept"}, items[0])
+	assert.Equal(t, FirewallItem{Backend: firewallIptables, Kind: firewallChain, Family: "ip", Table: "nat", Chain: "DOCKER"}, items[1])
+	assert.Equal(t, FirewallItem{Backend: firewallIptables, Kind: firewallChain, Family: "ip", Table: "filter", Chain: "INPUT", Hook: "input", Policy: "drop"}, items[3])
+	assert.Equal(t, FirewallItem{
+		Backend: firewallIptables,
+		Kind:    firewallRule,
+		Family:  "ip",
+		Table:   "filter",
+		Chain:   "INPUT",
+		Rule:    `-p tcp -m tcp --dport 22 -m comment --comment "ssh access" -j ACCEPT`,
+	}, items[6])
+}
+
+func TestWithFirewallIDs_SameChainInSeveralTables(t *testing.T) {
+	output := `*raw
+:PREROUTING ACCEPT [0:0]
+-A PREROUTING -p udp --dport 53 -j CT --notrack
+-A PREROUTING -p udp --sport 53 -j CT --notrack
+COMMIT
+*mangle
+:PREROUTING ACCEPT [0:0]
+-A PREROUTING -p tcp -j MARK --set-mark 1
+COMMIT
+*nat
+:PREROUTING ACCEPT [0:0]
+-A PREROUTING -p tcp --dport 80 -j REDIRECT --to-ports 8080
+-A PREROUTING -p tcp --dport 443 -j REDIRECT --to-ports 8443
+COMMIT
+`
+	dataset := withFirewallIDs(parseIptablesSave(output, "ip"))
+	require.Len(t, dataset, 8)
+
+	rules := map[string][]FirewallItem{}
+	for _, item := range dataset {
+		if rule := item.(FirewallItem); rule.Kind == firewallRule {
+			rules[rule.Table] = append(rules[rule.Table], rule)
+		}
+	}
+	for table, expected := range map[string]int{"raw": 2, "mangle": 1, "nat": 2} {
+		require.Len(t, rules[table], expected, table)
+		assert.True(t, strings.HasPrefix(rules[table][0].ID, "ip/"+table+"/PREROUTING#"), table)
+		assert.Empty(t, rules[table][0].After, "first rule of %s", table)
+		if expected > 1 {
+			assert.Equal(t, rules[table][0].ID, rules[table][1].After, table)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Flatpak Plugin
+// Reports the flatpak applications and runtimes installed system-wide
+package linux
+
+import (
+	"bufio"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+// Paths
+const (
+	FlatpakPath          = "/usr/bin/flatpak"
+	FlatpakSystemInstall = "/var/lib/flatpak"
+)
+
+const flatpakColumns = "application,version,branch,arch,origin,installation"
+
+var flatpaklog = log.WithPlugin("Flatpak")
+
+var flatpakPluginID = ids.PluginID{Category: "packages", Term: "flatpak"}
+
+type flatpakPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+}
+
+type FlatpakItem struct {
+	Name         string `json:"id"`
+	Version      string `json:"version"`
+	Branch       string `json:"branch"`
+	Architecture string `json:"architecture"`
+	Origin       string `json:"origin"`
+	Installation string `json:"installation"`
+}
+
+// SortKey follows the flatpak ref format, name/arch/branch, given that an application can be installed from several
+// branches and for several architectures (e.g. the i386 runtimes required by some x86_64 applications).
+func (p FlatpakItem) SortKey() string {
+	return p.Name + "/" + p.Architecture + "/" + p.Branch
+}
+
+func NewFlatpakPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &flatpakPlugin{
+		PluginCommon: agent.PluginCommon{ID: flatpakPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.FlatpakRefreshSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_PACKAGE_MGRS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+	}
+}
+
+func (p *flatpakPlugin) fetchPackageInfo() (agent.PluginInventoryDataset, error) {
+	output, err := helpers.RunCommand(FlatpakPath, "", "list", "--system", "--columns="+flatpakColumns)
+	if err != nil {
+		return nil, err
+	}
+	return parseFlatpakList(output), nil
+}
+
+// parseFlatpakList parses the tab separated output of `flatpak list --columns`. Branches and
+// architectures of the same application are reported as different items.
+func parseFlatpakList(output string) (packages agent.PluginInventoryDataset) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) < 6 || parts[0] == "" {
+			continue
+		}
+		packages = append(packages, FlatpakItem{
+			Name:         strings.TrimSpace(parts[0]),
+			Version:      strings.TrimSpace(parts[1]),
+			Branch:       strings.TrimSpace(parts[2]),
+			Architecture: strings.TrimSpace(parts[3]),
+			Origin:       strings.TrimSpace(parts[4]),
+			Installation: strings.TrimSpace(parts[5]),
+		})
+	}
+	return packages
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *flatpakPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		flatpaklog.Debug("Disabled.")
+		return
+	}
+
+	// flatpak touches the .changed file within the installation folder on every modification
+	runPackageWatcher(&p.PluginCommon, flatpaklog, p.frequency, []string{FlatpakSystemInstall}, p.fetchPackageInfo)
+}
@@ -0,0 +1,35 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlatpakList(t *testing.T) {
+	output := "org.mozilla.firefox\t106.0.5\tstable\tx86_64\tflathub\tsystem\n" +
+		"org.freedesktop.Platform\t22.08.6\t22.08\tx86_64\tflathub\tsystem\n" +
+		"org.freedesktop.Platform\t21.08.17\t21.08\tx86_64\tflathub\tsystem\n" +
+		"org.freedesktop.Platform\t21.08.17\t21.08\ti386\tflathub\tsystem\n" +
+		"\n"
+
+	packages := parseFlatpakList(output)
+	require.Len(t, packages, 4)
+	assert.Equal(t, FlatpakItem{
+		Name:         "org.mozilla.firefox",
+		Version:      "106.0.5",
+		Branch:       "stable",
+		Architecture: "x86_64",
+		Origin:       "flathub",
+		Installation: "system",
+	}, packages[0])
+	assert.Equal(t, "org.mozilla.firefox/x86_64/stable", packages[0].SortKey())
+	assert.NotEqual(t, packages[1].SortKey(), packages[2].SortKey(), "branches are different items")
+	assert.NotEqual(t, packages[2].SortKey(), packages[3].SortKey(), "architectures are different items")
+}
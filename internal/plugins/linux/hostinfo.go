@@ -66,6 +66,9 @@ type HostinfoData struct {
 	AWSAccountID        string `json:"aws_account_id,omitempty"`
 	AWSAvailabilityZone string `json:"aws_availability_zone,omitempty"`
 	AWSImageID          string `json:"aws_image_id,omitempty"`
+	// region and availability zone of the clouds without specific attributes
+	CloudRegion           string `json:"cloud_region,omitempty"`
+	CloudAvailabilityZone string `json:"cloud_availability_zone,omitempty"`
 }
 
 func (self HostinfoData) SortKey() string {
@@ -242,7 +245,7 @@ func (self *HostinfoPlugin) setCloudRegion(data *HostinfoData) (err error) {
 	}
 
 	region, err := self.cloudHarvester.GetRegion()
-	if err != nil {
+	if err != nil && err != cloud.ErrMethodNotImplemented {
 		return fmt.Errorf("couldn't retrieve cloud region: %v", err)
 	}
 
@@ -256,8 +259,12 @@ func (self *HostinfoPlugin) setCloudRegion(data *HostinfoData) (err error) {
 	case cloud.TypeAlibaba:
 		data.RegionAlibaba = region
 	default:
+		data.CloudRegion = region
+		if zone, zoneErr := self.cloudHarvester.GetZone(); zoneErr == nil {
+			data.CloudAvailabilityZone = zone
+		}
 	}
-	return
+	return nil
 }
 
 // Only for AWS cloud instances
@@ -310,6 +310,18 @@ func TestHostinfoPluginSetCloudRegion(t *testing.T) {
 				h.On("GetCloudType").Return(cloud.TypeAlibaba)
 				h.On("GetRegion").Return("us-east-1", nil)
 			},
+		}, {
+			name: "cloud openstack",
+			assertions: func(d *HostinfoData) {
+				assert.Equal(t, "", d.RegionAWS)
+				assert.Equal(t, "", d.CloudRegion)
+				assert.Equal(t, "nova", d.CloudAvailabilityZone)
+			},
+			setMock: func(h *fakeHarvester) {
+				h.On("GetCloudType").Return(cloud.TypeOpenStack)
+				h.On("GetRegion").Return("", cloud.ErrMethodNotImplemented)
+				h.On("GetZone").Return("nova", nil)
+			},
 		},
 	}
 
@@ -0,0 +1,243 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Language Packages Plugin
+// Reports the Python, Node.js and Ruby packages installed globally in the host
+package linux
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var langpkglog = log.WithPlugin("LanguagePackages")
+
+// LanguageEcosystem describes where the packages of a language are installed and how to read them.
+type LanguageEcosystem struct {
+	id ids.PluginID
+	// dirGlobs are the globally installed package folders. Each of them is watched for changes.
+	dirGlobs []string
+	// readDir returns the packages installed in one of the folders.
+	readDir func(dir string) []LanguagePackageItem
+}
+
+var (
+	PythonPackagesEcosystem = LanguageEcosystem{
+		id: ids.PluginID{Category: "packages", Term: "python"},
+		dirGlobs: []string{
+			"/usr/lib/python3*/site-packages",
+			"/usr/lib/python3/dist-packages",
+			"/usr/lib64/python3*/site-packages",
+			"/usr/local/lib/python3*/site-packages",
+			"/usr/local/lib/python3*/dist-packages",
+		},
+		readDir: readPythonDistInfo,
+	}
+	NodePackagesEcosystem = LanguageEcosystem{
+		id: ids.PluginID{Category: "packages", Term: "nodejs"},
+		dirGlobs: []string{
+			"/usr/lib/node_modules",
+			"/usr/local/lib/node_modules",
+		},
+		readDir: readNodeModules,
+	}
+	RubyPackagesEcosystem = LanguageEcosystem{
+		id: ids.PluginID{Category: "packages", Term: "ruby"},
+		dirGlobs: []string{
+			"/usr/share/gems/specifications",
+			"/usr/lib/ruby/gems/*/specifications",
+			"/usr/local/lib/ruby/gems/*/specifications",
+			"/var/lib/gems/*/specifications",
+		},
+		readDir: readGemSpecifications,
+	}
+)
+
+type languagePackagesPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	ecosystem LanguageEcosystem
+}
+
+type LanguagePackageItem struct {
+	Name     string `json:"id"`
+	Version  string `json:"version"`
+	Location string `json:"location"`
+}
+
+func (p LanguagePackageItem) SortKey() string {
+	return p.Name
+}
+
+// NewLanguagePackagesPlugin creates a plugin reporting the packages of the given ecosystem.
+func NewLanguagePackagesPlugin(ctx agent.AgentContext, ecosystem LanguageEcosystem) agent.Plugin {
+	cfg := ctx.Config()
+	return &languagePackagesPlugin{
+		PluginCommon: agent.PluginCommon{ID: ecosystem.id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.LanguagePackagesRefreshSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_LANGUAGE_PACKAGES_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		ecosystem: ecosystem,
+	}
+}
+
+func (p *languagePackagesPlugin) dirs() (dirs []string) {
+	for _, pattern := range p.ecosystem.dirGlobs {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, matches...)
+	}
+	return dirs
+}
+
+func (p *languagePackagesPlugin) fetchPackageInfo() (packages agent.PluginInventoryDataset, err error) {
+	var items []LanguagePackageItem
+	for _, dir := range p.dirs() {
+		items = append(items, p.ecosystem.readDir(dir)...)
+	}
+	return deduplicateLanguagePackages(items), nil
+}
+
+// deduplicateLanguagePackages sorts the items and, as the rpm plugin does, appends -1, -2, etc
+// to the names of packages installed in more than one location.
+func deduplicateLanguagePackages(items []LanguagePackageItem) (packages agent.PluginInventoryDataset) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].Location < items[j].Location
+	})
+
+	nameDuplicateTracker := make(map[string]int)
+	for _, item := range items {
+		nameCount := nameDuplicateTracker[item.Name]
+		nameDuplicateTracker[item.Name] = nameCount + 1
+		if nameCount > 0 {
+			item.Name = fmt.Sprintf("%v-%v", item.Name, nameCount)
+		}
+		packages = append(packages, item)
+	}
+	return packages
+}
+
+// readPythonDistInfo reads the Name and Version headers from the METADATA file of every
+// <package>-<version>.dist-info folder.
+func readPythonDistInfo(dir string) (items []LanguagePackageItem) {
+	metadataFiles, _ := filepath.Glob(filepath.Join(dir, "*.dist-info", "METADATA"))
+	for _, metadataFile := range metadataFiles {
+		f, err := os.Open(metadataFile)
+		if err != nil {
+			continue
+		}
+		header, _ := textproto.NewReader(bufio.NewReader(f)).ReadMIMEHeader()
+		f.Close()
+
+		if name := header.Get("Name"); name != "" {
+			items = append(items, LanguagePackageItem{
+				Name:     name,
+				Version:  header.Get("Version"),
+				Location: dir,
+			})
+		}
+	}
+	return items
+}
+
+type nodePackageJSON struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// readNodeModules reads the package.json of every global module, including scoped (@scope/name) ones.
+func readNodeModules(dir string) (items []LanguagePackageItem) {
+	manifests, _ := filepath.Glob(filepath.Join(dir, "*", "package.json"))
+	scoped, _ := filepath.Glob(filepath.Join(dir, "@*", "*", "package.json"))
+	for _, manifest := range append(manifests, scoped...) {
+		content, err := ioutil.ReadFile(manifest)
+		if err != nil {
+			continue
+		}
+		var pkg nodePackageJSON
+		if err := json.Unmarshal(content, &pkg); err != nil || pkg.Name == "" {
+			continue
+		}
+		items = append(items, LanguagePackageItem{
+			Name:     pkg.Name,
+			Version:  pkg.Version,
+			Location: dir,
+		})
+	}
+	return items
+}
+
+var (
+	gemspecName    = regexp.MustCompile(`^\s*s\.name\s*=\s*"([^"]+)"`)
+	gemspecVersion = regexp.MustCompile(`^\s*s\.version\s*=\s*"([^"]+)"`)
+)
+
+// readGemSpecifications reads the name and version of every installed gem from its generated
+// <name>-<version>.gemspec, falling back to the file name when they can't be found.
+func readGemSpecifications(dir string) (items []LanguagePackageItem) {
+	specs, _ := filepath.Glob(filepath.Join(dir, "*.gemspec"))
+	for _, spec := range specs {
+		item := LanguagePackageItem{Location: dir}
+		if content, err := ioutil.ReadFile(spec); err == nil {
+			scanner := bufio.NewScanner(strings.NewReader(string(content)))
+			for scanner.Scan() {
+				if m := gemspecName.FindStringSubmatch(scanner.Text()); m != nil {
+					item.Name = m[1]
+				} else if m := gemspecVersion.FindStringSubmatch(scanner.Text()); m != nil {
+					item.Version = m[1]
+				}
+			}
+		}
+		if item.Name == "" {
+			base := strings.TrimSuffix(filepath.Base(spec), ".gemspec")
+			if sep := strings.LastIndex(base, "-"); sep > 0 {
+				item.Name, item.Version = base[:sep], base[sep+1:]
+			} else {
+				item.Name = base
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *languagePackagesPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		langpkglog.WithField("ecosystem", p.ID.Term).Debug("Disabled.")
+		return
+	}
+
+	dirs := p.dirs()
+	if len(dirs) == 0 {
+		langpkglog.WithField("ecosystem", p.ID.Term).Debug("No package folders found.")
+		p.Unregister()
+		return
+	}
+
+	runPackageWatcher(&p.PluginCommon, langpkglog.WithField("ecosystem", p.ID.Term), p.frequency, dirs, p.fetchPackageInfo)
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestReadPythonDistInfo(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "requests-2.28.1.dist-info", "METADATA"),
+		"Metadata-Version: 2.1\nName: requests\nVersion: 2.28.1\nSummary: Python HTTP for Humans.\n\nLong description\n")
+	writeTestFile(t, filepath.Join(dir, "broken.dist-info", "RECORD"), "")
+
+	items := readPythonDistInfo(dir)
+	require.Len(t, items, 1)
+	assert.Equal(t, LanguagePackageItem{Name: "requests", Version: "2.28.1", Location: dir}, items[0])
+}
+
+func TestReadNodeModules(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "npm", "package.json"), `{"name": "npm", "version": "8.19.2"}`)
+	writeTestFile(t, filepath.Join(dir, "@angular", "cli", "package.json"), `{"name": "@angular/cli", "version": "14.2.9"}`)
+	writeTestFile(t, filepath.Join(dir, "invalid", "package.json"), `{`)
+
+	items := readNodeModules(dir)
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	require.Len(t, items, 2)
+	assert.Equal(t, LanguagePackageItem{Name: "@angular/cli", Version: "14.2.9", Location: dir}, items[0])
+	assert.Equal(t, LanguagePackageItem{Name: "npm", Version: "8.19.2", Location: dir}, items[1])
+}
+
+func TestReadGemSpecifications(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "rake-13.0.6.gemspec"),
+		"# stub: rake 13.0.6 ruby lib\n\nGem::Specification.new do |s|\n  s.name = \"rake\".freeze\n  s.version = \"13.0.6\"\nend\n")
+	writeTestFile(t, filepath.Join(dir, "net-http-persistent-4.0.1.gemspec"), "")
+
+	items := readGemSpecifications(dir)
+	require.Len(t, items, 2)
+	assert.Equal(t, LanguagePackageItem{Name: "net-http-persistent", Version: "4.0.1", Location: dir}, items[0])
+	assert.Equal(t, LanguagePackageItem{Name: "rake", Version: "13.0.6", Location: dir}, items[1])
+}
+
+func TestLanguagePackagesPlugin_DuplicatedPackages(t *testing.T) {
+	root := t.TempDir()
+	for _, version := range []string{"python3.8", "python3.10"} {
+		writeTestFile(t, filepath.Join(root, version, "site-packages", "six-1.16.0.dist-info", "METADATA"),
+			"Name: six\nVersion: 1.16.0\n")
+	}
+
+	ecosystem := PythonPackagesEcosystem
+	ecosystem.dirGlobs = []string{filepath.Join(root, "python3*", "site-packages")}
+	p := NewLanguagePackagesPlugin(testing2.NewMockAgent(), ecosystem).(*languagePackagesPlugin)
+
+	packages, err := p.fetchPackageInfo()
+	require.NoError(t, err)
+	require.Len(t, packages, 2)
+	assert.Equal(t, "six", packages[0].SortKey())
+	assert.Equal(t, "six-1", packages[1].SortKey())
+	assert.Equal(t, filepath.Join(root, "python3.8", "site-packages"), packages[1].(LanguagePackageItem).Location)
+}
@@ -0,0 +1,295 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Listening Ports Plugin
+// Reports the sockets listening in the host and the processes owning them
+package linux
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var lplog = log.WithPlugin("ListeningPorts")
+
+var listeningPortsPluginID = ids.PluginID{Category: "network", Term: "listening_ports"}
+
+const (
+	// tcpListen is the TCP_LISTEN state in /proc/net/tcp*.
+	tcpListen = "0A"
+	// udpUnconnected is the TCP_CLOSE state that /proc/net/udp* reports for bound sockets.
+	udpUnconnected = "07"
+	// unixAcceptCon is the __SO_ACCEPTCON flag that /proc/net/unix reports for listening sockets.
+	unixAcceptCon = 0x10000
+)
+
+type ListeningPortsPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	procPath  string
+	userNames map[string]string
+}
+
+type ListeningPort struct {
+	ID          string `json:"id"`
+	Protocol    string `json:"protocol"`
+	Address     string `json:"address"`
+	Port        int    `json:"port,omitempty"`
+	Pid         int    `json:"pid,omitempty"`
+	ProcessName string `json:"process_name,omitempty"`
+	User        string `json:"user,omitempty"`
+	Service     string `json:"systemd_unit,omitempty"`
+}
+
+func (p ListeningPort) SortKey() string {
+	return p.ID
+}
+
+// socket is a listening socket as read from /proc/net, before being attributed to a process.
+type socket struct {
+	protocol string
+	address  string
+	port     int
+	uid      string
+	inode    string
+}
+
+func (s socket) id() string {
+	if s.protocol == "unix" {
+		return s.protocol + ":" + s.address
+	}
+	return s.protocol + ":" + net.JoinHostPort(s.address, strconv.Itoa(s.port))
+}
+
+func NewListeningPortsPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &ListeningPortsPlugin{
+		PluginCommon: agent.PluginCommon{ID: listeningPortsPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.ListeningPortsRefreshSec,
+			config.FREQ_MINIMUM_FAST_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_LISTENING_PORTS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		procPath:  helpers.HostProc(),
+		userNames: make(map[string]string),
+	}
+}
+
+// parseInetSockets parses /proc/net/{tcp,tcp6,udp,udp6}, returning the sockets in the given state.
+func parseInetSockets(r io.Reader, protocol, state string) (sockets []socket) {
+	scanner := bufio.NewScanner(r)
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != state {
+			continue
+		}
+		address, port, err := parseHexAddress(fields[1])
+		if err != nil {
+			lplog.WithError(err).WithField("line", scanner.Text()).Debug("Can't parse socket address.")
+			continue
+		}
+		sockets = append(sockets, socket{
+			protocol: protocol,
+			address:  address,
+			port:     port,
+			uid:      fields[7],
+			inode:    fields[9],
+		})
+	}
+	return sockets
+}
+
+// parseHexAddress decodes the "ADDRESS:PORT" hexadecimal form used by /proc/net. Addresses are
+// stored as 32 bit words in host (little endian) byte order.
+func parseHexAddress(hexAddress string) (string, int, error) {
+	parts := strings.Split(hexAddress, ":")
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid address %q", hexAddress)
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, fmt.Errorf("invalid address %q", hexAddress)
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %q", hexAddress)
+	}
+
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		for i := 0; i < 4; i++ {
+			ip[word+i] = raw[word+3-i]
+		}
+	}
+	return ip.String(), int(port), nil
+}
+
+// parseUnixSockets parses /proc/net/unix, returning the named sockets that accept connections.
+func parseUnixSockets(r io.Reader) (sockets []socket) {
+	scanner := bufio.NewScanner(r)
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&unixAcceptCon == 0 {
+			continue
+		}
+		sockets = append(sockets, socket{
+			protocol: "unix",
+			address:  fields[7],
+			inode:    fields[6],
+		})
+	}
+	return sockets
+}
+
+// socketOwners maps socket inodes to the lowest PID holding a descriptor for them.
+func (p *ListeningPortsPlugin) socketOwners() map[string]int {
+	owners := make(map[string]int)
+	fdDirs, err := filepath.Glob(filepath.Join(p.procPath, "[0-9]*", "fd"))
+	if err != nil {
+		return owners
+	}
+	for _, fdDir := range fdDirs {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(fdDir)))
+		if err != nil {
+			continue
+		}
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			// process finished or not enough privileges
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			if owner, ok := owners[inode]; !ok || pid < owner {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
+
+func (p *ListeningPortsPlugin) userName(uid string) string {
+	if name, ok := p.userNames[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	p.userNames[uid] = name
+	return name
+}
+
+func (p *ListeningPortsPlugin) readSockets() (sockets []socket) {
+	inetFiles := []struct {
+		file     string
+		protocol string
+		state    string
+	}{
+		{"tcp", "tcp", tcpListen},
+		{"tcp6", "tcp6", tcpListen},
+		{"udp", "udp", udpUnconnected},
+		{"udp6", "udp6", udpUnconnected},
+	}
+	for _, inet := range inetFiles {
+		f, err := os.Open(filepath.Join(p.procPath, "net", inet.file))
+		if err != nil {
+			lplog.WithError(err).WithField("file", inet.file).Debug("Can't read sockets.")
+			continue
+		}
+		sockets = append(sockets, parseInetSockets(f, inet.protocol, inet.state)...)
+		f.Close()
+	}
+
+	if f, err := os.Open(filepath.Join(p.procPath, "net", "unix")); err == nil {
+		sockets = append(sockets, parseUnixSockets(f)...)
+		f.Close()
+	}
+	return sockets
+}
+
+func (p *ListeningPortsPlugin) getListeningPortsDataset() (dataset agent.PluginInventoryDataset) {
+	sockets := p.readSockets()
+	owners := p.socketOwners()
+
+	seen := make(map[string]bool, len(sockets))
+	for _, s := range sockets {
+		// sockets sharing address (i.e. SO_REUSEPORT) are reported once
+		id := s.id()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		port := ListeningPort{
+			ID:       id,
+			Protocol: s.protocol,
+			Address:  s.address,
+			Port:     s.port,
+		}
+		if s.uid != "" {
+			port.User = p.userName(s.uid)
+		}
+		if pid, ok := owners[s.inode]; ok {
+			port.Pid = pid
+			if comm, err := ioutil.ReadFile(filepath.Join(p.procPath, strconv.Itoa(pid), "comm")); err == nil {
+				port.ProcessName = strings.TrimSpace(string(comm))
+			}
+			if service, ok := p.Context.GetServiceForPid(pid); ok {
+				port.Service = service
+			}
+		}
+		dataset = append(dataset, port)
+	}
+	return dataset
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *ListeningPortsPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		lplog.Debug("Disabled.")
+		return
+	}
+
+	refreshTimer := time.NewTicker(1)
+	for {
+		select {
+		case <-refreshTimer.C:
+			refreshTimer.Stop()
+			refreshTimer = time.NewTicker(p.frequency)
+			p.EmitInventory(p.getListeningPortsDataset(), entity.NewFromNameWithoutID(p.Context.EntityKey()))
+		}
+	}
+}
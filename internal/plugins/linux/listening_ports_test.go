@@ -0,0 +1,126 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:18EB 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0F02000A:0016 0202000A:C2A8 01 00000000:00000000 02:0009A6B4 00000000     0        0 1003 4 0000000000000000 20 4 31 10 -1
+`
+
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:0050 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 100 0 0 10 0
+`
+
+const procNetUDP = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  365: 3500007F:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 1005 2 0000000000000000 0
+`
+
+const procNetUnix = `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 1006 /run/systemd/private
+0000000000000000: 00000003 00000000 00000000 0001 03 1007 /run/systemd/journal/stdout
+`
+
+func fakeProc(t *testing.T) string {
+	proc := t.TempDir()
+	writeTestFile(t, filepath.Join(proc, "net", "tcp"), procNetTCP)
+	writeTestFile(t, filepath.Join(proc, "net", "tcp6"), procNetTCP6)
+	writeTestFile(t, filepath.Join(proc, "net", "udp"), procNetUDP)
+	writeTestFile(t, filepath.Join(proc, "net", "unix"), procNetUnix)
+
+	processes := map[string][]string{
+		"1":    {"systemd", "1006"},
+		"612":  {"sshd", "1001", "1003"},
+		"7331": {"sshd", "1001"},
+		"900":  {"nginx", "1004"},
+	}
+	for pid, process := range processes {
+		writeTestFile(t, filepath.Join(proc, pid, "comm"), process[0]+"\n")
+		require.NoError(t, os.MkdirAll(filepath.Join(proc, pid, "fd"), 0755))
+		for i, inode := range process[1:] {
+			require.NoError(t, os.Symlink("socket:["+inode+"]", filepath.Join(proc, pid, "fd", strings.Repeat("1", i+1))))
+		}
+		require.NoError(t, os.Symlink("/dev/null", filepath.Join(proc, pid, "fd", "0")))
+	}
+	return proc
+}
+
+func TestParseHexAddress(t *testing.T) {
+	tests := []struct {
+		hex     string
+		address string
+		port    int
+	}{
+		{"0100007F:18EB", "127.0.0.1", 6379},
+		{"00000000:0016", "0.0.0.0", 22},
+		{"00000000000000000000000001000000:0050", "::1", 80},
+		{"0000000000000000FFFF00000100007F:0035", "127.0.0.1", 53},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			address, port, err := parseHexAddress(tt.hex)
+			require.NoError(t, err)
+			assert.Equal(t, tt.address, address)
+			assert.Equal(t, tt.port, port)
+		})
+	}
+
+	_, _, err := parseHexAddress("0100007F")
+	assert.Error(t, err)
+}
+
+func TestListeningPortsPlugin_Dataset(t *testing.T) {
+	p := NewListeningPortsPlugin(testing2.NewMockAgent()).(*ListeningPortsPlugin)
+	p.procPath = fakeProc(t)
+
+	dataset := p.getListeningPortsDataset()
+	sort.Sort(dataset)
+
+	var ports []ListeningPort
+	for _, item := range dataset {
+		ports = append(ports, item.(ListeningPort))
+	}
+	require.Len(t, ports, 5)
+
+	assert.Equal(t, "tcp6:[::1]:80", ports[0].ID)
+	assert.Equal(t, "nginx", ports[0].ProcessName)
+
+	assert.Equal(t, ListeningPort{
+		ID:          "tcp:0.0.0.0:22",
+		Protocol:    "tcp",
+		Address:     "0.0.0.0",
+		Port:        22,
+		Pid:         612,
+		ProcessName: "sshd",
+		User:        "root",
+	}, ports[1])
+
+	assert.Equal(t, "tcp:127.0.0.1:6379", ports[2].ID)
+	assert.Zero(t, ports[2].Pid, "socket without visible owner")
+
+	assert.Equal(t, "udp:127.0.0.53:53", ports[3].ID)
+
+	assert.Equal(t, ListeningPort{
+		ID:          "unix:/run/systemd/private",
+		Protocol:    "unix",
+		Address:     "/run/systemd/private",
+		Pid:         1,
+		ProcessName: "systemd",
+	}, ports[4])
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// packageFetcher returns the full list of packages known by a package manager.
+type packageFetcher func() (agent.PluginInventoryDataset, error)
+
+// runPackageWatcher is the processing loop shared by the package manager plugins. It emits the
+// inventory once at start and then, at most every frequency, each time any of the watched paths
+// is modified. Paths that don't exist are ignored, but at least one of them must be watchable.
+func runPackageWatcher(p *agent.PluginCommon, plog log.Entry, frequency time.Duration, paths []string, fetch packageFetcher) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		plog.WithError(err).Error("can't instantiate package watcher")
+		p.Unregister()
+		return
+	}
+	defer watcher.Close()
+
+	watched := 0
+	for _, path := range paths {
+		if err = watcher.Add(path); err != nil {
+			plog.WithError(err).WithField("path", path).Debug("Can't watch path.")
+			continue
+		}
+		watched++
+	}
+	if watched == 0 {
+		plog.WithField("paths", paths).Error("can't setup trigger file watcher for package manager")
+		p.Unregister()
+		return
+	}
+
+	counter := 1
+	ticker := time.NewTicker(1)
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if ok {
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+					counter = counter + 1
+					if counter > 1 {
+						plog.WithFields(logrus.Fields{
+							"frequency": frequency,
+							"counter":   counter,
+						}).Debug("Package plugin oversampling.")
+					}
+				}
+			} else {
+				plog.Debug("Package watcher closed.")
+				return
+			}
+		case <-ticker.C:
+			ticker.Stop()
+			ticker = time.NewTicker(frequency)
+			if counter > 0 {
+				data, err := fetch()
+				if err != nil {
+					plog.WithError(err).Error("fetching package data")
+				} else {
+					p.EmitInventory(data, entity.NewFromNameWithoutID(p.Context.EntityKey()))
+				}
+				counter = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Pacman Plugin
+// Reads the Arch Linux local package database and reports the installed packages
+package linux
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+const (
+	PacmanLocalDB = "/var/lib/pacman/local"
+)
+
+var pacmanlog = log.WithPlugin("Pacman")
+
+var pacmanPluginID = ids.PluginID{Category: "packages", Term: "pacman"}
+
+type pacmanPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	dbPath    string
+}
+
+type PacmanItem struct {
+	Name         string `json:"id"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture"`
+	Base         string `json:"base"`
+	InstallTime  string `json:"installed_epoch"`
+	Reason       string `json:"install_reason"`
+}
+
+func (p PacmanItem) SortKey() string {
+	return p.Name
+}
+
+func NewPacmanPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &pacmanPlugin{
+		PluginCommon: agent.PluginCommon{ID: pacmanPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.PacmanRefreshSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_PACKAGE_MGRS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		dbPath: PacmanLocalDB,
+	}
+}
+
+// fetchPackageInfo reads the desc file stored in each package directory of the local database.
+func (p *pacmanPlugin) fetchPackageInfo() (packages agent.PluginInventoryDataset, err error) {
+	descs, err := filepath.Glob(filepath.Join(p.dbPath, "*", "desc"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, desc := range descs {
+		f, err := os.Open(desc)
+		if err != nil {
+			pacmanlog.WithError(err).WithField("file", desc).Debug("Can't open package description.")
+			continue
+		}
+		item, err := parsePacmanDesc(f)
+		f.Close()
+		if err != nil {
+			pacmanlog.WithError(err).WithField("file", desc).Warn("cannot parse package description")
+			continue
+		}
+		if item.Name != "" {
+			packages = append(packages, item)
+		}
+	}
+
+	return packages, nil
+}
+
+// parsePacmanDesc parses a pacman desc file, formed by %SECTION% headers followed by one or more
+// value lines and terminated by a blank line.
+func parsePacmanDesc(r io.Reader) (item PacmanItem, err error) {
+	// packages without a reason were explicitly installed
+	item.Reason = "explicit"
+
+	var section string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			section = ""
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			section = strings.Trim(line, "%")
+		default:
+			switch section {
+			case "NAME":
+				item.Name = line
+			case "VERSION":
+				item.Version = line
+			case "ARCH":
+				item.Architecture = line
+			case "BASE":
+				item.Base = line
+			case "INSTALLDATE":
+				item.InstallTime = line
+			case "REASON":
+				if line == "1" {
+					item.Reason = "dependency"
+				}
+			}
+		}
+	}
+
+	return item, scanner.Err()
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *pacmanPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		pacmanlog.Debug("Disabled.")
+		return
+	}
+
+	runPackageWatcher(&p.PluginCommon, pacmanlog, p.frequency, []string{p.dbPath}, p.fetchPackageInfo)
+}
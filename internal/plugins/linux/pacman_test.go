@@ -0,0 +1,43 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+)
+
+func TestPacmanPlugin_FetchPackageInfo(t *testing.T) {
+	p := NewPacmanPlugin(testing2.NewMockAgent()).(*pacmanPlugin)
+	p.dbPath = "testdata/pacman/local"
+
+	packages, err := p.fetchPackageInfo()
+	require.NoError(t, err)
+	require.Len(t, packages, 2)
+	sort.Sort(packages)
+
+	assert.Equal(t, PacmanItem{
+		Name:         "bash",
+		Version:      "5.1.016-1",
+		Architecture: "x86_64",
+		Base:         "bash",
+		InstallTime:  "1667296842",
+		Reason:       "explicit",
+	}, packages[0])
+	assert.Equal(t, PacmanItem{
+		Name:         "glibc",
+		Version:      "2.36-6",
+		Architecture: "x86_64",
+		Base:         "glibc",
+		InstallTime:  "1667296800",
+		Reason:       "dependency",
+	}, packages[1])
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+)
+
+// PAM risks
+const (
+	riskPermitSufficient = "permit_sufficient"
+	riskNullOk           = "nullok"
+)
+
+type PamService struct {
+	Name     string `json:"id"`
+	Auth     string `json:"auth,omitempty"`
+	Account  string `json:"account,omitempty"`
+	Password string `json:"password,omitempty"`
+	Session  string `json:"session,omitempty"`
+	Includes string `json:"includes,omitempty"`
+	Risky    bool   `json:"risky,omitempty"`
+	Risks    string `json:"risks,omitempty"`
+}
+
+func (p PamService) SortKey() string {
+	return p.Name
+}
+
+// pamFields splits a PAM line keeping bracketed controls such as [success=1 default=ignore] together.
+func pamFields(line string) (fields []string) {
+	var current strings.Builder
+	depth := 0
+	for _, r := range line {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case (r == ' ' || r == '\t') && depth == 0:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// parsePamService reads the stack of a /etc/pam.d service file. Each module type is reported as
+// the `control module args` of its lines joined by semicolons, in evaluation order.
+func parsePamService(r io.Reader, name string) PamService {
+	stacks := make(map[string][]string)
+	var includes []string
+	risks := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := pamFields(line)
+		// Debian style `@include common-auth`
+		if fields[0] == "@include" {
+			if len(fields) > 1 {
+				includes = append(includes, fields[1])
+			}
+			continue
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		// a leading dash silences errors for missing modules
+		moduleType := strings.ToLower(strings.TrimPrefix(fields[0], "-"))
+		control, module := fields[1], fields[2]
+		if control == "include" || control == "substack" {
+			includes = append(includes, module)
+		}
+		stacks[moduleType] = append(stacks[moduleType], strings.Join(fields[1:], " "))
+
+		if moduleType == "auth" && control == "sufficient" && filepath.Base(module) == "pam_permit.so" {
+			risks[riskPermitSufficient] = true
+		}
+		if (moduleType == "auth" || moduleType == "password") && strings.Contains(strings.Join(fields[3:], " "), "nullok") {
+			risks[riskNullOk] = true
+		}
+	}
+
+	service := PamService{
+		Name:     name,
+		Auth:     strings.Join(stacks["auth"], "; "),
+		Account:  strings.Join(stacks["account"], "; "),
+		Password: strings.Join(stacks["password"], "; "),
+		Session:  strings.Join(stacks["session"], "; "),
+		Includes: strings.Join(includes, ","),
+	}
+	for _, risk := range []string{riskPermitSufficient, riskNullOk} {
+		if risks[risk] {
+			service.Risky = true
+			if service.Risks != "" {
+				service.Risks += ","
+			}
+			service.Risks += risk
+		}
+	}
+	return service
+}
+
+func pamDataset(etcPath string) (dataset agent.PluginInventoryDataset, err error) {
+	dir := filepath.Join(etcPath, "pam.d")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		dataset = append(dataset, parsePamService(f, entry.Name()))
+		f.Close()
+	}
+	return dataset, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Security Configuration Plugins
+// Report the accounts, SSH authorized keys, sudoers rules and PAM stacks of the host
+package linux
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var seclog = log.WithPlugin("SecurityConfig")
+
+var (
+	accountsPluginID       = ids.PluginID{Category: "security", Term: "accounts"}
+	groupsPluginID         = ids.PluginID{Category: "security", Term: "groups"}
+	authorizedKeysPluginID = ids.PluginID{Category: "security", Term: "authorized_keys"}
+	sudoersPluginID        = ids.PluginID{Category: "security", Term: "sudoers"}
+	pamPluginID            = ids.PluginID{Category: "security", Term: "pam"}
+)
+
+// securityConfigPlugin periodically reports a dataset read from the host configuration files.
+type securityConfigPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	etcPath   string
+	dataset   func(etcPath string) (agent.PluginInventoryDataset, error)
+}
+
+func newSecurityConfigPlugin(id ids.PluginID, ctx agent.AgentContext, dataset func(string) (agent.PluginInventoryDataset, error)) *securityConfigPlugin {
+	cfg := ctx.Config()
+	return &securityConfigPlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.SecurityInventoryRefreshSec,
+			config.FREQ_MINIMUM_FAST_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_SECURITY_CONFIG_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		etcPath: helpers.HostEtc(),
+		dataset: dataset,
+	}
+}
+
+// NewAccountsPlugin reports the /etc/passwd accounts, with their shell and last password change.
+func NewAccountsPlugin(ctx agent.AgentContext) agent.Plugin {
+	return newSecurityConfigPlugin(accountsPluginID, ctx, accountsDataset)
+}
+
+// NewGroupsPlugin reports the /etc/group groups and their members.
+func NewGroupsPlugin(ctx agent.AgentContext) agent.Plugin {
+	return newSecurityConfigPlugin(groupsPluginID, ctx, groupsDataset)
+}
+
+// NewAuthorizedKeysPlugin reports the fingerprints of the SSH keys authorized for every account.
+func NewAuthorizedKeysPlugin(ctx agent.AgentContext) agent.Plugin {
+	return newSecurityConfigPlugin(authorizedKeysPluginID, ctx, authorizedKeysDataset)
+}
+
+// NewSudoersPlugin reports the rules in /etc/sudoers and its included files.
+func NewSudoersPlugin(ctx agent.AgentContext) agent.Plugin {
+	return newSecurityConfigPlugin(sudoersPluginID, ctx, sudoersDataset)
+}
+
+// NewPamPlugin reports the PAM stack of every service in /etc/pam.d.
+func NewPamPlugin(ctx agent.AgentContext) agent.Plugin {
+	return newSecurityConfigPlugin(pamPluginID, ctx, pamDataset)
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *securityConfigPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		seclog.WithField("id", p.ID.String()).Debug("Disabled.")
+		return
+	}
+
+	refreshTimer := time.NewTicker(1)
+	for {
+		select {
+		case <-refreshTimer.C:
+			refreshTimer.Stop()
+			refreshTimer = time.NewTicker(p.frequency)
+			dataset, err := p.dataset(p.etcPath)
+			if err != nil {
+				seclog.WithError(err).WithField("id", p.ID.String()).Error("reading security configuration")
+				continue
+			}
+			p.EmitInventory(dataset, entity.NewFromNameWithoutID(p.Context.EntityKey()))
+		}
+	}
+}
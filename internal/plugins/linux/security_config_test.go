@@ -0,0 +1,174 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testED25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHheSE03jSZhZgUKcrakkGWZW/XG+VSiw1e2hY+4cdt4 alice@laptop"
+	testRSA1024Key = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDGTlKEPQPnaqaMnij2YIzfIKcDZz8IALajnYuQSbEaGj/hssRqyvaTGoHOSNFS8LUa+OAUxfAYnFeD1T+PXJp0oEzvoocmaGXhP+4I6rmJLRcaJmiQHVKT6cwQsa829f03Ry+056WVmhoTYiItbMdDndzlEazf4mAFBCO3uPfXww== legacy"
+)
+
+func TestAccountsDataset(t *testing.T) {
+	etc := t.TempDir()
+	writeTestFile(t, filepath.Join(etc, "passwd"), `root:x:0:0:root:/root:/bin/bash
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+alice:x:1000:1000:Alice,,,:/home/alice:/bin/zsh
+toor:x:0:0::/root:/bin/sh
+`)
+	writeTestFile(t, filepath.Join(etc, "shadow"), `root:$6$salt$hash:19300:0:99999:7:::
+daemon:*:19000:0:99999:7:::
+alice:!$6$salt$hash:19301:0:99999:7:::
+toor::19302:0:99999:7:::
+`)
+
+	dataset, err := accountsDataset(etc)
+	require.NoError(t, err)
+	require.Len(t, dataset, 4)
+
+	assert.Equal(t, Account{
+		Name:               "root",
+		UID:                "0",
+		GID:                "0",
+		Home:               "/root",
+		Shell:              "/bin/bash",
+		LoginShell:         true,
+		LastPasswordChange: "2022-11-04",
+	}, dataset[0])
+	assert.False(t, dataset[1].(Account).LoginShell)
+	assert.True(t, dataset[2].(Account).Locked)
+	assert.Equal(t, Account{
+		Name:               "toor",
+		UID:                "0",
+		GID:                "0",
+		Home:               "/root",
+		Shell:              "/bin/sh",
+		LoginShell:         true,
+		LastPasswordChange: "2022-11-06",
+		Risky:              true,
+		Risks:              "uid_zero,empty_password",
+	}, dataset[3])
+}
+
+func TestGroupsDataset(t *testing.T) {
+	etc := t.TempDir()
+	writeTestFile(t, filepath.Join(etc, "group"), "root:x:0:\nsudo:x:27:alice,bob\n")
+
+	dataset, err := groupsDataset(etc)
+	require.NoError(t, err)
+	assert.Equal(t, []Group{{Name: "root", GID: "0"}, {Name: "sudo", GID: "27", Members: "alice,bob"}},
+		[]Group{dataset[0].(Group), dataset[1].(Group)})
+}
+
+func TestAuthorizedKeysDataset(t *testing.T) {
+	// the host root is mounted in a directory, as in containers
+	root := t.TempDir()
+	etc := filepath.Join(root, "etc")
+	writeTestFile(t, filepath.Join(etc, "passwd"),
+		"root:x:0:0:root:/root:/bin/bash\n"+
+			"alice:x:1000:1000::/home/alice:/bin/bash\n")
+	writeTestFile(t, filepath.Join(etc, "ssh", "sshd_config"),
+		"Include sshd_config.d/*.conf\nPermitRootLogin no\n")
+	writeTestFile(t, filepath.Join(etc, "ssh", "sshd_config.d", "keys.conf"),
+		"AuthorizedKeysFile .ssh/authorized_keys /etc/ssh/keys/%u\n")
+	writeTestFile(t, filepath.Join(root, "home", "alice", ".ssh", "authorized_keys"),
+		"# laptop\n"+`command="/usr/bin/backup",from="10.0.0.0/8" `+testED25519Key+"\n")
+	writeTestFile(t, filepath.Join(etc, "ssh", "keys", "root"), testRSA1024Key+"\n"+testED25519Key+"\n")
+
+	dataset, err := authorizedKeysDataset(etc)
+	require.NoError(t, err)
+	sort.Sort(dataset)
+	require.Len(t, dataset, 3)
+
+	assert.Equal(t, AuthorizedKey{
+		ID:          "alice:SHA256:D7L3mPMb/r8DFy1TqIiY4+HJdl9p646A/Fo4I5OnFGA",
+		User:        "alice",
+		File:        "/home/alice/.ssh/authorized_keys",
+		Type:        "ssh-ed25519",
+		Fingerprint: "SHA256:D7L3mPMb/r8DFy1TqIiY4+HJdl9p646A/Fo4I5OnFGA",
+		Comment:     "alice@laptop",
+		Options:     `command="/usr/bin/backup",from="10.0.0.0/8"`,
+	}, dataset[0])
+
+	rsa := dataset[2].(AuthorizedKey)
+	assert.Equal(t, "root:SHA256:rdi6AZRqUCEZG9yA5Z5Opm2r4/aBKfvkmUvyi9Kfy7E", rsa.ID)
+	assert.Equal(t, 1024, rsa.Bits)
+	assert.Equal(t, "weak_rsa_key,root_account", rsa.Risks)
+	assert.True(t, rsa.Risky)
+}
+
+func TestSudoersDataset(t *testing.T) {
+	etc := t.TempDir()
+	writeTestFile(t, filepath.Join(etc, "sudoers"), `# sudoers file
+Defaults	env_reset
+Defaults:deploy !authenticate
+Cmnd_Alias SERVICES = /usr/bin/systemctl restart nginx, \
+                      /usr/bin/systemctl reload nginx
+
+root	ALL=(ALL:ALL) ALL
+%sudo	ALL=(ALL:ALL) ALL
+
+#includedir /does/not/exist
+@includedir sudoers.d
+#include /etc/sudoers.local
+`)
+	writeTestFile(t, filepath.Join(etc, "sudoers.local"), "backup ALL=(root) /usr/bin/rsync\n")
+	writeTestFile(t, filepath.Join(etc, "sudoers.d", "90-cloud-init-users"), "ubuntu ALL=(ALL) NOPASSWD:ALL\n")
+	writeTestFile(t, filepath.Join(etc, "sudoers.d", "deploy"), "deploy ALL=(root) NOPASSWD: SERVICES\n")
+	writeTestFile(t, filepath.Join(etc, "sudoers.d", "README.txt"), "ignored ALL=(ALL) ALL\n")
+
+	dataset, err := sudoersDataset(etc)
+	require.NoError(t, err)
+	require.Len(t, dataset, 8)
+
+	byRule := map[string]SudoersEntry{}
+	for _, item := range dataset {
+		byRule[item.(SudoersEntry).Rule] = item.(SudoersEntry)
+	}
+
+	assert.Equal(t, sudoersAlias, byRule["Cmnd_Alias SERVICES = /usr/bin/systemctl restart nginx, /usr/bin/systemctl reload nginx"].Kind)
+	assert.Equal(t, riskNoAuthenticate, byRule["Defaults:deploy !authenticate"].Risks)
+	assert.False(t, byRule["root ALL=(ALL:ALL) ALL"].Risky)
+	assert.Equal(t, riskAllCommands, byRule["%sudo ALL=(ALL:ALL) ALL"].Risks)
+
+	ubuntu := byRule["ubuntu ALL=(ALL) NOPASSWD:ALL"]
+	assert.Equal(t, "ubuntu", ubuntu.Principal)
+	assert.Equal(t, filepath.Join(etc, "sudoers.d", "90-cloud-init-users"), ubuntu.Source)
+	assert.Equal(t, "nopasswd,all_commands", ubuntu.Risks)
+	assert.Equal(t, riskNoPasswd, byRule["deploy ALL=(root) NOPASSWD: SERVICES"].Risks)
+	assert.True(t, strings.HasPrefix(ubuntu.ID, ubuntu.Source+"#"))
+	// absolute includes are read from the host /etc
+	assert.Equal(t, filepath.Join(etc, "sudoers.local"), byRule["backup ALL=(root) /usr/bin/rsync"].Source)
+}
+
+func TestParsePamService(t *testing.T) {
+	service := parsePamService(strings.NewReader(`#%PAM-1.0
+auth	[success=1 default=ignore]	pam_unix.so nullok
+auth	requisite			pam_deny.so
+auth	sufficient			pam_permit.so
+@include common-account
+-session optional pam_systemd.so
+password include system-auth
+`), "sshd")
+
+	assert.Equal(t, PamService{
+		Name:     "sshd",
+		Auth:     "[success=1 default=ignore] pam_unix.so nullok; requisite pam_deny.so; sufficient pam_permit.so",
+		Password: "include system-auth",
+		Session:  "optional pam_systemd.so",
+		Includes: "common-account,system-auth",
+		Risky:    true,
+		Risks:    "permit_sufficient,nullok",
+	}, service)
+}
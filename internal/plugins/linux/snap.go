@@ -0,0 +1,100 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Snap Plugin
+// Reports the snaps installed in the host
+package linux
+
+import (
+	"bufio"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+// Paths
+const (
+	SnapPath     = "/usr/bin/snap"
+	SnapSnapsDir = "/var/lib/snapd/snaps"
+)
+
+var snaplog = log.WithPlugin("Snap")
+
+var snapPluginID = ids.PluginID{Category: "packages", Term: "snap"}
+
+type snapPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+}
+
+type SnapItem struct {
+	Name      string `json:"id"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Tracking  string `json:"tracking"`
+	Publisher string `json:"publisher"`
+	Notes     string `json:"notes"`
+}
+
+func (p SnapItem) SortKey() string {
+	return p.Name
+}
+
+func NewSnapPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &snapPlugin{
+		PluginCommon: agent.PluginCommon{ID: snapPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.SnapRefreshSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_PACKAGE_MGRS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+	}
+}
+
+func (p *snapPlugin) fetchPackageInfo() (agent.PluginInventoryDataset, error) {
+	output, err := helpers.RunCommand(SnapPath, "", "list", "--unicode=never", "--color=never")
+	if err != nil {
+		return nil, err
+	}
+	return parseSnapList(output), nil
+}
+
+// parseSnapList parses the `snap list` table, skipping its header line.
+func parseSnapList(output string) (packages agent.PluginInventoryDataset) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 6 || parts[0] == "Name" {
+			continue
+		}
+		packages = append(packages, SnapItem{
+			Name:      parts[0],
+			Version:   parts[1],
+			Revision:  parts[2],
+			Tracking:  parts[3],
+			Publisher: strings.TrimSuffix(parts[4], "*"),
+			Notes:     parts[5],
+		})
+	}
+	return packages
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *snapPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		snaplog.Debug("Disabled.")
+		return
+	}
+
+	// every snap install, refresh or removal adds or deletes a squashfs file in this folder
+	runPackageWatcher(&p.PluginCommon, snaplog, p.frequency, []string{SnapSnapsDir}, p.fetchPackageInfo)
+}
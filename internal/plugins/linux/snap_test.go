@@ -0,0 +1,33 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSnapList(t *testing.T) {
+	output := `Name    Version        Rev    Tracking         Publisher   Notes
+core20  20220826       1623   latest/stable    canonical*  base
+lxd     5.7-c62733b    23889  latest/stable/…  canonical*  -
+yq      v4.27.5        1945   latest/stable    mikefarah   disabled
+`
+	packages := parseSnapList(output)
+	require.Len(t, packages, 3)
+	assert.Equal(t, SnapItem{
+		Name:      "core20",
+		Version:   "20220826",
+		Revision:  "1623",
+		Tracking:  "latest/stable",
+		Publisher: "canonical",
+		Notes:     "base",
+	}, packages[0])
+	assert.Equal(t, "mikefarah", packages[2].(SnapItem).Publisher)
+	assert.Equal(t, "disabled", packages[2].(SnapItem).Notes)
+}
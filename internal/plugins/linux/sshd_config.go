@@ -6,9 +6,11 @@
 package linux
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"io/ioutil"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -45,6 +47,7 @@ func NewSshdConfigPlugin(id ids.PluginID, ctx agent.AgentContext) *SshdConfigPlu
 type SshdConfigValue struct {
 	Key   string `json:"id"`
 	Value string `json:"value"`
+	Risky bool   `json:"risky,omitempty"`
 }
 
 func (self SshdConfigValue) SortKey() string {
@@ -59,6 +62,27 @@ var SshdConfigProperties = map[string]bool{
 	"ChallengeResponseAuthentication": true,
 }
 
+// SshdRiskyValues are the property values flagged as risky in the inventory.
+var SshdRiskyValues = map[string]string{
+	"PermitRootLogin":      "yes",
+	"PermitEmptyPasswords": "yes",
+}
+
+// sshdMaxIncludeDepth protects against Include loops, as sshd does.
+const sshdMaxIncludeDepth = 16
+
+// sshdMatchBlock holds the lines of a Match block, identified by its criteria (e.g. `User admin`).
+type sshdMatchBlock struct {
+	criteria string
+	lines    []string
+}
+
+// sshdConfig is the sshd configuration after Include directives have been expanded.
+type sshdConfig struct {
+	global  []string
+	matches []sshdMatchBlock
+}
+
 func init() {
 	pattern := `(?m)^(%s)`
 	parts := []string{}
@@ -84,7 +108,10 @@ func parseSshdConfig(configText string) (values map[string]string, err error) {
 
 		key, value := fields[0], fields[1]
 		if _, wanted := SshdConfigProperties[key]; wanted {
-			values[key] = value
+			// as sshd does, the first obtained value is used
+			if _, ok := values[key]; !ok {
+				values[key] = value
+			}
 		} else {
 			sshdlog.WithField("key", key).Warn("captured unknown config key from ssh config")
 			continue
@@ -94,13 +121,140 @@ func parseSshdConfig(configText string) (values map[string]string, err error) {
 	return
 }
 
+// convertSshValuesToPluginData converts the parsed values into inventory items. Values from Match
+// blocks are prefixed with the block criteria, e.g. `Match User admin/PasswordAuthentication`.
 func convertSshValuesToPluginData(configValues map[string]string) (dataset agent.PluginInventoryDataset) {
 	for key, value := range configValues {
-		dataset = append(dataset, SshdConfigValue{key, value})
+		property := key
+		if sep := strings.LastIndex(key, "/"); sep >= 0 {
+			property = key[sep+1:]
+		}
+		dataset = append(dataset, SshdConfigValue{
+			Key:   key,
+			Value: value,
+			Risky: strings.EqualFold(SshdRiskyValues[property], value),
+		})
 	}
 	return
 }
 
+// hostPath resolves the absolute paths of the host, e.g. in Include directives or home directories, when
+// the agent runs in a container. The paths of the host /etc directory are resolved under etcPath, which is
+// HOST_ETC, and the rest of absolute paths under its parent directory when it's named etc (e.g. /host/etc
+// for the host root mounted on /host). The relative paths are returned as they are.
+func hostPath(etcPath, path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	if path == "/etc" || strings.HasPrefix(path, "/etc/") {
+		return filepath.Join(etcPath, strings.TrimPrefix(path, "/etc"))
+	}
+	if filepath.Base(etcPath) == "etc" {
+		return filepath.Join(filepath.Dir(etcPath), path)
+	}
+	return path
+}
+
+// loadSshdConfig reads the ssh/sshd_config file of the etcPath directory, expanding its Include
+// directives and splitting the global settings from the Match blocks.
+func loadSshdConfig(etcPath string) (cfg sshdConfig, err error) {
+	path := filepath.Join(etcPath, "ssh", "sshd_config")
+	lines, err := readSshdConfigLines(path, filepath.Dir(path), etcPath, 0)
+	if err != nil {
+		return cfg, err
+	}
+
+	var block *sshdMatchBlock
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && strings.EqualFold(fields[0], "Match") {
+			cfg.matches = append(cfg.matches, sshdMatchBlock{criteria: strings.Join(fields, " ")})
+			block = &cfg.matches[len(cfg.matches)-1]
+			continue
+		}
+		if block == nil {
+			cfg.global = append(cfg.global, line)
+		} else {
+			block.lines = append(block.lines, line)
+		}
+	}
+	return cfg, nil
+}
+
+// readSshdConfigLines returns the lines of a config file, replacing the Include directives with
+// the content of the files they reference. Relative paths are resolved from the ssh config folder and
+// absolute paths under /etc from etcPath.
+func readSshdConfigLines(path, baseDir, etcPath string, depth int) (lines []string, err error) {
+	if depth > sshdMaxIncludeDepth {
+		return nil, fmt.Errorf("too many nested Include directives at %s", path)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "Include") {
+			lines = append(lines, line)
+			continue
+		}
+		for _, pattern := range fields[1:] {
+			if filepath.IsAbs(pattern) {
+				pattern = hostPath(etcPath, pattern)
+			} else {
+				pattern = filepath.Join(baseDir, pattern)
+			}
+			includes, err := filepath.Glob(pattern)
+			if err != nil {
+				sshdlog.WithError(err).WithField("pattern", pattern).Warn("invalid Include pattern in sshd config")
+				continue
+			}
+			for _, include := range includes {
+				included, err := readSshdConfigLines(include, baseDir, etcPath, depth+1)
+				if err != nil {
+					sshdlog.WithError(err).WithField("file", include).Warn("cannot read sshd config include")
+					continue
+				}
+				lines = append(lines, included...)
+			}
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// sshdConfigValues returns the wanted properties of the global section and of every Match block.
+func sshdConfigValues(cfg sshdConfig) (map[string]string, error) {
+	values, err := parseSshdConfig(strings.Join(cfg.global, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range cfg.matches {
+		matchValues, err := parseSshdConfig(strings.Join(match.lines, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range matchValues {
+			values[match.criteria+"/"+key] = value
+		}
+	}
+	return values, nil
+}
+
+// sshdAuthorizedKeysFiles returns the AuthorizedKeysFile patterns configured in the global section,
+// or the sshd defaults.
+func sshdAuthorizedKeysFiles(cfg sshdConfig) []string {
+	for _, line := range cfg.global {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && strings.EqualFold(fields[0], "AuthorizedKeysFile") {
+			return fields[1:]
+		}
+	}
+	return []string{".ssh/authorized_keys", ".ssh/authorized_keys2"}
+}
+
 func (self *SshdConfigPlugin) Run() {
 	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
 		sshdlog.Debug("Disabled.")
@@ -109,13 +263,13 @@ func (self *SshdConfigPlugin) Run() {
 
 	refreshTimer := time.NewTicker(self.frequency)
 	for {
-		configBuf, err := ioutil.ReadFile(helpers.HostEtc("/ssh/sshd_config"))
+		sshdCfg, err := loadSshdConfig(helpers.HostEtc())
 		if err != nil {
 			sshdlog.WithError(err).Error("reading sshd config file")
 			self.Unregister()
 			return
 		}
-		config, err := parseSshdConfig(string(configBuf))
+		config, err := sshdConfigValues(sshdCfg)
 		if err != nil {
 			sshdlog.WithError(err).Error("parsing sshd config file")
 		} else {
@@ -5,7 +5,13 @@
 
 package linux
 
-import . "gopkg.in/check.v1"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
 
 type SshdConfigSuite struct{}
 
@@ -50,3 +56,61 @@ PermitRootLogin no
 		c.Check(config, DeepEquals, inputs.expectedConfig)
 	}
 }
+
+func (self *SshdConfigSuite) TestLoadSshdConfigIncludeAndMatch(c *C) {
+	etc := c.MkDir()
+	dir := filepath.Join(etc, "ssh")
+	c.Assert(os.MkdirAll(filepath.Join(dir, "sshd_config.d"), 0755), IsNil)
+	// absolute includes are read from the host /etc (HOST_ETC)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "sshd_config"), []byte(`
+Include /etc/ssh/sshd_config.d/*.conf
+PermitRootLogin no
+PasswordAuthentication no
+
+Match User backup
+	PasswordAuthentication yes
+	PermitEmptyPasswords yes
+`), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "sshd_config.d", "50-cloud.conf"), []byte("PermitRootLogin yes\n"), 0644), IsNil)
+
+	cfg, err := loadSshdConfig(etc)
+	c.Assert(err, IsNil)
+	values, err := sshdConfigValues(cfg)
+	c.Assert(err, IsNil)
+
+	// the included file comes first, so its value wins
+	c.Check(values, DeepEquals, map[string]string{
+		"PermitRootLogin":                          "yes",
+		"PasswordAuthentication":                   "no",
+		"Match User backup/PasswordAuthentication": "yes",
+		"Match User backup/PermitEmptyPasswords":   "yes",
+	})
+
+	risky := map[string]bool{}
+	for _, item := range convertSshValuesToPluginData(values) {
+		value := item.(SshdConfigValue)
+		risky[value.Key] = value.Risky
+	}
+	c.Check(risky, DeepEquals, map[string]bool{
+		"PermitRootLogin":                          true,
+		"PasswordAuthentication":                   false,
+		"Match User backup/PasswordAuthentication": false,
+		"Match User backup/PermitEmptyPasswords":   true,
+	})
+}
+
+func (self *SshdConfigSuite) TestHostPath(c *C) {
+	for _, tc := range []struct {
+		etcPath, path, expected string
+	}{
+		{"/etc", "/etc/ssh/keys", "/etc/ssh/keys"},
+		{"/etc", "/home/alice/.ssh", "/home/alice/.ssh"},
+		{"/host/etc", "/etc/ssh/keys", "/host/etc/ssh/keys"},
+		{"/host/etc", "/home/alice/.ssh", "/host/home/alice/.ssh"},
+		{"/host-etc", "/etc/ssh/keys", "/host-etc/ssh/keys"},
+		{"/host-etc", "/home/alice/.ssh", "/home/alice/.ssh"},
+		{"/host/etc", ".ssh/authorized_keys", ".ssh/authorized_keys"},
+	} {
+		c.Check(hostPath(tc.etcPath, tc.path), Equals, tc.expected, Commentf("%s under %s", tc.path, tc.etcPath))
+	}
+}
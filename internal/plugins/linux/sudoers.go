@@ -0,0 +1,217 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+)
+
+// Sudoers risks
+const (
+	riskNoPasswd       = "nopasswd"
+	riskNoAuthenticate = "no_authenticate"
+	riskAllCommands    = "all_commands"
+)
+
+// sudoersMaxIncludeDepth is the include nesting limit enforced by sudo.
+const sudoersMaxIncludeDepth = 128
+
+// Sudoers entry kinds
+const (
+	sudoersDefaults = "defaults"
+	sudoersAlias    = "alias"
+	sudoersRule     = "rule"
+)
+
+var sudoersAliasKeywords = []string{"User_Alias", "Runas_Alias", "Host_Alias", "Cmnd_Alias", "Cmd_Alias"}
+
+type SudoersEntry struct {
+	ID        string `json:"id"`
+	Source    string `json:"source"`
+	Kind      string `json:"kind"`
+	Principal string `json:"principal,omitempty"`
+	Rule      string `json:"rule"`
+	Risky     bool   `json:"risky,omitempty"`
+	Risks     string `json:"risks,omitempty"`
+}
+
+func (s SudoersEntry) SortKey() string {
+	return s.ID
+}
+
+// sudoersLines returns the logical lines of a sudoers file, joining backslash continued lines and
+// dropping comments. Include directives, which look like comments, are kept.
+func sudoersLines(content string) (lines []string) {
+	var current strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\"))
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(line)
+		logical := strings.Join(strings.Fields(current.String()), " ")
+		current.Reset()
+
+		if logical == "" || (strings.HasPrefix(logical, "#") && !isSudoersInclude(logical)) {
+			continue
+		}
+		lines = append(lines, logical)
+	}
+	return lines
+}
+
+func isSudoersInclude(line string) bool {
+	for _, directive := range []string{"#include ", "@include ", "#includedir ", "@includedir "} {
+		if strings.HasPrefix(line, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// sudoersIncludedDirFiles returns the files of an includedir, skipping those that sudo ignores:
+// names ending in ~ or containing a dot.
+func sudoersIncludedDirFiles(dir string) (files []string) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), "~") || strings.Contains(entry.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	return files
+}
+
+// parseSudoersEntry classifies a sudoers line and flags its risks.
+func parseSudoersEntry(source, line string) SudoersEntry {
+	entry := SudoersEntry{Source: source, Rule: line}
+	first := strings.Fields(line)[0]
+
+	var risks []string
+	switch {
+	case strings.HasPrefix(first, "Defaults"):
+		entry.Kind = sudoersDefaults
+		if strings.Contains(line, "!authenticate") {
+			risks = append(risks, riskNoAuthenticate)
+		}
+	case isSudoersAlias(first):
+		entry.Kind = sudoersAlias
+	default:
+		entry.Kind = sudoersRule
+		entry.Principal = first
+		if strings.Contains(line, "NOPASSWD:") {
+			risks = append(risks, riskNoPasswd)
+		}
+		if entry.Principal != "root" && grantsAllCommands(line) {
+			risks = append(risks, riskAllCommands)
+		}
+	}
+	entry.Risky = len(risks) > 0
+	entry.Risks = strings.Join(risks, ",")
+	return entry
+}
+
+func isSudoersAlias(keyword string) bool {
+	for _, alias := range sudoersAliasKeywords {
+		if keyword == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsAllCommands tells whether any command list of a user specification is ALL, as in
+// `alice ALL=(ALL:ALL) NOPASSWD: ALL`.
+func grantsAllCommands(line string) bool {
+	eq := strings.Index(line, "=")
+	if eq < 0 {
+		return false
+	}
+	for _, spec := range strings.Split(line[eq+1:], ",") {
+		fields := strings.Fields(spec)
+		if len(fields) == 0 {
+			continue
+		}
+		// tags may be written without a space, as in NOPASSWD:ALL
+		command := fields[len(fields)-1]
+		if colon := strings.LastIndex(command, ":"); colon >= 0 {
+			command = command[colon+1:]
+		}
+		if command == "ALL" {
+			return true
+		}
+	}
+	return false
+}
+
+// readSudoers returns the entries of a sudoers file and the files it includes. The absolute include paths
+// under /etc are resolved from etcPath.
+func readSudoers(path, etcPath string, depth int) (entries []SudoersEntry) {
+	if depth > sudoersMaxIncludeDepth {
+		seclog.WithField("file", path).Warn("too many nested sudoers includes")
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		seclog.WithError(err).WithField("file", path).Debug("Can't read sudoers file.")
+		return nil
+	}
+
+	for _, line := range sudoersLines(string(content)) {
+		if isSudoersInclude(line) {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			target := fields[1]
+			if filepath.IsAbs(target) {
+				target = hostPath(etcPath, target)
+			} else {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			if strings.HasSuffix(fields[0], "includedir") {
+				for _, file := range sudoersIncludedDirFiles(target) {
+					entries = append(entries, readSudoers(file, etcPath, depth+1)...)
+				}
+			} else {
+				entries = append(entries, readSudoers(target, etcPath, depth+1)...)
+			}
+			continue
+		}
+		entries = append(entries, parseSudoersEntry(path, line))
+	}
+	return entries
+}
+
+func sudoersDataset(etcPath string) (dataset agent.PluginInventoryDataset, err error) {
+	seen := make(map[string]int)
+	for _, entry := range readSudoers(filepath.Join(etcPath, "sudoers"), etcPath, 0) {
+		sum := sha1.Sum([]byte(entry.Rule))
+		id := entry.Source + "#" + hex.EncodeToString(sum[:4])
+		count := seen[id]
+		seen[id] = count + 1
+		if count > 0 {
+			id = fmt.Sprintf("%s-%d", id, count)
+		}
+		entry.ID = id
+		dataset = append(dataset, entry)
+	}
+	return dataset, nil
+}
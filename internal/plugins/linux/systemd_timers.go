@@ -0,0 +1,217 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+// Systemd Timers Plugin
+// Reports the systemd timers and the units they activate
+package linux
+
+import (
+	"bufio"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var sdtlog = log.WithPlugin("SystemdTimers")
+
+var systemdTimersPluginID = ids.PluginID{Category: "scheduled", Term: "systemd_timers"}
+
+// timerProperties are the properties requested to `systemctl show` for every timer.
+var timerProperties = []string{
+	"Id", "Triggers", "ActiveState", "UnitFileState", "TimersCalendar", "TimersMonotonic",
+	"NextElapseUSecRealtime", "LastTriggerUSec", "Persistent",
+}
+
+// systemdTimerEventType is the type of the events reporting the trigger times of the timers.
+const systemdTimerEventType = "SystemdTimerSample"
+
+type SystemdTimersPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+}
+
+type SystemdTimer struct {
+	Name        string `json:"id"`
+	Activates   string `json:"activates"`
+	ActiveState string `json:"active_state"`
+	FileState   string `json:"unit_file_state,omitempty"`
+	OnCalendar  string `json:"on_calendar,omitempty"`
+	Monotonic   string `json:"monotonic,omitempty"`
+	Persistent  string `json:"persistent,omitempty"`
+	// The next and last trigger times change on every run, so they are reported as events instead of
+	// as inventory, where they would produce a delta on every refresh.
+	NextTrigger string `json:"-"`
+	LastTrigger string `json:"-"`
+}
+
+func (t SystemdTimer) SortKey() string {
+	return t.Name
+}
+
+func NewSystemdTimersPlugin(ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &SystemdTimersPlugin{
+		PluginCommon: agent.PluginCommon{ID: systemdTimersPluginID, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.SystemdTimersIntervalSec,
+			config.FREQ_MINIMUM_FAST_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_SCHEDULED_TASKS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+	}
+}
+
+// parseTimerUnits returns the timer names from `systemctl list-units --type=timer --plain --no-legend`.
+func parseTimerUnits(output string) (timers []string) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && strings.HasSuffix(fields[0], ".timer") {
+			timers = append(timers, fields[0])
+		}
+	}
+	return timers
+}
+
+// parseTimerProperties parses the `systemctl show` output, made of one block of Key=Value lines
+// per unit separated by blank lines.
+func parseTimerProperties(output string) (dataset agent.PluginInventoryDataset) {
+	var timer SystemdTimer
+	flush := func() {
+		if timer.Name != "" {
+			dataset = append(dataset, timer)
+		}
+		timer = SystemdTimer{}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+		if value == "n/a" {
+			value = ""
+		}
+		switch kv[0] {
+		case "Id":
+			timer.Name = value
+		case "Triggers":
+			timer.Activates = value
+		case "ActiveState":
+			timer.ActiveState = value
+		case "UnitFileState":
+			timer.FileState = value
+		case "TimersCalendar":
+			timer.OnCalendar = timerSpec(value, "OnCalendar")
+		case "TimersMonotonic":
+			timer.Monotonic = timerSpec(value, "")
+		case "NextElapseUSecRealtime":
+			timer.NextTrigger = value
+		case "LastTriggerUSec":
+			timer.LastTrigger = value
+		case "Persistent":
+			timer.Persistent = value
+		}
+	}
+	flush()
+
+	return dataset
+}
+
+// timerSpec extracts the trigger definition from a property such as
+// `{ OnCalendar=*-*-* 06:00:00 ; next_elapse=Mon 2022-11-07 06:00:00 UTC }`, dropping the
+// next_elapse part that changes on every run. When key is empty the trigger kind is kept, as for
+// `{ OnUnitActiveSec=1d ; next_elapse=1d 4h }`.
+func timerSpec(value, key string) string {
+	var specs []string
+	for _, block := range strings.Split(value, "}") {
+		block = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(block), "{"))
+		if block == "" {
+			continue
+		}
+		spec := strings.TrimSpace(strings.Split(block, ";")[0])
+		if key != "" {
+			spec = strings.TrimPrefix(spec, key+"=")
+		}
+		specs = append(specs, spec)
+	}
+	return strings.Join(specs, ", ")
+}
+
+// timerEvent returns the event reporting the trigger times of the timer.
+func timerEvent(timer SystemdTimer) map[string]interface{} {
+	event := map[string]interface{}{
+		"eventType": systemdTimerEventType,
+		"timerName": timer.Name,
+		"activates": timer.Activates,
+	}
+	if timer.NextTrigger != "" {
+		event["nextTrigger"] = timer.NextTrigger
+	}
+	if timer.LastTrigger != "" {
+		event["lastTrigger"] = timer.LastTrigger
+	}
+	return event
+}
+
+func (p *SystemdTimersPlugin) getTimersDataset() agent.PluginInventoryDataset {
+	output, err := helpers.RunCommand("/bin/systemctl", "", "--plain", "--no-pager", "--no-legend", "--all", "--type=timer", "list-units")
+	if err != nil {
+		sdtlog.WithError(err).Error("unable to list systemd timers")
+		return nil
+	}
+	timers := parseTimerUnits(output)
+	if len(timers) == 0 {
+		return nil
+	}
+
+	args := append([]string{"--no-pager", "--property=" + strings.Join(timerProperties, ","), "show"}, timers...)
+	output, err = helpers.RunCommand("/bin/systemctl", "", args...)
+	if err != nil {
+		sdtlog.WithError(err).Error("unable to get systemd timers properties")
+		return nil
+	}
+	return parseTimerProperties(output)
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (p *SystemdTimersPlugin) Run() {
+	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
+		sdtlog.Debug("Disabled.")
+		return
+	}
+
+	if !systemdPresent() {
+		p.Unregister()
+		return
+	}
+
+	refreshTimer := time.NewTicker(1)
+	for {
+		select {
+		case <-refreshTimer.C:
+			refreshTimer.Stop()
+			refreshTimer = time.NewTicker(p.frequency)
+			dataset := p.getTimersDataset()
+			p.EmitInventory(dataset, entity.NewFromNameWithoutID(p.Context.EntityKey()))
+			for _, timer := range dataset {
+				p.EmitEvent(timerEvent(timer.(SystemdTimer)), entity.Key(p.Context.EntityKey()))
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimerUnits(t *testing.T) {
+	output := `apt-daily.timer        loaded active waiting Daily apt download activities
+logrotate.timer        loaded active waiting Daily rotation of log files
+fstrim.timer           loaded inactive dead  Discard unused blocks once a week
+`
+	assert.Equal(t, []string{"apt-daily.timer", "logrotate.timer", "fstrim.timer"}, parseTimerUnits(output))
+}
+
+func TestParseTimerProperties(t *testing.T) {
+	output := `Id=logrotate.timer
+Triggers=logrotate.service
+ActiveState=active
+UnitFileState=enabled
+TimersCalendar={ OnCalendar=daily ; next_elapse=Tue 2022-11-08 00:00:00 UTC }
+NextElapseUSecRealtime=Tue 2022-11-08 00:00:00 UTC
+LastTriggerUSec=Mon 2022-11-07 00:00:02 UTC
+Persistent=yes
+
+Id=systemd-tmpfiles-clean.timer
+Triggers=systemd-tmpfiles-clean.service
+ActiveState=active
+UnitFileState=static
+TimersMonotonic={ OnBootUSec=15min ; next_elapse=0 } { OnUnitActiveUSec=1d ; next_elapse=1d 15min }
+NextElapseUSecRealtime=
+LastTriggerUSec=n/a
+Persistent=no
+`
+	dataset := parseTimerProperties(output)
+	require.Len(t, dataset, 2)
+	assert.Equal(t, SystemdTimer{
+		Name:        "logrotate.timer",
+		Activates:   "logrotate.service",
+		ActiveState: "active",
+		FileState:   "enabled",
+		OnCalendar:  "daily",
+		Persistent:  "yes",
+		NextTrigger: "Tue 2022-11-08 00:00:00 UTC",
+		LastTrigger: "Mon 2022-11-07 00:00:02 UTC",
+	}, dataset[0])
+	assert.Equal(t, SystemdTimer{
+		Name:        "systemd-tmpfiles-clean.timer",
+		Activates:   "systemd-tmpfiles-clean.service",
+		ActiveState: "active",
+		FileState:   "static",
+		Monotonic:   "OnBootUSec=15min, OnUnitActiveUSec=1d",
+		Persistent:  "no",
+	}, dataset[1])
+}
+
+func TestTimerTriggersAreEventsNotInventory(t *testing.T) {
+	timer := SystemdTimer{
+		Name:        "logrotate.timer",
+		Activates:   "logrotate.service",
+		ActiveState: "active",
+		NextTrigger: "Tue 2022-11-08 00:00:00 UTC",
+		LastTrigger: "Mon 2022-11-07 00:00:02 UTC",
+	}
+
+	inventory, err := json.Marshal(timer)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"logrotate.timer","activates":"logrotate.service","active_state":"active"}`, string(inventory))
+
+	assert.Equal(t, map[string]interface{}{
+		"eventType":   "SystemdTimerSample",
+		"timerName":   "logrotate.timer",
+		"activates":   "logrotate.service",
+		"nextTrigger": "Tue 2022-11-08 00:00:00 UTC",
+		"lastTrigger": "Mon 2022-11-07 00:00:02 UTC",
+	}, timerEvent(timer))
+
+	timer.LastTrigger = ""
+	assert.NotContains(t, timerEvent(timer), "lastTrigger")
+}
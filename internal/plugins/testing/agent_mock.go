@@ -60,6 +60,8 @@ func (m *MockAgent) ActiveEntitiesChannel() chan string {
 	return m.entities
 }
 
+func (m *MockAgent) InstanceGone(string) {}
+
 func (self *MockAgent) WithConfig(cfg *config.Config) *MockAgent {
 	self.cfg = cfg
 	return self
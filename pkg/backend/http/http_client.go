@@ -4,6 +4,7 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
@@ -75,6 +76,20 @@ func getCertPool(certFile string, certDirectory string) *x509.CertPool {
 	return caCertPool
 }
 
+// getClientCertificates loads the client certificate presented to the servers requesting mutual TLS authentication.
+func getClientCertificates(certFile string, keyFile string) []tls.Certificate {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		plog.WithFields(logrus.Fields{
+			"action":   "getClientCertificates",
+			"certFile": certFile,
+			"keyFile":  keyFile,
+		}).WithError(err).Error("can't load client certificate")
+		os.Exit(1)
+	}
+	return []tls.Certificate{cert}
+}
+
 // Client sends a request and returns a response or error.
 type Client func(req *http.Request) (*http.Response, error)
 
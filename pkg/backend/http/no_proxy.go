@@ -0,0 +1,115 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// noProxy matches the hosts that are connected directly, bypassing the proxy. It follows the NO_PROXY format: a
+// comma-separated list of:
+//
+// - IP addresses (1.2.3.4) and CIDRs (10.0.0.0/8)
+// - domain names, also matching their subdomains (example.com, .example.com)
+// - any of the above with a port (example.com:8080), only matching that port
+// - an asterisk (*), matching all the hosts
+//
+// The loopback addresses are always connected directly.
+type noProxy struct {
+	all     bool
+	cidrs   []*net.IPNet
+	ips     []hostPort
+	domains []hostPort
+}
+
+type hostPort struct {
+	host string
+	port string
+}
+
+func (h hostPort) matchesPort(port string) bool {
+	return h.port == "" || h.port == port
+}
+
+// newNoProxy parses the NO_PROXY lists.
+func newNoProxy(lists ...string) *noProxy {
+	np := &noProxy{}
+	for _, list := range lists {
+		for _, entry := range strings.Split(list, ",") {
+			entry = strings.ToLower(strings.TrimSpace(entry))
+			if entry == "" {
+				continue
+			}
+			if entry == "*" {
+				np.all = true
+				continue
+			}
+			if _, cidr, err := net.ParseCIDR(entry); err == nil {
+				np.cidrs = append(np.cidrs, cidr)
+				continue
+			}
+
+			hp := hostPort{host: entry}
+			if host, port, err := net.SplitHostPort(entry); err == nil {
+				hp = hostPort{host: host, port: port}
+			}
+			if ip := net.ParseIP(hp.host); ip != nil {
+				hp.host = ip.String()
+				np.ips = append(np.ips, hp)
+				continue
+			}
+			hp.host = strings.TrimPrefix(strings.TrimPrefix(hp.host, "*"), ".")
+			np.domains = append(np.domains, hp)
+		}
+	}
+	return np
+}
+
+// bypass returns true if the requests to the URL must bypass the proxy.
+func (np *noProxy) bypass(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return false
+	}
+	if host == LOCALHOST || np.all {
+		return true
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsLoopback() {
+			return true
+		}
+		for _, cidr := range np.cidrs {
+			if cidr.Contains(ip) {
+				return true
+			}
+		}
+		for _, entry := range np.ips {
+			if entry.host == ip.String() && entry.matchesPort(port) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, entry := range np.domains {
+		if (host == entry.host || strings.HasSuffix(host, "."+entry.host)) && entry.matchesPort(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultPort(scheme string) string {
+	if scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
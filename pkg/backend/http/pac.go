@@ -0,0 +1,175 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robertkrimen/otto"
+)
+
+// pacFetchTimeout is the timeout for downloading a PAC file from a URL.
+const pacFetchTimeout = 10 * time.Second
+
+// pac evaluates Proxy Auto-Config files, which define the proxy of each URL in their FindProxyForURL JavaScript
+// function. The time based functions (weekdayRange, dateRange and timeRange) aren't supported.
+type pac struct {
+	lock sync.Mutex // the JavaScript VM isn't safe for concurrent use
+	vm   *otto.Otto
+}
+
+// loadPAC loads the PAC file from a path or an http(s) URL. The PAC file is downloaded without proxy.
+func loadPAC(location string) (*pac, error) {
+	var script []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		script, err = fetchPAC(location)
+	} else {
+		script, err = ioutil.ReadFile(strings.TrimPrefix(location, "file://"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot load PAC file %q: %v", location, err)
+	}
+	return newPAC(string(script))
+}
+
+func fetchPAC(location string) ([]byte, error) {
+	client := http.Client{Timeout: pacFetchTimeout, Transport: &http.Transport{Proxy: nil}}
+	response, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %s", response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+// newPAC compiles the PAC script.
+func newPAC(script string) (*pac, error) {
+	vm := otto.New()
+	for name, fn := range pacFunctions {
+		if err := vm.Set(name, fn); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := vm.Run(script); err != nil {
+		return nil, fmt.Errorf("invalid PAC script: %v", err)
+	}
+	if fn, err := vm.Get("FindProxyForURL"); err != nil || !fn.IsFunction() {
+		return nil, fmt.Errorf("PAC script doesn't define the FindProxyForURL function")
+	}
+	return &pac{vm: vm}, nil
+}
+
+// findProxy returns the proxy for the URL, or nil when it must be connected directly. Only the first of the
+// proxies returned by the PAC script is used.
+func (p *pac) findProxy(u *url.URL) (*url.URL, error) {
+	p.lock.Lock()
+	result, err := p.vm.Call("FindProxyForURL", nil, u.String(), u.Hostname())
+	p.lock.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("cannot evaluate PAC script for %q: %v", u.Host, err)
+	}
+	return parsePACResult(result.String())
+}
+
+// parsePACResult parses the first entry of a FindProxyForURL result, like "PROXY proxy:3128; DIRECT".
+func parsePACResult(result string) (*url.URL, error) {
+	first := strings.TrimSpace(strings.Split(result, ";")[0])
+	fields := strings.Fields(first)
+	if len(fields) == 0 || (len(fields) == 1 && strings.EqualFold(fields[0], "DIRECT")) {
+		return nil, nil
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid PAC result %q", result)
+	}
+
+	var scheme string
+	switch strings.ToUpper(fields[0]) {
+	case "PROXY", "HTTP":
+		scheme = "http"
+	case "HTTPS":
+		scheme = "https"
+	case "SOCKS", "SOCKS5":
+		scheme = "socks5"
+	default:
+		return nil, fmt.Errorf("unsupported PAC result %q", result)
+	}
+	return &url.URL{Scheme: scheme, Host: fields[1]}, nil
+}
+
+// pacFunctions are the predefined functions available to the PAC scripts.
+var pacFunctions = map[string]interface{}{
+	"isPlainHostName": func(host string) bool {
+		return !strings.Contains(host, ".")
+	},
+	"dnsDomainIs": func(host, domain string) bool {
+		return strings.HasSuffix(strings.ToLower(host), strings.ToLower(domain))
+	},
+	"localHostOrDomainIs": func(host, hostDomain string) bool {
+		host, hostDomain = strings.ToLower(host), strings.ToLower(hostDomain)
+		return host == hostDomain || (!strings.Contains(host, ".") && strings.HasPrefix(hostDomain, host+"."))
+	},
+	"isResolvable": func(host string) bool {
+		_, err := net.LookupHost(host)
+		return err == nil
+	},
+	"isInNet": func(host, pattern, mask string) bool {
+		ip := net.ParseIP(pacResolve(host)).To4()
+		patternIP := net.ParseIP(pattern).To4()
+		maskIP := net.ParseIP(mask).To4()
+		if ip == nil || patternIP == nil || maskIP == nil {
+			return false
+		}
+		m := binary.BigEndian.Uint32(maskIP)
+		return binary.BigEndian.Uint32(ip)&m == binary.BigEndian.Uint32(patternIP)&m
+	},
+	"dnsResolve": pacResolve,
+	"myIpAddress": func() string {
+		addrs, err := net.InterfaceAddrs()
+		if err == nil {
+			for _, addr := range addrs {
+				if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+					return ipNet.IP.String()
+				}
+			}
+		}
+		return "127.0.0.1"
+	},
+	"dnsDomainLevels": func(host string) int {
+		return strings.Count(host, ".")
+	},
+	"shExpMatch": func(str, shExp string) bool {
+		expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(shExp))
+		matched, _ := regexp.MatchString("^"+expr+"$", str)
+		return matched
+	},
+}
+
+// pacResolve returns the first IPv4 address of the host, or an empty string if it can't be resolved.
+func pacResolve(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return ""
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
@@ -5,11 +5,13 @@ package http
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/pkg/config"
@@ -41,6 +43,8 @@ func proxyWithError(err error) proxyFunc {
 func defaultHttpTransport(
 	certFile string,
 	certDirectory string,
+	clientCertFile string,
+	clientKeyFile string,
 	httpTimeout time.Duration,
 	p proxyFunc,
 ) *http.Transport {
@@ -48,6 +52,12 @@ func defaultHttpTransport(
 	if certFile != "" || certDirectory != "" {
 		cfg = &tls.Config{RootCAs: getCertPool(certFile, certDirectory)}
 	}
+	if clientCertFile != "" {
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		cfg.Certificates = getClientCertificates(clientCertFile, clientKeyFile)
+	}
 	// go default Http Transport
 	return &http.Transport{
 		Proxy:                 p,
@@ -125,67 +135,162 @@ func proxyByPriority(cfg *config.Config) proxyConfig {
 // If the configuration option ignore_system_proxy is set, it ignores the HTTPS_PROXY and HTTP_PROXY configuration
 // If the configuration option proxy_validate_certificates is set, it will force the HTTPS proxy options to verify the
 // certificates
+//
+// The proxy_routes, no_proxy (and NO_PROXY env var) and proxy_pac configuration options take precedence over the
+// above proxy, as described in proxyRouter.
+//
+// The proxy_auth configuration option sets the authentication scheme of the proxy, as described in negotiateAuth and
+// ntlmTunnel. The client certificate of the client_cert_file configuration option is presented to both the HTTPS
+// proxies and the New Relic endpoints.
 func BuildTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper {
+	p, u := defaultProxy(cfg)
+	proxies := []*url.URL{u}
+	if router := newProxyRouter(cfg, p); router != nil {
+		p = router.proxy
+		proxies = append(proxies, router.routeProxies()...)
+	}
+
+	t := defaultHttpTransport(
+		cfg.CABundleFile,
+		cfg.CABundleDir,
+		cfg.ClientCertFile,
+		cfg.ClientKeyFile,
+		timeout,
+		p,
+	)
+
+	switch cfg.ProxyAuth {
+	case config.ProxyAuthNegotiate:
+		t.Proxy = withoutCredentials(p)
+		t.GetProxyConnectHeader = newNegotiateAuth(cfg).proxyConnectHeader
+	case config.ProxyAuthNTLM:
+		if p != nil {
+			// the connections are tunneled through the proxy by the dialer
+			tunnel := newNTLMTunnel(t, p, cfg.ProxyValidateCerts, timeout)
+			t.Proxy = nil
+			t.DialContext = tunnel.dialContext
+			return t
+		}
+	}
+
+	// the default proxy and the proxy_routes ones share the transport, so all of them are dialed in the same way
+	var httpsProxy bool
+	for _, proxyURL := range proxies {
+		if proxyURL == nil {
+			continue
+		}
+		if proxyURL.Scheme == "https" {
+			httpsProxy = true
+		} else if cfg.ProxyValidateCerts {
+			plog.WithField("scheme", proxyURL.Scheme).
+				Warn("proxy_validate_certificates option is set to 'true', but the Proxy URL scheme is not HTTPS")
+		}
+	}
+	if !httpsProxy {
+		return t
+	}
+
+	if !cfg.ProxyValidateCerts {
+		plog.WithField("scheme", "https").
+			Info("If using an HTTPS proxy, for enhanced security it is recommended to configure the agent with the" +
+				" 'proxy_validate_certificates' configuration option set to true")
+	}
+	dialer := newHTTPSProxyDialer(t, cfg.ProxyValidateCerts, timeout)
+	t.Proxy = dialer.recordProxies(t.Proxy)
+	t.DialTLS = dialer.dialTLS
+	return t
+}
+
+// httpsProxyDialer is the DialTLS of a transport with HTTPS proxies. The transport also uses it to connect to the
+// HTTPS endpoints that are reached without proxy (no_proxy, direct proxy_routes and PAC DIRECT), so only the proxies
+// returned by the proxy function are dialed with the legacy fallbacks of proxyTLSDialer. They have their own TLS
+// configuration, as the fallbacks change it, and the other connections are always verified.
+type httpsProxyDialer struct {
+	proxies  sync.Map // addresses of the HTTPS proxies
+	proxyTLS *http.Transport
+	direct   *tls.Config
+	dialer   *net.Dialer
+}
+
+func newHTTPSProxyDialer(t *http.Transport, validateCerts bool, timeout time.Duration) *httpsProxyDialer {
+	proxyTLS := &http.Transport{TLSClientConfig: t.TLSClientConfig.Clone()}
+	proxyTLS.DialTLS = proxyTLSDialer(proxyTLS, validateCerts)
+	return &httpsProxyDialer{
+		proxyTLS: proxyTLS,
+		direct:   t.TLSClientConfig,
+		dialer:   &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second},
+	}
+}
+
+// recordProxies wraps the proxy function of the transport to record the addresses of the HTTPS proxies it returns,
+// including the ones of the PAC file.
+func (d *httpsProxyDialer) recordProxies(p proxyFunc) proxyFunc {
+	return func(req *http.Request) (*url.URL, error) {
+		u, err := p(req)
+		if err == nil && u != nil && u.Scheme == "https" {
+			addr := u.Host
+			if u.Port() == "" {
+				addr = net.JoinHostPort(u.Hostname(), defaultPort(u.Scheme))
+			}
+			d.proxies.Store(addr, true)
+		}
+		return u, err
+	}
+}
+
+func (d *httpsProxyDialer) dialTLS(network, addr string) (net.Conn, error) {
+	if _, ok := d.proxies.Load(addr); ok {
+		return d.proxyTLS.DialTLS(network, addr)
+	}
+	return tls.DialWithDialer(d.dialer, network, addr, d.direct)
+}
+
+// proxyTLSDialer returns the dialer of the HTTPS proxies, according to the proxy_validate_certificates option.
+func proxyTLSDialer(t *http.Transport, validateCerts bool) func(network string, addr string) (net.Conn, error) {
+	if validateCerts {
+		return fullTLSToHTTPConnectFallbackDialer(t)
+	}
+	return fallbackDialer(t)
+}
+
+// defaultProxy returns the proxy function for the proxy chosen by proxyByPriority, and its URL. Both are nil if
+// there is no proxy, and the URL is nil if the proxy is wrongly configured.
+func defaultProxy(cfg *config.Config) (proxyFunc, *url.URL) {
 	proxyConfig := proxyByPriority(cfg)
 
 	if proxyConfig.isEmpty() {
-		return defaultHttpTransport(
-			cfg.CABundleFile,
-			cfg.CABundleDir,
-			timeout,
-			nil, // no proxy configuration
-		)
+		return nil, nil // no proxy configuration
 	}
 
-	u, err := url.Parse(proxyConfig.raw)
-	if err != nil || !hasValidScheme(u.Scheme) {
-		// (taken from "ProxyFromEnvironment" Go standard library function)
-		// proxy was bogus. Try prepending "http://" to it and
-		// see if that parses correctly. If not, we fall
-		// through and complain about the original one.
-		u, err = url.Parse("http://" + proxyConfig.raw)
-	}
+	u, err := parseProxyURL(proxyConfig.raw)
 	if err != nil {
-		err = fmt.Errorf("invalid proxy address %q: %v", proxyConfig.raw, err)
 		logrus.WithError(err).Error()
-		return defaultHttpTransport(
-			cfg.CABundleFile,
-			cfg.CABundleDir,
-			timeout,
-			proxyWithError(err))
+		return proxyWithError(err), nil
 	}
 
 	if proxyConfig.forceSchema != "" && proxyConfig.forceSchema != u.Scheme {
 		err = fmt.Errorf("schema from %s must be %q", proxyConfig.source, proxyConfig.forceSchema)
 		logrus.WithError(err).Error()
-		return defaultHttpTransport(
-			cfg.CABundleFile,
-			cfg.CABundleDir,
-			timeout,
-			proxyWithError(err))
+		return proxyWithError(err), nil
 	}
 
-	t := defaultHttpTransport(
-		cfg.CABundleFile,
-		cfg.CABundleDir,
-		timeout,
-		proxy(u),
-	)
+	return proxy(u), u
+}
 
-	if cfg.ProxyValidateCerts {
-		if u.Scheme == "https" {
-			t.DialTLS = fullTLSToHTTPConnectFallbackDialer(t)
-		} else {
-			plog.WithField("scheme", u.Scheme).
-				Warn("proxy_validate_certificates option is set to 'true', but the Proxy URL scheme is not HTTPS")
-		}
-	} else if u.Scheme == "https" {
-		plog.WithField("scheme", u.Scheme).
-			Info("If using an HTTPS proxy, for enhanced security it is recommended to configure the agent with the" +
-				" 'proxy_validate_certificates' configuration option set to true")
-		t.DialTLS = fallbackDialer(t)
+// parseProxyURL parses a proxy address, which defaults to the http scheme.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || !hasValidScheme(u.Scheme) {
+		// (taken from "ProxyFromEnvironment" Go standard library function)
+		// proxy was bogus. Try prepending "http://" to it and
+		// see if that parses correctly. If not, we fall
+		// through and complain about the original one.
+		u, err = url.Parse("http://" + raw)
 	}
-	return t
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address %q: %v", raw, err)
+	}
+	return u, nil
 }
 
 func hasValidScheme(s string) bool {
@@ -219,6 +324,11 @@ func fallbackDialer(transport *http.Transport) func(network string, addr string)
 			transport.DialTLS = dialer
 			return conn, err
 		}
+		// the verification errors are wrapped in a tls.CertificateVerificationError since Go 1.20
+		var unknownAuthority x509.UnknownAuthorityError
+		if errors.As(err, &unknownAuthority) {
+			err = unknownAuthority
+		}
 		switch err.(type) {
 		case x509.UnknownAuthorityError:
 			plog.WithError(err).Debug("Usual, secured configuration did not work as expected. Retrying with verification skip.")
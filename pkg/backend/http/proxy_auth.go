@@ -0,0 +1,308 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-ntlmssp"
+	"github.com/jcmturner/gokrb5/v8/client"
+	krb5config "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+const (
+	defaultKrb5Conf = "/etc/krb5.conf"
+	// maxNTLMLegs is the maximum number of CONNECT requests of the NTLM handshake (negotiate and authenticate).
+	maxNTLMLegs = 2
+)
+
+// withoutCredentials removes the credentials from the proxy URLs, so the transport doesn't send them as basic
+// authentication.
+func withoutCredentials(p proxyFunc) proxyFunc {
+	if p == nil {
+		return nil
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		u, err := p(req)
+		if u == nil || u.User == nil {
+			return u, err
+		}
+		withoutUser := *u
+		withoutUser.User = nil
+		return &withoutUser, err
+	}
+}
+
+// negotiateAuth authenticates the CONNECT requests to the proxy with Kerberos (SPNEGO), with the credentials of the
+// proxy_auth_keytab, or the proxy_auth_ccache credential cache. The Kerberos client is created on the first request,
+// and recreated after a failure, so the renewed credential caches are loaded.
+type negotiateAuth struct {
+	principal string
+	keytab    string
+	ccache    string
+	krb5Conf  string
+	spn       string
+
+	lock   sync.Mutex
+	client *client.Client
+}
+
+func newNegotiateAuth(cfg *config.Config) *negotiateAuth {
+	a := &negotiateAuth{
+		principal: cfg.ProxyAuthPrincipal,
+		keytab:    cfg.ProxyAuthKeytab,
+		ccache:    cfg.ProxyAuthCCache,
+		krb5Conf:  cfg.ProxyAuthKrb5Conf,
+		spn:       cfg.ProxyAuthSPN,
+	}
+	if a.ccache == "" {
+		a.ccache = strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
+	}
+	if a.ccache == "" {
+		a.ccache = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+	}
+	if a.krb5Conf == "" {
+		a.krb5Conf = os.Getenv("KRB5_CONFIG")
+	}
+	if a.krb5Conf == "" {
+		a.krb5Conf = defaultKrb5Conf
+	}
+	return a
+}
+
+// proxyConnectHeader is the GetProxyConnectHeader function of the transport.
+func (a *negotiateAuth) proxyConnectHeader(_ context.Context, proxyURL *url.URL, _ string) (http.Header, error) {
+	authorization, err := a.authorization(proxyURL)
+	if err != nil {
+		plog.WithError(err).WithField("proxy", proxyURL.Host).Error("cannot authenticate with the proxy")
+		return nil, err
+	}
+	return http.Header{"Proxy-Authorization": {authorization}}, nil
+}
+
+// authorization returns the Proxy-Authorization header with the SPNEGO token for the proxy.
+func (a *negotiateAuth) authorization(proxyURL *url.URL) (string, error) {
+	spn := a.spn
+	if spn == "" {
+		spn = "HTTP/" + proxyURL.Hostname()
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.client == nil {
+		var err error
+		if a.client, err = a.newClient(); err != nil {
+			return "", err
+		}
+	}
+
+	s := spnego.SPNEGOClient(a.client, spn)
+	if err := s.AcquireCred(); err != nil {
+		a.client = nil
+		return "", fmt.Errorf("cannot acquire Kerberos credentials: %v", err)
+	}
+	token, err := s.InitSecContext()
+	if err != nil {
+		return "", fmt.Errorf("cannot get Kerberos ticket for %q: %v", spn, err)
+	}
+	data, err := token.Marshal()
+	if err != nil {
+		return "", err
+	}
+	return "Negotiate " + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// newClient creates the Kerberos client from the keytab, or from the credential cache if there isn't keytab.
+func (a *negotiateAuth) newClient() (*client.Client, error) {
+	krb5Conf, err := krb5config.Load(a.krb5Conf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load Kerberos configuration %q: %v", a.krb5Conf, err)
+	}
+
+	if a.keytab != "" {
+		kt, err := keytab.Load(a.keytab)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load keytab %q: %v", a.keytab, err)
+		}
+		user, realm := a.principal, krb5Conf.LibDefaults.DefaultRealm
+		if i := strings.LastIndex(a.principal, "@"); i >= 0 {
+			user, realm = a.principal[:i], a.principal[i+1:]
+		}
+		return client.NewWithKeytab(user, realm, kt, krb5Conf, client.DisablePAFXFAST(true)), nil
+	}
+
+	ccache, err := credentials.LoadCCache(a.ccache)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load credential cache %q: %v", a.ccache, err)
+	}
+	return client.NewFromCCache(ccache, krb5Conf, client.DisablePAFXFAST(true))
+}
+
+// ntlmTunnel dials the connections through the proxy with an HTTP CONNECT tunnel authenticated with NTLM, using the
+// credentials of the proxy URL (DOMAIN\user:password). The NTLM handshake authenticates the connection instead of
+// the requests, so it can't be done by the transport. As the proxy is chosen when dialing, the proxy_routes of the
+// endpoints sharing host are applied by host.
+type ntlmTunnel struct {
+	proxy  proxyFunc
+	dialer *net.Dialer
+	// proxyTLS holds the dialer of the HTTPS proxies (see fallbackDialer and fullTLSToHTTPConnectFallbackDialer).
+	proxyTLS *http.Transport
+}
+
+func newNTLMTunnel(t *http.Transport, p proxyFunc, validateCerts bool, timeout time.Duration) *ntlmTunnel {
+	proxyTLS := &http.Transport{TLSClientConfig: t.TLSClientConfig.Clone()}
+	proxyTLS.DialTLS = proxyTLSDialer(proxyTLS, validateCerts)
+	return &ntlmTunnel{
+		proxy:    p,
+		dialer:   &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second},
+		proxyTLS: proxyTLS,
+	}
+}
+
+// dialContext is the DialContext function of the transport.
+func (n *ntlmTunnel) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyURL, err := n.proxy(&http.Request{URL: targetURL(addr)})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return n.dialer.DialContext(ctx, network, addr)
+	}
+	if proxyURL.Scheme == "socks5" {
+		return nil, fmt.Errorf("ntlm proxy authentication isn't supported by SOCKS5 proxies")
+	}
+
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), defaultPort(proxyURL.Scheme))
+	}
+	var conn net.Conn
+	if proxyURL.Scheme == "https" {
+		conn, err = n.proxyTLS.DialTLS(network, proxyAddr)
+	} else {
+		conn, err = n.dialer.DialContext(ctx, network, proxyAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else if n.dialer.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(n.dialer.Timeout))
+	}
+	tunnel, err := connectNTLM(conn, proxyURL, addr)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// targetURL returns the URL of the dialed address, for choosing its proxy. The default ports are omitted, so the URL
+// matches the endpoints of the proxy_routes.
+func targetURL(addr string) *url.URL {
+	u := &url.URL{Scheme: "http", Host: addr}
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		if port == "443" {
+			u.Scheme = "https"
+		}
+		if port == defaultPort(u.Scheme) {
+			u.Host = strings.TrimSuffix(addr, ":"+port)
+		}
+	}
+	return u
+}
+
+// connectNTLM opens the tunnel to the address through the proxy connection, sending the NTLM negotiate message, and
+// answering the challenge of the proxy with the authenticate message.
+func connectNTLM(conn net.Conn, proxyURL *url.URL, addr string) (net.Conn, error) {
+	if proxyURL.User == nil {
+		return nil, fmt.Errorf("ntlm proxy authentication requires the credentials in the proxy URL")
+	}
+	user, domain := ntlmssp.GetDomain(proxyURL.User.Username())
+	password, _ := proxyURL.User.Password()
+
+	message, err := ntlmssp.NewNegotiateMessage(domain, "")
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	for leg := 1; ; leg++ {
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: http.Header{
+				"Proxy-Authorization": {"NTLM " + base64.StdEncoding.EncodeToString(message)},
+				"Proxy-Connection":    {"Keep-Alive"},
+			},
+		}
+		if err := req.Write(conn); err != nil {
+			return nil, err
+		}
+		resp, err := http.ReadResponse(reader, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			if reader.Buffered() > 0 {
+				return &bufferedConn{Conn: conn, reader: reader}, nil
+			}
+			return conn, nil
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		challenge := ntlmChallenge(resp.Header)
+		if resp.StatusCode != http.StatusProxyAuthRequired || challenge == "" || resp.Close || leg == maxNTLMLegs {
+			return nil, fmt.Errorf("ntlm proxy authentication failed: %s", resp.Status)
+		}
+		data, err := base64.StdEncoding.DecodeString(challenge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ntlm challenge from the proxy: %v", err)
+		}
+		if message, err = ntlmssp.ProcessChallenge(data, user, password); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ntlmChallenge returns the NTLM challenge of the Proxy-Authenticate headers, or an empty string if there isn't.
+func ntlmChallenge(header http.Header) string {
+	for _, value := range header.Values("Proxy-Authenticate") {
+		if fields := strings.Fields(value); len(fields) == 2 && strings.EqualFold(fields[0], "NTLM") {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// bufferedConn is a tunneled connection whose first bytes were already read from the proxy.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+const (
+	sourceProxyRoutes = "proxy_routes configuration option"
+	sourceNoProxy     = "no_proxy configuration option or NO_PROXY environment variable"
+	sourceProxyPAC    = "proxy_pac configuration option"
+)
+
+// endpointRoute routes the requests to a New Relic endpoint through a proxy, or directly when the proxy is nil.
+type endpointRoute struct {
+	name     string
+	endpoint *url.URL
+	proxy    *url.URL
+}
+
+// matches returns true if the URL belongs to the endpoint. The URLs without path, like the ones of the connections
+// tunneled by ntlmTunnel, match any endpoint of their host.
+func (r endpointRoute) matches(u *url.URL) bool {
+	return strings.EqualFold(r.endpoint.Host, u.Host) && (u.Path == "" || strings.HasPrefix(u.Path, r.endpoint.Path))
+}
+
+// proxyRouter chooses the proxy of each request, according to the following priorities (from larger to lower
+// priority):
+//
+// 1. proxy_routes config option, for the requests to the routed New Relic endpoints
+// 2. no_proxy config option and NO_PROXY env var, connecting directly
+// 3. proxy_pac config option
+// 4. the default proxy (see proxyByPriority)
+type proxyRouter struct {
+	routes         []endpointRoute // sorted from the longest to the shortest endpoint path
+	noProxy        *noProxy
+	pac            *pac
+	fallback       proxyFunc
+	fallbackSource string
+}
+
+// newProxyRouter returns a proxyRouter falling back to the default proxy, or nil if neither proxy_routes,
+// no_proxy/NO_PROXY nor proxy_pac are configured.
+func newProxyRouter(cfg *config.Config, fallback proxyFunc) *proxyRouter {
+	noProxyList := cfg.NoProxy
+	if !cfg.IgnoreSystemProxy {
+		noProxyList = strings.Join([]string{noProxyList, os.Getenv("NO_PROXY"), os.Getenv("no_proxy")}, ",")
+	}
+	if strings.Trim(noProxyList, ", ") == "" && cfg.ProxyPAC == "" && len(cfg.ProxyRoutes) == 0 {
+		return nil
+	}
+
+	r := &proxyRouter{
+		routes:         endpointRoutes(cfg),
+		noProxy:        newNoProxy(noProxyList),
+		fallback:       fallback,
+		fallbackSource: proxyByPriority(cfg).source,
+	}
+	if cfg.ProxyPAC != "" {
+		// the PAC file is loaded once, so its changes require restarting the agent
+		var err error
+		if r.pac, err = loadPAC(cfg.ProxyPAC); err != nil {
+			plog.WithError(err).Error("cannot load the proxy_pac file, ignoring it")
+		}
+	}
+	return r
+}
+
+// endpointRoutes returns the routes of the New Relic endpoints defined in proxy_routes, ignoring the logs, which
+// are sent by the log forwarder.
+func endpointRoutes(cfg *config.Config) (routes []endpointRoute) {
+	endpoints := endpointURLs(cfg)
+	for name, rawRoute := range cfg.ProxyRoutes {
+		var proxyURL *url.URL
+		if rawRoute != config.ProxyRouteDirect {
+			var err error
+			if proxyURL, err = parseProxyURL(rawRoute); err != nil {
+				plog.WithError(err).WithField("endpoint", name).Error("invalid proxy route, ignoring it")
+				continue
+			}
+		}
+		for _, rawEndpoint := range endpoints[name] {
+			endpoint, err := url.Parse(rawEndpoint)
+			if err != nil || endpoint.Host == "" {
+				continue
+			}
+			routes = append(routes, endpointRoute{name: name, endpoint: endpoint, proxy: proxyURL})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return len(routes[i].endpoint.Path) > len(routes[j].endpoint.Path)
+	})
+	return routes
+}
+
+// endpointURLs returns the URLs of the New Relic endpoints requested by the agent, by route name.
+func endpointURLs(cfg *config.Config) map[string][]string {
+	return map[string][]string{
+		config.ProxyRouteMetrics:        {cfg.CollectorURL + cfg.MetricsIngestEndpoint, cfg.DMIngestURL()},
+		config.ProxyRouteInventory:      {cfg.CollectorURL + cfg.InventoryIngestEndpoint},
+		config.ProxyRouteIdentity:       {cfg.IdentityURL + cfg.IdentityIngestEndpoint},
+		config.ProxyRouteCommandChannel: {cfg.CommandChannelURL + cfg.CommandChannelEndpoint},
+	}
+}
+
+// routeProxies returns the proxies of the proxy_routes option.
+func (r *proxyRouter) routeProxies() (proxies []*url.URL) {
+	for _, route := range r.routes {
+		if route.proxy != nil {
+			proxies = append(proxies, route.proxy)
+		}
+	}
+	return proxies
+}
+
+// proxy is the proxyFunc for the transport.
+func (r *proxyRouter) proxy(req *http.Request) (*url.URL, error) {
+	u, _, err := r.resolve(req)
+	return u, err
+}
+
+// resolve returns the proxy of the request, or nil when it's connected directly, and the source of the decision.
+func (r *proxyRouter) resolve(req *http.Request) (*url.URL, string, error) {
+	for _, route := range r.routes {
+		if route.matches(req.URL) {
+			return route.proxy, sourceProxyRoutes, nil
+		}
+	}
+
+	if r.noProxy.bypass(req.URL) {
+		return nil, sourceNoProxy, nil
+	}
+
+	if r.pac != nil {
+		u, err := r.pac.findProxy(req.URL)
+		if err == nil {
+			return u, sourceProxyPAC, nil
+		}
+		plog.WithError(err).Warn("cannot evaluate the proxy_pac file, using the default proxy")
+	}
+
+	if r.fallback == nil {
+		return nil, "", nil
+	}
+	u, err := r.fallback(req)
+	return u, r.fallbackSource, err
+}
+
+// EndpointRoute describes how the agent connects to a New Relic endpoint. The proxy address isn't included, to avoid
+// reporting its credentials.
+type EndpointRoute struct {
+	Direct bool
+	Scheme string // Scheme of the proxy.
+	Source string // Configuration deciding the route.
+	Error  string
+}
+
+// EffectiveRoutes returns how the agent connects to each New Relic endpoint that can be routed in proxy_routes.
+func EffectiveRoutes(cfg *config.Config) map[string]EndpointRoute {
+	fallback, _ := defaultProxy(cfg)
+	router := newProxyRouter(cfg, fallback)
+	if router == nil {
+		router = &proxyRouter{noProxy: newNoProxy(), fallback: fallback, fallbackSource: proxyByPriority(cfg).source}
+	}
+
+	routes := make(map[string]EndpointRoute)
+	for name, urls := range endpointURLs(cfg) {
+		req, err := http.NewRequest(http.MethodPost, urls[0], nil)
+		if err != nil {
+			routes[name] = EndpointRoute{Error: "wrong url"}
+			continue
+		}
+		routes[name] = newEndpointRoute(router.resolve(req))
+	}
+
+	// logs are sent by the log forwarder, only supporting proxy_routes and the default proxy
+	if rawRoute, ok := cfg.ProxyRoutes[config.ProxyRouteLogs]; ok {
+		if rawRoute == config.ProxyRouteDirect {
+			routes[config.ProxyRouteLogs] = newEndpointRoute(nil, sourceProxyRoutes, nil)
+		} else {
+			u, err := parseProxyURL(rawRoute)
+			routes[config.ProxyRouteLogs] = newEndpointRoute(u, sourceProxyRoutes, err)
+		}
+	} else {
+		u, err := router.fallbackProxy()
+		routes[config.ProxyRouteLogs] = newEndpointRoute(u, router.fallbackSource, err)
+	}
+	return routes
+}
+
+// fallbackProxy returns the default proxy, which doesn't depend on the request.
+func (r *proxyRouter) fallbackProxy() (*url.URL, error) {
+	if r.fallback == nil {
+		return nil, nil
+	}
+	return r.fallback(nil)
+}
+
+func newEndpointRoute(proxyURL *url.URL, source string, err error) EndpointRoute {
+	if err != nil {
+		// generic error message to avoid reporting the proxy url, by security reasons
+		return EndpointRoute{Source: source, Error: "wrong proxy"}
+	}
+	if proxyURL == nil {
+		return EndpointRoute{Direct: true, Source: source}
+	}
+	return EndpointRoute{Scheme: proxyURL.Scheme, Source: source}
+}
@@ -77,6 +77,9 @@ type RegisterClient interface {
 
 	// RegisterEntity registers a protocol.Entity
 	RegisterEntity(agentEntityID entity.ID, entity entity.Fields) (RegisterEntityResponse, error)
+
+	// DecommissionEntities notifies the entities registered by the agent that aren't monitored anymore.
+	DecommissionEntities(agentEntityID entity.ID, entityIDs []entity.ID) error
 }
 
 type registerClient struct {
@@ -103,6 +106,10 @@ type RegisterEntityResponse struct {
 	Warnings []string  `json:"warnings"`
 }
 
+type decommissionRequest struct {
+	ID entity.ID `json:"entityId"`
+}
+
 func NewRegisterEntity(key entity.Key) RegisterEntity {
 	return RegisterEntity{key, "", "", nil, nil}
 }
@@ -256,6 +263,47 @@ func (rc *registerClient) RegisterEntitiesRemoveMe(agentID entity.ID, entities [
 	return
 }
 
+// DecommissionEntities submits a batch of entity IDs to be decommissioned. Rejected requests return a
+// RegisterEntityError, so the caller can decide whether to retry them.
+func (rc *registerClient) DecommissionEntities(agentEntityID entity.ID, entityIDs []entity.ID) error {
+	if agentEntityID.IsEmpty() {
+		return ErrEmptyAgentID
+	}
+
+	reqs := make([]decommissionRequest, len(entityIDs))
+	for i, id := range entityIDs {
+		reqs[i] = decommissionRequest{ID: id}
+	}
+	buf, err := rc.marshal(reqs)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", rc.makeURL(identityPath+"/decommission/batch"), buf)
+	if err != nil {
+		return fmt.Errorf("decommission request build failed: %s", err)
+	}
+	if rc.compressionLevel > gzip.NoCompression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := rc.do(req, agentEntityID)
+	if err != nil {
+		return fmt.Errorf("decommission request failed: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.WithError(err).Debug("Error closing decommission body response.")
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return NewRegisterEntityError(resp.Status, resp.StatusCode, fmt.Errorf("decommission rejected: %s", body))
+	}
+	return nil
+}
+
 func (rc *registerClient) makeURL(requestPath string) string {
 	requestPath = strings.TrimPrefix(requestPath, "/")
 	return fmt.Sprintf("%s/%s", rc.svcUrl, requestPath)
@@ -117,6 +117,53 @@ func TestRegisterOk(t *testing.T) {
 	assert.EqualValues(t, testRegisterEntityResponse, entities)
 }
 
+func TestRegisterClient_DecommissionEntities(t *testing.T) {
+	var path string
+	var body []decommissionRequest
+	mockHttpClient := &http.Client{
+		Transport: &mockHttpTransport{
+			roundTrip: func(req *http.Request) (*http.Response, error) {
+				path = req.URL.Path
+				gzipReader, err := gzip.NewReader(req.Body)
+				require.NoError(t, err)
+				require.NoError(t, json.NewDecoder(gzipReader).Decode(&body))
+				return getRegisterResponse(nil)
+			},
+		},
+	}
+
+	client, err := NewRegisterClient(testUrl, testLicenseKey, testUserAgent, gzip.BestCompression, mockHttpClient)
+	require.NoError(t, err)
+
+	require.NoError(t, client.DecommissionEntities(testAgentEntityId, []entity.ID{12345, 54321}))
+	assert.Equal(t, "/identity/v1/decommission/batch", path)
+	assert.Equal(t, []decommissionRequest{{ID: 12345}, {ID: 54321}}, body)
+}
+
+func TestRegisterClient_DecommissionEntitiesRejected(t *testing.T) {
+	mockHttpClient := &http.Client{
+		Transport: &mockHttpTransport{
+			roundTrip: func(req *http.Request) (*http.Response, error) {
+				resp, err := getRegisterResponse(nil)
+				resp.StatusCode = StatusCodeLimitExceed
+				resp.Status = "503 Service Unavailable"
+				return resp, err
+			},
+		},
+	}
+
+	client, err := NewRegisterClient(testUrl, testLicenseKey, testUserAgent, gzip.BestCompression, mockHttpClient)
+	require.NoError(t, err)
+
+	err = client.DecommissionEntities(testAgentEntityId, []entity.ID{12345})
+	require.Error(t, err)
+	e, ok := err.(*RegisterEntityError)
+	require.True(t, ok)
+	assert.True(t, e.ShouldRetry())
+
+	assert.Equal(t, ErrEmptyAgentID, client.DecommissionEntities(entity.EmptyID, []entity.ID{12345}))
+}
+
 func TestRegisterMakeUrl(t *testing.T) {
 	client := registerClient{svcUrl: testUrl}
 
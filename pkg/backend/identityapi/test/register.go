@@ -3,6 +3,7 @@ package test
 import (
 	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/pkg/backend/identityapi"
@@ -25,6 +26,10 @@ func (icc *EmptyRegisterClient) RegisterEntity(agentEntityID entity.ID, entity e
 	return
 }
 
+func (icc *EmptyRegisterClient) DecommissionEntities(agentEntityID entity.ID, entityIDs []entity.ID) error {
+	return nil
+}
+
 type IncrementalRegister struct {
 	state state.Register
 }
@@ -84,3 +89,35 @@ func (r *IncrementalRegister) RegisterEntity(agentEntityID entity.ID, ent entity
 		Name: ent.Name,
 	}, nil
 }
+
+func (r *IncrementalRegister) DecommissionEntities(agentEntityID entity.ID, entityIDs []entity.ID) error {
+	return nil
+}
+
+// DecommissionRecorder is a RegisterClient recording the decommissioned entity IDs.
+type DecommissionRecorder struct {
+	EmptyRegisterClient
+	lock           sync.Mutex
+	decommissioned []entity.ID
+	// Err is returned by the decommission requests when set.
+	Err error
+}
+
+func (r *DecommissionRecorder) DecommissionEntities(agentEntityID entity.ID, entityIDs []entity.ID) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	r.decommissioned = append(r.decommissioned, entityIDs...)
+	return nil
+}
+
+// Decommissioned returns the entity IDs decommissioned so far.
+func (r *DecommissionRecorder) Decommissioned() []entity.ID {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]entity.ID{}, r.decommissioned...)
+}
@@ -634,6 +634,46 @@ func Test_ParseIncludeMatchingRule_EnvVar(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(cfg.IncludeMetricsMatchers, expected))
 }
 
+func Test_ParseSamplers(t *testing.T) {
+	configStr := `
+license_key: abc123
+samplers:
+  statsfile:
+    interval: 1
+    config:
+      path: /dev/shm/myapp.stats
+  other: {}
+`
+	f, err := ioutil.TempFile("", "samplers_config_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(configStr)
+	f.Close()
+
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	require.Len(t, cfg.Samplers, 2)
+	assert.Equal(t, 1, cfg.Samplers["statsfile"].Interval)
+	assert.Equal(t, "/dev/shm/myapp.stats", cfg.Samplers["statsfile"].Config["path"])
+	assert.Equal(t, FREQ_DEFAULT_SAMPLING, cfg.Samplers["other"].Interval)
+}
+
+func Test_ParseSamplers_EnvVar(t *testing.T) {
+	os.Setenv("NRIA_SAMPLERS", `{"statsfile": {"interval": 1, "config": {"path": "/dev/shm/myapp.stats"}}}`)
+	defer os.Unsetenv("NRIA_SAMPLERS")
+
+	f, err := ioutil.TempFile("", "yaml_config_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("license_key: abc123")
+	f.Close()
+
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	expected := SamplersConfig{"statsfile": {Interval: 1, Config: map[string]interface{}{"path": "/dev/shm/myapp.stats"}}}
+	assert.Equal(t, expected, cfg.Samplers)
+}
+
 func Test_ParseLogConfigRule_EnvVar(t *testing.T) {
 	os.Setenv("NRIA_LOG_FILE", "agent.log")
 	defer os.Unsetenv("NRIA_LOG_FILE")
@@ -852,6 +892,106 @@ license_key: ${license}
 	assert.Equal(t, "XXX", cfg.License)
 }
 
+func TestLoadYamlConfig_ProxyRoutes(t *testing.T) {
+	yamlData := []byte(`
+license_key: abc123
+proxy: http://default-proxy:3128
+proxy_routes:
+  metrics: http://telemetry-proxy:3128
+  logs: direct
+  unknown: http://other-proxy:3128
+`)
+
+	tmp, err := createTestFile(yamlData)
+	require.NoError(t, err)
+	defer os.Remove(tmp.Name())
+
+	cfg, err := LoadConfig(tmp.Name())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		ProxyRouteMetrics: "http://telemetry-proxy:3128",
+		ProxyRouteLogs:    ProxyRouteDirect,
+	}, cfg.ProxyRoutes)
+
+	logForward := NewLogForward(cfg, Troubleshoot{})
+	assert.Empty(t, logForward.ProxyCfg.Proxy)
+	assert.True(t, logForward.ProxyCfg.IgnoreSystemProxy)
+}
+
+func TestLoadYamlConfig_ProcessAggregation(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("the systemdUnit aggregation key is not available on macOS")
+	}
+	yamlData := []byte(`
+license_key: abc123
+enable_process_aggregation: true
+process_aggregation_keys:
+  - systemdUnit
+  - unknown
+process_sample_attributes:
+  - oom
+  - unknown
+`)
+
+	tmp, err := createTestFile(yamlData)
+	require.NoError(t, err)
+	defer os.Remove(tmp.Name())
+
+	cfg, err := LoadConfig(tmp.Name())
+	require.NoError(t, err)
+	assert.Equal(t, []string{ProcessAggregationKeySystemdUnit}, cfg.ProcessAggregationKeys)
+	assert.Equal(t, []string{ProcessAttributesOOM, ProcessAttributesCgroup}, cfg.ProcessSampleAttributes,
+		"the systemd unit is read from the cgroup")
+	assert.Equal(t, DefaultProcessAggregationTopN, cfg.ProcessAggregationTopN)
+}
+
+func TestLoadYamlConfig_ProcessAggregationCommandLineWithoutRegex(t *testing.T) {
+	yamlData := []byte(`
+license_key: abc123
+enable_process_aggregation: true
+process_aggregation_keys:
+  - commandLine
+`)
+
+	tmp, err := createTestFile(yamlData)
+	require.NoError(t, err)
+	defer os.Remove(tmp.Name())
+
+	_, err = LoadConfig(tmp.Name())
+	assert.Error(t, err, "the processes must not be grouped by command name instead")
+}
+
+func TestValidProcessAggregationKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		regex   string
+		goos    string
+		want    []string
+		wantErr bool
+	}{
+		{"known keys", []string{"userName", "containerId", "systemdUnit"}, "", "linux", []string{"userName", "containerId", "systemdUnit"}, false},
+		{"unknown key", []string{"commandName", "unknown"}, "", "linux", []string{"commandName"}, false},
+		{"command line", []string{"commandLine"}, "celery .*-Q (\\S+)", "linux", []string{"commandLine"}, false},
+		{"command line without regex", []string{"commandName", "commandLine"}, "", "linux", nil, true},
+		{"command line with invalid regex", []string{"commandLine"}, "(", "linux", nil, true},
+		{"container on macOS", []string{"containerId"}, "", "darwin", nil, true},
+		{"systemd unit on macOS", []string{"systemdUnit"}, "", "darwin", nil, true},
+		{"command line on macOS", []string{"commandLine"}, ".*", "darwin", []string{"commandLine"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := validProcessAggregationKeys(tt.keys, tt.regex, tt.goos)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, keys)
+		})
+	}
+}
+
 func createTestFile(data []byte) (*os.File, error) {
 	tmp, err := ioutil.TempFile("", "loadconfig")
 	if err != nil {
@@ -12,6 +12,43 @@ const (
 	// JSON log format.
 	LogFormatJSON = "json"
 
+	// Inventory delta store backed by a tree of files.
+	InventoryStoreBackendFiles = "files"
+	// Inventory delta store backed by an embedded bolt database.
+	InventoryStoreBackendBolt = "bolt"
+
+	// New Relic endpoints that can be routed through a different proxy in proxy_routes.
+	ProxyRouteMetrics        = "metrics"
+	ProxyRouteInventory      = "inventory"
+	ProxyRouteIdentity       = "identity"
+	ProxyRouteCommandChannel = "command_channel"
+	ProxyRouteLogs           = "logs"
+	// Route value to connect directly to an endpoint, without proxy.
+	ProxyRouteDirect = "direct"
+
+	// Authentication schemes for the proxy_auth option.
+	ProxyAuthBasic     = "basic"
+	ProxyAuthNegotiate = "negotiate"
+	ProxyAuthNTLM      = "ntlm"
+
+	// Groups of extra attributes for the process_sample_attributes option.
+	ProcessAttributesCgroup          = "cgroup"
+	ProcessAttributesNamespaces      = "namespaces"
+	ProcessAttributesOOM             = "oom"
+	ProcessAttributesContextSwitches = "context_switches"
+	ProcessAttributesMemory          = "memory"
+	ProcessAttributesSwap            = "swap"
+	ProcessAttributesFdLimit         = "fd_limit"
+	ProcessAttributesStartTime       = "start_time"
+	ProcessAttributesScheduling      = "scheduling"
+
+	// Keys of the process_aggregation_keys option.
+	ProcessAggregationKeyCommandName = "commandName"
+	ProcessAggregationKeyUserName    = "userName"
+	ProcessAggregationKeyContainerID = "containerId"
+	ProcessAggregationKeySystemdUnit = "systemdUnit"
+	ProcessAggregationKeyCommandLine = "commandLine"
+
 	// Non configurable stuff
 	defaultIdentityURLEu                 = "https://identity-api.eu.newrelic.com"
 	defaultIdentityStagingURLEu          = "https://staging-identity-api.eu.newrelic.com"
@@ -37,6 +74,7 @@ const (
 var (
 	// public
 	DefaultContainerCacheMetadataLimit = 60
+	DefaultCpuCoreSampleLimit          = 256
 	DefaultDockerApiVersion            = "1.24" // minimum supported API by Docker 18.09.0
 	DefaultHeartBeatFrequencySecs      = 60
 	DefaultDMPeriodSecs                = 5           // default telemetry SDK value
@@ -44,10 +82,12 @@ var (
 	DefaultMaxMetricBatchEntitiesCount = 300         // Amount limit from Vortex collector service header (8k ~ 300 entities)
 	DefaultMaxMetricBatchEntitiesQueue = 1000        // Limit the amount of queued entities to be processed by Vortex collector service
 	DefaultMetricsNFSSampleRate        = 20
+	DefaultProcessAggregationTopN      = 5
 	DefaultOfflineTimeToReset          = "24h"
 	DefaultStorageSamplerRateSecs      = 20
 	DefaultStripCommandLine            = true
 	DefaultSmartVerboseModeEntryLimit  = 1000
+	DefaultSmartctlPath                = "smartctl"
 	DefaultIntegrationsDir             = "newrelic-integrations"
 	DefaultInventoryQueue              = 0
 
@@ -57,6 +97,8 @@ var (
 	defaultCmdChannelIntervalSec         = 60
 	defaultCompactEnabled                = true
 	defaultCompactThreshold              = 20 * 1024 * 1024 // (in bytes) compact repo when it hits 20MB
+	defaultInventoryHistoryMaxSnapshots  = 100
+	defaultInventoryHistoryMaxAge        = "168h"
 	defaultIgnoreReclaimable             = false
 	defaultDebugLogSec                   = 600
 	defaultDisableInventorySplit         = false
@@ -80,7 +122,8 @@ var (
 	defaultSelinuxEnableSemodule         = true
 	defaultStartupConnectionTimeout      = "10s"
 	defaultPartitionsTTL                 = "60s" // TTL for the partitions cache, to avoid polling continuously for them
-	defaultStartupConnectionRetries      = 6     // -1 will try forever with an exponential backoff algorithm
+	defaultStorageMountTimeout           = "5s"
+	defaultStartupConnectionRetries      = 6 // -1 will try forever with an exponential backoff algorithm
 	defaultSupervisorRpcSock             = "/var/run/supervisor.sock"
 	defaultWinUpdatePlugin               = false
 	defaultDMIngestEndpoint              = "/metric/v1/infra"
@@ -36,6 +36,11 @@ const (
 
 	FREQ_PLUGIN_FACTER_UPDATES            = 30 // seconds -- facter plugin
 	FREQ_PLUGIN_PACKAGE_MGRS_UPDATES      = 30 // seconds -- rpm, deb plugins. RPM watches /var/lib/rpm/.rpm.lock, dpkg: /var/lib/dpkg/lock
+	FREQ_PLUGIN_FIREWALL_UPDATES          = 60 // seconds -- nftables or iptables rules
+	FREQ_PLUGIN_LANGUAGE_PACKAGES_UPDATES = 60 // seconds -- python, nodejs and ruby global packages
+	FREQ_PLUGIN_LISTENING_PORTS_UPDATES   = 30 // seconds
+	FREQ_PLUGIN_SCHEDULED_TASKS_UPDATES   = 60 // seconds -- cron and systemd timers
+	FREQ_PLUGIN_SECURITY_CONFIG_UPDATES   = 60 // seconds -- accounts, authorized keys, sudoers and pam
 	FREQ_PLUGIN_SELINUX_UPDATES           = 30 // seconds
 	FREQ_PLUGIN_HOST_ALIASES              = 30 // seconds
 	FREQ_PLUGIN_NETWORK_INTERFACE_UPDATES = 60 // seconds
@@ -11,6 +11,8 @@ import (
 type Container struct {
 	Match      map[string]string `yaml:"match"`
 	ApiVersion string            `yaml:"api_version"` // for docker client
+	Socket     string            `yaml:"socket"`      // unix socket of the docker, podman or containerd API
+	Namespace  string            `yaml:"namespace"`   // containerd namespace, all of them if empty
 }
 
 func (d *Container) Validate() error {
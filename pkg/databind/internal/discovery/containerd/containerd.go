@@ -0,0 +1,33 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containerd
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery/docker"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/containerd"
+)
+
+// Discoverer returns a containerd container discoverer from the provided configuration.
+// Running containers of the configured namespace, or of all of them, are described as Docker
+// ones so that the discovered containers have the same keys as the Docker discovery. As containerd
+// doesn't manage networking, the container IP and listening ports are read from the network
+// namespace of the container task.
+func Discoverer(d discovery.Container) (fetchDiscoveries func() (discoveries []discovery.Discovery, err error), err error) {
+	if d.Socket == "" {
+		d.Socket = containerd.DefaultSocket
+	}
+	matcher, err := discovery.NewMatcher(d.Match)
+	if err != nil {
+		return nil, err
+	}
+	return func() ([]discovery.Discovery, error) {
+		containers, err := containerd.Containers(d.Socket, d.Namespace, helpers.HostProc)
+		if err != nil {
+			return nil, err
+		}
+		return docker.Discoveries(containers, &matcher), nil
+	}, nil
+}
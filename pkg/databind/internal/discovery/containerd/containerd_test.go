@@ -0,0 +1,30 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containerd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+)
+
+func TestDiscoverer_Unreachable(t *testing.T) {
+	fetch, err := Discoverer(discovery.Container{
+		Socket: filepath.Join(t.TempDir(), "missing.sock"),
+		Match:  map[string]string{"name": "redis"},
+	})
+	require.NoError(t, err)
+
+	_, err = fetch()
+	assert.Error(t, err)
+}
+
+func TestDiscoverer_InvalidMatch(t *testing.T) {
+	_, err := Discoverer(discovery.Container{Match: map[string]string{"name": "/[/"}})
+	assert.Error(t, err)
+}
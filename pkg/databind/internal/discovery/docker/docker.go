@@ -26,7 +26,8 @@ const (
 
 // Discoverer returns a Docker container discoverer from the provided configuration.
 // The fetching process will return an array of map values for each discovered container, with the
-// keys discovery.port and discovery.ip
+// keys discovery.port and discovery.ip. The Docker host is taken from the environment unless a
+// socket is configured, which allows discovering containers from any Docker compatible API.
 func Discoverer(d discovery.Container) (fetchDiscoveries func() (discoveries []discovery.Discovery, err error), err error) {
 	if d.ApiVersion == "" {
 		d.ApiVersion = defaultDockerAPIVersion
@@ -41,7 +42,11 @@ func Discoverer(d discovery.Container) (fetchDiscoveries func() (discoveries []d
 }
 
 func fetch(d discovery.Container, matcher *discovery.FieldsMatcher) ([]discovery.Discovery, error) {
-	dc, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if d.Socket != "" {
+		opts = append(opts, client.WithHost("unix://"+d.Socket))
+	}
+	dc, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -52,11 +57,12 @@ func fetch(d discovery.Container, matcher *discovery.FieldsMatcher) ([]discovery
 		return nil, err
 	}
 
-	return getDiscoveries(containers, matcher), nil
+	return Discoveries(containers, matcher), nil
 }
 
-// getDiscoveries will filter container list to only the ones that match the config and extract discovery variables from those.
-func getDiscoveries(containers []types.Container, matcher *discovery.FieldsMatcher) []discovery.Discovery {
+// Discoveries will filter container list to only the ones that match the config and extract discovery variables from those.
+// It is shared by the container runtimes whose containers can be described as Docker ones.
+func Discoveries(containers []types.Container, matcher *discovery.FieldsMatcher) []discovery.Discovery {
 	var matches []discovery.Discovery
 
 	for _, cont := range containers {
@@ -115,7 +115,7 @@ func TestGetMatchingContainers(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	actualDiscoveryData := getDiscoveries(givenContainerList, &matcher)
+	actualDiscoveryData := Discoveries(givenContainerList, &matcher)
 	assert.Equal(t, expectedDiscoveryData, actualDiscoveryData)
 }
 
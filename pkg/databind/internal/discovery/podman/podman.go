@@ -0,0 +1,22 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery/docker"
+)
+
+// DefaultSocket is the socket of the rootful Podman API service (podman.socket systemd unit).
+const DefaultSocket = "/run/podman/podman.sock"
+
+// Discoverer returns a Podman container discoverer from the provided configuration. Containers
+// are listed through the Docker compatible API that Podman serves, so the discovered containers
+// have the same keys as the Docker ones.
+func Discoverer(d discovery.Container) (fetchDiscoveries func() (discoveries []discovery.Discovery, err error), err error) {
+	if d.Socket == "" {
+		d.Socket = DefaultSocket
+	}
+	return docker.Discoverer(d)
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+)
+
+// podman 4 answer to GET /v1.40/containers/json
+const containersJSON = `[
+  {
+    "Id": "9b3cbbb6b0a8c5aeda5f4e3c0e9f3d5b8e4bba6a29ee2e4b7f24c4b0c26f1b0b",
+    "Names": ["/redis"],
+    "Image": "docker.io/library/redis:7",
+    "ImageID": "sha256:3358aea34e8c2e53b45dfab2ef8bc2b5ae3a1e6a4e8a1e3cf0b6d1f4f8a2c9d7",
+    "Command": "redis-server",
+    "Labels": {"app": "cache"},
+    "Ports": [{"IP": "0.0.0.0", "PrivatePort": 6379, "PublicPort": 16379, "Type": "tcp"}],
+    "State": "running",
+    "NetworkSettings": {"Networks": {"podman": {"IPAddress": "10.88.0.4"}}}
+  },
+  {
+    "Id": "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0",
+    "Names": ["/web"],
+    "Image": "docker.io/library/nginx:latest",
+    "Labels": {"app": "web"},
+    "Ports": [],
+    "State": "running",
+    "NetworkSettings": {"Networks": {"podman": {"IPAddress": "10.88.0.5"}}}
+  }
+]`
+
+// fakePodman serves the Docker compatible API on a unix socket.
+func fakePodman(t *testing.T) string {
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.40")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			_, _ = w.Write([]byte("OK"))
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(containersJSON))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return socket
+}
+
+func TestDiscoverer(t *testing.T) {
+	fetch, err := Discoverer(discovery.Container{
+		Socket: fakePodman(t),
+		Match:  map[string]string{"label.app": "cache"},
+	})
+	require.NoError(t, err)
+
+	discoveries, err := fetch()
+	require.NoError(t, err)
+	require.Len(t, discoveries, 1)
+
+	vars := discoveries[0].Variables
+	assert.Equal(t, "redis", vars["discovery.name"])
+	assert.Equal(t, "docker.io/library/redis:7", vars["discovery.image"])
+	assert.Equal(t, "9b3cbbb6b0a8c5aeda5f4e3c0e9f3d5b8e4bba6a29ee2e4b7f24c4b0c26f1b0b", vars["discovery.containerId"])
+	assert.Equal(t, "0.0.0.0", vars["discovery.ip"])
+	assert.Equal(t, "16379", vars["discovery.port"])
+	assert.Equal(t, "6379", vars["discovery.private.port"])
+	assert.Equal(t, "10.88.0.4", vars["discovery.private.ip"])
+}
+
+func TestDiscoverer_Unreachable(t *testing.T) {
+	fetch, err := Discoverer(discovery.Container{
+		Socket: filepath.Join(t.TempDir(), "missing.sock"),
+		Match:  map[string]string{"name": "redis"},
+	})
+	require.NoError(t, err)
+
+	_, err = fetch()
+	assert.Error(t, err)
+}
@@ -0,0 +1,21 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"errors"
+)
+
+// Process discovery parameters. Processes can be matched by name, cmdline, user or any of
+// their listening ports.
+type Process struct {
+	Match map[string]string `yaml:"match"`
+}
+
+func (p *Process) Validate() error {
+	if len(p.Match) == 0 {
+		return errors.New("missing 'match' entries")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package process
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/process/snapshot"
+)
+
+// fetch returns the running processes, read with the same snapshots as the process samples. Attributes that can't
+// be read, usually because of the agent privileges, are left empty.
+func fetch() ([]hostProcess, error) {
+	pids, err := process.Pids()
+	if err != nil {
+		return nil, err
+	}
+	bootTime, err := host.BootTime()
+	if err != nil {
+		return nil, err
+	}
+	listeners := listeningSockets()
+
+	processes := make([]hostProcess, 0, len(pids))
+	for _, pid := range pids {
+		snap, err := snapshot.NewLinuxProcess(pid, nil, false)
+		if err != nil {
+			// the process finished
+			continue
+		}
+		hp := hostProcess{
+			pid:       pid,
+			name:      snap.Command(),
+			startTime: snap.StartTime(bootTime).UnixNano() / int64(time.Millisecond),
+			listeners: listeners[pid],
+		}
+		hp.cmdline, _ = snap.CmdLine(true)
+		hp.user, _ = snap.Username()
+		hp.cwd, _ = os.Readlink(helpers.HostProc(strconv.Itoa(int(pid)), "cwd"))
+		processes = append(processes, hp)
+	}
+	return processes, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package process
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetch_CurrentProcess(t *testing.T) {
+	processes, err := fetch()
+	require.NoError(t, err)
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	for _, proc := range processes {
+		if int(proc.pid) != os.Getpid() {
+			continue
+		}
+		assert.Equal(t, "process.test", proc.name)
+		assert.Contains(t, proc.cmdline, "process.test")
+		assert.NotEmpty(t, proc.user)
+		assert.Equal(t, cwd, proc.cwd)
+		assert.WithinDuration(t, time.Now(), time.Unix(0, proc.startTime*int64(time.Millisecond)), time.Hour)
+		return
+	}
+	t.Fatal("the current process wasn't fetched")
+}
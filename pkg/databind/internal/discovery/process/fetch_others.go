@@ -0,0 +1,36 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+// +build !linux
+
+package process
+
+import (
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// fetch returns the running processes. Attributes that can't be read, usually because of the
+// agent privileges, are left empty.
+func fetch() ([]hostProcess, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	listeners := listeningSockets()
+
+	processes := make([]hostProcess, 0, len(procs))
+	for _, proc := range procs {
+		name, err := proc.Name()
+		if err != nil {
+			// the process finished
+			continue
+		}
+		hp := hostProcess{pid: proc.Pid, name: name, listeners: listeners[proc.Pid]}
+		hp.cmdline, _ = proc.Cmdline()
+		hp.user, _ = proc.Username()
+		hp.cwd, _ = proc.Cwd()
+		hp.startTime, _ = proc.CreateTime()
+		processes = append(processes, hp)
+	}
+	return processes, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package process
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	gopsnet "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+)
+
+const listenStatus = "LISTEN"
+
+// listener is a listening socket of a process.
+type listener struct {
+	ip   string
+	port uint32
+}
+
+// hostProcess holds the attributes of a running process that can be matched and bound.
+type hostProcess struct {
+	pid       int32
+	name      string
+	cmdline   string
+	user      string
+	cwd       string
+	startTime int64 // milliseconds since the epoch
+	listeners []listener
+}
+
+// Discoverer returns a host process discoverer from the provided configuration.
+// The fetching process will return an array of map values for each matching process, with the
+// keys discovery.pid, discovery.startTime, discovery.name, discovery.cmdline, discovery.user,
+// discovery.cwd and, if the process is listening, discovery.ip and discovery.port
+func Discoverer(d discovery.Process) (fetchDiscoveries func() (discoveries []discovery.Discovery, err error), err error) {
+	matcher, err := discovery.NewMatcher(d.Match)
+	if err != nil {
+		return nil, err
+	}
+	return func() ([]discovery.Discovery, error) {
+		processes, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		return getDiscoveries(processes, &matcher), nil
+	}, nil
+}
+
+// listeningSockets returns the listening sockets of the host processes, by PID.
+func listeningSockets() map[int32][]listener {
+	listeners := map[int32][]listener{}
+	if conns, err := gopsnet.Connections("inet"); err == nil {
+		for _, conn := range conns {
+			if conn.Status == listenStatus && conn.Pid > 0 {
+				listeners[conn.Pid] = append(listeners[conn.Pid], listener{ip: conn.Laddr.IP, port: conn.Laddr.Port})
+			}
+		}
+	}
+	return listeners
+}
+
+// getDiscoveries filters the processes matching the config and extracts their discovery variables.
+// When the process listens on several ports, discovery.port is the lowest one matching the config.
+func getDiscoveries(processes []hostProcess, matcher *discovery.FieldsMatcher) []discovery.Discovery {
+	var matches []discovery.Discovery
+
+	for _, proc := range processes {
+		labels := map[string]string{
+			data.Pid:       strconv.Itoa(int(proc.pid)),
+			data.StartTime: strconv.FormatInt(proc.startTime, 10),
+			data.Name:      proc.name,
+			data.Cmdline:   proc.cmdline,
+			data.User:      proc.user,
+			data.Cwd:       proc.cwd,
+		}
+		listeners := uniqueListeners(proc.listeners)
+		for index, l := range listeners {
+			labels[data.Ports+"."+strconv.Itoa(index)] = strconv.Itoa(int(l.port))
+		}
+
+		if len(listeners) == 0 {
+			if matcher.All(labels) {
+				matches = append(matches, newDiscovery(labels))
+			}
+			continue
+		}
+		for _, l := range listeners {
+			labels[data.IP] = connectableIP(l.ip)
+			labels[data.Port] = strconv.Itoa(int(l.port))
+			if matcher.All(labels) {
+				matches = append(matches, newDiscovery(labels))
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func newDiscovery(labels map[string]string) discovery.Discovery {
+	return discovery.Discovery{
+		Variables: discovery.LabelsToMap(data.DiscoveryPrefix, labels),
+	}
+}
+
+// uniqueListeners sorts the listeners by port, removing the same port being listened on several
+// addresses (e.g. 0.0.0.0 and ::). IPv4 addresses are preferred.
+func uniqueListeners(listeners []listener) (unique []listener) {
+	sorted := make([]listener, len(listeners))
+	copy(sorted, listeners)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].port != sorted[j].port {
+			return sorted[i].port < sorted[j].port
+		}
+		return isIPv4(sorted[i].ip) && !isIPv4(sorted[j].ip)
+	})
+	for _, l := range sorted {
+		if len(unique) > 0 && unique[len(unique)-1].port == l.port {
+			continue
+		}
+		unique = append(unique, l)
+	}
+	return unique
+}
+
+// connectableIP returns the address an integration should connect to for a listening address,
+// which is the loopback for processes listening on all the interfaces.
+func connectableIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return ip
+	case parsed.IsUnspecified() && parsed.To4() != nil:
+		return "127.0.0.1"
+	case parsed.IsUnspecified():
+		return "::1"
+	}
+	return ip
+}
+
+// isIPv4 returns true if ip string has a IPv4 format.
+func isIPv4(ip string) bool {
+	return net.ParseIP(ip).To4() != nil
+}
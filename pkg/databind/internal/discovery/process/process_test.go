@@ -0,0 +1,110 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+)
+
+var processes = []hostProcess{
+	{
+		pid:     1,
+		name:    "systemd",
+		cmdline: "/sbin/init splash",
+		user:    "root",
+		cwd:     "/",
+	},
+	{
+		pid:       812,
+		name:      "redis-server",
+		cmdline:   "/usr/bin/redis-server 127.0.0.1:6379",
+		user:      "redis",
+		cwd:       "/var/lib/redis",
+		startTime: 1650000000000,
+		listeners: []listener{
+			{ip: "::1", port: 6379},
+			{ip: "127.0.0.1", port: 6379},
+		},
+	},
+	{
+		pid:     1024,
+		name:    "redis-server",
+		cmdline: "/usr/bin/redis-server *:6380 --cluster-enabled yes",
+		user:    "redis",
+		cwd:     "/var/lib/redis-cluster",
+		listeners: []listener{
+			{ip: "0.0.0.0", port: 16380},
+			{ip: "0.0.0.0", port: 6380},
+		},
+	},
+}
+
+func TestGetDiscoveries_ByName(t *testing.T) {
+	matcher, err := discovery.NewMatcher(map[string]string{"name": "redis-server"})
+	require.NoError(t, err)
+
+	discoveries := getDiscoveries(processes, &matcher)
+	require.Len(t, discoveries, 2)
+
+	assert.Equal(t, data.Map{
+		"discovery.pid":       "812",
+		"discovery.startTime": "1650000000000",
+		"discovery.name":      "redis-server",
+		"discovery.cmdline":   "/usr/bin/redis-server 127.0.0.1:6379",
+		"discovery.user":      "redis",
+		"discovery.cwd":       "/var/lib/redis",
+		"discovery.ip":        "127.0.0.1",
+		"discovery.port":      "6379",
+		"discovery.ports.0":   "6379",
+	}, discoveries[0].Variables)
+
+	// lowest port, connecting through the loopback
+	assert.Equal(t, "6380", discoveries[1].Variables["discovery.port"])
+	assert.Equal(t, "16380", discoveries[1].Variables["discovery.ports.1"])
+	assert.Equal(t, "127.0.0.1", discoveries[1].Variables["discovery.ip"])
+}
+
+func TestGetDiscoveries_ByPort(t *testing.T) {
+	matcher, err := discovery.NewMatcher(map[string]string{"port": "16380"})
+	require.NoError(t, err)
+
+	discoveries := getDiscoveries(processes, &matcher)
+	require.Len(t, discoveries, 1)
+	assert.Equal(t, "1024", discoveries[0].Variables["discovery.pid"])
+	assert.Equal(t, "16380", discoveries[0].Variables["discovery.port"])
+}
+
+func TestGetDiscoveries_ByCmdlineAndUser(t *testing.T) {
+	matcher, err := discovery.NewMatcher(map[string]string{
+		"cmdline": "/--cluster-enabled yes/",
+		"user":    "redis",
+	})
+	require.NoError(t, err)
+
+	discoveries := getDiscoveries(processes, &matcher)
+	require.Len(t, discoveries, 1)
+	assert.Equal(t, "/var/lib/redis-cluster", discoveries[0].Variables["discovery.cwd"])
+}
+
+func TestGetDiscoveries_NoListeningPorts(t *testing.T) {
+	matcher, err := discovery.NewMatcher(map[string]string{"user": "root"})
+	require.NoError(t, err)
+
+	discoveries := getDiscoveries(processes, &matcher)
+	require.Len(t, discoveries, 1)
+	assert.Equal(t, "1", discoveries[0].Variables["discovery.pid"])
+	assert.NotContains(t, discoveries[0].Variables, "discovery.port")
+}
+
+func TestConnectableIP(t *testing.T) {
+	assert.Equal(t, "127.0.0.1", connectableIP("0.0.0.0"))
+	assert.Equal(t, "::1", connectableIP("::"))
+	assert.Equal(t, "10.0.0.3", connectableIP("10.0.0.3"))
+}
@@ -28,6 +28,11 @@ const (
 	Label                      = "label"
 	Command                    = "command"
 	DockerContainerName        = "dockerContainerName"
+	Pid                        = "pid"
+	Cmdline                    = "cmdline"
+	User                       = "user"
+	Cwd                        = "cwd"
+	StartTime                  = "startTime"
 	EntityRewriteActionReplace = "replace"
 )
 
@@ -38,6 +43,7 @@ type Transformed struct {
 	Variables         interface{}
 	MetricAnnotations Map
 	EntityRewrites    []EntityRewrite
+	InstanceID        string // empty if the discovery source can't identify the discovered item
 }
 
 type EntityRewrite struct {
@@ -48,6 +54,20 @@ type EntityRewrite struct {
 
 type EntityRewrites []EntityRewrite
 
+// InstanceID returns a stable identifier of a discovered item from its variables: the container ID
+// for containers, and the pid plus start time for processes, as pids are reused. It returns an
+// empty string when the item can't be identified.
+func InstanceID(variables Map) string {
+	if id := variables[DiscoveryPrefix+ContainerID]; id != "" {
+		return id
+	}
+	pid, startTime := variables[DiscoveryPrefix+Pid], variables[DiscoveryPrefix+StartTime]
+	if pid != "" && startTime != "" {
+		return pid + "-" + startTime
+	}
+	return ""
+}
+
 func InterfaceMapToMap(original InterfaceMap) (out Map) {
 	out = make(Map, len(original))
 	AddValues(out, "", original)
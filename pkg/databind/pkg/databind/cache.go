@@ -52,9 +52,12 @@ func (d *discoverer) do(now time.Time) ([]discovery.Discovery, error) {
 type DiscovererType string
 
 const (
-	typeDocker  DiscovererType = "docker"
-	typeFargate DiscovererType = "fargate"
-	typeCmd     DiscovererType = "command"
+	typeDocker     DiscovererType = "docker"
+	typeContainerd DiscovererType = "containerd"
+	typePodman     DiscovererType = "podman"
+	typeFargate    DiscovererType = "fargate"
+	typeCmd        DiscovererType = "command"
+	typeProcess    DiscovererType = "process"
 )
 
 // DiscovererInfo keeps util info about the discoverer.
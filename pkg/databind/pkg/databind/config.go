@@ -12,8 +12,11 @@ import (
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery/containerd"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery/docker"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery/fargate"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery/podman"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery/process"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/secrets"
 )
 
@@ -29,18 +32,24 @@ type YAMLAgentConfig struct {
 type YAMLConfig struct {
 	YAMLAgentConfig `yaml:",inline"`
 	Discovery       struct {
-		TTL     string               `yaml:"ttl,omitempty"`
-		Docker  *discovery.Container `yaml:"docker,omitempty"`
-		Fargate *discovery.Container `yaml:"fargate,omitempty"`
-		Command *discovery.Command   `yaml:"command,omitempty"`
+		TTL        string               `yaml:"ttl,omitempty"`
+		Docker     *discovery.Container `yaml:"docker,omitempty"`
+		Containerd *discovery.Container `yaml:"containerd,omitempty"`
+		Podman     *discovery.Container `yaml:"podman,omitempty"`
+		Fargate    *discovery.Container `yaml:"fargate,omitempty"`
+		Command    *discovery.Command   `yaml:"command,omitempty"`
+		Process    *discovery.Process   `yaml:"process,omitempty"`
 	} `yaml:"discovery"`
 }
 
 func (y *YAMLConfig) Enabled() bool {
 	return len(y.Variables) > 0 ||
 		y.Discovery.Docker != nil ||
+		y.Discovery.Containerd != nil ||
+		y.Discovery.Podman != nil ||
 		y.Discovery.Fargate != nil ||
-		y.Discovery.Command != nil
+		y.Discovery.Command != nil ||
+		y.Discovery.Process != nil
 }
 
 type varEntry struct {
@@ -149,6 +158,20 @@ func (dc *YAMLConfig) selectDiscoverer(ttl time.Duration) (*discoverer, error) {
 			fetch: fetch,
 		}, err
 
+	} else if dc.Discovery.Containerd != nil {
+		fetch, err := containerd.Discoverer(*dc.Discovery.Containerd)
+		return &discoverer{
+			cache: cachedEntry{ttl: ttl},
+			fetch: fetch,
+		}, err
+
+	} else if dc.Discovery.Podman != nil {
+		fetch, err := podman.Discoverer(*dc.Discovery.Podman)
+		return &discoverer{
+			cache: cachedEntry{ttl: ttl},
+			fetch: fetch,
+		}, err
+
 	} else if dc.Discovery.Command != nil {
 		fetch, err := command.Discoverer(*dc.Discovery.Command)
 		return &discoverer{
@@ -156,6 +179,13 @@ func (dc *YAMLConfig) selectDiscoverer(ttl time.Duration) (*discoverer, error) {
 			fetch: fetch,
 		}, err
 
+	} else if dc.Discovery.Process != nil {
+		fetch, err := process.Discoverer(*dc.Discovery.Process)
+		return &discoverer{
+			cache: cachedEntry{ttl: ttl},
+			fetch: fetch,
+		}, err
+
 	}
 	return nil, nil
 }
@@ -167,6 +197,17 @@ func (y *YAMLConfig) addDiscoveryInfo() DiscovererInfo {
 			Type:     typeDocker,
 			Matchers: y.Discovery.Docker.Match,
 		}
+	} else if y.Discovery.Containerd != nil {
+		res = DiscovererInfo{
+			Type:     typeContainerd,
+			Name:     y.Discovery.Containerd.Namespace,
+			Matchers: y.Discovery.Containerd.Match,
+		}
+	} else if y.Discovery.Podman != nil {
+		res = DiscovererInfo{
+			Type:     typePodman,
+			Matchers: y.Discovery.Podman.Match,
+		}
 	} else if y.Discovery.Fargate != nil {
 		res = DiscovererInfo{
 			Type:     typeFargate,
@@ -178,6 +219,11 @@ func (y *YAMLConfig) addDiscoveryInfo() DiscovererInfo {
 			Name:     fmt.Sprintf("%v", y.Discovery.Command.Exec),
 			Matchers: y.Discovery.Command.Matcher,
 		}
+	} else if y.Discovery.Process != nil {
+		res = DiscovererInfo{
+			Type:     typeProcess,
+			Matchers: y.Discovery.Process.Match,
+		}
 	}
 	return res
 }
@@ -190,6 +236,18 @@ func (y *YAMLConfig) validate() error {
 			return err
 		}
 	}
+	if y.Discovery.Containerd != nil {
+		sections++
+		if err := y.Discovery.Containerd.Validate(); err != nil {
+			return err
+		}
+	}
+	if y.Discovery.Podman != nil {
+		sections++
+		if err := y.Discovery.Podman.Validate(); err != nil {
+			return err
+		}
+	}
 	if y.Discovery.Fargate != nil {
 		sections++
 		if err := y.Discovery.Fargate.Validate(); err != nil {
@@ -204,6 +262,13 @@ func (y *YAMLConfig) validate() error {
 		}
 	}
 
+	if y.Discovery.Process != nil {
+		sections++
+		if err := y.Discovery.Process.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if sections > 1 {
 		return errors.New("only one discovery source allowed")
 	}
@@ -41,6 +41,24 @@ variables:
     cyberark-api:
       http:
         url: https://10.1.0.5/AIMWebService/api/Accounts?AppID=NewRelic&Query=Safe=ALL-NERE-WIN-A-NEWRELIC-UP;Object=ALL-localhost-testuser
+`}, {"containerd discovery", `
+discovery:
+  containerd:
+    namespace: k8s.io
+    match:
+      name: redis
+`}, {"podman discovery", `
+discovery:
+  podman:
+    socket: /run/user/1000/podman/podman.sock
+    match:
+      image: /redis/
+`}, {"process discovery", `
+discovery:
+  process:
+    match:
+      name: redis-server
+      cmdline: /--port 6379/
 `}}
 	for _, input := range inputs {
 		t.Run(input.description, func(t *testing.T) {
@@ -97,7 +115,23 @@ variables:
     cyberark-api:
       http:
         url: 
-      `}}
+      `}, {"process discovery without matchers", `
+discovery:
+  process:
+    match:
+`}, {"containerd discovery without matchers", `
+discovery:
+  containerd:
+    namespace: default
+`}, {"process and docker discovery", `
+discovery:
+  process:
+    match:
+      name: redis-server
+  docker:
+    match:
+      image: redis
+`}}
 	for _, input := range inputs {
 		t.Run(input.description, func(t *testing.T) {
 			_, err := LoadYAML([]byte(input.yaml))
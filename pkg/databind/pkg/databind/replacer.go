@@ -76,6 +76,7 @@ func replaceAllSources(tmpl interface{}, src []discovery.Discovery, common data.
 				Variables:         replaced.Interface(),
 				MetricAnnotations: data.InterfaceMapToMap(discov.MetricAnnotations),
 				EntityRewrites:    entityRewrites,
+				InstanceID:        data.InstanceID(discov.Variables),
 			})
 	}
 	return transformedData
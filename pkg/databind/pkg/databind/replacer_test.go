@@ -595,3 +595,22 @@ func TestReplace_EntityRewrite(t *testing.T) {
 		}
 	})
 }
+
+func TestReplace_InstanceID(t *testing.T) {
+	// GIVEN a container, a process and an unidentifiable discovered item
+	ctx := &Values{discov: []discovery.Discovery{
+		{Variables: data.Map{"discovery.containerId": "1234abc", "discovery.ip": "1.2.3.4"}},
+		{Variables: data.Map{"discovery.pid": "812", "discovery.startTime": "1650000000000", "discovery.ip": "127.0.0.1"}},
+		{Variables: data.Map{"discovery.ip": "5.6.7.8"}},
+	}}
+
+	// WHEN they are replaced into a template
+	ret, err := Replace(ctx, map[string]string{"host": "${discovery.ip}"})
+	require.NoError(t, err)
+
+	// THEN each instance is identified by its container ID or its pid and start time
+	require.Len(t, ret, 3)
+	assert.Equal(t, "1234abc", ret[0].InstanceID)
+	assert.Equal(t, "812-1650000000000", ret[1].InstanceID)
+	assert.Empty(t, ret[2].InstanceID)
+}
@@ -101,6 +101,14 @@ func (k *KnownIDs) Get(key Key) (ID, bool) {
 	return entry.id, true
 }
 
+// Remove removes the entry of the given entity Key, if exists.
+func (k *KnownIDs) Remove(key Key) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	delete(k.ids, key)
+}
+
 // SetTTL registers a custom TTL for the given entity Type
 func (k *KnownIDs) SetTTL(entityType Type, ttl time.Duration) {
 	k.lock.Lock()
@@ -34,6 +34,23 @@ func TestKnownIDs_Put(t *testing.T) {
 	assert.EqualValues(t, id, 54321)
 }
 
+func TestKnownIDs_Remove(t *testing.T) {
+	// Given a Key to IDs map with two entries
+	kn := NewKnownIDs()
+	kn.Put("entity-1", 12345)
+	kn.Put("entity-2", 54321)
+
+	// When removing one of them
+	kn.Remove("entity-1")
+
+	// Only the other one can be retrieved
+	_, ok := kn.Get("entity-1")
+	assert.False(t, ok)
+	id, ok := kn.Get("entity-2")
+	assert.True(t, ok)
+	assert.EqualValues(t, id, 54321)
+}
+
 func TestKnownIDs_Put_ExpiredEntry(t *testing.T) {
 	// Given a Key to IDs map
 	kn := NewKnownIDs()
@@ -0,0 +1,317 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package register
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/identityapi"
+	"github.com/newrelic/infrastructure-agent/pkg/disk"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LifecycleStateFile is the file of the tracked entities, inside the agent data directory.
+	LifecycleStateFile = "entity_lifecycle.json"
+	// maxLifecycleCheckInterval is the maximum time between the checks for expired entities.
+	maxLifecycleCheckInterval = time.Minute
+	// maxDecommissionedEntities bounds the decommissioned entities kept for the status report.
+	maxDecommissionedEntities = 100
+	decommissionBatchSize     = 100
+	// minReportIntervals is the minimum number of reporting intervals of an entity before it can be
+	// decommissioned, so the entities reported by integrations running less often than the TTL are kept.
+	minReportIntervals = 3
+	stateFileMode      = 0644
+)
+
+var llog = log.WithComponent("EntityLifecycle")
+
+// TrackedEntity is an entity registered by the agent and the last time it reported data.
+type TrackedEntity struct {
+	ID       entity.ID  `json:"id"`
+	Key      entity.Key `json:"key"`
+	LastSeen time.Time  `json:"lastSeen"`
+	// Interval is the longest time observed between two reports of the entity.
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+// expiresAt returns when the entity expires if it doesn't report data again.
+func (e *TrackedEntity) expiresAt(ttl time.Duration) time.Time {
+	if minTTL := minReportIntervals * e.Interval; minTTL > ttl {
+		ttl = minTTL
+	}
+	return e.LastSeen.Add(ttl)
+}
+
+// DecommissionedEntity is an entity decommissioned after not reporting data during the TTL.
+type DecommissionedEntity struct {
+	TrackedEntity
+	DecommissionedAt time.Time `json:"decommissionedAt"`
+}
+
+// LifecycleStatus reports the state of the entities lifecycle, with the latest decommissioned
+// entities first.
+type LifecycleStatus struct {
+	TTL            string                 `json:"ttl"`
+	Tracked        int                    `json:"tracked"`
+	Decommissioned []DecommissionedEntity `json:"decommissioned"`
+	LastError      string                 `json:"lastError,omitempty"`
+}
+
+// Lifecycle tracks when each registered entity was last seen, and decommissions the entities
+// that haven't reported data for longer than a TTL, so they aren't kept as "not reporting".
+// The tracked entities are saved periodically to a state file, so the entities that stop
+// reporting while the agent is restarted are decommissioned too.
+type Lifecycle struct {
+	agentIDProvide id.Provide
+	client         identityapi.RegisterClient
+	ttl            time.Duration
+	stateFile      string
+	now            func() time.Time
+
+	lock           sync.Mutex
+	entities       map[entity.ID]*TrackedEntity
+	decommissioned []DecommissionedEntity
+	lastErr        error
+	dirty          bool
+	forget         func(key entity.Key)
+}
+
+// NewLifecycle creates a Lifecycle decommissioning the entities not seen during the ttl, nor
+// during minReportIntervals times their reporting interval. The entities are restored from and
+// saved to stateFile, unless it's empty.
+func NewLifecycle(agentIDProvide id.Provide, client identityapi.RegisterClient, ttl time.Duration, stateFile string) *Lifecycle {
+	l := &Lifecycle{
+		agentIDProvide: agentIDProvide,
+		client:         client,
+		ttl:            ttl,
+		stateFile:      stateFile,
+		now:            time.Now,
+		entities:       make(map[entity.ID]*TrackedEntity),
+	}
+	if err := l.load(); err != nil {
+		llog.WithError(err).WithField("file", stateFile).Warn("cannot restore the tracked entities, starting fresh")
+	}
+	return l
+}
+
+// OnDecommission sets a function called with the key of each decommissioned entity, to forget
+// any data kept about it.
+func (l *Lifecycle) OnDecommission(forget func(key entity.Key)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.forget = forget
+}
+
+// Seen records that the entity reported data.
+func (l *Lifecycle) Seen(key entity.Key, entityID entity.ID) {
+	if entityID.IsEmpty() {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.dirty = true
+	if e, ok := l.entities[entityID]; ok {
+		if interval := now.Sub(e.LastSeen); interval > e.Interval {
+			e.Interval = interval
+		}
+		e.Key = key
+		e.LastSeen = now
+		return
+	}
+	l.entities[entityID] = &TrackedEntity{ID: entityID, Key: key, LastSeen: now}
+}
+
+// Run decommissions periodically the expired entities until the context is done.
+func (l *Lifecycle) Run(ctx context.Context) {
+	interval := l.ttl / 2
+	if interval > maxLifecycleCheckInterval || interval <= 0 {
+		interval = maxLifecycleCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.saveLogged()
+			return
+		case <-ticker.C:
+			if err := l.DecommissionExpired(); err != nil {
+				llog.WithError(err).Warn("cannot decommission expired entities, retrying later")
+			}
+			l.saveLogged()
+		}
+	}
+}
+
+// DecommissionExpired decommissions the entities that weren't seen during the TTL. The entities
+// that fail to be decommissioned are kept to be retried.
+func (l *Lifecycle) DecommissionExpired() error {
+	expired := l.expired()
+	if len(expired) == 0 {
+		return nil
+	}
+
+	agentID := l.agentIDProvide().ID
+	if agentID.IsEmpty() {
+		// not connected yet, the entities will be decommissioned later
+		return nil
+	}
+
+	for start := 0; start < len(expired); start += decommissionBatchSize {
+		end := start + decommissionBatchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+		batch := expired[start:end]
+
+		ids := make([]entity.ID, len(batch))
+		for i, e := range batch {
+			ids[i] = e.ID
+		}
+		if err := l.client.DecommissionEntities(agentID, ids); err != nil {
+			l.lock.Lock()
+			l.lastErr = err
+			l.lock.Unlock()
+			return err
+		}
+		l.decommission(batch)
+	}
+	return nil
+}
+
+// expired returns the entities not seen during the TTL.
+func (l *Lifecycle) expired() (expired []TrackedEntity) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	for _, e := range l.entities {
+		if e.expiresAt(l.ttl).Before(now) {
+			expired = append(expired, *e)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].LastSeen.Before(expired[j].LastSeen)
+	})
+	return
+}
+
+// decommission stops tracking the entities, unless they reported data again meanwhile. All of them
+// are forgotten, so the ones that reported again are registered again.
+func (l *Lifecycle) decommission(entities []TrackedEntity) {
+	l.lock.Lock()
+	now := l.now()
+	forget := l.forget
+	for _, e := range entities {
+		if tracked, ok := l.entities[e.ID]; !ok || !tracked.LastSeen.Equal(e.LastSeen) {
+			continue
+		}
+		delete(l.entities, e.ID)
+		l.dirty = true
+		l.decommissioned = append(l.decommissioned, DecommissionedEntity{TrackedEntity: e, DecommissionedAt: now})
+		llog.WithFields(logrus.Fields{
+			"entityID":  e.ID,
+			"entityKey": e.Key,
+			"lastSeen":  e.LastSeen,
+		}).Info("Entity decommissioned.")
+	}
+	if extra := len(l.decommissioned) - maxDecommissionedEntities; extra > 0 {
+		l.decommissioned = append([]DecommissionedEntity{}, l.decommissioned[extra:]...)
+	}
+	l.lastErr = nil
+	l.lock.Unlock()
+
+	if forget != nil {
+		for _, e := range entities {
+			forget(e.Key)
+		}
+	}
+}
+
+// load restores the tracked entities from the state file.
+func (l *Lifecycle) load() error {
+	if l.stateFile == "" {
+		return nil
+	}
+	buf, err := ioutil.ReadFile(l.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entities []*TrackedEntity
+	if err = json.Unmarshal(buf, &entities); err != nil {
+		return err
+	}
+	for _, e := range entities {
+		l.entities[e.ID] = e
+	}
+	return nil
+}
+
+// save writes the tracked entities to the state file, if they changed since the last time.
+func (l *Lifecycle) save() error {
+	l.lock.Lock()
+	if l.stateFile == "" || !l.dirty {
+		l.lock.Unlock()
+		return nil
+	}
+	entities := make([]TrackedEntity, 0, len(l.entities))
+	for _, e := range l.entities {
+		entities = append(entities, *e)
+	}
+	l.dirty = false
+	l.lock.Unlock()
+
+	buf, err := json.Marshal(entities)
+	if err == nil {
+		err = disk.WriteFile(l.stateFile, buf, stateFileMode)
+	}
+	if err != nil {
+		l.lock.Lock()
+		l.dirty = true
+		l.lock.Unlock()
+	}
+	return err
+}
+
+func (l *Lifecycle) saveLogged() {
+	if err := l.save(); err != nil {
+		llog.WithError(err).WithField("file", l.stateFile).Warn("cannot save the tracked entities")
+	}
+}
+
+// Status returns the current state of the entities lifecycle.
+func (l *Lifecycle) Status() LifecycleStatus {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	s := LifecycleStatus{
+		TTL:            l.ttl.String(),
+		Tracked:        len(l.entities),
+		Decommissioned: make([]DecommissionedEntity, 0, len(l.decommissioned)),
+	}
+	for i := len(l.decommissioned) - 1; i >= 0; i-- {
+		s.Decommissioned = append(s.Decommissioned, l.decommissioned[i])
+	}
+	if l.lastErr != nil {
+		s.LastError = l.lastErr.Error()
+	}
+	return s
+}
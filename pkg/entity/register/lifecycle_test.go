@@ -0,0 +1,159 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package register
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/identityapi/test"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+func newTestLifecycle(client *test.DecommissionRecorder, agentID entity.ID, now *time.Time) *Lifecycle {
+	l := NewLifecycle(func() entity.Identity {
+		return entity.Identity{ID: agentID}
+	}, client, time.Hour, "")
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLifecycle_DecommissionExpired(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	client := &test.DecommissionRecorder{}
+	l := newTestLifecycle(client, 1, &now)
+
+	l.Seen("container:a", 10)
+	l.Seen("container:b", 11)
+	l.Seen("container:c", entity.EmptyID)
+
+	now = now.Add(30 * time.Minute)
+	l.Seen("container:b", 11)
+	require.NoError(t, l.DecommissionExpired())
+	assert.Empty(t, client.Decommissioned())
+
+	now = now.Add(31 * time.Minute)
+	require.NoError(t, l.DecommissionExpired())
+	assert.Equal(t, []entity.ID{10}, client.Decommissioned())
+
+	status := l.Status()
+	assert.Equal(t, "1h0m0s", status.TTL)
+	assert.Equal(t, 1, status.Tracked)
+	require.Len(t, status.Decommissioned, 1)
+	assert.Equal(t, entity.Key("container:a"), status.Decommissioned[0].Key)
+	assert.Equal(t, now, status.Decommissioned[0].DecommissionedAt)
+
+	// already decommissioned entities aren't submitted again
+	require.NoError(t, l.DecommissionExpired())
+	assert.Equal(t, []entity.ID{10}, client.Decommissioned())
+}
+
+func TestLifecycle_DecommissionFailureIsRetried(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	client := &test.DecommissionRecorder{Err: errors.New("unavailable")}
+	l := newTestLifecycle(client, 1, &now)
+
+	l.Seen("container:a", 10)
+	now = now.Add(2 * time.Hour)
+
+	assert.Error(t, l.DecommissionExpired())
+	status := l.Status()
+	assert.Equal(t, 1, status.Tracked)
+	assert.Equal(t, "unavailable", status.LastError)
+
+	client.Err = nil
+	require.NoError(t, l.DecommissionExpired())
+	assert.Equal(t, []entity.ID{10}, client.Decommissioned())
+	status = l.Status()
+	assert.Equal(t, 0, status.Tracked)
+	assert.Empty(t, status.LastError)
+}
+
+func TestLifecycle_WaitsForAgentID(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	client := &test.DecommissionRecorder{}
+	l := newTestLifecycle(client, entity.EmptyID, &now)
+
+	l.Seen("container:a", 10)
+	now = now.Add(2 * time.Hour)
+
+	require.NoError(t, l.DecommissionExpired())
+	assert.Empty(t, client.Decommissioned())
+	assert.Equal(t, 1, l.Status().Tracked)
+}
+
+// reportingClient reports an entity again while its decommission request is in flight.
+type reportingClient struct {
+	*test.DecommissionRecorder
+	report func()
+}
+
+func (c *reportingClient) DecommissionEntities(agentEntityID entity.ID, entityIDs []entity.ID) error {
+	c.report()
+	return c.DecommissionRecorder.DecommissionEntities(agentEntityID, entityIDs)
+}
+
+func TestLifecycle_ForgetsDecommissioned(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	client := &reportingClient{DecommissionRecorder: &test.DecommissionRecorder{}}
+	l := NewLifecycle(func() entity.Identity { return entity.Identity{ID: 1} }, client, time.Hour, "")
+	l.now = func() time.Time { return now }
+	var forgotten []entity.Key
+	l.OnDecommission(func(key entity.Key) { forgotten = append(forgotten, key) })
+	client.report = func() { l.Seen("container:b", 11) }
+
+	l.Seen("container:a", 10)
+	l.Seen("container:b", 11)
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, l.DecommissionExpired())
+
+	// both are forgotten, so the one reporting again is registered again
+	assert.ElementsMatch(t, []entity.Key{"container:a", "container:b"}, forgotten)
+	status := l.Status()
+	assert.Equal(t, 1, status.Tracked)
+	require.Len(t, status.Decommissioned, 1)
+	assert.Equal(t, entity.Key("container:a"), status.Decommissioned[0].Key)
+}
+
+func TestLifecycle_KeepsEntitiesReportingLessOftenThanTTL(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	client := &test.DecommissionRecorder{}
+	l := newTestLifecycle(client, 1, &now)
+
+	// reported every 2 hours
+	l.Seen("db:a", 10)
+	now = now.Add(2 * time.Hour)
+	l.Seen("db:a", 10)
+
+	now = now.Add(5 * time.Hour)
+	require.NoError(t, l.DecommissionExpired())
+	assert.Empty(t, client.Decommissioned())
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, l.DecommissionExpired())
+	assert.Equal(t, []entity.ID{10}, client.Decommissioned())
+}
+
+func TestLifecycle_RestoresTrackedEntities(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	stateFile := filepath.Join(t.TempDir(), LifecycleStateFile)
+	client := &test.DecommissionRecorder{}
+	agentID := func() entity.Identity { return entity.Identity{ID: 1} }
+
+	l := NewLifecycle(agentID, client, time.Hour, stateFile)
+	l.now = func() time.Time { return now }
+	l.Seen("container:a", 10)
+	require.NoError(t, l.save())
+
+	// the entity stops reporting while the agent is restarted
+	restarted := NewLifecycle(agentID, client, time.Hour, stateFile)
+	restarted.now = func() time.Time { return now.Add(2 * time.Hour) }
+	assert.Equal(t, 1, restarted.Status().Tracked)
+	require.NoError(t, restarted.DecommissionExpired())
+	assert.Equal(t, []entity.ID{10}, client.Decommissioned())
+}
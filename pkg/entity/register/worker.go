@@ -27,6 +27,8 @@ type WorkerConfig struct {
 	MaxBatchDuration  time.Duration
 	MaxRetryBo        time.Duration
 	VerboseLogLevel   int
+	// Lifecycle tracks the registered entities to decommission them once expired. Optional.
+	Lifecycle *Lifecycle
 }
 
 type worker struct {
@@ -151,6 +153,9 @@ func (w *worker) send(ctx context.Context, batch map[entity.Key]fwrequest.Entity
 			continue
 		} else {
 			r.RegisteredWith(resp.ID)
+			if w.config.Lifecycle != nil {
+				w.config.Lifecycle.Seen(entity.Key(resp.Name), resp.ID)
+			}
 			w.reqsRegisteredQueue <- r
 			w.measure(instrumentation.Counter, instrumentation.EntityRegisterEntitiesRegistered, 1)
 		}
@@ -82,6 +82,11 @@ func (c *fakeClient) RegisterEntity(agentEntityID entity.ID, entity entity.Field
 	return
 }
 
+func (c *fakeClient) DecommissionEntities(agentEntityID entity.ID, entityIDs []entity.ID) error {
+	// won't be called
+	return nil
+}
+
 func (c *fakeClient) RegisterEntitiesRemoveMe(agentEntityID entity.ID, entities []identityapi.RegisterEntity) (r []identityapi.RegisterEntityResponse, t time.Duration, err error) {
 	// won't be called
 	return
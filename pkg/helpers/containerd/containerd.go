@@ -0,0 +1,239 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containerd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DefaultSocket is the containerd API socket.
+	DefaultSocket = "/run/containerd/containerd.sock"
+
+	// namespaceHeader is the gRPC metadata key selecting the containerd namespace of a request.
+	namespaceHeader = "containerd-namespace"
+	requestTimeout  = 10 * time.Second
+
+	// labels set by the CRI plugin (Kubernetes) and nerdctl
+	criContainerNameLabel = "io.kubernetes.container.name"
+	criKindLabel          = "io.cri-containerd.kind"
+	criSandboxKind        = "sandbox"
+	nerdctlNameLabel      = "nerdctl/name"
+	nerdctlPortsLabel     = "nerdctl/ports"
+
+	tcpListen = "0A"
+)
+
+// ProcPathFunc returns a path inside the host /proc folder.
+type ProcPathFunc func(combineWith ...string) string
+
+// nerdctlPort is a published port, as stored by nerdctl in the container labels.
+type nerdctlPort struct {
+	HostIP        string
+	HostPort      uint16
+	ContainerPort uint16
+	Protocol      string
+}
+
+// Containers returns the running containers of the containerd API listening on socket, from the given namespace or
+// from all of them when it's empty. Containers are described as Docker ones so that they can be handled as the
+// containers of the Docker API. As containerd doesn't manage networking, the container IP and listening ports are
+// read from the network namespace of the container task, through procPath.
+func Containers(socket, namespace string, procPath ProcPathFunc) ([]types.Container, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "unix://"+socket, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	namespaces := []string{namespace}
+	if namespace == "" {
+		resp, err := namespacesapi.NewNamespacesClient(conn).List(ctx, &namespacesapi.ListNamespacesRequest{})
+		if err != nil {
+			return nil, err
+		}
+		namespaces = namespaces[:0]
+		for _, ns := range resp.Namespaces {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	var result []types.Container
+	for _, ns := range namespaces {
+		nsCtx := metadata.AppendToOutgoingContext(ctx, namespaceHeader, ns)
+		containers, err := containersapi.NewContainersClient(conn).List(nsCtx, &containersapi.ListContainersRequest{})
+		if err != nil {
+			return nil, err
+		}
+		tasks, err := tasksapi.NewTasksClient(conn).List(nsCtx, &tasksapi.ListTasksRequest{})
+		if err != nil {
+			return nil, err
+		}
+
+		running := map[string]uint32{}
+		for _, t := range tasks.Tasks {
+			if t.Status == task.StatusRunning {
+				running[t.ID] = t.Pid
+			}
+		}
+		for _, c := range containers.Containers {
+			pid, ok := running[c.ID]
+			// the pause containers of the Kubernetes pods are not discovered
+			if !ok || c.Labels[criKindLabel] == criSandboxKind {
+				continue
+			}
+			result = append(result, dockerContainer(c, pid, procPath))
+		}
+	}
+	return result, nil
+}
+
+// dockerContainer describes a containerd container as a Docker one.
+func dockerContainer(c containersapi.Container, pid uint32, procPath ProcPathFunc) types.Container {
+	name := c.Labels[criContainerNameLabel]
+	if name == "" {
+		name = c.Labels[nerdctlNameLabel]
+	}
+	if name == "" {
+		name = c.ID
+	}
+
+	pidStr := strconv.Itoa(int(pid))
+	settings := &types.SummaryNetworkSettings{Networks: map[string]*network.EndpointSettings{}}
+	if ip := localIP(procPath(pidStr, "net", "fib_trie")); ip != "" {
+		settings.Networks["default"] = &network.EndpointSettings{IPAddress: ip}
+	}
+
+	return types.Container{
+		ID:              c.ID,
+		Names:           []string{name},
+		Image:           c.Image,
+		Labels:          c.Labels,
+		State:           "running",
+		Ports:           containerPorts(c.Labels[nerdctlPortsLabel], procPath(pidStr, "net")),
+		NetworkSettings: settings,
+	}
+}
+
+// containerPorts returns the ports published by nerdctl, plus the unpublished ones the container
+// listens on.
+func containerPorts(publishedLabel string, netDir string) (ports []types.Port) {
+	published := map[uint16]bool{}
+	if publishedLabel != "" {
+		var nerdctlPorts []nerdctlPort
+		if err := json.Unmarshal([]byte(publishedLabel), &nerdctlPorts); err == nil {
+			for _, p := range nerdctlPorts {
+				ports = append(ports, types.Port{
+					IP:          p.HostIP,
+					PrivatePort: p.ContainerPort,
+					PublicPort:  p.HostPort,
+					Type:        p.Protocol,
+				})
+				if p.Protocol == "tcp" {
+					published[p.ContainerPort] = true
+				}
+			}
+		}
+	}
+	for _, port := range listeningPorts(netDir) {
+		if !published[port] {
+			ports = append(ports, types.Port{PrivatePort: port, Type: "tcp"})
+		}
+	}
+	return ports
+}
+
+// listeningPorts returns the TCP ports listened on non loopback addresses, read from the tcp and
+// tcp6 files of a network namespace.
+func listeningPorts(netDir string) []uint16 {
+	found := map[uint16]bool{}
+	for _, file := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join(netDir, file))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 || fields[3] != tcpListen {
+				continue
+			}
+			local := strings.Split(fields[1], ":")
+			if len(local) != 2 || isLoopback(local[0]) {
+				continue
+			}
+			if port, err := strconv.ParseUint(local[1], 16, 16); err == nil {
+				found[uint16(port)] = true
+			}
+		}
+		f.Close()
+	}
+
+	ports := make([]uint16, 0, len(found))
+	for port := range found {
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
+
+// isLoopback tells whether an hex encoded /proc/net address is a loopback one. IPv4 addresses are
+// stored in host byte order, so 127.0.0.1 is 0100007F.
+func isLoopback(hexAddr string) bool {
+	switch len(hexAddr) {
+	case 8:
+		return strings.HasSuffix(hexAddr, "7F")
+	case 32:
+		return hexAddr == "00000000000000000000000001000000"
+	}
+	return false
+}
+
+// localIP returns the first non loopback local IPv4 address of a network namespace fib_trie file,
+// where local addresses are the leaves followed by a "/32 host LOCAL" line.
+func localIP(fibTrie string) string {
+	f, err := os.Open(fibTrie)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var leaf string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "|--") {
+			leaf = strings.TrimSpace(strings.TrimPrefix(line, "|--"))
+			continue
+		}
+		if strings.HasPrefix(line, "/32 host LOCAL") {
+			if ip := net.ParseIP(leaf); ip != nil && !ip.IsLoopback() {
+				return leaf
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containerd
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeContainerd holds the containers and tasks of a set of namespaces.
+type fakeContainerd struct {
+	containers map[string][]containersapi.Container
+	tasks      map[string][]*task.Process
+}
+
+func namespaceOf(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(namespaceHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type fakeContainers struct {
+	containersapi.UnimplementedContainersServer
+	*fakeContainerd
+}
+
+func (f fakeContainers) List(ctx context.Context, _ *containersapi.ListContainersRequest) (*containersapi.ListContainersResponse, error) {
+	return &containersapi.ListContainersResponse{Containers: f.containers[namespaceOf(ctx)]}, nil
+}
+
+type fakeTasks struct {
+	tasksapi.UnimplementedTasksServer
+	*fakeContainerd
+}
+
+func (f fakeTasks) List(ctx context.Context, _ *tasksapi.ListTasksRequest) (*tasksapi.ListTasksResponse, error) {
+	return &tasksapi.ListTasksResponse{Tasks: f.tasks[namespaceOf(ctx)]}, nil
+}
+
+type fakeNamespaces struct {
+	namespacesapi.UnimplementedNamespacesServer
+	*fakeContainerd
+}
+
+func (f fakeNamespaces) List(context.Context, *namespacesapi.ListNamespacesRequest) (*namespacesapi.ListNamespacesResponse, error) {
+	resp := &namespacesapi.ListNamespacesResponse{}
+	for ns := range f.containers {
+		resp.Namespaces = append(resp.Namespaces, namespacesapi.Namespace{Name: ns})
+	}
+	return resp, nil
+}
+
+func serveFakeContainerd(t *testing.T, fake *fakeContainerd) string {
+	socket := filepath.Join(t.TempDir(), "containerd.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	containersapi.RegisterContainersServer(server, &fakeContainers{fakeContainerd: fake})
+	tasksapi.RegisterTasksServer(server, &fakeTasks{fakeContainerd: fake})
+	namespacesapi.RegisterNamespacesServer(server, &fakeNamespaces{fakeContainerd: fake})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return socket
+}
+
+const fibTrie = `Main:
+  +-- 0.0.0.0/0 3 0 5
+     |-- 0.0.0.0
+        /0 universe UNICAST
+     +-- 10.4.0.0/24 2 0 2
+        |-- 10.4.0.0
+           /24 link UNICAST
+        |-- 10.4.0.12
+           /32 host LOCAL
+  +-- 127.0.0.0/8 2 0 2
+     |-- 127.0.0.1
+        /32 host LOCAL
+`
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0 100 0 0 10 0
+   1: 00000000:18EB 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2 1 0 100 0 0 10 0
+   2: 0C00040A:18EB 0500040A:D2A4 01 00000000:00000000 00:00000000 00000000     0        0 3 1 0 100 0 0 10 0
+`
+
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:42A3 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 4 1 0 100 0 0 10 0
+`
+
+func fakeProc(t *testing.T) ProcPathFunc {
+	dir := t.TempDir()
+	netDir := filepath.Join(dir, "4242", "net")
+	require.NoError(t, os.MkdirAll(netDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(netDir, "fib_trie"), []byte(fibTrie), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(netDir, "tcp"), []byte(procNetTCP), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(netDir, "tcp6"), []byte(procNetTCP6), 0644))
+	return func(combineWith ...string) string {
+		return filepath.Join(append([]string{dir}, combineWith...)...)
+	}
+}
+
+func newFake() *fakeContainerd {
+	return &fakeContainerd{
+		containers: map[string][]containersapi.Container{
+			"k8s.io": {
+				{ID: "pause0", Image: "registry.k8s.io/pause:3.6", Labels: map[string]string{criKindLabel: criSandboxKind}},
+				{ID: "redis0", Image: "docker.io/library/redis:7", Labels: map[string]string{
+					criKindLabel:          "container",
+					criContainerNameLabel: "redis",
+				}},
+			},
+			"default": {
+				{ID: "web0", Image: "docker.io/library/nginx:latest", Labels: map[string]string{
+					nerdctlNameLabel:  "web",
+					nerdctlPortsLabel: `[{"HostIP":"0.0.0.0","HostPort":8080,"ContainerPort":6379,"Protocol":"tcp"}]`,
+				}},
+				{ID: "stopped0", Image: "docker.io/library/busybox:latest"},
+			},
+		},
+		tasks: map[string][]*task.Process{
+			"k8s.io": {
+				{ID: "pause0", Pid: 4242, Status: task.StatusRunning},
+				{ID: "redis0", Pid: 4242, Status: task.StatusRunning},
+			},
+			"default": {
+				{ID: "web0", Pid: 4242, Status: task.StatusRunning},
+				{ID: "stopped0", Pid: 0, Status: task.StatusStopped},
+			},
+		},
+	}
+}
+
+func TestContainers_CRIContainer(t *testing.T) {
+	socket := serveFakeContainerd(t, newFake())
+
+	containers, err := Containers(socket, "k8s.io", fakeProc(t))
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+
+	c := containers[0]
+	assert.Equal(t, "redis0", c.ID)
+	assert.Equal(t, []string{"redis"}, c.Names)
+	assert.Equal(t, "docker.io/library/redis:7", c.Image)
+	assert.Equal(t, "10.4.0.12", c.NetworkSettings.Networks["default"].IPAddress)
+	// loopback listeners are not reachable from outside the container
+	require.Len(t, c.Ports, 2)
+	assert.EqualValues(t, 6379, c.Ports[0].PrivatePort)
+	assert.EqualValues(t, 17059, c.Ports[1].PrivatePort)
+}
+
+func TestContainers_AllNamespaces(t *testing.T) {
+	socket := serveFakeContainerd(t, newFake())
+
+	containers, err := Containers(socket, "", fakeProc(t))
+	require.NoError(t, err)
+	require.Len(t, containers, 2)
+
+	var web types.Container
+	for _, c := range containers {
+		if c.ID == "web0" {
+			web = c
+		}
+	}
+	assert.Equal(t, []string{"web"}, web.Names)
+	require.Len(t, web.Ports, 2)
+	assert.Equal(t, types.Port{IP: "0.0.0.0", PrivatePort: 6379, PublicPort: 8080, Type: "tcp"}, web.Ports[0])
+	assert.Equal(t, types.Port{PrivatePort: 17059, Type: "tcp"}, web.Ports[1])
+}
+
+func TestContainers_Unreachable(t *testing.T) {
+	_, err := Containers(filepath.Join(t.TempDir(), "missing.sock"), "", fakeProc(t))
+	assert.Error(t, err)
+}
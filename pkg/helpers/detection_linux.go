@@ -32,6 +32,10 @@ func GetLinuxDistro() int {
 				return LINUX_COREOS
 			case identity == "sles":
 				return LINUX_SUSE
+			case identity == "alpine":
+				return LINUX_ALPINE
+			case identity == "arch":
+				return LINUX_ARCH
 			}
 		}
 		// Look alikes
@@ -41,6 +45,8 @@ func GetLinuxDistro() int {
 				return LINUX_DEBIAN
 			case strings.Contains(like, "rhel"), strings.Contains(like, "fedora"):
 				return LINUX_REDHAT
+			case strings.Contains(like, "arch"):
+				return LINUX_ARCH
 			}
 		}
 	}
@@ -52,6 +58,10 @@ func GetLinuxDistro() int {
 		return LINUX_DEBIAN
 	}
 
+	if _, err := os.Open(HostEtc("/alpine-release")); err == nil {
+		return LINUX_ALPINE
+	}
+
 	if IsAmazonOS() {
 		return LINUX_AWS_REDHAT
 	}
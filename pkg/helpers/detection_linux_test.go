@@ -49,6 +49,23 @@ ANSI_COLOR="1;32"
 HOME_URL="https://coreos.com/"
 BUG_REPORT_URL="https://github.com/coreos/bugs/issues"`,
 	)
+
+	ALPINE = []byte(`
+NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.16.2
+PRETTY_NAME="Alpine Linux v3.16"
+HOME_URL="https://alpinelinux.org/"
+BUG_REPORT_URL="https://gitlab.alpinelinux.org/alpine/aports/-/issues"`,
+	)
+
+	MANJARO = []byte(`
+NAME="Manjaro Linux"
+ID=manjaro
+ID_LIKE=arch
+BUILD_ID=rolling
+PRETTY_NAME="Manjaro Linux"`,
+	)
 )
 
 func (s *DetectionSuite) TestGetLinuxDistroCoreOS(c *C) {
@@ -83,6 +100,26 @@ func (s *DetectionSuite) TestGetLinuxDistro(c *C) {
 	c.Assert(val, Equals, LINUX_REDHAT)
 }
 
+func (s *DetectionSuite) TestGetLinuxDistroAlpineAndArch(c *C) {
+	tmpEtc, err := ioutil.TempDir("", "/testing")
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer os.RemoveAll(tmpEtc)
+	os.Setenv("HOST_ETC", tmpEtc)
+
+	tmpEtc2 := filepath.Join(tmpEtc, "os-release")
+	if err := ioutil.WriteFile(tmpEtc2, ALPINE, 0666); err != nil {
+		log.Fatal(err)
+	}
+	c.Assert(GetLinuxDistro(), Equals, LINUX_ALPINE)
+
+	if err := ioutil.WriteFile(tmpEtc2, MANJARO, 0666); err != nil {
+		log.Fatal(err)
+	}
+	c.Assert(GetLinuxDistro(), Equals, LINUX_ARCH)
+}
+
 func (s *DetectionSuite) TestGetLinuxOSInfo(c *C) {
 	tmpEtc, err := ioutil.TempDir("", "/testing")
 	if err != nil {
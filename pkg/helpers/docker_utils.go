@@ -44,6 +44,21 @@ func (dc *DockerClient) Initialize(apiVersion string) (err error) {
 	return
 }
 
+// InitializeSocket initializes the client for the Docker compatible API listening on a unix socket, as the one of
+// Podman.
+func (dc *DockerClient) InitializeSocket(socket string) (err error) {
+	if info, err := os.Stat(socket); err != nil || info.Mode()&os.ModeSocket == 0 {
+		return errors.Errorf("no API listening on %s", socket)
+	}
+
+	dc.client, err = client.NewClientWithOpts(client.WithHost("unix://"+socket), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize docker client")
+	}
+
+	return
+}
+
 func (dc *DockerClient) Containers() ([]types.Container, error) {
 	return dc.client.ContainerList(context.Background(), types.ContainerListOptions{})
 }
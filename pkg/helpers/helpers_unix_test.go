@@ -19,6 +19,15 @@ func TestHostProc(t *testing.T) {
 	assert.Equal(t, filepath.Join("/dockerproc/testing"), newPath)
 }
 
+func TestHostDev(t *testing.T) {
+	path := HostDev("sda")
+	assert.Equal(t, filepath.Join("/dev/sda"), path)
+	require.NoError(t, os.Setenv("HOST_DEV", "/host/dev"))
+	defer func() { require.NoError(t, os.Unsetenv("HOST_DEV")) }()
+	newPath := HostDev("nvme0n1")
+	assert.Equal(t, filepath.Join("/host/dev/nvme0n1"), newPath)
+}
+
 func TestHostVar(t *testing.T) {
 	path := HostVar("/test/something/something")
 	assert.Equal(t, filepath.Join("/var/test/something/something"), path)
@@ -425,6 +425,8 @@ func (cc customContext) ActiveEntitiesChannel() chan string {
 	return make(chan string, 100)
 }
 
+func (cc customContext) InstanceGone(string) {}
+
 func (cc customContext) EntityKey() string {
 	return ""
 }
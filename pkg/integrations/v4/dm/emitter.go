@@ -64,6 +64,7 @@ type emitter struct {
 	metricsSender             MetricsSender
 	agentContext              agent.AgentContext
 	registerClient            identityapi.RegisterClient
+	lifecycle                 *register.Lifecycle
 	registerWorkers           int
 	registerMaxBatchSize      int
 	registerMaxBatchBytesSize int
@@ -80,9 +81,10 @@ func NewEmitter(
 	agentContext agent.AgentContext,
 	dmSender MetricsSender,
 	registerClient identityapi.RegisterClient,
+	lifecycle *register.Lifecycle,
 	measure instrumentation.Measure) Emitter {
 
-	return &emitter{
+	e := &emitter{
 		retryBo:                   backoff.NewDefaultBackoff(),
 		maxRetryBo:                time.Duration(agentContext.Config().RegisterMaxRetryBoSecs) * time.Second,
 		reqsQueue:                 make(chan fwrequest.FwRequest, defaultRequestsQueueLen),
@@ -93,12 +95,18 @@ func NewEmitter(
 		agentContext:              agentContext,
 		metricsSender:             dmSender,
 		registerClient:            registerClient,
+		lifecycle:                 lifecycle,
 		registerMaxBatchSize:      defaultRegisterBatchSize,
 		registerMaxBatchBytesSize: defaultRegisterBatchBytesSize,
 		registerMaxBatchTime:      defaultRegisterBatchSecs * time.Second,
 		verboseLogLevel:           agentContext.Config().Verbose,
 		measure:                   measure,
 	}
+	if lifecycle != nil {
+		// decommissioned entities are registered again if they report data
+		lifecycle.OnDecommission(e.idCache.Remove)
+	}
+	return e
 }
 
 // Send receives data forward requests and queues them while processing them on different goroutine.
@@ -116,6 +124,9 @@ func (e *emitter) lazyLoadProcessor() {
 
 		go e.runFwReqConsumer(ctx)
 		go e.runReqsRegisteredConsumer(ctx)
+		if e.lifecycle != nil {
+			go e.lifecycle.Run(ctx)
+		}
 		for w := 0; w < e.registerWorkers; w++ {
 			config := register.WorkerConfig{
 				MaxBatchSize:      e.registerMaxBatchSize,
@@ -123,6 +134,7 @@ func (e *emitter) lazyLoadProcessor() {
 				MaxBatchDuration:  e.registerMaxBatchTime,
 				MaxRetryBo:        e.maxRetryBo,
 				VerboseLogLevel:   e.verboseLogLevel,
+				Lifecycle:         e.lifecycle,
 			}
 			regWorker := register.NewWorker(
 				e.agentContext.Identity,
@@ -171,6 +183,7 @@ func (e *emitter) emitDataset(r fwrequest.EntityFwRequest) {
 	labels, annos := r.LabelsAndExtraAnnotations()
 
 	plugin := agent.NewExternalPluginCommon(r.Definition.PluginID(r.Integration.Name), e.agentContext, r.Definition.Name)
+	plugin.InstanceID = r.Definition.InstanceID
 
 	emitInventory(&plugin, r.Definition, r.Integration, r.ID(), r.Data, labels)
 
@@ -39,6 +39,9 @@ func (e *emitter) processEntityFwRequest(r fwrequest.EntityFwRequest) {
 	} else {
 		e.idCache.CleanOld()
 		e.idCache.Put(key, r.ID())
+		if e.lifecycle != nil && !r.Data.Entity.IsAgent() {
+			e.lifecycle.Seen(key, r.ID())
+		}
 	}
 	e.emitDataset(r)
 }
@@ -149,7 +149,7 @@ func TestEmitter_Send_usingIDCache(t *testing.T) {
 		Return(nil)
 	dmSender.wg.Add(2)
 
-	em := NewEmitter(aCtx, dmSender, &test.EmptyRegisterClient{}, instrumentation.NoopMeasure)
+	em := NewEmitter(aCtx, dmSender, &test.EmptyRegisterClient{}, nil, instrumentation.NoopMeasure)
 	e := em.(*emitter)
 
 	e.idCache.Put(entity.Key(fmt.Sprintf("%s:%s", data.DataSets[0].Entity.Type, data.DataSets[0].Entity.Name)), firstEntity.ID)
@@ -186,7 +186,7 @@ func TestEmitter_Send_ignoreEntity(t *testing.T) {
 		On("SendMetricsWithCommonAttributes", mock.AnythingOfType("protocol.Common"), mock.AnythingOfType("[]protocol.Metric")).
 		Return(nil)
 
-	em := NewEmitter(aCtx, dmSender, &test.EmptyRegisterClient{}, instrumentation.NoopMeasure)
+	em := NewEmitter(aCtx, dmSender, &test.EmptyRegisterClient{}, nil, instrumentation.NoopMeasure)
 
 	dmSender.wg.Add(getMetricsSend(data))
 
@@ -226,7 +226,7 @@ func TestEmitter_Send(t *testing.T) {
 		On("SendMetricsWithCommonAttributes", mock.AnythingOfType("protocol.Common"), mock.AnythingOfType("[]protocol.Metric")).
 		Return(nil)
 
-	em := NewEmitter(aCtx, ms, test.NewIncrementalRegister(), instrumentation.NoopMeasure)
+	em := NewEmitter(aCtx, ms, test.NewIncrementalRegister(), nil, instrumentation.NoopMeasure)
 
 	// avoid waiting for more data to create register submission batch
 	e := em.(*emitter)
@@ -288,7 +288,7 @@ func TestEmitter_Send_failedToSubmitMetrics_dropAndLog(t *testing.T) {
 	ms.On("SendMetricsWithCommonAttributes", mock.Anything, mock.Anything).Return(errors.New("failed to submit metrics"))
 	ms.wg.Add(1)
 
-	em := NewEmitter(ctx, ms, test.NewIncrementalRegister(), instrumentation.NoopMeasure).(*emitter)
+	em := NewEmitter(ctx, ms, test.NewIncrementalRegister(), nil, instrumentation.NoopMeasure).(*emitter)
 	em.idCache.Put(entity.Key(fmt.Sprintf("%s:%s", data.DataSets[0].Entity.Type, data.DataSets[0].Entity.Name)), identity.ID)
 	em.Send(fwrequest.NewFwRequest(integration.Definition{Name: "nri-test", ExecutorConfig: executor.Config{User: "root"}}, nil, nil, data))
 
@@ -17,9 +17,11 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/protocol"
 
 	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/integration"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/dm"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 )
 
 var (
@@ -27,25 +29,44 @@ var (
 	elog = log.WithComponent("integrations.emitter.Emitter")
 )
 
+// Summaries of the events reporting the lifecycle of discovered integration instances.
+const (
+	InstanceStartedSummary = "InstanceStarted"
+	InstanceGoneSummary    = "InstanceGone"
+)
+
 // Emitter forwards agent/integration payload to  parser & processors (entity ID decoration...)
 type Emitter interface {
 	Emit(definition integration.Definition, ExtraLabels data.Map, entityRewrite []data.EntityRewrite, integrationJSON []byte) error
 }
 
+// InstanceLifecycle is implemented by the emitters that are notified about the instances of
+// discovery-driven integrations starting and going away. The definitions carry the instance ID.
+type InstanceLifecycle interface {
+	InstanceStarted(definition integration.Definition)
+	InstanceGone(definition integration.Definition)
+}
+
 type Agent interface {
 	GetContext() agent.AgentContext
+	GetCloudTags() *cloud.TagsAttributes
 }
 
 func NewIntegrationEmittor(
 	a Agent,
 	dmEmitter dm.Emitter,
 	ffRetriever feature_flags.Retriever) Emitter {
-	return &VersionAwareEmitter{
+	e := &VersionAwareEmitter{
 		aCtx:                a.GetContext(),
 		forceProtocolV2ToV3: true,
 		ffRetriever:         ffRetriever,
 		dmEmitter:           dmEmitter,
+		cloudTags:           a.GetCloudTags(),
 	}
+	if e.cloudTags != nil && e.aCtx.Config().IsForwardOnly {
+		e.cloudTags.Start(e.aCtx.Context())
+	}
+	return e
 }
 
 // VersionAwareEmitter actual Emitter for all integration protocol versions.
@@ -54,6 +75,7 @@ type VersionAwareEmitter struct {
 	forceProtocolV2ToV3 bool
 	ffRetriever         feature_flags.Retriever
 	dmEmitter           dm.Emitter
+	cloudTags           *cloud.TagsAttributes // nil unless cloud_tags_as_attributes is enabled.
 }
 
 func (e *VersionAwareEmitter) Emit(definition integration.Definition, extraLabels data.Map, entityRewrite []data.EntityRewrite, integrationJSON []byte) error {
@@ -76,7 +98,8 @@ func (e *VersionAwareEmitter) Emit(definition integration.Definition, extraLabel
 
 	// Agent creating the Host entity (and decorating it correctly in the backend) in secure forward with Custom Attributes: pkg/plugins/plugins_linux.go:46
 	// But in forward only there is no host entity and custom attributes are not being decorated.
-	// Here then we add CustomAttributes to extraLabels in case we are in that mode.
+	// Here then we add CustomAttributes, and the cloud tags reported as attributes, to extraLabels in case we are
+	// in that mode.
 	if e.aCtx.Config().IsForwardOnly {
 		extraLabelsCopy := make(map[string]string)
 		customAttributes := e.aCtx.Config().CustomAttributes.DataMap()
@@ -84,6 +107,11 @@ func (e *VersionAwareEmitter) Emit(definition integration.Definition, extraLabel
 		for k, v := range extraLabels {
 			extraLabelsCopy[k] = v
 		}
+		if e.cloudTags != nil {
+			for k, v := range e.cloudTags.Get() {
+				extraLabelsCopy[k] = v
+			}
+		}
 		for k, v := range customAttributes {
 			extraLabelsCopy[k] = v
 		}
@@ -112,8 +140,36 @@ func (e *VersionAwareEmitter) Emit(definition integration.Definition, extraLabel
 	return e.emitV3(fwrequest.NewFwRequestLegacy(definition, extraLabels, entityRewrite, pluginDataV3), protocolVersion)
 }
 
+// InstanceStarted reports a new discovered instance of an integration.
+func (e *VersionAwareEmitter) InstanceStarted(definition integration.Definition) {
+	e.emitInstanceEvent(definition, InstanceStartedSummary)
+}
+
+// InstanceGone reports a discovered instance of an integration that is not discovered anymore, and
+// removes the inventory of the entities it was reporting.
+func (e *VersionAwareEmitter) InstanceGone(definition integration.Definition) {
+	e.emitInstanceEvent(definition, InstanceGoneSummary)
+	e.aCtx.InstanceGone(definition.InstanceID)
+}
+
+func (e *VersionAwareEmitter) emitInstanceEvent(definition integration.Definition, summary string) {
+	plugin := agent.NewExternalPluginCommon(definition.PluginID(definition.Name), e.aCtx, definition.Name)
+	event := map[string]interface{}{
+		"eventType":       "InfrastructureEvent",
+		"category":        "integration",
+		"summary":         summary,
+		"integrationName": definition.Name,
+		"instanceId":      definition.InstanceID,
+	}
+	for key, value := range definition.Labels {
+		event["label."+key] = value
+	}
+	plugin.EmitEvent(event, entity.Key(e.aCtx.EntityKey()))
+}
+
 func (e *VersionAwareEmitter) emitV3(dto fwrequest.FwRequestLegacy, protocolVersion int) error {
 	plugin := agent.NewExternalPluginCommon(dto.Definition.PluginID(dto.Data.Name), e.aCtx, dto.Definition.Name)
+	plugin.InstanceID = dto.Definition.InstanceID
 	labels, extraAnnotations := dto.LabelsAndExtraAnnotations()
 
 	var emitErrs []error
@@ -3,10 +3,12 @@
 package emitter
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
 	"github.com/newrelic/infrastructure-agent/pkg/fwrequest"
@@ -24,7 +26,9 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/dm"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/protocol"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -790,6 +794,94 @@ func TestEmit_SendCustomAttributes_SendCAInSecureForwardMode(t *testing.T) {
 	dmEmitter.AssertExpectations(t)
 }
 
+type fakeTagsHarvester struct {
+	cloud.Harvester
+}
+
+func (f *fakeTagsHarvester) GetCloudType() cloud.Type {
+	return cloud.TypeAzure
+}
+
+func (f *fakeTagsHarvester) GetInstanceTags() (map[string]string, error) {
+	return map[string]string{"team": "from-cloud", "env": "prod"}, nil
+}
+
+func TestEmit_SendCloudTagsInSecureForwardMode(t *testing.T) {
+	intDefinition := integration.Definition{
+		InventorySource: *ids.NewPluginID("cat", "term"),
+	}
+	extraLabels := data.Map{
+		"label.foo": "bar",
+	}
+	customAttributes := config.CustomAttributeMap{
+		"team": "from-config",
+	}
+	entityRewrite := []data.EntityRewrite{}
+
+	// Given the cloud tags reported as attributes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cloudTags := cloud.NewTagsAttributes(&fakeTagsHarvester{}, nil, nil, "", time.Hour)
+	cloudTags.Start(ctx)
+	require.Eventually(t, func() bool { return len(cloudTags.Get()) > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// Then the integration data is decorated with them, the custom attributes taking precedence
+	dmEmitter := &mockDmEmitter{}
+	dmEmitter.On("Send", fwrequest.NewFwRequest(
+		intDefinition,
+		data.Map{"label.foo": "bar", "team": "from-config", "env": "prod"},
+		entityRewrite,
+		integration2.ProtocolV4.ParsedV4,
+	))
+
+	em := &VersionAwareEmitter{
+		aCtx:        mockForwardAgent(true, customAttributes),
+		ffRetriever: feature_flags.NewManager(map[string]bool{fflag.FlagProtocolV4: true}),
+		dmEmitter:   dmEmitter,
+		cloudTags:   cloudTags,
+	}
+
+	err := em.Emit(intDefinition, extraLabels, entityRewrite, integration2.ProtocolV4.Payload)
+	require.NoError(t, err)
+
+	dmEmitter.AssertExpectations(t)
+}
+
+func TestVersionAwareEmitter_InstanceLifecycle(t *testing.T) {
+	ma := &mocks.AgentContext{}
+	ma.On("EntityKey").Return("bob")
+	ma.On("Config").Return(&config.Config{})
+	ma.On("SendEvent", mock.Anything, entity.Key("bob"))
+	ma.On("InstanceGone", "container-1")
+
+	var em InstanceLifecycle = &VersionAwareEmitter{aCtx: ma}
+	def := integration.Definition{Name: "nri-redis", InstanceID: "container-1", Labels: map[string]string{"env": "prod"}}
+
+	// WHEN a discovered instance starts and goes away
+	em.InstanceStarted(def)
+	em.InstanceGone(def)
+
+	// THEN both lifecycle events are sent
+	var summaries []interface{}
+	for _, call := range ma.Calls {
+		if call.Method == "SendEvent" {
+			event := call.Arguments[0].(sample.Event)
+			encoded, err := json.Marshal(event)
+			require.NoError(t, err)
+			var decoded map[string]interface{}
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			assert.Equal(t, "container-1", decoded["instanceId"])
+			assert.Equal(t, "nri-redis", decoded["integrationName"])
+			assert.Equal(t, "prod", decoded["label.env"])
+			summaries = append(summaries, decoded["summary"])
+		}
+	}
+	assert.Equal(t, []interface{}{InstanceStartedSummary, InstanceGoneSummary}, summaries)
+
+	// AND the agent is told to remove the inventory of the gone instance
+	ma.AssertCalled(t, "InstanceGone", "container-1")
+}
+
 func mockAgent2Payloads() *mocks.AgentContext {
 	ma := mockAgent()
 	ma.On("SendData", mock.AnythingOfType("agent.PluginOutput")).Twice()
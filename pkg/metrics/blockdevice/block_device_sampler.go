@@ -0,0 +1,299 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package blockdevice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+var bdlog = log.WithComponent("BlockDeviceSampler")
+
+// sectorSize is the unit of the sectors of /proc/diskstats, regardless of the sector size of the device.
+const sectorSize = 512
+
+// ignoredDevices are the prefixes of the virtual block devices that aren't reported.
+var ignoredDevices = []string{"loop", "ram", "zram", "fd"}
+
+// BlockDeviceSample reports the activity of a disk since the previous sample, which is omitted in the first one, and
+// optionally its SMART health.
+type BlockDeviceSample struct {
+	sample.BaseEvent
+
+	Device     string `json:"device"`
+	Model      string `json:"model,omitempty"`
+	Rotational *bool  `json:"rotational,omitempty"`
+	SizeBytes  uint64 `json:"sizeBytes"`
+
+	ReadsPerSec        *float64 `json:"readsPerSecond,omitempty"`
+	WritesPerSec       *float64 `json:"writesPerSecond,omitempty"`
+	ReadBytesPerSec    *float64 `json:"readBytesPerSecond,omitempty"`
+	WriteBytesPerSec   *float64 `json:"writeBytesPerSecond,omitempty"`
+	DiscardsPerSec     *float64 `json:"discardsPerSecond,omitempty"`
+	DiscardBytesPerSec *float64 `json:"discardBytesPerSecond,omitempty"`
+	ReadAwaitMs        *float64 `json:"readAwaitMs,omitempty"`
+	WriteAwaitMs       *float64 `json:"writeAwaitMs,omitempty"`
+	AwaitMs            *float64 `json:"awaitMs,omitempty"`
+	AverageQueueDepth  *float64 `json:"averageQueueDepth,omitempty"`
+	UtilizationPercent *float64 `json:"utilizationPercent,omitempty"`
+	IOInProgress       uint64   `json:"ioInProgress"`
+
+	*SmartSample
+}
+
+// diskStats are the counters of a line of /proc/diskstats. The discard counters are reported since Linux 4.18.
+type diskStats struct {
+	reads, readSectors, readMs     uint64
+	writes, writeSectors, writeMs  uint64
+	inProgress, ioMs, weightedIOMs uint64
+	discards, discardSectors       uint64
+	hasDiscards                    bool
+}
+
+// BlockDeviceSampler reports a BlockDeviceSample for each disk of /sys/block (see the override_host_sys option),
+// excluding the partitions and the virtual devices, like loop and ram.
+type BlockDeviceSampler struct {
+	sampleInterval time.Duration
+	lastRun        time.Time
+	lastStats      map[string]diskStats
+	smart          *smartReader
+	now            func() time.Time
+}
+
+func NewBlockDeviceSampler(context agent.AgentContext) *BlockDeviceSampler {
+	samplerIntervalSec := config.FREQ_DISABLE_SAMPLING
+	var smart *smartReader
+	if context != nil {
+		cfg := context.Config()
+		samplerIntervalSec = cfg.MetricsBlockDeviceSampleRate
+		if cfg.EnableSmartMetrics {
+			smart = newSmartReader(cfg.SmartctlPath)
+		}
+	}
+	return &BlockDeviceSampler{
+		sampleInterval: time.Second * time.Duration(samplerIntervalSec),
+		smart:          smart,
+		now:            time.Now,
+	}
+}
+
+func (s *BlockDeviceSampler) Name() string { return "BlockDeviceSampler" }
+
+func (s *BlockDeviceSampler) Interval() time.Duration {
+	return s.sampleInterval
+}
+
+func (s *BlockDeviceSampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
+
+func (s *BlockDeviceSampler) OnStartup() {}
+
+func (s *BlockDeviceSampler) Sample() (results sample.EventBatch, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("Panic in BlockDeviceSampler.Sample: %v\nStack: %s", panicErr, debug.Stack())
+		}
+	}()
+
+	devices, err := blockDevices()
+	if err != nil {
+		return nil, err
+	}
+	stats, err := readDiskStats()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	elapsedMs := float64(now.Sub(s.lastRun).Milliseconds())
+	s.lastRun = now
+
+	current := make(map[string]diskStats, len(devices))
+	for _, device := range devices {
+		st, ok := stats[device]
+		if !ok {
+			continue
+		}
+		current[device] = st
+
+		bs := newBlockDeviceSample(device)
+		bs.IOInProgress = st.inProgress
+		if last, ok := s.lastStats[device]; ok && elapsedMs > 0 {
+			bs.setRates(st, last, elapsedMs)
+		}
+		if s.smart != nil && !isSmartIgnoredDevice(device) {
+			bs.SmartSample = s.smart.read(device)
+		}
+		helpers.LogStructureDetails(bdlog, bs, "BlockDeviceSample", "final", nil)
+		results = append(results, bs)
+	}
+	s.lastStats = current
+	if s.smart != nil {
+		present := make(map[string]bool, len(current))
+		for device := range current {
+			present[device] = true
+		}
+		s.smart.prune(present)
+	}
+
+	return results, nil
+}
+
+// blockDevices returns the names of the disks in /sys/block, which doesn't contain the partitions.
+func blockDevices() ([]string, error) {
+	entries, err := ioutil.ReadDir(helpers.HostSys("block"))
+	if err != nil {
+		return nil, err
+	}
+	var devices []string
+	for _, entry := range entries {
+		if !isIgnoredDevice(entry.Name()) {
+			devices = append(devices, entry.Name())
+		}
+	}
+	sort.Strings(devices)
+	return devices, nil
+}
+
+func isIgnoredDevice(device string) bool {
+	for _, prefix := range ignoredDevices {
+		if strings.HasPrefix(device, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func newBlockDeviceSample(device string) *BlockDeviceSample {
+	bs := &BlockDeviceSample{Device: device}
+	bs.Type("BlockDeviceSample")
+
+	dir := helpers.HostSys("block", device)
+	if size := readUint(filepath.Join(dir, "size")); size != nil {
+		bs.SizeBytes = *size * sectorSize
+	}
+	if rotational := readUint(filepath.Join(dir, "queue", "rotational")); rotational != nil {
+		isRotational := *rotational == 1
+		bs.Rotational = &isRotational
+	}
+	if model, err := ioutil.ReadFile(filepath.Join(dir, "device", "model")); err == nil {
+		bs.Model = strings.TrimSpace(string(model))
+	}
+	return bs
+}
+
+// setRates calculates the metrics of the device from the counters of two samplings, as iostat does.
+func (bs *BlockDeviceSample) setRates(current, previous diskStats, elapsedMs float64) {
+	perSecond := func(c, p uint64, factor float64) *float64 {
+		if c < p {
+			return nil
+		}
+		r := float64(c-p) * factor / elapsedMs * 1000
+		return &r
+	}
+	// await is the average time that the requests completed in the period spent in the queue and being served
+	await := func(ms, msPrev, ios, iosPrev uint64) *float64 {
+		if ios <= iosPrev || ms < msPrev {
+			zero := 0.0
+			return &zero
+		}
+		a := float64(ms-msPrev) / float64(ios-iosPrev)
+		return &a
+	}
+
+	bs.ReadsPerSec = perSecond(current.reads, previous.reads, 1)
+	bs.WritesPerSec = perSecond(current.writes, previous.writes, 1)
+	bs.ReadBytesPerSec = perSecond(current.readSectors, previous.readSectors, sectorSize)
+	bs.WriteBytesPerSec = perSecond(current.writeSectors, previous.writeSectors, sectorSize)
+	if current.hasDiscards && previous.hasDiscards {
+		bs.DiscardsPerSec = perSecond(current.discards, previous.discards, 1)
+		bs.DiscardBytesPerSec = perSecond(current.discardSectors, previous.discardSectors, sectorSize)
+	}
+
+	bs.ReadAwaitMs = await(current.readMs, previous.readMs, current.reads, previous.reads)
+	bs.WriteAwaitMs = await(current.writeMs, previous.writeMs, current.writes, previous.writes)
+	bs.AwaitMs = await(current.readMs+current.writeMs, previous.readMs+previous.writeMs,
+		current.reads+current.writes, previous.reads+previous.writes)
+
+	if current.weightedIOMs >= previous.weightedIOMs {
+		depth := float64(current.weightedIOMs-previous.weightedIOMs) / elapsedMs
+		bs.AverageQueueDepth = &depth
+	}
+	if current.ioMs >= previous.ioMs {
+		utilization := float64(current.ioMs-previous.ioMs) / elapsedMs * 100
+		if utilization > 100 {
+			utilization = 100
+		}
+		bs.UtilizationPercent = &utilization
+	}
+}
+
+// readDiskStats returns the counters of /proc/diskstats by device name. Its lines look like:
+// 252       0 vda 9651 3045 830738 3585 96523 59911 3381690 115457 0 125732 127574 0 0 0 0 11325 8530
+func readDiskStats() (map[string]diskStats, error) {
+	content, err := ioutil.ReadFile(helpers.HostProc("diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]diskStats)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 14 {
+			continue
+		}
+		values := make([]uint64, len(fields)-3)
+		valid := true
+		for i, field := range fields[3:] {
+			if values[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+		st := diskStats{
+			reads:        values[0],
+			readSectors:  values[2],
+			readMs:       values[3],
+			writes:       values[4],
+			writeSectors: values[6],
+			writeMs:      values[7],
+			inProgress:   values[8],
+			ioMs:         values[9],
+			weightedIOMs: values[10],
+		}
+		if len(values) >= 14 {
+			st.discards = values[11]
+			st.discardSectors = values[13]
+			st.hasDiscards = true
+		}
+		stats[fields[2]] = st
+	}
+	return stats, nil
+}
+
+func readUint(path string) *uint64 {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
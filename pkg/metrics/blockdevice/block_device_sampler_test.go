@@ -0,0 +1,258 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package blockdevice
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk/sdktest"
+)
+
+func TestBlockDeviceSampler(t *testing.T) {
+	dir := sdktest.WriteFiles(t, map[string]string{
+		"sys/block/sda/size":                 "7814037168\n",
+		"sys/block/sda/queue/rotational":     "1\n",
+		"sys/block/sda/device/model":         "WDC WD40EFRX-68N\n",
+		"sys/block/nvme0n1/size":             "2000409264\n",
+		"sys/block/nvme0n1/queue/rotational": "0\n",
+		"sys/block/loop0/size":               "0\n",
+		"proc/diskstats": "   7       0 loop0 10 0 20 0 0 0 0 0 0 0 0 0 0 0 0\n" +
+			"   8       0 sda 1000 0 8000 5000 2000 0 16000 10000 1 3000 15000\n" +
+			"   8       1 sda1 900 0 7000 4000 1900 0 15000 9000 1 2500 13000\n" +
+			" 259       0 nvme0n1 100 0 800 50 200 0 1600 100 0 100 150 10 0 80 5 0 0\n",
+	})
+	t.Setenv("HOST_PROC", filepath.Join(dir, "proc"))
+	t.Setenv("HOST_SYS", filepath.Join(dir, "sys"))
+
+	now := time.Now()
+	s := NewBlockDeviceSampler(nil)
+	s.now = func() time.Time { return now }
+
+	result, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, result, 2, "partitions and virtual devices aren't reported")
+
+	nvme := result[0].(*BlockDeviceSample)
+	assert.Equal(t, "nvme0n1", nvme.Device)
+	assert.False(t, *nvme.Rotational)
+	assert.Equal(t, uint64(2000409264*512), nvme.SizeBytes)
+	assert.Nil(t, nvme.ReadsPerSec, "rates aren't reported in the first sample")
+
+	sda := result[1].(*BlockDeviceSample)
+	assert.Equal(t, "sda", sda.Device)
+	assert.Equal(t, "WDC WD40EFRX-68N", sda.Model)
+	assert.True(t, *sda.Rotational)
+	assert.Equal(t, uint64(1), sda.IOInProgress)
+	assert.Nil(t, sda.SmartSample)
+
+	now = now.Add(10 * time.Second)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "proc", "diskstats"), []byte(
+		"   8       0 sda 1100 0 8800 5500 2400 0 17600 14000 3 8000 25000\n"+
+			" 259       0 nvme0n1 100 0 800 50 200 0 1600 100 0 100 150 30 0 2080 5 0 0\n"), 0644))
+	result, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	nvme = result[0].(*BlockDeviceSample)
+	assert.Equal(t, 0.0, *nvme.ReadsPerSec)
+	assert.Equal(t, 0.0, *nvme.AwaitMs, "no requests completed")
+	assert.Equal(t, 2.0, *nvme.DiscardsPerSec)
+	assert.Equal(t, 102400.0, *nvme.DiscardBytesPerSec)
+
+	sda = result[1].(*BlockDeviceSample)
+	assert.Equal(t, 10.0, *sda.ReadsPerSec)
+	assert.Equal(t, 40.0, *sda.WritesPerSec)
+	assert.Equal(t, 40960.0, *sda.ReadBytesPerSec)
+	assert.Equal(t, 81920.0, *sda.WriteBytesPerSec)
+	assert.Equal(t, 5.0, *sda.ReadAwaitMs)
+	assert.Equal(t, 10.0, *sda.WriteAwaitMs)
+	assert.Equal(t, 9.0, *sda.AwaitMs)
+	assert.Equal(t, 1.0, *sda.AverageQueueDepth)
+	assert.Equal(t, 50.0, *sda.UtilizationPercent)
+	assert.Equal(t, uint64(3), sda.IOInProgress)
+	assert.Nil(t, sda.DiscardsPerSec, "discards aren't reported by old kernels")
+}
+
+// waitSmart waits for the background reading of the device health.
+func waitSmart(t *testing.T, r *smartReader, device string) {
+	require.Eventually(t, func() bool {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		return !r.pending[device]
+	}, time.Second, time.Millisecond)
+}
+
+func TestSmartReader(t *testing.T) {
+	fixtures := map[string]string{
+		"sda":     "testdata/smartctl_ata.json",
+		"nvme0n1": "testdata/smartctl_nvme.json",
+		"vda":     "testdata/smartctl_virtual.json",
+	}
+	var runs int32
+	now := time.Now()
+	r := newSmartReader("smartctl")
+	r.now = func() time.Time { return now }
+	r.run = func(device string) ([]byte, error) {
+		atomic.AddInt32(&runs, 1)
+		return ioutil.ReadFile(fixtures[device])
+	}
+	read := func(device string) *SmartSample {
+		r.read(device)
+		waitSmart(t, r, device)
+		return r.read(device)
+	}
+
+	ata := read("sda")
+	require.NotNil(t, ata)
+	assert.True(t, *ata.SmartPassed)
+	assert.Equal(t, int64(36), *ata.TemperatureCelsius)
+	assert.Equal(t, int64(34015), *ata.PowerOnHours)
+	assert.Equal(t, int64(8), *ata.ReallocatedSectors)
+	assert.Equal(t, int64(2), *ata.PendingSectors)
+	assert.Equal(t, int64(0), *ata.UncorrectableSectors)
+	assert.Nil(t, ata.NVMePercentageUsed)
+
+	nvme := read("nvme0n1")
+	require.NotNil(t, nvme)
+	assert.False(t, *nvme.SmartPassed)
+	assert.Equal(t, int64(3), *nvme.NVMePercentageUsed)
+	assert.Equal(t, int64(100), *nvme.NVMeAvailableSparePct)
+	assert.Equal(t, int64(1), *nvme.NVMeMediaErrors)
+	assert.Equal(t, int64(4), *nvme.NVMeCriticalWarning)
+	assert.Nil(t, nvme.ReallocatedSectors)
+
+	assert.Nil(t, read("vda"), "devices without SMART support")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+
+	r.read("sda")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs), "the health is cached")
+	now = now.Add(smartInterval)
+	assert.Equal(t, ata, r.read("sda"), "the cached health is reported while it's read again")
+	waitSmart(t, r, "sda")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&runs))
+}
+
+func TestSmartReader_DoesNotBlock(t *testing.T) {
+	var runs int32
+	release := make(chan struct{})
+	r := newSmartReader("smartctl")
+	r.run = func(device string) ([]byte, error) {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return ioutil.ReadFile("testdata/smartctl_ata.json")
+	}
+
+	assert.Nil(t, r.read("sda"), "the health isn't known until smartctl returns")
+	assert.Nil(t, r.read("sda"))
+	close(release)
+	waitSmart(t, r, "sda")
+
+	assert.NotNil(t, r.read("sda"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs), "smartctl isn't run again while it's running")
+}
+
+func TestSmartReader_Standby(t *testing.T) {
+	now := time.Now()
+	standby := false
+	r := newSmartReader("smartctl")
+	r.now = func() time.Time { return now }
+	r.run = func(device string) ([]byte, error) {
+		if standby {
+			return []byte(`{"smartctl": {"exit_status": 2}}`), exec.Command("sh", "-c", "exit 2").Run()
+		}
+		return ioutil.ReadFile("testdata/smartctl_ata.json")
+	}
+	r.read("sda")
+	waitSmart(t, r, "sda")
+	ata := r.read("sda")
+	require.NotNil(t, ata)
+
+	standby = true
+	now = now.Add(smartInterval)
+	r.read("sda")
+	waitSmart(t, r, "sda")
+
+	assert.Equal(t, ata, r.read("sda"), "the health is kept while the device is in standby")
+}
+
+func TestBlockDeviceSampler_SmartDevices(t *testing.T) {
+	dir := sdktest.WriteFiles(t, map[string]string{
+		"sys/block/sda/size":  "7814037168\n",
+		"sys/block/dm-0/size": "7814037168\n",
+		"sys/block/md0/size":  "7814037168\n",
+		"sys/block/sr0/size":  "2097151\n",
+		"proc/diskstats": "   8       0 sda 1000 0 8000 5000 2000 0 16000 10000 1 3000 15000\n" +
+			" 253       0 dm-0 1000 0 8000 5000 2000 0 16000 10000 1 3000 15000\n" +
+			"   9       0 md0 1000 0 8000 5000 2000 0 16000 10000 1 3000 15000\n" +
+			"  11       0 sr0 10 0 80 50 0 0 0 0 0 30 50\n",
+	})
+	t.Setenv("HOST_PROC", filepath.Join(dir, "proc"))
+	t.Setenv("HOST_SYS", filepath.Join(dir, "sys"))
+
+	var lock sync.Mutex
+	var read []string
+	s := NewBlockDeviceSampler(nil)
+	s.smart = newSmartReader("smartctl")
+	s.smart.run = func(device string) ([]byte, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		read = append(read, device)
+		return ioutil.ReadFile("testdata/smartctl_ata.json")
+	}
+
+	result, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, result, 4)
+	waitSmart(t, s.smart, "sda")
+	lock.Lock()
+	assert.Equal(t, []string{"sda"}, read, "device-mapper, software RAID and optical devices aren't read")
+	lock.Unlock()
+	assert.Contains(t, s.smart.cache, "sda")
+
+	// When the disk is detached
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "proc", "diskstats"), []byte(
+		" 253       0 dm-0 1000 0 8000 5000 2000 0 16000 10000 1 3000 15000\n"), 0644))
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "sys/block/sda")))
+	_, err = s.Sample()
+	require.NoError(t, err)
+
+	// Then its health is forgotten
+	assert.Empty(t, s.smart.cache)
+}
+
+func TestSmartReader_PruneWhileReading(t *testing.T) {
+	r := newSmartReader("smartctl")
+	r.run = func(device string) ([]byte, error) {
+		return ioutil.ReadFile("testdata/smartctl_ata.json")
+	}
+	r.pending["sda"] = true
+
+	r.prune(map[string]bool{})
+	r.refresh("sda", time.Now())
+
+	assert.Empty(t, r.cache, "the health of the gone devices isn't cached when their reading ends")
+	assert.Empty(t, r.pending)
+}
+
+func TestBlockDeviceSampler_Disabled(t *testing.T) {
+	assert.True(t, NewBlockDeviceSampler(nil).Disabled())
+
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{MetricsBlockDeviceSampleRate: 20, EnableSmartMetrics: true, SmartctlPath: "smartctl"})
+	s := NewBlockDeviceSampler(ctx)
+	assert.False(t, s.Disabled())
+	assert.Equal(t, 20*time.Second, s.Interval())
+	assert.NotNil(t, s.smart)
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package blockdevice
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+const (
+	// smartInterval is the time between two health readings of a device, since smartctl may wake up sleeping disks.
+	smartInterval = 5 * time.Minute
+	smartTimeout  = 30 * time.Second
+	// smartStandbyStatus is the exit status of `smartctl -n standby` when the device is in a low-power mode, so it
+	// isn't read to avoid waking it up, or when it can't be opened.
+	smartStandbyStatus = 2
+
+	// ATA attributes, which are reported as raw values
+	ataReallocatedSectors = 5
+	ataPendingSectors     = 197
+	ataUncorrectable      = 198
+)
+
+// smartIgnoredDevices are the prefixes of the device-mapper, software RAID, optical and loop devices, which don't
+// report SMART health.
+var smartIgnoredDevices = []string{"dm-", "md", "sr", "loop"}
+
+// SmartSample is the health of a device reported by smartctl, for both ATA and NVMe devices.
+type SmartSample struct {
+	SmartPassed           *bool  `json:"smartPassed,omitempty"`
+	TemperatureCelsius    *int64 `json:"temperatureCelsius,omitempty"`
+	PowerOnHours          *int64 `json:"powerOnHours,omitempty"`
+	ReallocatedSectors    *int64 `json:"reallocatedSectors,omitempty"`
+	PendingSectors        *int64 `json:"pendingSectors,omitempty"`
+	UncorrectableSectors  *int64 `json:"uncorrectableSectors,omitempty"`
+	NVMePercentageUsed    *int64 `json:"nvmePercentageUsed,omitempty"`
+	NVMeAvailableSparePct *int64 `json:"nvmeAvailableSparePercent,omitempty"`
+	NVMeMediaErrors       *int64 `json:"nvmeMediaErrors,omitempty"`
+	NVMeCriticalWarning   *int64 `json:"nvmeCriticalWarning,omitempty"`
+}
+
+// smartctlOutput holds the fields of `smartctl --json` used by the SmartSample.
+type smartctlOutput struct {
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATASmartAttributes *struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeHealth *struct {
+		CriticalWarning int64 `json:"critical_warning"`
+		AvailableSpare  int64 `json:"available_spare"`
+		PercentageUsed  int64 `json:"percentage_used"`
+		MediaErrors     int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// smartReader runs smartctl for each device, caching its health for the smartInterval. As smartctl may take seconds
+// to answer (e.g. while a disk spins up), it runs in the background so that it doesn't delay the samples, which
+// report the last cached health meanwhile.
+type smartReader struct {
+	run     func(device string) ([]byte, error)
+	now     func() time.Time
+	lock    sync.Mutex
+	cache   map[string]cachedSmart
+	pending map[string]bool
+}
+
+type cachedSmart struct {
+	sample *SmartSample
+	read   time.Time
+}
+
+func newSmartReader(smartctlPath string) *smartReader {
+	return &smartReader{
+		run: func(device string) ([]byte, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), smartTimeout)
+			defer cancel()
+			return exec.CommandContext(ctx, smartctlPath, "--json", "-n", "standby", "-a", helpers.HostDev(device)).Output()
+		},
+		now:     time.Now,
+		cache:   make(map[string]cachedSmart),
+		pending: make(map[string]bool),
+	}
+}
+
+// read returns the cached health of the device, or nil if smartctl can't report it, e.g. for virtual disks, or it
+// hasn't been read yet. A new reading is started in the background when the cached one is older than the
+// smartInterval, unless the previous one is still running.
+func (r *smartReader) read(device string) *SmartSample {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+	cached, ok := r.cache[device]
+	if (!ok || now.Sub(cached.read) >= smartInterval) && !r.pending[device] {
+		r.pending[device] = true
+		go r.refresh(device, now)
+	}
+	return cached.sample
+}
+
+// refresh runs smartctl for the device and caches its health. The previous health is kept while the device is in
+// standby.
+func (r *smartReader) refresh(device string, now time.Time) {
+	smart, standby := r.readSmartctl(device)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.pending[device] {
+		// the device is gone
+		return
+	}
+	delete(r.pending, device)
+	if standby {
+		smart = r.cache[device].sample
+	}
+	r.cache[device] = cachedSmart{sample: smart, read: now}
+}
+
+// prune drops the health of the devices that aren't in the given set, e.g. detached disks.
+func (r *smartReader) prune(devices map[string]bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for device := range r.cache {
+		if !devices[device] {
+			delete(r.cache, device)
+		}
+	}
+	for device := range r.pending {
+		if !devices[device] {
+			delete(r.pending, device)
+		}
+	}
+}
+
+// readSmartctl returns the health reported by smartctl for the device, or nil if it doesn't report any. It also
+// returns whether the device is in standby, so it wasn't read.
+func (r *smartReader) readSmartctl(device string) (smart *SmartSample, standby bool) {
+	// smartctl reports problems with the device in the bits of its exit status, so the output is parsed anyway
+	output, err := r.run(device)
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if ok && exitErr.ExitCode() == smartStandbyStatus {
+			bdlog.WithField("device", device).Debug("Device in standby, SMART health not read.")
+			return nil, true
+		}
+		if !ok || len(output) == 0 {
+			bdlog.WithError(err).WithField("device", device).Debug("Can't read SMART health.")
+			output = nil
+		}
+	}
+	if output != nil {
+		if smart, err = parseSmartctl(output); err != nil {
+			bdlog.WithError(err).WithField("device", device).Debug("Can't parse smartctl output.")
+		}
+	}
+	return smart, false
+}
+
+func isSmartIgnoredDevice(device string) bool {
+	for _, prefix := range smartIgnoredDevices {
+		if strings.HasPrefix(device, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSmartctl returns the health of the `smartctl --json` output, or nil if it doesn't report any.
+func parseSmartctl(output []byte) (*SmartSample, error) {
+	var out smartctlOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, err
+	}
+
+	smart := &SmartSample{}
+	found := false
+	if out.SmartStatus != nil {
+		smart.SmartPassed = &out.SmartStatus.Passed
+		found = true
+	}
+	if out.Temperature != nil {
+		smart.TemperatureCelsius = &out.Temperature.Current
+		found = true
+	}
+	if out.PowerOnTime != nil {
+		smart.PowerOnHours = &out.PowerOnTime.Hours
+		found = true
+	}
+	if out.ATASmartAttributes != nil {
+		for _, attribute := range out.ATASmartAttributes.Table {
+			value := attribute.Raw.Value
+			switch attribute.ID {
+			case ataReallocatedSectors:
+				smart.ReallocatedSectors = &value
+			case ataPendingSectors:
+				smart.PendingSectors = &value
+			case ataUncorrectable:
+				smart.UncorrectableSectors = &value
+			}
+		}
+		found = true
+	}
+	if health := out.NVMeHealth; health != nil {
+		smart.NVMePercentageUsed = &health.PercentageUsed
+		smart.NVMeAvailableSparePct = &health.AvailableSpare
+		smart.NVMeMediaErrors = &health.MediaErrors
+		smart.NVMeCriticalWarning = &health.CriticalWarning
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	return smart, nil
+}
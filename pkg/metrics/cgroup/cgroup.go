@@ -0,0 +1,277 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// v1UnlimitedMemory is the lower bound of the memory limits that cgroup v1 reports for the cgroups without limit,
+// which are the maximum int64 rounded down to the page size.
+const v1UnlimitedMemory = uint64(1) << 62
+
+// Names of the counters of a cgroup, whose rates are calculated between samplings. The CPU times are in nanoseconds.
+const (
+	cpuUsage         = "cpu.usage"
+	cpuUser          = "cpu.user"
+	cpuSystem        = "cpu.system"
+	cpuPeriods       = "cpu.periods"
+	cpuThrottled     = "cpu.throttled"
+	cpuThrottledTime = "cpu.throttledTime"
+	ioReadBytes      = "io.readBytes"
+	ioWriteBytes     = "io.writeBytes"
+	ioReads          = "io.reads"
+	ioWrites         = "io.writes"
+)
+
+// stats are the metrics read from the files of a cgroup. The values that the kernel doesn't provide, because the
+// controller isn't enabled for the cgroup or because of its version, are missing or nil.
+type stats struct {
+	counters    map[string]uint64
+	memoryUsage *uint64
+	memoryLimit *uint64
+	oomEvents   *uint64
+	oomKills    *uint64
+	pidsCurrent *uint64
+	pidsLimit   *uint64
+	// pressure holds the avg10 Pressure Stall Information by "<resource>.<some|full>"
+	pressure map[string]float64
+}
+
+// hierarchy reads the cgroups of one of the cgroup versions.
+type hierarchy interface {
+	// version returns the cgroup version, 1 or 2.
+	version() int
+	// root returns the folder whose tree is walked to find the cgroups.
+	root() string
+	// read returns the stats of the cgroup, by its path relative to the root.
+	read(cgroup string) stats
+}
+
+// detectHierarchy returns the hierarchy mounted in /sys/fs/cgroup (see the override_host_sys option). The hybrid
+// setups, which mount cgroup v2 in /sys/fs/cgroup/unified, are read as cgroup v1, as the controllers are there.
+func detectHierarchy() hierarchy {
+	base := helpers.HostSys("fs", "cgroup")
+	if _, err := os.Stat(filepath.Join(base, "cgroup.controllers")); err == nil {
+		return v2{base: base}
+	}
+	return v1{base: base}
+}
+
+// v2 reads the unified hierarchy of cgroup v2.
+type v2 struct {
+	base string
+}
+
+func (h v2) version() int { return 2 }
+
+func (h v2) root() string { return h.base }
+
+func (h v2) read(cgroup string) stats {
+	dir := filepath.Join(h.base, cgroup)
+	s := stats{counters: make(map[string]uint64), pressure: make(map[string]float64)}
+
+	cpu := readKeyValues(filepath.Join(dir, "cpu.stat"))
+	for name, counter := range map[string]string{
+		"usage_usec":     cpuUsage,
+		"user_usec":      cpuUser,
+		"system_usec":    cpuSystem,
+		"throttled_usec": cpuThrottledTime,
+	} {
+		if value, ok := cpu[name]; ok {
+			s.counters[counter] = value * 1000
+		}
+	}
+	copyCounter(cpu, "nr_periods", s.counters, cpuPeriods)
+	copyCounter(cpu, "nr_throttled", s.counters, cpuThrottled)
+
+	s.memoryUsage = readValue(filepath.Join(dir, "memory.current"))
+	s.memoryLimit = readValue(filepath.Join(dir, "memory.max"))
+	events := readKeyValues(filepath.Join(dir, "memory.events"))
+	s.oomEvents = optional(events, "oom")
+	s.oomKills = optional(events, "oom_kill")
+
+	// 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+	if content, err := ioutil.ReadFile(filepath.Join(dir, "io.stat")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			for _, field := range fields[1:] {
+				kv := strings.SplitN(field, "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				value, err := strconv.ParseUint(kv[1], 10, 64)
+				if err != nil {
+					continue
+				}
+				switch kv[0] {
+				case "rbytes":
+					s.counters[ioReadBytes] += value
+				case "wbytes":
+					s.counters[ioWriteBytes] += value
+				case "rios":
+					s.counters[ioReads] += value
+				case "wios":
+					s.counters[ioWrites] += value
+				}
+			}
+		}
+	}
+
+	s.pidsCurrent = readValue(filepath.Join(dir, "pids.current"))
+	s.pidsLimit = readValue(filepath.Join(dir, "pids.max"))
+
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		readPressure(filepath.Join(dir, resource+".pressure"), resource, s.pressure)
+	}
+	return s
+}
+
+// v1 reads the per-controller hierarchies of cgroup v1.
+type v1 struct {
+	base string
+}
+
+func (h v1) version() int { return 1 }
+
+// root returns the hierarchy of systemd, which contains all the units and containers, or the cpuacct one if the host
+// doesn't run systemd.
+func (h v1) root() string {
+	if _, err := os.Stat(filepath.Join(h.base, "systemd")); err == nil {
+		return filepath.Join(h.base, "systemd")
+	}
+	return filepath.Join(h.base, "cpuacct")
+}
+
+func (h v1) read(cgroup string) stats {
+	s := stats{counters: make(map[string]uint64)}
+
+	cpuacct := filepath.Join(h.base, "cpuacct", cgroup)
+	for file, counter := range map[string]string{
+		"cpuacct.usage":      cpuUsage,
+		"cpuacct.usage_user": cpuUser,
+		"cpuacct.usage_sys":  cpuSystem,
+	} {
+		if value := readValue(filepath.Join(cpuacct, file)); value != nil {
+			s.counters[counter] = *value
+		}
+	}
+	cpu := readKeyValues(filepath.Join(h.base, "cpu", cgroup, "cpu.stat"))
+	copyCounter(cpu, "nr_periods", s.counters, cpuPeriods)
+	copyCounter(cpu, "nr_throttled", s.counters, cpuThrottled)
+	copyCounter(cpu, "throttled_time", s.counters, cpuThrottledTime)
+
+	memory := filepath.Join(h.base, "memory", cgroup)
+	s.memoryUsage = readValue(filepath.Join(memory, "memory.usage_in_bytes"))
+	if limit := readValue(filepath.Join(memory, "memory.limit_in_bytes")); limit != nil && *limit < v1UnlimitedMemory {
+		s.memoryLimit = limit
+	}
+	// oom_kill is reported since Linux 4.13
+	s.oomKills = optional(readKeyValues(filepath.Join(memory, "memory.oom_control")), "oom_kill")
+
+	blkio := filepath.Join(h.base, "blkio", cgroup)
+	readBlkio(filepath.Join(blkio, "blkio.throttle.io_service_bytes"), ioReadBytes, ioWriteBytes, s.counters)
+	readBlkio(filepath.Join(blkio, "blkio.throttle.io_serviced"), ioReads, ioWrites, s.counters)
+
+	pids := filepath.Join(h.base, "pids", cgroup)
+	s.pidsCurrent = readValue(filepath.Join(pids, "pids.current"))
+	s.pidsLimit = readValue(filepath.Join(pids, "pids.max"))
+	return s
+}
+
+// readBlkio adds the Read and Write values of the devices of a blkio file to the counters. Its lines look like:
+// 8:0 Read 1459200
+func readBlkio(path, readCounter, writeCounter string, counters map[string]uint64) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			counters[readCounter] += value
+		case "Write":
+			counters[writeCounter] += value
+		}
+	}
+}
+
+// readPressure stores the avg10 values of a Pressure Stall Information file of the resource, whose lines look like:
+// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPressure(path, resource string, pressure map[string]float64) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "avg10=") {
+			continue
+		}
+		if value, err := strconv.ParseFloat(strings.TrimPrefix(fields[1], "avg10="), 64); err == nil {
+			pressure[resource+"."+fields[0]] = value
+		}
+	}
+}
+
+// readKeyValues reads a file of "<key> <value>" lines, like cpu.stat or memory.events.
+func readKeyValues(path string) map[string]uint64 {
+	values := make(map[string]uint64)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values
+}
+
+// readValue reads a file with a single value, returning nil if it's missing or if it's "max" (unlimited).
+func readValue(path string) *uint64 {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+func copyCounter(from map[string]uint64, name string, to map[string]uint64, counter string) {
+	if value, ok := from[name]; ok {
+		to[counter] = value
+	}
+}
+
+func optional(values map[string]uint64, name string) *uint64 {
+	if value, ok := values[name]; ok {
+		return &value
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cgroup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/acquire"
+	metricTypes "github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+var clog = log.WithComponent("ContainerSampler")
+
+// containerCgroup matches the cgroup folder of a container, as named by the systemd and cgroupfs drivers of the
+// runtimes, e.g. docker-<id>.scope, cri-containerd-<id>.scope, crio-<id>.scope, libpod-<id>.scope or <id>.
+var containerCgroup = regexp.MustCompile(`^(?:(docker|cri-containerd|crio|libpod)-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+// runtimes maps the prefixes of the container cgroups to the runtime names.
+var runtimes = map[string]string{
+	"docker":         "docker",
+	"cri-containerd": "containerd",
+	"crio":           "cri-o",
+	"libpod":         "podman",
+}
+
+// ContainerSample reports the resource usage of the cgroup of a container or of a systemd service. The CPU percentages
+// and the IO rates cover the time since the cgroup was previously sampled, so a cgroup that has just appeared reports
+// only its memory and pids. The files missing in the cgroup version or controllers of the host are left out.
+type ContainerSample struct {
+	sample.BaseEvent
+
+	CgroupPath    string `json:"cgroupPath"`
+	CgroupVersion int    `json:"cgroupVersion"`
+
+	ContainerID        string `json:"containerId,omitempty"`
+	ContainerRuntime   string `json:"containerRuntime,omitempty"`
+	ContainerName      string `json:"containerName,omitempty"`
+	ContainerImage     string `json:"containerImage,omitempty"`
+	ContainerImageName string `json:"containerImageName,omitempty"`
+	SystemdUnit        string `json:"systemdUnit,omitempty"`
+	SystemdSlice       string `json:"systemdSlice,omitempty"`
+
+	CPUPercent                 *float64 `json:"cpuPercent,omitempty"`
+	CPUUserPercent             *float64 `json:"cpuUserPercent,omitempty"`
+	CPUSystemPercent           *float64 `json:"cpuSystemPercent,omitempty"`
+	CPUThrottledPeriodsPercent *float64 `json:"cpuThrottledPeriodsPercent,omitempty"`
+	CPUThrottledTimePercent    *float64 `json:"cpuThrottledTimePercent,omitempty"`
+
+	MemoryUsageBytes   *uint64  `json:"memoryUsageBytes,omitempty"`
+	MemoryLimitBytes   *uint64  `json:"memoryLimitBytes,omitempty"`
+	MemoryUsagePercent *float64 `json:"memoryUsagePercent,omitempty"`
+	MemoryOOMEvents    *uint64  `json:"memoryOOMEvents,omitempty"`
+	MemoryOOMKills     *uint64  `json:"memoryOOMKills,omitempty"`
+
+	IOReadBytesPerSec  *float64 `json:"ioReadBytesPerSecond,omitempty"`
+	IOWriteBytesPerSec *float64 `json:"ioWriteBytesPerSecond,omitempty"`
+	IOReadCountPerSec  *float64 `json:"ioReadCountPerSecond,omitempty"`
+	IOWriteCountPerSec *float64 `json:"ioWriteCountPerSecond,omitempty"`
+
+	PidsCurrent *uint64 `json:"pidsCurrent,omitempty"`
+	PidsLimit   *uint64 `json:"pidsLimit,omitempty"`
+
+	CPUPressureSomeAvg10    *float64 `json:"cpuPressureSomeAvg10,omitempty"`
+	MemoryPressureSomeAvg10 *float64 `json:"memoryPressureSomeAvg10,omitempty"`
+	MemoryPressureFullAvg10 *float64 `json:"memoryPressureFullAvg10,omitempty"`
+	IOPressureSomeAvg10     *float64 `json:"ioPressureSomeAvg10,omitempty"`
+	IOPressureFullAvg10     *float64 `json:"ioPressureFullAvg10,omitempty"`
+
+	// Auxiliary values, reported as containerLabel_<name> attributes
+	ContainerLabels map[string]string `json:"-"`
+}
+
+// ContainerSampler reports a ContainerSample for each container and systemd service of the host, reading their cgroup
+// v1 or v2 files in /sys/fs/cgroup (see the override_host_sys option). The containerd, Podman and Docker APIs, when
+// available, provide the name, image and labels of the containers.
+type ContainerSampler struct {
+	sampleInterval time.Duration
+	hierarchy      func() hierarchy
+	metadata       metadataSource
+	lastRun        time.Time
+	lastCounters   map[string]map[string]uint64
+	now            func() time.Time
+}
+
+func NewContainerSampler(context agent.AgentContext) *ContainerSampler {
+	samplerIntervalSec := config.FREQ_DISABLE_SAMPLING
+	apiVersion := config.DefaultDockerApiVersion
+	if context != nil {
+		samplerIntervalSec = context.Config().MetricsContainerSampleRate
+		apiVersion = context.Config().DockerApiVersion
+	}
+	return &ContainerSampler{
+		sampleInterval: time.Second * time.Duration(samplerIntervalSec),
+		hierarchy:      detectHierarchy,
+		metadata:       newRuntimesMetadata(apiVersion),
+		now:            time.Now,
+	}
+}
+
+func (s *ContainerSampler) Name() string { return "ContainerSampler" }
+
+func (s *ContainerSampler) Interval() time.Duration {
+	return s.sampleInterval
+}
+
+func (s *ContainerSampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
+
+func (s *ContainerSampler) OnStartup() {}
+
+func (s *ContainerSampler) Sample() (results sample.EventBatch, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("Panic in ContainerSampler.Sample: %v\nStack: %s", panicErr, debug.Stack())
+		}
+	}()
+
+	h := s.hierarchy()
+	samples, err := findCgroups(h.root())
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := s.metadata.containers()
+	if err != nil {
+		clog.WithError(err).Debug("Cannot list the containers of a container runtime API.")
+	}
+
+	now := s.now()
+	elapsedSeconds := now.Sub(s.lastRun).Seconds()
+	s.lastRun = now
+
+	counters := make(map[string]map[string]uint64, len(samples))
+	for _, cs := range samples {
+		st := h.read(cs.CgroupPath)
+		cs.CgroupVersion = h.version()
+		counters[cs.CgroupPath] = st.counters
+		if last, ok := s.lastCounters[cs.CgroupPath]; ok {
+			setRates(cs, st.counters, last, elapsedSeconds)
+		}
+		setGauges(cs, st)
+		if container, ok := containers[cs.ContainerID]; ok {
+			setMetadata(cs, container)
+		}
+		helpers.LogStructureDetails(clog, cs, "ContainerSample", "final", nil)
+		results = append(results, normalizeSample(cs))
+	}
+	s.lastCounters = counters
+
+	return results, nil
+}
+
+// findCgroups walks the hierarchy from its root, returning a sample for each cgroup of a container or a systemd
+// service. The cgroups nested in them aren't reported, as their usage is already accounted by their parent.
+func findCgroups(root string) ([]*ContainerSample, error) {
+	var samples []*ContainerSample
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the cgroups of the containers and services that stop while walking are ignored
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() || path == root {
+			return nil
+		}
+		name := info.Name()
+		parent := filepath.Base(filepath.Dir(path))
+
+		cs := &ContainerSample{}
+		if match := containerCgroup.FindStringSubmatch(name); match != nil {
+			cs.ContainerID = match[2]
+			cs.ContainerRuntime = runtimes[match[1]]
+			if cs.ContainerRuntime == "" && parent == "docker" {
+				cs.ContainerRuntime = "docker"
+			}
+		} else if strings.HasSuffix(name, ".service") {
+			cs.SystemdUnit = name
+		} else {
+			return nil
+		}
+		if strings.HasSuffix(parent, ".slice") {
+			cs.SystemdSlice = parent
+		}
+		cs.CgroupPath, _ = filepath.Rel(root, path)
+		cs.CgroupPath = "/" + cs.CgroupPath
+		cs.Type("ContainerSample")
+		samples = append(samples, cs)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].CgroupPath < samples[j].CgroupPath
+	})
+	return samples, nil
+}
+
+// setRates calculates the rates of the counters of the cgroup. The CPU times are converted to percentages of a single
+// CPU, so a container using two CPUs reports a 200% usage.
+func setRates(cs *ContainerSample, current, previous map[string]uint64, elapsedSeconds float64) {
+	cpuPercent := func(counter string) *float64 {
+		r := rate(current, previous, counter, elapsedSeconds)
+		if r != nil {
+			*r = *r / 1e9 * 100
+		}
+		return r
+	}
+	cs.CPUPercent = cpuPercent(cpuUsage)
+	cs.CPUUserPercent = cpuPercent(cpuUser)
+	cs.CPUSystemPercent = cpuPercent(cpuSystem)
+	cs.CPUThrottledTimePercent = cpuPercent(cpuThrottledTime)
+
+	periods := rate(current, previous, cpuPeriods, elapsedSeconds)
+	throttled := rate(current, previous, cpuThrottled, elapsedSeconds)
+	if periods != nil && throttled != nil && *periods > 0 {
+		percent := *throttled / *periods * 100
+		cs.CPUThrottledPeriodsPercent = &percent
+	}
+
+	cs.IOReadBytesPerSec = rate(current, previous, ioReadBytes, elapsedSeconds)
+	cs.IOWriteBytesPerSec = rate(current, previous, ioWriteBytes, elapsedSeconds)
+	cs.IOReadCountPerSec = rate(current, previous, ioReads, elapsedSeconds)
+	cs.IOWriteCountPerSec = rate(current, previous, ioWrites, elapsedSeconds)
+}
+
+func setGauges(cs *ContainerSample, st stats) {
+	cs.MemoryUsageBytes = st.memoryUsage
+	cs.MemoryLimitBytes = st.memoryLimit
+	if st.memoryUsage != nil && st.memoryLimit != nil && *st.memoryLimit > 0 {
+		percent := float64(*st.memoryUsage) / float64(*st.memoryLimit) * 100
+		cs.MemoryUsagePercent = &percent
+	}
+	cs.MemoryOOMEvents = st.oomEvents
+	cs.MemoryOOMKills = st.oomKills
+	cs.PidsCurrent = st.pidsCurrent
+	cs.PidsLimit = st.pidsLimit
+
+	pressure := func(name string) *float64 {
+		if value, ok := st.pressure[name]; ok {
+			return &value
+		}
+		return nil
+	}
+	cs.CPUPressureSomeAvg10 = pressure("cpu.some")
+	cs.MemoryPressureSomeAvg10 = pressure("memory.some")
+	cs.MemoryPressureFullAvg10 = pressure("memory.full")
+	cs.IOPressureSomeAvg10 = pressure("io.some")
+	cs.IOPressureFullAvg10 = pressure("io.full")
+}
+
+// setMetadata adds the container metadata of the runtime API, as the DockerSampler does for the process samples.
+func setMetadata(cs *ContainerSample, container types.Container) {
+	imageIDComponents := strings.Split(container.ImageID, ":")
+	cs.ContainerImage = imageIDComponents[len(imageIDComponents)-1]
+	cs.ContainerImageName = container.Image
+	cs.ContainerLabels = container.Labels
+	if len(container.Names) > 0 {
+		cs.ContainerName = strings.TrimPrefix(container.Names[0], "/")
+	}
+}
+
+// normalizeSample flattens the container labels into containerLabel_<name> attributes.
+func normalizeSample(cs *ContainerSample) sample.Event {
+	if len(cs.ContainerLabels) == 0 {
+		return cs
+	}
+	sb, err := json.Marshal(cs)
+	if err != nil {
+		clog.WithError(err).WithField("containerId", cs.ContainerID).Debug("normalizeSample can't operate on the sample.")
+		return cs
+	}
+	flat := &metricTypes.FlatProcessSample{}
+	if err = json.Unmarshal(sb, flat); err != nil {
+		return cs
+	}
+	for name, value := range cs.ContainerLabels {
+		(*flat)["containerLabel_"+name] = value
+	}
+	return flat
+}
+
+// rate returns the rate per second of the counter, or nil if it's missing in any of the samplings.
+func rate(current, previous map[string]uint64, counter string, elapsedSeconds float64) *float64 {
+	c, ok := current[counter]
+	if !ok {
+		return nil
+	}
+	p, ok := previous[counter]
+	if !ok {
+		return nil
+	}
+	r := acquire.CalculateSafeDelta(c, p, elapsedSeconds)
+	return &r
+}
@@ -0,0 +1,275 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cgroup
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/containerd"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk/sdktest"
+	metricTypes "github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+)
+
+var containerID = strings.Repeat("ab", 32)
+
+type fakeMetadata map[string]types.Container
+
+func (f fakeMetadata) containers() (map[string]types.Container, error) { return f, nil }
+
+type fakeDocker struct {
+	helpers.Docker
+	list []types.Container
+}
+
+func (f *fakeDocker) Containers() ([]types.Container, error) { return f.list, nil }
+
+func TestContainerSampler_V2(t *testing.T) {
+	container := "fs/cgroup/system.slice/docker-" + containerID + ".scope/"
+	t.Setenv("HOST_SYS", sdktest.WriteFiles(t, map[string]string{
+		"fs/cgroup/cgroup.controllers": "cpu io memory pids\n",
+		container + "cpu.stat": `usage_usec 5000000
+user_usec 3000000
+system_usec 2000000
+nr_periods 100
+nr_throttled 25
+throttled_usec 1000000
+`,
+		container + "memory.current": "268435456\n",
+		container + "memory.max":     "1073741824\n",
+		container + "memory.events":  "low 0\nhigh 0\nmax 3\noom 2\noom_kill 1\n",
+		container + "io.stat": `8:0 rbytes=1000 wbytes=2000 rios=10 wios=20 dbytes=0 dios=0
+8:16 rbytes=1000 wbytes=0 rios=10 wios=0 dbytes=0 dios=0
+`,
+		container + "pids.current":    "12\n",
+		container + "pids.max":        "max\n",
+		container + "cpu.pressure":    "some avg10=1.50 avg60=1.00 avg300=0.50 total=100\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		container + "memory.pressure": "some avg10=0.25 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.10 avg60=0.00 avg300=0.00 total=0\n",
+		// nested cgroups of a container aren't reported
+		container + "init/cpu.stat":                                             "usage_usec 1\n",
+		"fs/cgroup/system.slice/nginx.service/memory.current":                   "1024\n",
+		"fs/cgroup/system.slice/systemd-logind.service/pids.current":            "1\n",
+		"fs/cgroup/user.slice/user-1000.slice/user@1000.service/memory.current": "2048\n",
+		"fs/cgroup/init.scope/memory.current":                                   "512\n",
+	}))
+
+	now := time.Now()
+	s := NewContainerSampler(nil)
+	s.now = func() time.Time { return now }
+	s.metadata = fakeMetadata{containerID: {
+		ID:      containerID,
+		Names:   []string{"/web"},
+		Image:   "nginx:latest",
+		ImageID: "sha256:0123abcd",
+		Labels:  map[string]string{"app": "web"},
+	}}
+
+	result, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, result, 4)
+
+	flat, ok := result[0].(*metricTypes.FlatProcessSample)
+	require.True(t, ok, "the labels are flattened")
+	assert.Equal(t, "web", (*flat)["containerLabel_app"])
+	assert.Equal(t, "web", (*flat)["containerName"])
+	assert.Equal(t, "0123abcd", (*flat)["containerImage"])
+	assert.Equal(t, "nginx:latest", (*flat)["containerImageName"])
+	assert.Equal(t, "docker", (*flat)["containerRuntime"])
+	assert.Equal(t, "system.slice", (*flat)["systemdSlice"])
+	assert.Equal(t, "ContainerSample", (*flat)["eventType"])
+	assert.NotContains(t, *flat, "cpuPercent", "rates aren't reported in the first sample")
+	assert.EqualValues(t, 25, (*flat)["memoryUsagePercent"])
+	assert.EqualValues(t, 12, (*flat)["pidsCurrent"])
+	assert.NotContains(t, *flat, "pidsLimit", "unlimited values aren't reported")
+
+	nginx := result[1].(*ContainerSample)
+	assert.Equal(t, "/system.slice/nginx.service", nginx.CgroupPath)
+	assert.Equal(t, "nginx.service", nginx.SystemdUnit)
+	assert.Equal(t, "system.slice", nginx.SystemdSlice)
+	assert.Equal(t, 2, nginx.CgroupVersion)
+	assert.Equal(t, uint64(1024), *nginx.MemoryUsageBytes)
+	assert.Nil(t, nginx.MemoryLimitBytes)
+	assert.Equal(t, "systemd-logind.service", result[2].(*ContainerSample).SystemdUnit)
+	user := result[3].(*ContainerSample)
+	assert.Equal(t, "user@1000.service", user.SystemdUnit)
+	assert.Equal(t, "user-1000.slice", user.SystemdSlice)
+
+	now = now.Add(10 * time.Second)
+	t.Setenv("HOST_SYS", sdktest.WriteFiles(t, map[string]string{
+		"fs/cgroup/cgroup.controllers": "cpu io memory pids\n",
+		container + "cpu.stat": `usage_usec 10000000
+user_usec 6000000
+system_usec 4000000
+nr_periods 200
+nr_throttled 50
+throttled_usec 2000000
+`,
+		container + "memory.current": "268435456\n",
+		container + "memory.max":     "1073741824\n",
+		container + "memory.events":  "low 0\nhigh 0\nmax 3\noom 2\noom_kill 1\n",
+		container + "io.stat": `8:0 rbytes=2000 wbytes=4000 rios=20 wios=40 dbytes=0 dios=0
+8:16 rbytes=2000 wbytes=0 rios=20 wios=0 dbytes=0 dios=0
+`,
+		container + "pids.current":    "12\n",
+		container + "pids.max":        "max\n",
+		container + "cpu.pressure":    "some avg10=1.50 avg60=1.00 avg300=0.50 total=100\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		container + "memory.pressure": "some avg10=0.25 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.10 avg60=0.00 avg300=0.00 total=0\n",
+	}))
+	s.metadata = fakeMetadata{}
+	result, err = s.Sample()
+	require.NoError(t, err)
+	cs := result[0].(*ContainerSample)
+	assert.Equal(t, containerID, cs.ContainerID)
+	assert.Empty(t, cs.ContainerName)
+	assert.Equal(t, 50.0, *cs.CPUPercent)
+	assert.Equal(t, 30.0, *cs.CPUUserPercent)
+	assert.Equal(t, 20.0, *cs.CPUSystemPercent)
+	assert.Equal(t, 25.0, *cs.CPUThrottledPeriodsPercent)
+	assert.Equal(t, 10.0, *cs.CPUThrottledTimePercent)
+	assert.Equal(t, 200.0, *cs.IOReadBytesPerSec)
+	assert.Equal(t, 200.0, *cs.IOWriteBytesPerSec)
+	assert.Equal(t, 2.0, *cs.IOReadCountPerSec)
+	assert.Equal(t, 2.0, *cs.IOWriteCountPerSec)
+	assert.Equal(t, uint64(2), *cs.MemoryOOMEvents)
+	assert.Equal(t, uint64(1), *cs.MemoryOOMKills)
+	assert.Equal(t, 1.5, *cs.CPUPressureSomeAvg10)
+	assert.Equal(t, 0.1, *cs.MemoryPressureFullAvg10)
+	assert.Nil(t, cs.IOPressureSomeAvg10)
+}
+
+func TestContainerSampler_V1(t *testing.T) {
+	cgroup := "/kubepods/burstable/pod1234/" + containerID
+	t.Setenv("HOST_SYS", sdktest.WriteFiles(t, map[string]string{
+		"fs/cgroup/systemd/kubepods/burstable/pod1234/" + containerID + "/cgroup.procs":           "1\n",
+		"fs/cgroup/systemd/docker/" + strings.Repeat("cd", 32) + "/cgroup.procs":                  "2\n",
+		"fs/cgroup/systemd/system.slice/crio-" + strings.Repeat("ef", 32) + ".scope/cgroup.procs": "3\n",
+		"fs/cgroup/cpuacct" + cgroup + "/cpuacct.usage":                                           "2000000000\n",
+		"fs/cgroup/cpuacct" + cgroup + "/cpuacct.usage_user":                                      "1500000000\n",
+		"fs/cgroup/cpu" + cgroup + "/cpu.stat":                                                    "nr_periods 10\nnr_throttled 1\nthrottled_time 5000\n",
+		"fs/cgroup/memory" + cgroup + "/memory.usage_in_bytes":                                    "4096\n",
+		"fs/cgroup/memory" + cgroup + "/memory.limit_in_bytes":                                    "9223372036854771712\n",
+		"fs/cgroup/memory" + cgroup + "/memory.oom_control":                                       "oom_kill_disable 0\nunder_oom 0\noom_kill 4\n",
+		"fs/cgroup/blkio" + cgroup + "/blkio.throttle.io_service_bytes":                           "8:0 Read 4096\n8:0 Write 8192\n8:0 Sync 0\n8:0 Total 12288\nTotal 12288\n",
+		"fs/cgroup/pids" + cgroup + "/pids.max":                                                   "100\n",
+	}))
+
+	samples, err := findCgroups(detectHierarchy().root())
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Equal(t, "docker", samples[0].ContainerRuntime)
+	assert.Equal(t, strings.Repeat("cd", 32), samples[0].ContainerID)
+	assert.Equal(t, cgroup, samples[1].CgroupPath)
+	assert.Empty(t, samples[1].ContainerRuntime, "the runtime isn't known for the cgroupfs driver of Kubernetes")
+	assert.Equal(t, "cri-o", samples[2].ContainerRuntime)
+
+	h := detectHierarchy()
+	assert.Equal(t, 1, h.version())
+	st := h.read(cgroup)
+	assert.Equal(t, uint64(2000000000), st.counters[cpuUsage])
+	assert.Equal(t, uint64(1500000000), st.counters[cpuUser])
+	assert.NotContains(t, st.counters, cpuSystem)
+	assert.Equal(t, uint64(5000), st.counters[cpuThrottledTime])
+	assert.Equal(t, uint64(4096), st.counters[ioReadBytes])
+	assert.Equal(t, uint64(8192), st.counters[ioWriteBytes])
+	assert.Equal(t, uint64(4096), *st.memoryUsage)
+	assert.Nil(t, st.memoryLimit, "unlimited memory isn't reported")
+	assert.Equal(t, uint64(4), *st.oomKills)
+	assert.Nil(t, st.oomEvents)
+	assert.Nil(t, st.pidsCurrent)
+	assert.Equal(t, uint64(100), *st.pidsLimit)
+	assert.Empty(t, st.pressure)
+}
+
+func TestDockerMetadata(t *testing.T) {
+	attempts := 0
+	d := newDockerMetadata("1.24")
+	d.newClient = func(string) (helpers.Docker, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("no docker")
+		}
+		return &fakeDocker{list: []types.Container{{ID: containerID}}}, nil
+	}
+
+	containers, err := d.containers()
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+
+	containers, err = d.containers()
+	require.NoError(t, err)
+	assert.Contains(t, containers, containerID)
+
+	_, err = d.containers()
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts, "the client is reused")
+}
+
+func TestPodmanMetadata_NoSocket(t *testing.T) {
+	p := newPodmanMetadata(filepath.Join(t.TempDir(), "podman.sock"))
+
+	containers, err := p.containers()
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+	assert.Nil(t, p.client)
+}
+
+func TestContainerdMetadata(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "containerd.sock")
+	c := newContainerdMetadata(socket)
+	c.list = func(s, namespace string, _ containerd.ProcPathFunc) ([]types.Container, error) {
+		assert.Equal(t, socket, s)
+		assert.Empty(t, namespace, "containers of all the namespaces")
+		return []types.Container{{ID: containerID, Image: "docker.io/library/redis:7"}}, nil
+	}
+
+	containers, err := c.containers()
+	require.NoError(t, err)
+	assert.Empty(t, containers, "the API is not queried without socket")
+
+	require.NoError(t, ioutil.WriteFile(socket, nil, 0600))
+	containers, err = c.containers()
+	require.NoError(t, err)
+	assert.Equal(t, "docker.io/library/redis:7", containers[containerID].Image)
+}
+
+type failingMetadata struct{}
+
+func (failingMetadata) containers() (map[string]types.Container, error) {
+	return nil, errors.New("unreachable")
+}
+
+func TestRuntimesMetadata(t *testing.T) {
+	other := strings.Repeat("cd", 32)
+	r := runtimesMetadata{
+		fakeMetadata{containerID: {ID: containerID, Image: "containerd"}, other: {ID: other}},
+		failingMetadata{},
+		fakeMetadata{containerID: {ID: containerID, Image: "docker"}},
+	}
+
+	containers, err := r.containers()
+	assert.Error(t, err)
+	require.Len(t, containers, 2)
+	assert.Equal(t, "docker", containers[containerID].Image, "the latest runtime takes precedence")
+	assert.Contains(t, containers, other)
+}
+
+func TestContainerSampler_Disabled(t *testing.T) {
+	assert.True(t, NewContainerSampler(nil).Disabled())
+
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{MetricsContainerSampleRate: 15, DockerApiVersion: "1.24"})
+	s := NewContainerSampler(ctx)
+	assert.False(t, s.Disabled())
+	assert.Equal(t, 15*time.Second, s.Interval())
+}
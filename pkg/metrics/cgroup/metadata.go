@@ -0,0 +1,146 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cgroup
+
+import (
+	"os"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/containerd"
+)
+
+// maxClientRetries is the number of samplings in which the client of the runtime API is initialized before giving
+// up, as in the DockerSampler.
+const maxClientRetries = 100
+
+// podmanSocket is the socket of the rootful Podman API service (podman.socket systemd unit).
+const podmanSocket = "/run/podman/podman.sock"
+
+// metadataSource returns the containers known by a container runtime, by ID.
+type metadataSource interface {
+	containers() (map[string]types.Container, error)
+}
+
+// runtimesMetadata returns the containers of every container runtime whose API is available. When a container is
+// known by several runtimes, as the Docker ones that are also containerd ones, the latest source takes precedence.
+type runtimesMetadata []metadataSource
+
+func newRuntimesMetadata(dockerAPIVersion string) runtimesMetadata {
+	return runtimesMetadata{
+		newContainerdMetadata(containerd.DefaultSocket),
+		newPodmanMetadata(podmanSocket),
+		newDockerMetadata(dockerAPIVersion),
+	}
+}
+
+// containers returns the containers of the available runtimes, along with the last error of the ones that failed.
+func (r runtimesMetadata) containers() (map[string]types.Container, error) {
+	var lastErr error
+	all := map[string]types.Container{}
+	for _, source := range r {
+		containers, err := source.containers()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for id, container := range containers {
+			all[id] = container
+		}
+	}
+	return all, lastErr
+}
+
+// dockerMetadata returns the containers of the Docker API, or of a Docker compatible one as Podman's. The API is
+// optional: the client is initialized lazily, and the containers are reported without metadata while it's not
+// available.
+type dockerMetadata struct {
+	runtime    string
+	apiVersion string
+	client     helpers.Docker
+	retries    int
+	newClient  func(apiVersion string) (helpers.Docker, error)
+}
+
+func newDockerMetadata(apiVersion string) *dockerMetadata {
+	return &dockerMetadata{
+		runtime:    "Docker",
+		apiVersion: apiVersion,
+		newClient: func(apiVersion string) (helpers.Docker, error) {
+			client := &helpers.DockerClient{}
+			if err := client.Initialize(apiVersion); err != nil {
+				return nil, err
+			}
+			return client, nil
+		},
+	}
+}
+
+func newPodmanMetadata(socket string) *dockerMetadata {
+	return &dockerMetadata{
+		runtime: "Podman",
+		newClient: func(string) (helpers.Docker, error) {
+			client := &helpers.DockerClient{}
+			if err := client.InitializeSocket(socket); err != nil {
+				return nil, err
+			}
+			return client, nil
+		},
+	}
+}
+
+func (d *dockerMetadata) containers() (map[string]types.Container, error) {
+	if d.client == nil {
+		if d.retries >= maxClientRetries {
+			return nil, nil
+		}
+		d.retries++
+		client, err := d.newClient(d.apiVersion)
+		if err != nil {
+			clog.WithError(err).Debugf("%s API not available, reporting its containers without metadata.", d.runtime)
+			return nil, nil
+		}
+		d.client = client
+	}
+
+	list, err := d.client.Containers()
+	if err != nil {
+		return nil, err
+	}
+	return byID(list), nil
+}
+
+// containerdMetadata returns the running containers of all the namespaces of the containerd API, as Kubernetes and
+// nerdctl ones, when its socket is available.
+type containerdMetadata struct {
+	socket string
+	list   func(socket, namespace string, procPath containerd.ProcPathFunc) ([]types.Container, error)
+}
+
+func newContainerdMetadata(socket string) *containerdMetadata {
+	return &containerdMetadata{
+		socket: socket,
+		list:   containerd.Containers,
+	}
+}
+
+func (c *containerdMetadata) containers() (map[string]types.Container, error) {
+	if _, err := os.Stat(c.socket); err != nil {
+		return nil, nil
+	}
+
+	list, err := c.list(c.socket, "", helpers.HostProc)
+	if err != nil {
+		return nil, err
+	}
+	return byID(list), nil
+}
+
+func byID(list []types.Container) map[string]types.Container {
+	containers := make(map[string]types.Container, len(list))
+	for _, container := range list {
+		containers[container.ID] = container
+	}
+	return containers
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cpucore
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+var cclog = log.WithComponent("CpuCoreSampler")
+
+// CpuCoreSample reports the usage percentages of a logical CPU since the previous sample.
+type CpuCoreSample struct {
+	sample.BaseEvent
+
+	Core string `json:"cpuCore"`
+
+	CPUPercent        float64 `json:"cpuPercent"`
+	CPUUserPercent    float64 `json:"cpuUserPercent"`
+	CPUSystemPercent  float64 `json:"cpuSystemPercent"`
+	CPUIOWaitPercent  float64 `json:"cpuIOWaitPercent"`
+	CPUStealPercent   float64 `json:"cpuStealPercent"`
+	CPUIRQPercent     float64 `json:"cpuIRQPercent"`
+	CPUSoftIRQPercent float64 `json:"cpuSoftIRQPercent"`
+	CPUIdlePercent    float64 `json:"cpuIdlePercent"`
+}
+
+// CpuCoreSampler reports a CpuCoreSample for each logical CPU, up to the cpu_core_sample_limit busiest ones.
+type CpuCoreSampler struct {
+	sampleInterval time.Duration
+	limit          int
+	last           map[string]cpu.TimesStat
+	cpuTimes       func(bool) ([]cpu.TimesStat, error)
+	limitLogged    bool
+}
+
+func NewCpuCoreSampler(context agent.AgentContext) *CpuCoreSampler {
+	samplerIntervalSec := config.FREQ_DISABLE_SAMPLING
+	limit := config.DefaultCpuCoreSampleLimit
+	if context != nil {
+		samplerIntervalSec = context.Config().MetricsCpuCoreSampleRate
+		limit = context.Config().CpuCoreSampleLimit
+	}
+	return &CpuCoreSampler{
+		sampleInterval: time.Second * time.Duration(samplerIntervalSec),
+		limit:          limit,
+		cpuTimes:       cpu.Times,
+	}
+}
+
+func (s *CpuCoreSampler) Name() string { return "CpuCoreSampler" }
+
+func (s *CpuCoreSampler) Interval() time.Duration {
+	return s.sampleInterval
+}
+
+func (s *CpuCoreSampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
+
+func (s *CpuCoreSampler) OnStartup() {}
+
+// Sample returns the samples of the logical CPUs. The first invocation doesn't return any sample, as the usage is
+// calculated from the CPU times of the previous one.
+func (s *CpuCoreSampler) Sample() (results sample.EventBatch, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("Panic in CpuCoreSampler.Sample: %v\nStack: %s", panicErr, debug.Stack())
+		}
+	}()
+
+	currentTimes, err := s.cpuTimes(true)
+	if err != nil {
+		return nil, err
+	}
+	helpers.LogStructureDetails(cclog, currentTimes, "CpuTimes", "raw", nil)
+
+	current := make(map[string]cpu.TimesStat, len(currentTimes))
+	samples := make([]*CpuCoreSample, 0, len(currentTimes))
+	for _, times := range currentTimes {
+		current[times.CPU] = times
+		if previous, ok := s.last[times.CPU]; ok {
+			samples = append(samples, newCpuCoreSample(&times, &previous))
+		}
+	}
+	s.last = current
+
+	if s.limit > 0 && len(samples) > s.limit {
+		if !s.limitLogged {
+			cclog.WithField("cores", len(samples)).WithField("limit", s.limit).
+				Info("The host has more logical CPUs than cpu_core_sample_limit, only the busiest ones are reported.")
+			s.limitLogged = true
+		}
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].CPUPercent > samples[j].CPUPercent
+		})
+		samples = samples[:s.limit]
+	}
+
+	for _, cs := range samples {
+		results = append(results, cs)
+	}
+	return results, nil
+}
+
+// newCpuCoreSample calculates the usage percentages of the logical CPU from the CPU times of two samplings.
+func newCpuCoreSample(current, previous *cpu.TimesStat) *CpuCoreSample {
+	user := current.User + current.Nice - previous.User - previous.Nice
+	system := current.System - previous.System
+	ioWait := current.Iowait - previous.Iowait
+	irq := current.Irq - previous.Irq
+	softIRQ := current.Softirq - previous.Softirq
+	idle := current.Idle - previous.Idle
+	steal := current.Steal - previous.Steal
+	// steal time can decrease in some paravirtualized environments during migrations (see metrics.cpuDelta)
+	if steal < 0 {
+		steal = 0
+	}
+
+	cs := &CpuCoreSample{Core: current.CPU, CPUIdlePercent: 100}
+	cs.Type("CpuCoreSample")
+
+	total := user + system + ioWait + irq + softIRQ + idle + steal
+	if total <= 0 {
+		return cs
+	}
+	cs.CPUUserPercent = user / total * 100
+	cs.CPUSystemPercent = system / total * 100
+	cs.CPUIOWaitPercent = ioWait / total * 100
+	cs.CPUIRQPercent = irq / total * 100
+	cs.CPUSoftIRQPercent = softIRQ / total * 100
+	cs.CPUStealPercent = steal / total * 100
+	cs.CPUIdlePercent = idle / total * 100
+	cs.CPUPercent = 100 - cs.CPUIdlePercent
+	return cs
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cpucore
+
+import (
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCpuCoreSampler_Disabled(t *testing.T) {
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{MetricsCpuCoreSampleRate: config.FREQ_DISABLE_SAMPLING})
+
+	assert.True(t, NewCpuCoreSampler(ctx).Disabled())
+	assert.True(t, NewCpuCoreSampler(nil).Disabled())
+}
+
+func TestCpuCoreSampler_Sample(t *testing.T) {
+	times := [][]cpu.TimesStat{
+		{
+			{CPU: "cpu0", User: 100, System: 50, Idle: 850},
+			{CPU: "cpu1", User: 100, System: 50, Idle: 850},
+			{CPU: "cpu2", User: 100, System: 50, Idle: 850},
+		},
+		{
+			// pegged by a single threaded process
+			{CPU: "cpu0", User: 190, System: 60, Idle: 850},
+			// interrupt storm
+			{CPU: "cpu1", User: 100, System: 50, Irq: 30, Softirq: 20, Idle: 900},
+			// idle, with a steal time decreased by a migration
+			{CPU: "cpu2", User: 100, System: 50, Idle: 950, Steal: -5},
+		},
+	}
+	s := &CpuCoreSampler{limit: 2}
+	s.cpuTimes = func(perCPU bool) ([]cpu.TimesStat, error) {
+		assert.True(t, perCPU)
+		current := times[0]
+		times = times[1:]
+		return current, nil
+	}
+
+	results, err := s.Sample()
+	require.NoError(t, err)
+	assert.Empty(t, results, "first sample only stores the CPU times")
+
+	results, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, results, 2, "limited to the 2 busiest cores")
+
+	pegged := results[0].(*CpuCoreSample)
+	assert.Equal(t, "CpuCoreSample", pegged.EventType)
+	assert.Equal(t, "cpu0", pegged.Core)
+	assert.InDelta(t, 100, pegged.CPUPercent, 0.001)
+	assert.InDelta(t, 90, pegged.CPUUserPercent, 0.001)
+	assert.InDelta(t, 10, pegged.CPUSystemPercent, 0.001)
+
+	storm := results[1].(*CpuCoreSample)
+	assert.Equal(t, "cpu1", storm.Core)
+	assert.InDelta(t, 50, storm.CPUPercent, 0.001)
+	assert.InDelta(t, 30, storm.CPUIRQPercent, 0.001)
+	assert.InDelta(t, 20, storm.CPUSoftIRQPercent, 0.001)
+	assert.InDelta(t, 50, storm.CPUIdlePercent, 0.001)
+}
+
+func TestNewCpuCoreSample_NoTime(t *testing.T) {
+	times := cpu.TimesStat{CPU: "cpu0", User: 10, Idle: 10}
+	cs := newCpuCoreSample(&times, &times)
+
+	assert.Equal(t, 0.0, cs.CPUPercent)
+	assert.Equal(t, 100.0, cs.CPUIdlePercent)
+}
@@ -45,6 +45,10 @@ func FilterStorageSamples(samples sample.EventBatch) []*storage.Sample {
 		if !ok {
 			continue
 		}
+		// stale or hung filesystems are reported without usage
+		if ss.MountState == storage.MountStateStale || ss.MountState == storage.MountStateTimeout {
+			continue
+		}
 		// Remove duplicated devices.
 		if _, ok := seen[ss.Device]; ok {
 			continue
@@ -66,6 +66,11 @@ func TestFilterStorageSamples(t *testing.T) {
 			Device:     "test",
 			MountPoint: "/var/lib/kubelet/",
 		}},
+		&storage.Sample{BaseSample: storage.BaseSample{
+			Device:     "server:/export",
+			MountPoint: "/mnt/nfs",
+			MountState: storage.MountStateTimeout,
+		}},
 	}
 
 	var expectedValues = []*storage.Sample{
@@ -0,0 +1,336 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package hardware
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/acquire"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+var hwlog = log.WithComponent("HardwareSampler")
+
+// Values of the sensorType attribute of the HardwareSample
+const (
+	SensorTemperature  = "temperature"
+	SensorFan          = "fan"
+	SensorVoltage      = "voltage"
+	SensorThermalZone  = "thermalZone"
+	SensorPowerSupply  = "powerSupply"
+	SensorCPUFrequency = "cpuFrequency"
+)
+
+// hwmonInput matches the input files of the hwmon sensors, e.g. temp1_input, fan2_input or in0_input
+var hwmonInput = regexp.MustCompile(`^(temp|fan|in)(\d+)_input$`)
+
+var cpuDir = regexp.MustCompile(`^cpu\d+$`)
+
+// HardwareSample reports a hardware sensor. Only the attributes of its sensorType are set.
+type HardwareSample struct {
+	sample.BaseEvent
+
+	SensorType string `json:"sensorType"`
+	// Chip is the name of the hwmon device (e.g. coretemp or nct6775), the type of the thermal zone, the name of the
+	// power supply or the CPU.
+	Chip  string `json:"chip"`
+	Label string `json:"label,omitempty"`
+
+	TemperatureCelsius         *float64 `json:"temperatureCelsius,omitempty"`
+	TemperatureMaxCelsius      *float64 `json:"temperatureMaxCelsius,omitempty"`
+	TemperatureCriticalCelsius *float64 `json:"temperatureCriticalCelsius,omitempty"`
+	FanRPM                     *float64 `json:"fanRpm,omitempty"`
+	FanMinRPM                  *float64 `json:"fanMinRpm,omitempty"`
+	Volts                      *float64 `json:"volts,omitempty"`
+
+	PowerSupplyType *string  `json:"powerSupplyType,omitempty"`
+	Status          *string  `json:"status,omitempty"`
+	Online          *bool    `json:"online,omitempty"`
+	CapacityPercent *float64 `json:"capacityPercent,omitempty"`
+	PowerWatts      *float64 `json:"powerWatts,omitempty"`
+
+	FrequencyMHz              *float64 `json:"frequencyMHz,omitempty"`
+	MinFrequencyMHz           *float64 `json:"minFrequencyMHz,omitempty"`
+	MaxFrequencyMHz           *float64 `json:"maxFrequencyMHz,omitempty"`
+	CoreThrottlesPerSecond    *float64 `json:"coreThrottlesPerSecond,omitempty"`
+	PackageThrottlesPerSecond *float64 `json:"packageThrottlesPerSecond,omitempty"`
+}
+
+// throttleCounters are the thermal throttle events of a CPU since boot.
+type throttleCounters struct {
+	core, pkg *uint64
+}
+
+// HardwareSampler reports a HardwareSample for each sensor of /sys/class/hwmon, thermal zone of /sys/class/thermal,
+// power supply of /sys/class/power_supply and CPU of /sys/devices/system/cpu. The sysfs root can be changed with the
+// override_host_sys option.
+type HardwareSampler struct {
+	sampleInterval time.Duration
+	lastRun        time.Time
+	lastThrottles  map[string]throttleCounters
+	now            func() time.Time
+}
+
+func NewHardwareSampler(context agent.AgentContext) *HardwareSampler {
+	samplerIntervalSec := config.FREQ_DISABLE_SAMPLING
+	if context != nil {
+		samplerIntervalSec = context.Config().MetricsHardwareSampleRate
+	}
+	return &HardwareSampler{
+		sampleInterval: time.Second * time.Duration(samplerIntervalSec),
+		now:            time.Now,
+	}
+}
+
+func (s *HardwareSampler) Name() string { return "HardwareSampler" }
+
+func (s *HardwareSampler) Interval() time.Duration {
+	return s.sampleInterval
+}
+
+func (s *HardwareSampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
+
+func (s *HardwareSampler) OnStartup() {}
+
+func (s *HardwareSampler) Sample() (results sample.EventBatch, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("Panic in HardwareSampler.Sample: %v\nStack: %s", panicErr, debug.Stack())
+		}
+	}()
+
+	var samples []*HardwareSample
+	samples = append(samples, hwmonSamples()...)
+	samples = append(samples, thermalZoneSamples()...)
+	samples = append(samples, powerSupplySamples()...)
+	samples = append(samples, s.cpuSamples()...)
+
+	for _, hs := range samples {
+		helpers.LogStructureDetails(hwlog, hs, "HardwareSample", "final", nil)
+		results = append(results, hs)
+	}
+	return results, nil
+}
+
+func newHardwareSample(sensorType, chip, label string) *HardwareSample {
+	hs := &HardwareSample{SensorType: sensorType, Chip: chip, Label: label}
+	hs.Type("HardwareSample")
+	return hs
+}
+
+// hwmonSamples returns the temperatures, fan speeds and voltages of the hwmon devices, which are reported in
+// millidegrees Celsius, RPM and millivolts.
+func hwmonSamples() []*HardwareSample {
+	var samples []*HardwareSample
+	for _, dir := range subdirectories(helpers.HostSys("class", "hwmon"), nil) {
+		// before Linux 5.4, some drivers placed the sensors in the device directory
+		if _, err := ioutil.ReadFile(filepath.Join(dir, "name")); err != nil {
+			dir = filepath.Join(dir, "device")
+		}
+		chip := readString(filepath.Join(dir, "name"))
+		if chip == "" {
+			continue
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		var inputs []string
+		for _, file := range files {
+			if hwmonInput.MatchString(file.Name()) {
+				inputs = append(inputs, file.Name())
+			}
+		}
+		sort.Strings(inputs)
+
+		for _, input := range inputs {
+			sensor := strings.TrimSuffix(input, "_input")
+			value := readScaled(filepath.Join(dir, input), 1)
+			if value == nil {
+				continue
+			}
+			label := readString(filepath.Join(dir, sensor+"_label"))
+			if label == "" {
+				label = sensor
+			}
+
+			var hs *HardwareSample
+			switch hwmonInput.FindStringSubmatch(input)[1] {
+			case "temp":
+				hs = newHardwareSample(SensorTemperature, chip, label)
+				hs.TemperatureCelsius = divide(value, 1000)
+				hs.TemperatureMaxCelsius = readScaled(filepath.Join(dir, sensor+"_max"), 1000)
+				hs.TemperatureCriticalCelsius = readScaled(filepath.Join(dir, sensor+"_crit"), 1000)
+			case "fan":
+				hs = newHardwareSample(SensorFan, chip, label)
+				hs.FanRPM = value
+				hs.FanMinRPM = readScaled(filepath.Join(dir, sensor+"_min"), 1)
+			case "in":
+				hs = newHardwareSample(SensorVoltage, chip, label)
+				hs.Volts = divide(value, 1000)
+			}
+			samples = append(samples, hs)
+		}
+	}
+	return samples
+}
+
+// thermalZoneSamples returns the temperature of the thermal zones, e.g. the SoC of ARM boards.
+func thermalZoneSamples() []*HardwareSample {
+	var samples []*HardwareSample
+	zones := subdirectories(helpers.HostSys("class", "thermal"), func(name string) bool {
+		return strings.HasPrefix(name, "thermal_zone")
+	})
+	for _, dir := range zones {
+		temperature := readScaled(filepath.Join(dir, "temp"), 1000)
+		if temperature == nil {
+			continue
+		}
+		hs := newHardwareSample(SensorThermalZone, readString(filepath.Join(dir, "type")), filepath.Base(dir))
+		hs.TemperatureCelsius = temperature
+		samples = append(samples, hs)
+	}
+	return samples
+}
+
+// powerSupplySamples returns the state of the batteries, UPSs and mains adapters. The voltage and power are reported
+// in microvolts and microwatts.
+func powerSupplySamples() []*HardwareSample {
+	var samples []*HardwareSample
+	for _, dir := range subdirectories(helpers.HostSys("class", "power_supply"), nil) {
+		hs := newHardwareSample(SensorPowerSupply, filepath.Base(dir), "")
+		hs.PowerSupplyType = optionalString(readString(filepath.Join(dir, "type")))
+		hs.Status = optionalString(readString(filepath.Join(dir, "status")))
+		if online := readScaled(filepath.Join(dir, "online"), 1); online != nil {
+			isOnline := *online == 1
+			hs.Online = &isOnline
+		}
+		hs.CapacityPercent = readScaled(filepath.Join(dir, "capacity"), 1)
+		hs.Volts = readScaled(filepath.Join(dir, "voltage_now"), 1000000)
+		hs.PowerWatts = readScaled(filepath.Join(dir, "power_now"), 1000000)
+		if hs.PowerWatts == nil {
+			current := readScaled(filepath.Join(dir, "current_now"), 1000000)
+			if current != nil && hs.Volts != nil {
+				power := *current * *hs.Volts
+				hs.PowerWatts = &power
+			}
+		}
+		samples = append(samples, hs)
+	}
+	return samples
+}
+
+// cpuSamples returns the frequency of each CPU, reported in kHz, and the rate of its thermal throttle events, which
+// are only available in x86.
+func (s *HardwareSampler) cpuSamples() []*HardwareSample {
+	now := s.now()
+	elapsedSeconds := now.Sub(s.lastRun).Seconds()
+	s.lastRun = now
+
+	throttles := make(map[string]throttleCounters)
+	var samples []*HardwareSample
+	for _, dir := range subdirectories(helpers.HostSys("devices", "system", "cpu"), cpuDir.MatchString) {
+		cpu := filepath.Base(dir)
+		hs := newHardwareSample(SensorCPUFrequency, cpu, "")
+		hs.FrequencyMHz = readScaled(filepath.Join(dir, "cpufreq", "scaling_cur_freq"), 1000)
+		hs.MinFrequencyMHz = readScaled(filepath.Join(dir, "cpufreq", "cpuinfo_min_freq"), 1000)
+		hs.MaxFrequencyMHz = readScaled(filepath.Join(dir, "cpufreq", "cpuinfo_max_freq"), 1000)
+
+		current := throttleCounters{
+			core: readUint(filepath.Join(dir, "thermal_throttle", "core_throttle_count")),
+			pkg:  readUint(filepath.Join(dir, "thermal_throttle", "package_throttle_count")),
+		}
+		throttles[cpu] = current
+		if last, ok := s.lastThrottles[cpu]; ok && elapsedSeconds > 0 {
+			hs.CoreThrottlesPerSecond = throttleRate(current.core, last.core, elapsedSeconds)
+			hs.PackageThrottlesPerSecond = throttleRate(current.pkg, last.pkg, elapsedSeconds)
+		}
+
+		// offline CPUs and virtual machines don't report the frequency
+		if hs.FrequencyMHz == nil && current.core == nil {
+			continue
+		}
+		samples = append(samples, hs)
+	}
+	s.lastThrottles = throttles
+	return samples
+}
+
+func throttleRate(current, last *uint64, elapsedSeconds float64) *float64 {
+	if current == nil || last == nil {
+		return nil
+	}
+	rate := acquire.CalculateSafeDelta(*current, *last, elapsedSeconds)
+	return &rate
+}
+
+// subdirectories returns the sorted paths of the entries of the directory accepted by the filter, which is optional.
+// The entries of /sys/class are symbolic links to the device directories.
+func subdirectories(dir string, filter func(name string) bool) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		hwlog.WithError(err).WithField("dir", dir).Debug("Can't read hardware sensors.")
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if filter == nil || filter(entry.Name()) {
+			dirs = append(dirs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func readString(path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// readScaled returns the numeric value of the file divided by the scale, or nil if it can't be read.
+func readScaled(path string, scale float64) *float64 {
+	value, err := strconv.ParseFloat(readString(path), 64)
+	if err != nil {
+		return nil
+	}
+	value /= scale
+	return &value
+}
+
+func divide(value *float64, divisor float64) *float64 {
+	scaled := *value / divisor
+	return &scaled
+}
+
+func readUint(path string) *uint64 {
+	value, err := strconv.ParseUint(readString(path), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package hardware
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk/sdktest"
+)
+
+func TestHardwareSampler(t *testing.T) {
+	dir := sdktest.WriteFiles(t, map[string]string{
+		"class/hwmon/hwmon0/name":        "coretemp\n",
+		"class/hwmon/hwmon0/temp1_input": "62000\n",
+		"class/hwmon/hwmon0/temp1_label": "Package id 0\n",
+		"class/hwmon/hwmon0/temp1_max":   "84000\n",
+		"class/hwmon/hwmon0/temp1_crit":  "100000\n",
+		"class/hwmon/hwmon0/temp2_input": "58000\n",
+		// old kernels place the sensors in the device directory
+		"class/hwmon/hwmon1/device/name":                                  "nct6775\n",
+		"class/hwmon/hwmon1/device/fan1_input":                            "1250\n",
+		"class/hwmon/hwmon1/device/fan1_min":                              "300\n",
+		"class/hwmon/hwmon1/device/in0_input":                             "1216\n",
+		"class/hwmon/hwmon1/device/in0_label":                             "Vcore\n",
+		"class/thermal/thermal_zone0/type":                                "cpu-thermal\n",
+		"class/thermal/thermal_zone0/temp":                                "71500\n",
+		"class/thermal/cooling_device0/type":                              "Processor\n",
+		"class/power_supply/BAT0/type":                                    "Battery\n",
+		"class/power_supply/BAT0/status":                                  "Discharging\n",
+		"class/power_supply/BAT0/capacity":                                "87\n",
+		"class/power_supply/BAT0/voltage_now":                             "12000000\n",
+		"class/power_supply/BAT0/current_now":                             "500000\n",
+		"class/power_supply/AC/type":                                      "Mains\n",
+		"class/power_supply/AC/online":                                    "0\n",
+		"devices/system/cpu/cpu0/cpufreq/scaling_cur_freq":                "2400000\n",
+		"devices/system/cpu/cpu0/cpufreq/cpuinfo_min_freq":                "800000\n",
+		"devices/system/cpu/cpu0/cpufreq/cpuinfo_max_freq":                "3600000\n",
+		"devices/system/cpu/cpu0/thermal_throttle/core_throttle_count":    "10\n",
+		"devices/system/cpu/cpu0/thermal_throttle/package_throttle_count": "20\n",
+		"devices/system/cpu/cpufreq/policy0/scaling_governor":             "powersave\n",
+		// offline CPU
+		"devices/system/cpu/cpu1/online": "0\n",
+	})
+	t.Setenv("HOST_SYS", dir)
+
+	now := time.Now()
+	s := NewHardwareSampler(nil)
+	s.now = func() time.Time { return now }
+
+	result, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, result, 8)
+
+	pkg := result[0].(*HardwareSample)
+	assert.Equal(t, "HardwareSample", pkg.EventType)
+	assert.Equal(t, SensorTemperature, pkg.SensorType)
+	assert.Equal(t, "coretemp", pkg.Chip)
+	assert.Equal(t, "Package id 0", pkg.Label)
+	assert.Equal(t, 62.0, *pkg.TemperatureCelsius)
+	assert.Equal(t, 84.0, *pkg.TemperatureMaxCelsius)
+	assert.Equal(t, 100.0, *pkg.TemperatureCriticalCelsius)
+	assert.Equal(t, "temp2", result[1].(*HardwareSample).Label, "the sensor name is used when there is no label")
+
+	fan := result[2].(*HardwareSample)
+	assert.Equal(t, SensorFan, fan.SensorType)
+	assert.Equal(t, "nct6775", fan.Chip)
+	assert.Equal(t, 1250.0, *fan.FanRPM)
+	assert.Equal(t, 300.0, *fan.FanMinRPM)
+
+	vcore := result[3].(*HardwareSample)
+	assert.Equal(t, SensorVoltage, vcore.SensorType)
+	assert.Equal(t, "Vcore", vcore.Label)
+	assert.Equal(t, 1.216, *vcore.Volts)
+
+	zone := result[4].(*HardwareSample)
+	assert.Equal(t, SensorThermalZone, zone.SensorType)
+	assert.Equal(t, "cpu-thermal", zone.Chip)
+	assert.Equal(t, "thermal_zone0", zone.Label)
+	assert.Equal(t, 71.5, *zone.TemperatureCelsius)
+
+	ac := result[5].(*HardwareSample)
+	assert.Equal(t, "AC", ac.Chip)
+	assert.Equal(t, "Mains", *ac.PowerSupplyType)
+	assert.False(t, *ac.Online)
+	assert.Nil(t, ac.CapacityPercent)
+
+	battery := result[6].(*HardwareSample)
+	assert.Equal(t, SensorPowerSupply, battery.SensorType)
+	assert.Equal(t, "Battery", *battery.PowerSupplyType)
+	assert.Equal(t, "Discharging", *battery.Status)
+	assert.Equal(t, 87.0, *battery.CapacityPercent)
+	assert.Equal(t, 12.0, *battery.Volts)
+	assert.Equal(t, 6.0, *battery.PowerWatts, "the power is calculated from the current")
+
+	cpu := result[7].(*HardwareSample)
+	assert.Equal(t, SensorCPUFrequency, cpu.SensorType)
+	assert.Equal(t, "cpu0", cpu.Chip)
+	assert.Equal(t, 2400.0, *cpu.FrequencyMHz)
+	assert.Equal(t, 800.0, *cpu.MinFrequencyMHz)
+	assert.Equal(t, 3600.0, *cpu.MaxFrequencyMHz)
+	assert.Nil(t, cpu.CoreThrottlesPerSecond, "rates aren't reported in the first sample")
+
+	now = now.Add(10 * time.Second)
+	throttle := filepath.Join(dir, "devices", "system", "cpu", "cpu0", "thermal_throttle")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(throttle, "core_throttle_count"), []byte("30\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(throttle, "package_throttle_count"), []byte("70\n"), 0644))
+	result, err = s.Sample()
+	require.NoError(t, err)
+	cpu = result[7].(*HardwareSample)
+	assert.Equal(t, 2.0, *cpu.CoreThrottlesPerSecond)
+	assert.Equal(t, 5.0, *cpu.PackageThrottlesPerSecond)
+}
+
+func TestHardwareSampler_NoSensors(t *testing.T) {
+	t.Setenv("HOST_SYS", t.TempDir())
+
+	result, err := NewHardwareSampler(nil).Sample()
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestHardwareSampler_Disabled(t *testing.T) {
+	assert.True(t, NewHardwareSampler(nil).Disabled())
+
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{MetricsHardwareSampleRate: 60})
+	s := NewHardwareSampler(ctx)
+	assert.False(t, s.Disabled())
+	assert.Equal(t, 60*time.Second, s.Interval())
+}
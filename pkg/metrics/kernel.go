@@ -0,0 +1,91 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"time"
+)
+
+// PressureSample holds the Pressure Stall Information (PSI) of the CPU, memory and IO: the percentage of time that
+// some (or all, for full) of the non-idle tasks were stalled on the resource, on average over the last 10, 60 and 300
+// seconds, and since the previous sample (total). The values not provided by the kernel are omitted.
+type PressureSample struct {
+	CPUSomeAvg10     *float64 `json:"cpuPressureSomeAvg10,omitempty"`
+	CPUSomeAvg60     *float64 `json:"cpuPressureSomeAvg60,omitempty"`
+	CPUSomeAvg300    *float64 `json:"cpuPressureSomeAvg300,omitempty"`
+	CPUSomeTotal     *float64 `json:"cpuPressureSomeTotalPercent,omitempty"`
+	CPUFullAvg10     *float64 `json:"cpuPressureFullAvg10,omitempty"`
+	CPUFullAvg60     *float64 `json:"cpuPressureFullAvg60,omitempty"`
+	CPUFullAvg300    *float64 `json:"cpuPressureFullAvg300,omitempty"`
+	CPUFullTotal     *float64 `json:"cpuPressureFullTotalPercent,omitempty"`
+	MemorySomeAvg10  *float64 `json:"memoryPressureSomeAvg10,omitempty"`
+	MemorySomeAvg60  *float64 `json:"memoryPressureSomeAvg60,omitempty"`
+	MemorySomeAvg300 *float64 `json:"memoryPressureSomeAvg300,omitempty"`
+	MemorySomeTotal  *float64 `json:"memoryPressureSomeTotalPercent,omitempty"`
+	MemoryFullAvg10  *float64 `json:"memoryPressureFullAvg10,omitempty"`
+	MemoryFullAvg60  *float64 `json:"memoryPressureFullAvg60,omitempty"`
+	MemoryFullAvg300 *float64 `json:"memoryPressureFullAvg300,omitempty"`
+	MemoryFullTotal  *float64 `json:"memoryPressureFullTotalPercent,omitempty"`
+	IOSomeAvg10      *float64 `json:"ioPressureSomeAvg10,omitempty"`
+	IOSomeAvg60      *float64 `json:"ioPressureSomeAvg60,omitempty"`
+	IOSomeAvg300     *float64 `json:"ioPressureSomeAvg300,omitempty"`
+	IOSomeTotal      *float64 `json:"ioPressureSomeTotalPercent,omitempty"`
+	IOFullAvg10      *float64 `json:"ioPressureFullAvg10,omitempty"`
+	IOFullAvg60      *float64 `json:"ioPressureFullAvg60,omitempty"`
+	IOFullAvg300     *float64 `json:"ioPressureFullAvg300,omitempty"`
+	IOFullTotal      *float64 `json:"ioPressureFullTotalPercent,omitempty"`
+}
+
+// KernelSample holds the scheduler and virtual memory activity of the host: the processes that are running or blocked
+// on IO right now, and the context switches, interrupts, forks, page faults and swapped pages per second since the
+// previous sample. The kernel only exposes the latter as counters since boot, so the KernelMonitor has no rates to
+// report until its second sample.
+type KernelSample struct {
+	ContextSwitchesPerSec  *float64 `json:"contextSwitchesPerSecond,omitempty"`
+	InterruptsPerSec       *float64 `json:"interruptsPerSecond,omitempty"`
+	ProcessesCreatedPerSec *float64 `json:"processesCreatedPerSecond,omitempty"`
+	ProcessesRunning       *uint64  `json:"processesRunning,omitempty"`
+	ProcessesBlocked       *uint64  `json:"processesBlocked,omitempty"`
+	PageFaultsPerSec       *float64 `json:"pageFaultsPerSecond,omitempty"`
+	MajorPageFaultsPerSec  *float64 `json:"majorPageFaultsPerSecond,omitempty"`
+	SwapInPagesPerSec      *float64 `json:"swapInPagesPerSecond,omitempty"`
+	SwapOutPagesPerSec     *float64 `json:"swapOutPagesPerSecond,omitempty"`
+	OOMKillsPerSec         *float64 `json:"oomKillsPerSecond,omitempty"`
+}
+
+// KernelMonitor samples the PressureSample and the KernelSample, keeping the counters of the previous sample to
+// calculate the rates.
+type KernelMonitor struct {
+	previous     map[string]uint64
+	previousTime time.Time
+	now          func() time.Time
+}
+
+func NewKernelMonitor() *KernelMonitor {
+	return &KernelMonitor{now: time.Now}
+}
+
+// rates stores the counters and returns their rates per second since the previous call. The counters that weren't
+// present in the previous call, or that were reset, don't have rate.
+func (m *KernelMonitor) rates(counters map[string]uint64) map[string]float64 {
+	now := m.now()
+	elapsed := now.Sub(m.previousTime).Seconds()
+
+	rates := make(map[string]float64, len(counters))
+	for name, value := range counters {
+		if previous, ok := m.previous[name]; ok && elapsed > 0 && value >= previous {
+			rates[name] = float64(value-previous) / elapsed
+		}
+	}
+	m.previous = counters
+	m.previousTime = now
+	return rates
+}
+
+// optional returns the pointer to the named value, or nil if it's missing.
+func optional(values map[string]float64, name string) *float64 {
+	if v, ok := values[name]; ok {
+		return &v
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// pressureResources are the resources with Pressure Stall Information, in /proc/pressure.
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// statCounters maps the /proc/stat counters to the names of their rates.
+var statCounters = map[string]string{
+	"ctxt":      "contextSwitches",
+	"intr":      "interrupts",
+	"processes": "processesCreated",
+}
+
+// vmstatCounters maps the /proc/vmstat counters to the names of their rates.
+var vmstatCounters = map[string]string{
+	"pgfault":    "pageFaults",
+	"pgmajfault": "majorPageFaults",
+	"pswpin":     "swapInPages",
+	"pswpout":    "swapOutPages",
+	"oom_kill":   "oomKills",
+}
+
+// Sample returns the Pressure Stall Information, or nil if the kernel doesn't provide it (it requires Linux 4.20 or
+// newer), and the scheduler and virtual memory activity, from /proc/stat and /proc/vmstat.
+func (m *KernelMonitor) Sample() (pressure *PressureSample, kernel *KernelSample, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("Panic in KernelMonitor.Sample: %v\nStack: %s", panicErr, debug.Stack())
+		}
+	}()
+
+	counters := make(map[string]uint64)
+	averages := readPressure(counters)
+
+	kernel = &KernelSample{}
+	lines, err := readProcLines("stat")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "procs_running":
+			kernel.ProcessesRunning = &value
+		case "procs_blocked":
+			kernel.ProcessesBlocked = &value
+		default:
+			if name, ok := statCounters[fields[0]]; ok {
+				counters[name] = value
+			}
+		}
+	}
+
+	// vmstat is optional, as some of the counters, like oom_kill, depend on the kernel version
+	lines, _ = readProcLines("vmstat")
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if name, ok := vmstatCounters[fields[0]]; ok {
+			if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				counters[name] = value
+			}
+		}
+	}
+
+	rates := m.rates(counters)
+	kernel.ContextSwitchesPerSec = optional(rates, "contextSwitches")
+	kernel.InterruptsPerSec = optional(rates, "interrupts")
+	kernel.ProcessesCreatedPerSec = optional(rates, "processesCreated")
+	kernel.PageFaultsPerSec = optional(rates, "pageFaults")
+	kernel.MajorPageFaultsPerSec = optional(rates, "majorPageFaults")
+	kernel.SwapInPagesPerSec = optional(rates, "swapInPages")
+	kernel.SwapOutPagesPerSec = optional(rates, "swapOutPages")
+	kernel.OOMKillsPerSec = optional(rates, "oomKills")
+
+	if len(averages) > 0 {
+		// the total stall time is reported in microseconds, so its rate per second is converted to a percentage
+		for name, r := range rates {
+			if strings.HasSuffix(name, ".total") {
+				averages[name] = r / 1e4
+			}
+		}
+		pressure = newPressureSample(averages)
+	}
+	return pressure, kernel, nil
+}
+
+// readPressure returns the averages of /proc/pressure by "<resource>.<some|full>.<avg10|avg60|avg300>" name, and
+// stores the stall time counters as "<resource>.<some|full>.total".
+func readPressure(counters map[string]uint64) map[string]float64 {
+	averages := make(map[string]float64)
+	for _, resource := range pressureResources {
+		lines, err := readProcLines("pressure", resource)
+		if err != nil {
+			continue
+		}
+		for _, line := range lines {
+			// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+			fields := strings.Fields(line)
+			if len(fields) == 0 || (fields[0] != "some" && fields[0] != "full") {
+				continue
+			}
+			for _, field := range fields[1:] {
+				kv := strings.SplitN(field, "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				name := resource + "." + fields[0] + "." + kv[0]
+				if kv[0] == "total" {
+					if value, err := strconv.ParseUint(kv[1], 10, 64); err == nil {
+						counters[name] = value
+					}
+				} else if value, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					averages[name] = value
+				}
+			}
+		}
+	}
+	return averages
+}
+
+func newPressureSample(values map[string]float64) *PressureSample {
+	p := &PressureSample{}
+	for name, field := range map[string]**float64{
+		"cpu.some.avg10":     &p.CPUSomeAvg10,
+		"cpu.some.avg60":     &p.CPUSomeAvg60,
+		"cpu.some.avg300":    &p.CPUSomeAvg300,
+		"cpu.some.total":     &p.CPUSomeTotal,
+		"cpu.full.avg10":     &p.CPUFullAvg10,
+		"cpu.full.avg60":     &p.CPUFullAvg60,
+		"cpu.full.avg300":    &p.CPUFullAvg300,
+		"cpu.full.total":     &p.CPUFullTotal,
+		"memory.some.avg10":  &p.MemorySomeAvg10,
+		"memory.some.avg60":  &p.MemorySomeAvg60,
+		"memory.some.avg300": &p.MemorySomeAvg300,
+		"memory.some.total":  &p.MemorySomeTotal,
+		"memory.full.avg10":  &p.MemoryFullAvg10,
+		"memory.full.avg60":  &p.MemoryFullAvg60,
+		"memory.full.avg300": &p.MemoryFullAvg300,
+		"memory.full.total":  &p.MemoryFullTotal,
+		"io.some.avg10":      &p.IOSomeAvg10,
+		"io.some.avg60":      &p.IOSomeAvg60,
+		"io.some.avg300":     &p.IOSomeAvg300,
+		"io.some.total":      &p.IOSomeTotal,
+		"io.full.avg10":      &p.IOFullAvg10,
+		"io.full.avg60":      &p.IOFullAvg60,
+		"io.full.avg300":     &p.IOFullAvg300,
+		"io.full.total":      &p.IOFullTotal,
+	} {
+		*field = optional(values, name)
+	}
+	return p
+}
+
+// readProcLines reads the lines of a file in the host /proc (see the override_host_proc option).
+func readProcLines(path ...string) ([]string, error) {
+	content, err := ioutil.ReadFile(helpers.HostProc(path...))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(content), "\n"), nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk/sdktest"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKernelMonitor_Sample(t *testing.T) {
+	now := time.Now()
+	m := NewKernelMonitor()
+	m.now = func() time.Time { return now }
+
+	sdktest.FakeProc(t, map[string]string{
+		"stat": `cpu  1 2 3 4 5 6 7 8 9 10
+intr 1000 1 2 3
+ctxt 5000
+btime 1600000000
+processes 100
+procs_running 3
+procs_blocked 1
+`,
+		"vmstat": `pgfault 2000
+pgmajfault 10
+pswpin 0
+pswpout 50
+`,
+		"pressure/cpu": "some avg10=1.50 avg60=1.00 avg300=0.50 total=500000\n",
+		"pressure/memory": `some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+full avg10=0.00 avg60=0.00 avg300=0.00 total=100000
+`,
+		"pressure/io": `some avg10=2.00 avg60=3.00 avg300=4.00 total=0
+full avg10=1.00 avg60=2.00 avg300=3.00 total=0
+`,
+	})
+	pressure, kernel, err := m.Sample()
+	require.NoError(t, err)
+	require.NotNil(t, pressure)
+	require.NotNil(t, kernel)
+	assert.Equal(t, 1.5, *pressure.CPUSomeAvg10)
+	assert.Equal(t, 4.0, *pressure.IOSomeAvg300)
+	assert.Nil(t, pressure.CPUFullAvg10, "cpu full isn't reported by the fake kernel")
+	assert.Nil(t, pressure.CPUSomeTotal, "rates aren't reported in the first sample")
+	assert.Equal(t, uint64(3), *kernel.ProcessesRunning)
+	assert.Equal(t, uint64(1), *kernel.ProcessesBlocked)
+	assert.Nil(t, kernel.ContextSwitchesPerSec)
+
+	now = now.Add(10 * time.Second)
+	sdktest.FakeProc(t, map[string]string{
+		"stat": `cpu  1 2 3 4 5 6 7 8 9 10
+intr 2000 1 2 3
+ctxt 10000
+btime 1600000000
+processes 200
+procs_running 2
+procs_blocked 0
+`,
+		"vmstat": `pgfault 4000
+pgmajfault 20
+pswpin 0
+pswpout 100
+`,
+		"pressure/cpu": "some avg10=1.20 avg60=1.10 avg300=0.60 total=1000000\n",
+		"pressure/memory": `some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+full avg10=0.10 avg60=0.00 avg300=0.00 total=200000
+`,
+		"pressure/io": `some avg10=2.00 avg60=3.00 avg300=4.00 total=0
+full avg10=1.00 avg60=2.00 avg300=3.00 total=0
+`,
+	})
+	pressure, kernel, err = m.Sample()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), *kernel.ProcessesRunning)
+	assert.Equal(t, uint64(0), *kernel.ProcessesBlocked)
+	assert.Equal(t, 500.0, *kernel.ContextSwitchesPerSec)
+	assert.Equal(t, 100.0, *kernel.InterruptsPerSec)
+	assert.Equal(t, 10.0, *kernel.ProcessesCreatedPerSec)
+	assert.Equal(t, 200.0, *kernel.PageFaultsPerSec)
+	assert.Equal(t, 1.0, *kernel.MajorPageFaultsPerSec)
+	assert.Equal(t, 0.0, *kernel.SwapInPagesPerSec)
+	assert.Equal(t, 5.0, *kernel.SwapOutPagesPerSec)
+	assert.Nil(t, kernel.OOMKillsPerSec, "oom_kill isn't reported by the fake kernel")
+	// 500ms of stall in 10 seconds
+	assert.Equal(t, 5.0, *pressure.CPUSomeTotal)
+	assert.Equal(t, 1.0, *pressure.MemoryFullTotal)
+	assert.Equal(t, 0.0, *pressure.IOSomeTotal)
+}
+
+func TestKernelMonitor_NoPressure(t *testing.T) {
+	sdktest.FakeProc(t, map[string]string{
+		"stat": `cpu  1 2 3 4 5 6 7 8 9 10
+intr 1000 1 2 3
+ctxt 5000
+btime 1600000000
+processes 100
+procs_running 3
+procs_blocked 1
+`,
+		"vmstat": "pgfault 2000\npgmajfault 10\npswpin 0\npswpout 50\n",
+	})
+	pressure, kernel, err := NewKernelMonitor().Sample()
+	require.NoError(t, err)
+	assert.Nil(t, pressure)
+	assert.NotNil(t, kernel)
+}
+
+func TestSystemSample_KernelMetrics(t *testing.T) {
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{EnableKernelMetrics: true})
+
+	m := NewSystemSampler(ctx, storage.NewSampler(ctx))
+	require.NotNil(t, m.KernelMonitor)
+
+	result, err := m.Sample()
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.NotNil(t, result[0].(*SystemSample).KernelSample)
+}
@@ -0,0 +1,11 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+// +build !linux
+
+package metrics
+
+// Sample returns nil samples, as the Pressure Stall Information and the kernel counters are only available on Linux.
+func (m *KernelMonitor) Sample() (*PressureSample, *KernelSample, error) {
+	return nil, nil, nil
+}
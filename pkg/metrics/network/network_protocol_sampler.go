@@ -0,0 +1,276 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+var nplog = log.WithComponent("NetworkProtocolSampler")
+
+// protocolCounters are the counters of /proc/net/snmp and /proc/net/netstat that are reported as rates per second.
+var protocolCounters = []string{
+	"Tcp.ActiveOpens",
+	"Tcp.PassiveOpens",
+	"Tcp.AttemptFails",
+	"Tcp.EstabResets",
+	"Tcp.OutRsts",
+	"Tcp.OutSegs",
+	"Tcp.RetransSegs",
+	"Tcp.InErrs",
+	"TcpExt.ListenOverflows",
+	"TcpExt.ListenDrops",
+	"Udp.InDatagrams",
+	"Udp.OutDatagrams",
+	"Udp.NoPorts",
+	"Udp.InErrors",
+	"Udp.RcvbufErrors",
+	"Udp.SndbufErrors",
+}
+
+// NetworkProtocolSample reports the TCP and UDP activity of the host, the state of its sockets and the usage of the
+// connection tracking table. The segments, datagrams and errors are per second since the previous sample, while the
+// socket and conntrack values are read as they are. The values the kernel doesn't provide, as the conntrack ones
+// without the nf_conntrack module, are omitted.
+type NetworkProtocolSample struct {
+	sample.BaseEvent
+
+	TCPActiveOpensPerSec       *float64 `json:"tcpActiveOpensPerSecond,omitempty"`
+	TCPPassiveOpensPerSec      *float64 `json:"tcpPassiveOpensPerSecond,omitempty"`
+	TCPAttemptFailsPerSec      *float64 `json:"tcpAttemptFailsPerSecond,omitempty"`
+	TCPEstablishedResetsPerSec *float64 `json:"tcpEstablishedResetsPerSecond,omitempty"`
+	TCPResetsSentPerSec        *float64 `json:"tcpResetsSentPerSecond,omitempty"`
+	TCPSegmentsSentPerSec      *float64 `json:"tcpSegmentsSentPerSecond,omitempty"`
+	TCPRetransmitsPerSec       *float64 `json:"tcpRetransmitsPerSecond,omitempty"`
+	TCPRetransmitPercent       *float64 `json:"tcpRetransmitPercent,omitempty"`
+	TCPReceiveErrorsPerSec     *float64 `json:"tcpReceiveErrorsPerSecond,omitempty"`
+	TCPListenOverflowsPerSec   *float64 `json:"tcpListenOverflowsPerSecond,omitempty"`
+	TCPListenDropsPerSec       *float64 `json:"tcpListenDropsPerSecond,omitempty"`
+
+	TCPEstablished *uint64 `json:"tcpEstablished,omitempty"`
+	TCPTimeWait    *uint64 `json:"tcpTimeWait,omitempty"`
+	TCPInUse       *uint64 `json:"tcpInUse,omitempty"`
+	TCPOrphan      *uint64 `json:"tcpOrphan,omitempty"`
+	TCP6InUse      *uint64 `json:"tcp6InUse,omitempty"`
+
+	UDPReceivedPerSec            *float64 `json:"udpReceivedPerSecond,omitempty"`
+	UDPSentPerSec                *float64 `json:"udpSentPerSecond,omitempty"`
+	UDPNoPortsPerSec             *float64 `json:"udpNoPortsPerSecond,omitempty"`
+	UDPReceiveErrorsPerSec       *float64 `json:"udpReceiveErrorsPerSecond,omitempty"`
+	UDPReceiveBufferErrorsPerSec *float64 `json:"udpReceiveBufferErrorsPerSecond,omitempty"`
+	UDPSendBufferErrorsPerSec    *float64 `json:"udpSendBufferErrorsPerSecond,omitempty"`
+
+	UDPInUse  *uint64 `json:"udpInUse,omitempty"`
+	UDP6InUse *uint64 `json:"udp6InUse,omitempty"`
+
+	ConntrackCount       *uint64  `json:"conntrackCount,omitempty"`
+	ConntrackMax         *uint64  `json:"conntrackMax,omitempty"`
+	ConntrackUsedPercent *float64 `json:"conntrackUsedPercent,omitempty"`
+}
+
+// NetworkProtocolSampler reports a NetworkProtocolSample from /proc/net/snmp, /proc/net/netstat, /proc/net/sockstat,
+// /proc/net/sockstat6 and the nf_conntrack sysctls (see the override_host_proc option).
+type NetworkProtocolSampler struct {
+	sampleInterval time.Duration
+	lastRun        time.Time
+	lastCounters   map[string]uint64
+	now            func() time.Time
+}
+
+func NewNetworkProtocolSampler(context agent.AgentContext) *NetworkProtocolSampler {
+	samplerIntervalSec := config.FREQ_DISABLE_SAMPLING
+	if context != nil {
+		samplerIntervalSec = context.Config().MetricsNetworkProtocolSampleRate
+	}
+	return &NetworkProtocolSampler{
+		sampleInterval: time.Second * time.Duration(samplerIntervalSec),
+		now:            time.Now,
+	}
+}
+
+func (s *NetworkProtocolSampler) Name() string { return "NetworkProtocolSampler" }
+
+func (s *NetworkProtocolSampler) Interval() time.Duration {
+	return s.sampleInterval
+}
+
+func (s *NetworkProtocolSampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
+
+func (s *NetworkProtocolSampler) OnStartup() {}
+
+func (s *NetworkProtocolSampler) Sample() (results sample.EventBatch, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("Panic in NetworkProtocolSampler.Sample: %v\nStack: %s", panicErr, debug.Stack())
+		}
+	}()
+
+	snmp, err := readSNMP("snmp")
+	if err != nil {
+		return nil, err
+	}
+	// TcpExt counters are optional, as /proc/net/netstat may be missing in some containers
+	if netstat, err := readSNMP("netstat"); err == nil {
+		for name, value := range netstat {
+			snmp[name] = value
+		}
+	} else {
+		nplog.WithError(err).Debug("Cannot read TCP extended statistics.")
+	}
+
+	now := s.now()
+	elapsedSeconds := now.Sub(s.lastRun).Seconds()
+
+	ps := &NetworkProtocolSample{}
+	ps.Type("NetworkProtocolSample")
+
+	counters := make(map[string]uint64, len(protocolCounters))
+	for _, name := range protocolCounters {
+		if value, ok := snmp[name]; ok && value >= 0 {
+			counters[name] = uint64(value)
+		}
+	}
+	if s.lastCounters != nil && elapsedSeconds > 0 {
+		rates := counterRates(counters, s.lastCounters, elapsedSeconds)
+		ps.TCPActiveOpensPerSec = rates["Tcp.ActiveOpens"]
+		ps.TCPPassiveOpensPerSec = rates["Tcp.PassiveOpens"]
+		ps.TCPAttemptFailsPerSec = rates["Tcp.AttemptFails"]
+		ps.TCPEstablishedResetsPerSec = rates["Tcp.EstabResets"]
+		ps.TCPResetsSentPerSec = rates["Tcp.OutRsts"]
+		ps.TCPSegmentsSentPerSec = rates["Tcp.OutSegs"]
+		ps.TCPRetransmitsPerSec = rates["Tcp.RetransSegs"]
+		ps.TCPReceiveErrorsPerSec = rates["Tcp.InErrs"]
+		ps.TCPListenOverflowsPerSec = rates["TcpExt.ListenOverflows"]
+		ps.TCPListenDropsPerSec = rates["TcpExt.ListenDrops"]
+		ps.UDPReceivedPerSec = rates["Udp.InDatagrams"]
+		ps.UDPSentPerSec = rates["Udp.OutDatagrams"]
+		ps.UDPNoPortsPerSec = rates["Udp.NoPorts"]
+		ps.UDPReceiveErrorsPerSec = rates["Udp.InErrors"]
+		ps.UDPReceiveBufferErrorsPerSec = rates["Udp.RcvbufErrors"]
+		ps.UDPSendBufferErrorsPerSec = rates["Udp.SndbufErrors"]
+
+		if ps.TCPRetransmitsPerSec != nil && ps.TCPSegmentsSentPerSec != nil && *ps.TCPSegmentsSentPerSec > 0 {
+			percent := *ps.TCPRetransmitsPerSec / *ps.TCPSegmentsSentPerSec * 100
+			ps.TCPRetransmitPercent = &percent
+		}
+	}
+	s.lastCounters = counters
+	s.lastRun = now
+
+	if value, ok := snmp["Tcp.CurrEstab"]; ok && value >= 0 {
+		established := uint64(value)
+		ps.TCPEstablished = &established
+	}
+
+	sockstat := readSockstat("sockstat")
+	ps.TCPInUse = sockstat["TCP.inuse"]
+	ps.TCPOrphan = sockstat["TCP.orphan"]
+	ps.TCPTimeWait = sockstat["TCP.tw"]
+	ps.UDPInUse = sockstat["UDP.inuse"]
+	sockstat6 := readSockstat("sockstat6")
+	ps.TCP6InUse = sockstat6["TCP6.inuse"]
+	ps.UDP6InUse = sockstat6["UDP6.inuse"]
+
+	// connection tracking is only reported when the nf_conntrack module is loaded
+	ps.ConntrackCount = readConntrack("nf_conntrack_count")
+	ps.ConntrackMax = readConntrack("nf_conntrack_max")
+	if ps.ConntrackCount != nil && ps.ConntrackMax != nil && *ps.ConntrackMax > 0 {
+		percent := float64(*ps.ConntrackCount) / float64(*ps.ConntrackMax) * 100
+		ps.ConntrackUsedPercent = &percent
+	}
+
+	helpers.LogStructureDetails(nplog, ps, "NetworkProtocolSample", "final", nil)
+	return sample.EventBatch{ps}, nil
+}
+
+// readSNMP reads a file of /proc/net with the format of snmp and netstat, where each protocol has a line with the
+// names of its counters followed by a line with their values:
+// Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens ...
+// Tcp: 1 200 120000 -1 5174 ...
+// The counters are returned by "<protocol>.<name>". Some of them, like Tcp.MaxConn, may be negative.
+func readSNMP(file string) (map[string]int64, error) {
+	content, err := ioutil.ReadFile(helpers.HostProc("net", file))
+	if err != nil {
+		return nil, err
+	}
+	counters := make(map[string]int64)
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		names := strings.Fields(lines[i])
+		values := strings.Fields(lines[i+1])
+		if len(names) == 0 || len(names) != len(values) || names[0] != values[0] {
+			return nil, fmt.Errorf("unexpected format of %s at line %d", file, i+1)
+		}
+		protocol := strings.TrimSuffix(names[0], ":")
+		for j := 1; j < len(names); j++ {
+			if value, err := strconv.ParseInt(values[j], 10, 64); err == nil {
+				counters[protocol+"."+names[j]] = value
+			}
+		}
+	}
+	return counters, nil
+}
+
+// readSockstat reads the socket usage of /proc/net/sockstat or sockstat6, whose lines look like:
+// TCP: inuse 5 orphan 0 tw 2 alloc 7 mem 1
+// The values are returned by "<protocol>.<name>", and the file is ignored if it's missing.
+func readSockstat(file string) map[string]*uint64 {
+	values := make(map[string]*uint64)
+	content, err := ioutil.ReadFile(helpers.HostProc("net", file))
+	if err != nil {
+		nplog.WithError(err).WithField("file", file).Debug("Cannot read socket statistics.")
+		return values
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		protocol := strings.TrimSuffix(fields[0], ":")
+		for j := 1; j+1 < len(fields); j += 2 {
+			if value, err := strconv.ParseUint(fields[j+1], 10, 64); err == nil {
+				values[protocol+"."+fields[j]] = &value
+			}
+		}
+	}
+	return values
+}
+
+// readConntrack reads a connection tracking sysctl, or returns nil if it's missing.
+func readConntrack(name string) *uint64 {
+	content, err := ioutil.ReadFile(helpers.HostProc("sys", "net", "netfilter", name))
+	if err != nil {
+		return nil
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// counterRates returns the rates per second of the counters present in both samplings. The counters that were reset
+// don't have rate.
+func counterRates(current, previous map[string]uint64, elapsedSeconds float64) map[string]*float64 {
+	rates := make(map[string]*float64, len(current))
+	for name, value := range current {
+		if last, ok := previous[name]; ok && value >= last {
+			r := float64(value-last) / elapsedSeconds
+			rates[name] = &r
+		}
+	}
+	return rates
+}
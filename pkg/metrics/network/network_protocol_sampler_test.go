@@ -0,0 +1,139 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk/sdktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkProtocolSampler_Sample(t *testing.T) {
+	now := time.Now()
+	s := NewNetworkProtocolSampler(nil)
+	s.now = func() time.Time { return now }
+
+	sdktest.FakeProc(t, map[string]string{
+		"net/snmp": `Ip: Forwarding DefaultTTL
+Ip: 1 64
+Tcp: RtoAlgorithm MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts
+Tcp: 1 -1 100 50 10 5 12 0 10000 100 1 20
+Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors
+Udp: 1000 3 2 900 2 0
+`,
+		"net/netstat": `TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 4 6
+IpExt: InNoRoutes
+IpExt: 0
+`,
+		"net/sockstat": `sockets: used 20
+TCP: inuse 6 orphan 1 tw 3 alloc 7 mem 1
+UDP: inuse 2 mem 0
+`,
+		"net/sockstat6":                        "TCP6: inuse 4\nUDP6: inuse 1\n",
+		"sys/net/netfilter/nf_conntrack_count": "256\n",
+		"sys/net/netfilter/nf_conntrack_max":   "1024\n",
+	})
+	result, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	ps := result[0].(*NetworkProtocolSample)
+	assert.Equal(t, uint64(12), *ps.TCPEstablished)
+	assert.Equal(t, uint64(3), *ps.TCPTimeWait)
+	assert.Equal(t, uint64(6), *ps.TCPInUse)
+	assert.Equal(t, uint64(1), *ps.TCPOrphan)
+	assert.Equal(t, uint64(4), *ps.TCP6InUse)
+	assert.Equal(t, uint64(2), *ps.UDPInUse)
+	assert.Equal(t, uint64(1), *ps.UDP6InUse)
+	assert.Equal(t, uint64(1024), *ps.ConntrackMax)
+	assert.Equal(t, 25.0, *ps.ConntrackUsedPercent)
+	assert.Nil(t, ps.TCPActiveOpensPerSec, "rates aren't reported in the first sample")
+	assert.Nil(t, ps.TCPRetransmitPercent)
+
+	now = now.Add(10 * time.Second)
+	sdktest.FakeProc(t, map[string]string{
+		"net/snmp": `Ip: Forwarding DefaultTTL
+Ip: 1 64
+Tcp: RtoAlgorithm MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts
+Tcp: 1 -1 200 100 20 10 15 0 20000 200 2 40
+Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors
+Udp: 2000 6 4 1800 4 0
+`,
+		"net/netstat": `TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 8 12
+IpExt: InNoRoutes
+IpExt: 0
+`,
+		"net/sockstat": `sockets: used 24
+TCP: inuse 8 orphan 0 tw 5 alloc 9 mem 1
+UDP: inuse 2 mem 0
+`,
+		"net/sockstat6":                        "TCP6: inuse 4\nUDP6: inuse 1\n",
+		"sys/net/netfilter/nf_conntrack_count": "512\n",
+		"sys/net/netfilter/nf_conntrack_max":   "1024\n",
+	})
+	result, err = s.Sample()
+	require.NoError(t, err)
+	ps = result[0].(*NetworkProtocolSample)
+	assert.Equal(t, uint64(15), *ps.TCPEstablished)
+	assert.Equal(t, uint64(5), *ps.TCPTimeWait)
+	assert.Equal(t, 50.0, *ps.ConntrackUsedPercent)
+	assert.Equal(t, 10.0, *ps.TCPActiveOpensPerSec)
+	assert.Equal(t, 5.0, *ps.TCPPassiveOpensPerSec)
+	assert.Equal(t, 1.0, *ps.TCPAttemptFailsPerSec)
+	assert.Equal(t, 0.5, *ps.TCPEstablishedResetsPerSec)
+	assert.Equal(t, 2.0, *ps.TCPResetsSentPerSec)
+	assert.Equal(t, 10.0, *ps.TCPRetransmitsPerSec)
+	assert.Equal(t, 1.0, *ps.TCPRetransmitPercent)
+	assert.Equal(t, 0.1, *ps.TCPReceiveErrorsPerSec)
+	assert.Equal(t, 0.4, *ps.TCPListenOverflowsPerSec)
+	assert.Equal(t, 0.6, *ps.TCPListenDropsPerSec)
+	assert.Equal(t, 100.0, *ps.UDPReceivedPerSec)
+	assert.Equal(t, 90.0, *ps.UDPSentPerSec)
+	assert.Equal(t, 0.3, *ps.UDPNoPortsPerSec)
+	assert.Equal(t, 0.2, *ps.UDPReceiveErrorsPerSec)
+	assert.Equal(t, 0.2, *ps.UDPReceiveBufferErrorsPerSec)
+	assert.Equal(t, 0.0, *ps.UDPSendBufferErrorsPerSec)
+}
+
+func TestNetworkProtocolSampler_NoConntrack(t *testing.T) {
+	sdktest.FakeProc(t, map[string]string{
+		"net/snmp": `Tcp: RtoAlgorithm MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts
+Tcp: 1 -1 100 50 10 5 12 0 10000 100 1 20
+Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors
+Udp: 1000 3 2 900 2 0
+`,
+		"net/sockstat": "sockets: used 20\nTCP: inuse 6 orphan 1 tw 3 alloc 7 mem 1\nUDP: inuse 2 mem 0\n",
+	})
+
+	result, err := NewNetworkProtocolSampler(nil).Sample()
+	require.NoError(t, err)
+	ps := result[0].(*NetworkProtocolSample)
+	assert.Nil(t, ps.ConntrackCount)
+	assert.Nil(t, ps.ConntrackUsedPercent)
+	assert.NotNil(t, ps.TCPEstablished)
+}
+
+func TestNetworkProtocolSampler_MissingSNMP(t *testing.T) {
+	t.Setenv("HOST_PROC", t.TempDir())
+
+	_, err := NewNetworkProtocolSampler(nil).Sample()
+	assert.Error(t, err)
+}
+
+func TestNetworkProtocolSampler_Interval(t *testing.T) {
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{MetricsNetworkProtocolSampleRate: -1})
+	assert.True(t, NewNetworkProtocolSampler(ctx).Disabled())
+
+	ctx = new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{MetricsNetworkProtocolSampleRate: 30})
+	s := NewNetworkProtocolSampler(ctx)
+	assert.False(t, s.Disabled())
+	assert.Equal(t, 30*time.Second, s.Interval())
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package numa
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/acquire"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+var nnlog = log.WithComponent("NumaNodeSampler")
+
+// NumaNodeSample reports the memory usage of a NUMA node, and the rates of its allocation counters since the previous
+// sample, which are omitted in the first one.
+type NumaNodeSample struct {
+	sample.BaseEvent
+
+	Node    string `json:"numaNode"`
+	CPUList string `json:"cpuList"`
+
+	MemoryTotal       float64 `json:"memoryTotalBytes"`
+	MemoryFree        float64 `json:"memoryFreeBytes"`
+	MemoryUsed        float64 `json:"memoryUsedBytes"`
+	MemoryUsedPercent float64 `json:"memoryUsedPercent"`
+
+	NumaHitPerSec       *float64 `json:"numaHitPerSecond,omitempty"`
+	NumaMissPerSec      *float64 `json:"numaMissPerSecond,omitempty"`
+	NumaForeignPerSec   *float64 `json:"numaForeignPerSecond,omitempty"`
+	InterleaveHitPerSec *float64 `json:"interleaveHitPerSecond,omitempty"`
+	LocalNodePerSec     *float64 `json:"localNodePerSecond,omitempty"`
+	OtherNodePerSec     *float64 `json:"otherNodePerSecond,omitempty"`
+}
+
+// NumaNodeSampler reports a NumaNodeSample for each node in /sys/devices/system/node (see the override_host_sys
+// option).
+type NumaNodeSampler struct {
+	sampleInterval time.Duration
+	lastRun        time.Time
+	lastStats      map[string]map[string]uint64
+}
+
+func NewNumaNodeSampler(context agent.AgentContext) *NumaNodeSampler {
+	samplerIntervalSec := config.FREQ_DISABLE_SAMPLING
+	if context != nil {
+		samplerIntervalSec = context.Config().MetricsNumaNodeSampleRate
+	}
+	return &NumaNodeSampler{
+		sampleInterval: time.Second * time.Duration(samplerIntervalSec),
+	}
+}
+
+func (s *NumaNodeSampler) Name() string { return "NumaNodeSampler" }
+
+func (s *NumaNodeSampler) Interval() time.Duration {
+	return s.sampleInterval
+}
+
+func (s *NumaNodeSampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
+
+func (s *NumaNodeSampler) OnStartup() {}
+
+func (s *NumaNodeSampler) Sample() (results sample.EventBatch, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("Panic in NumaNodeSampler.Sample: %v\nStack: %s", panicErr, debug.Stack())
+		}
+	}()
+
+	nodeDirs, err := filepath.Glob(helpers.HostSys("devices", "system", "node", "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(nodeDirs)
+
+	now := time.Now()
+	elapsedSeconds := now.Sub(s.lastRun).Seconds()
+	s.lastRun = now
+
+	stats := make(map[string]map[string]uint64, len(nodeDirs))
+	for _, dir := range nodeDirs {
+		node := filepath.Base(dir)
+		ns := &NumaNodeSample{Node: node}
+		ns.Type("NumaNodeSample")
+
+		if cpuList, err := ioutil.ReadFile(filepath.Join(dir, "cpulist")); err == nil {
+			ns.CPUList = strings.TrimSpace(string(cpuList))
+		}
+		if err := readNodeMemory(dir, ns); err != nil {
+			nnlog.WithError(err).WithField("node", node).Debug("Cannot read NUMA node memory.")
+			continue
+		}
+
+		stats[node] = readNodeStats(dir)
+		if last, ok := s.lastStats[node]; ok {
+			ns.NumaHitPerSec = rate(stats[node], last, "numa_hit", elapsedSeconds)
+			ns.NumaMissPerSec = rate(stats[node], last, "numa_miss", elapsedSeconds)
+			ns.NumaForeignPerSec = rate(stats[node], last, "numa_foreign", elapsedSeconds)
+			ns.InterleaveHitPerSec = rate(stats[node], last, "interleave_hit", elapsedSeconds)
+			ns.LocalNodePerSec = rate(stats[node], last, "local_node", elapsedSeconds)
+			ns.OtherNodePerSec = rate(stats[node], last, "other_node", elapsedSeconds)
+		}
+
+		helpers.LogStructureDetails(nnlog, ns, "NumaNodeSample", "final", nil)
+		results = append(results, ns)
+	}
+	s.lastStats = stats
+
+	return results, nil
+}
+
+// readNodeMemory reads the memory of the node from its meminfo file, whose lines look like:
+// Node 0 MemTotal:        6147400 kB
+func readNodeMemory(dir string, ns *NumaNodeSample) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "meminfo"))
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[2] {
+		case "MemTotal:":
+			ns.MemoryTotal = float64(value * 1024)
+		case "MemFree:":
+			ns.MemoryFree = float64(value * 1024)
+		case "MemUsed:":
+			ns.MemoryUsed = float64(value * 1024)
+		}
+	}
+	if ns.MemoryTotal > 0 {
+		ns.MemoryUsedPercent = ns.MemoryUsed / ns.MemoryTotal * 100
+	}
+	return nil
+}
+
+// readNodeStats reads the allocation counters of the node from its numastat file.
+func readNodeStats(dir string) map[string]uint64 {
+	stats := make(map[string]uint64)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "numastat"))
+	if err != nil {
+		return stats
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			stats[fields[0]] = value
+		}
+	}
+	return stats
+}
+
+// rate returns the rate per second of the counter, or nil if it's missing in any of the samplings.
+func rate(current, previous map[string]uint64, counter string, elapsedSeconds float64) *float64 {
+	c, ok := current[counter]
+	if !ok {
+		return nil
+	}
+	p, ok := previous[counter]
+	if !ok {
+		return nil
+	}
+	r := acquire.CalculateSafeDelta(c, p, elapsedSeconds)
+	return &r
+}
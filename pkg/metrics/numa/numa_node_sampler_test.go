@@ -0,0 +1,102 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package numa
+
+import (
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk/sdktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumaNodeSampler_Sample(t *testing.T) {
+	t.Setenv("HOST_SYS", sdktest.WriteFiles(t, map[string]string{
+		"devices/system/node/node0/cpulist": "0-3\n",
+		"devices/system/node/node0/meminfo": `Node 0 MemTotal:        4000 kB
+Node 0 MemFree:         2000 kB
+Node 0 MemUsed:         2000 kB
+`,
+		"devices/system/node/node0/numastat": `numa_hit 1000
+numa_miss 10
+numa_foreign 0
+interleave_hit 10
+local_node 1000
+other_node 0
+`,
+		"devices/system/node/node1/cpulist": "4-7\n",
+		"devices/system/node/node1/meminfo": `Node 1 MemTotal:        4000 kB
+Node 1 MemFree:         1000 kB
+Node 1 MemUsed:         3000 kB
+`,
+		"devices/system/node/node1/numastat": `numa_hit 500
+numa_miss 0
+numa_foreign 10
+interleave_hit 10
+local_node 500
+other_node 0
+`,
+	}))
+	s := &NumaNodeSampler{}
+
+	results, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	node := results[1].(*NumaNodeSample)
+	assert.Equal(t, "NumaNodeSample", node.EventType)
+	assert.Equal(t, "node1", node.Node)
+	assert.Equal(t, "4-7", node.CPUList)
+	assert.Equal(t, float64(4000*1024), node.MemoryTotal)
+	assert.Equal(t, float64(1000*1024), node.MemoryFree)
+	assert.Equal(t, float64(3000*1024), node.MemoryUsed)
+	assert.Equal(t, 75.0, node.MemoryUsedPercent)
+	assert.Nil(t, node.NumaHitPerSec, "rates aren't reported in the first sample")
+
+	t.Setenv("HOST_SYS", sdktest.WriteFiles(t, map[string]string{
+		"devices/system/node/node0/cpulist": "0-3\n",
+		"devices/system/node/node0/meminfo": `Node 0 MemTotal:        4000 kB
+Node 0 MemFree:         1500 kB
+Node 0 MemUsed:         2500 kB
+`,
+		"devices/system/node/node0/numastat": `numa_hit 2000
+numa_miss 20
+numa_foreign 0
+interleave_hit 10
+local_node 2000
+other_node 0
+`,
+		"devices/system/node/node1/cpulist": "4-7\n",
+		"devices/system/node/node1/meminfo": `Node 1 MemTotal:        4000 kB
+Node 1 MemFree:         1000 kB
+Node 1 MemUsed:         3000 kB
+`,
+		"devices/system/node/node1/numastat": `numa_hit 500
+numa_miss 0
+numa_foreign 20
+interleave_hit 10
+local_node 500
+other_node 0
+`,
+	}))
+	results, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	node = results[0].(*NumaNodeSample)
+	assert.Equal(t, "node0", node.Node)
+	assert.Equal(t, 62.5, node.MemoryUsedPercent)
+	require.NotNil(t, node.NumaHitPerSec)
+	assert.True(t, *node.NumaHitPerSec > 0)
+	assert.True(t, *node.NumaMissPerSec > 0)
+	assert.Equal(t, 0.0, *node.InterleaveHitPerSec)
+	node = results[1].(*NumaNodeSample)
+	assert.Equal(t, 0.0, *node.NumaHitPerSec)
+	assert.True(t, *node.NumaForeignPerSec > 0)
+}
+
+func TestNumaNodeSampler_NoNodes(t *testing.T) {
+	t.Setenv("HOST_SYS", t.TempDir())
+
+	results, err := (&NumaNodeSampler{}).Sample()
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package process
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// ProcessGroupSample reports the usage of a group of processes that share the values of the process_aggregation_keys.
+// The usage is the sum of the usage of the processes of the group, and the max values are the highest ones among them.
+type ProcessGroupSample struct {
+	sample.BaseEvent
+
+	CommandName      string `json:"commandName,omitempty"`
+	User             string `json:"userName,omitempty"`
+	ContainerID      string `json:"containerId,omitempty"`
+	SystemdUnit      string `json:"systemdUnit,omitempty"`
+	CommandLineGroup string `json:"commandLineGroup,omitempty"`
+
+	ProcessCount          int      `json:"processCount"`
+	ThreadCount           int32    `json:"threadCount"`
+	CPUPercent            float64  `json:"cpuPercent"`
+	CPUUserPercent        float64  `json:"cpuUserPercent"`
+	CPUSystemPercent      float64  `json:"cpuSystemPercent"`
+	MemoryRSSBytes        int64    `json:"memoryResidentSizeBytes"`
+	MemoryVMSBytes        int64    `json:"memoryVirtualSizeBytes"`
+	FdCount               *int32   `json:"fileDescriptorCount,omitempty"`
+	IOReadCountPerSecond  *float64 `json:"ioReadCountPerSecond,omitempty"`
+	IOWriteCountPerSecond *float64 `json:"ioWriteCountPerSecond,omitempty"`
+	IOReadBytesPerSecond  *float64 `json:"ioReadBytesPerSecond,omitempty"`
+	IOWriteBytesPerSecond *float64 `json:"ioWriteBytesPerSecond,omitempty"`
+
+	MaxCPUPercent     float64 `json:"maxCpuPercent"`
+	MaxMemoryRSSBytes int64   `json:"maxMemoryResidentSizeBytes"`
+	MaxThreadCount    int32   `json:"maxThreadCount"`
+	MaxFdCount        *int32  `json:"maxFileDescriptorCount,omitempty"`
+}
+
+// aggregator groups the process samples when the enable_process_aggregation option is set.
+type aggregator struct {
+	keys             []string
+	commandLineRegex *regexp.Regexp
+	topN             int
+}
+
+// newAggregator returns the aggregator of the process samples, or nil if the aggregation is disabled.
+func newAggregator(cfg *config.Config) *aggregator {
+	if cfg == nil || !cfg.EnableProcessAggregation {
+		return nil
+	}
+	a := &aggregator{keys: cfg.ProcessAggregationKeys, topN: cfg.ProcessAggregationTopN}
+	if cfg.ProcessAggregationCommandLineRegex != "" {
+		var err error
+		if a.commandLineRegex, err = regexp.Compile(cfg.ProcessAggregationCommandLineRegex); err != nil {
+			mplog.WithError(err).Warn("Invalid process_aggregation_command_line_regex, processes won't be grouped by command line.")
+		}
+	}
+	return a
+}
+
+// aggregate returns a ProcessGroupSample for each group of processes, followed by the top processes by CPU and by
+// memory, which are normalized as the not aggregated samples.
+func (a *aggregator) aggregate(samples []*types.ProcessSample, normalize func(*types.ProcessSample) sample.Event) sample.EventBatch {
+	groups := make(map[string]*ProcessGroupSample)
+	var order []string
+	for _, s := range samples {
+		group := a.group(s)
+		id := strings.Join([]string{group.CommandName, group.User, group.ContainerID, group.SystemdUnit, group.CommandLineGroup}, "\x00")
+		if existing, ok := groups[id]; ok {
+			group = existing
+		} else {
+			groups[id] = group
+			order = append(order, id)
+		}
+		group.add(s)
+	}
+
+	results := make(sample.EventBatch, 0, len(groups)+2*a.topN)
+	for _, id := range order {
+		results = append(results, groups[id])
+	}
+	for _, s := range a.top(samples) {
+		results = append(results, normalize(s))
+	}
+	return results
+}
+
+// group returns an empty group sample with the values of the aggregation keys of the process.
+func (a *aggregator) group(s *types.ProcessSample) *ProcessGroupSample {
+	group := &ProcessGroupSample{}
+	group.Type("ProcessGroupSample")
+	for _, key := range a.keys {
+		switch key {
+		case config.ProcessAggregationKeyCommandName:
+			group.CommandName = s.CommandName
+		case config.ProcessAggregationKeyUserName:
+			group.User = s.User
+		case config.ProcessAggregationKeyContainerID:
+			group.ContainerID = s.ContainerID
+		case config.ProcessAggregationKeySystemdUnit:
+			group.SystemdUnit = s.SystemdUnit
+		case config.ProcessAggregationKeyCommandLine:
+			group.CommandLineGroup = a.commandLineGroup(s.CmdLine)
+		}
+	}
+	return group
+}
+
+// commandLineGroup returns the first capture group of the regular expression in the command line, or the whole match
+// if it has no groups.
+func (a *aggregator) commandLineGroup(cmdLine string) string {
+	if a.commandLineRegex == nil {
+		return ""
+	}
+	match := a.commandLineRegex.FindStringSubmatch(cmdLine)
+	switch len(match) {
+	case 0:
+		return ""
+	case 1:
+		return match[0]
+	default:
+		return match[1]
+	}
+}
+
+// top returns the processes with the highest CPU usage and with the highest memory usage, without duplicates.
+func (a *aggregator) top(samples []*types.ProcessSample) []*types.ProcessSample {
+	if a.topN <= 0 || len(samples) == 0 {
+		return nil
+	}
+	sorted := make([]*types.ProcessSample, len(samples))
+	copy(sorted, samples)
+	n := a.topN
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+
+	var top []*types.ProcessSample
+	included := make(map[int32]bool)
+	for _, less := range []func(i, j int) bool{
+		func(i, j int) bool { return sorted[i].CPUPercent > sorted[j].CPUPercent },
+		func(i, j int) bool { return sorted[i].MemoryRSSBytes > sorted[j].MemoryRSSBytes },
+	} {
+		sort.SliceStable(sorted, less)
+		for _, s := range sorted[:n] {
+			if !included[s.ProcessID] {
+				included[s.ProcessID] = true
+				top = append(top, s)
+			}
+		}
+	}
+	return top
+}
+
+// add accounts the usage of the process in the group.
+func (g *ProcessGroupSample) add(s *types.ProcessSample) {
+	g.ProcessCount++
+	g.ThreadCount += s.ThreadCount
+	g.CPUPercent += s.CPUPercent
+	g.CPUUserPercent += s.CPUUserPercent
+	g.CPUSystemPercent += s.CPUSystemPercent
+	g.MemoryRSSBytes += s.MemoryRSSBytes
+	g.MemoryVMSBytes += s.MemoryVMSBytes
+	g.IOReadCountPerSecond = addRate(g.IOReadCountPerSecond, s.IOReadCountPerSecond)
+	g.IOWriteCountPerSecond = addRate(g.IOWriteCountPerSecond, s.IOWriteCountPerSecond)
+	g.IOReadBytesPerSecond = addRate(g.IOReadBytesPerSecond, s.IOReadBytesPerSecond)
+	g.IOWriteBytesPerSecond = addRate(g.IOWriteBytesPerSecond, s.IOWriteBytesPerSecond)
+
+	if s.CPUPercent > g.MaxCPUPercent {
+		g.MaxCPUPercent = s.CPUPercent
+	}
+	if s.MemoryRSSBytes > g.MaxMemoryRSSBytes {
+		g.MaxMemoryRSSBytes = s.MemoryRSSBytes
+	}
+	if s.ThreadCount > g.MaxThreadCount {
+		g.MaxThreadCount = s.ThreadCount
+	}
+	if s.FdCount != nil {
+		if g.FdCount == nil {
+			g.FdCount, g.MaxFdCount = new(int32), new(int32)
+		}
+		*g.FdCount += *s.FdCount
+		if *s.FdCount > *g.MaxFdCount {
+			*g.MaxFdCount = *s.FdCount
+		}
+	}
+}
+
+// addRate returns the sum of the rates, which are missing in the first sample of a process.
+func addRate(sum, rate *float64) *float64 {
+	if rate == nil {
+		return sum
+	}
+	if sum == nil {
+		sum = new(float64)
+	}
+	*sum += *rate
+	return sum
+}
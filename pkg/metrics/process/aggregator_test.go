@@ -0,0 +1,110 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+func processSample(pid int32, command, user, cmdLine string, cpu float64, rss int64, fds *int32, ioRead *float64) *types.ProcessSample {
+	s := &types.ProcessSample{
+		ProcessID:            pid,
+		CommandName:          command,
+		User:                 user,
+		CmdLine:              cmdLine,
+		CPUPercent:           cpu,
+		MemoryRSSBytes:       rss,
+		ThreadCount:          2,
+		FdCount:              fds,
+		IOReadBytesPerSecond: ioRead,
+	}
+	s.Type("ProcessSample")
+	return s
+}
+
+func int32Ptr(v int32) *int32       { return &v }
+func float64Ptr(v float64) *float64 { return &v }
+
+func identity(s *types.ProcessSample) sample.Event { return s }
+
+func TestAggregator_Disabled(t *testing.T) {
+	assert.Nil(t, newAggregator(nil))
+	assert.Nil(t, newAggregator(&config.Config{ProcessAggregationKeys: []string{"commandName"}}))
+}
+
+func TestAggregator_Aggregate(t *testing.T) {
+	a := newAggregator(&config.Config{
+		EnableProcessAggregation: true,
+		ProcessAggregationKeys:   []string{config.ProcessAggregationKeyCommandName, config.ProcessAggregationKeyUserName},
+		ProcessAggregationTopN:   1,
+	})
+	require.NotNil(t, a)
+
+	samples := []*types.ProcessSample{
+		processSample(1, "php-fpm", "www-data", "php-fpm: pool www", 10, 100, int32Ptr(10), float64Ptr(5)),
+		processSample(2, "php-fpm", "www-data", "php-fpm: pool www", 30, 300, int32Ptr(20), nil),
+		processSample(3, "php-fpm", "root", "php-fpm: master process", 1, 50, nil, nil),
+		processSample(4, "mysqld", "mysql", "/usr/sbin/mysqld", 20, 1000, int32Ptr(100), float64Ptr(1)),
+	}
+	results := a.aggregate(samples, identity)
+	require.Len(t, results, 5, "3 groups, and the top processes by CPU and memory")
+
+	fpm := results[0].(*ProcessGroupSample)
+	assert.Equal(t, "php-fpm", fpm.CommandName)
+	assert.Equal(t, "www-data", fpm.User)
+	assert.Equal(t, 2, fpm.ProcessCount)
+	assert.Equal(t, int32(4), fpm.ThreadCount)
+	assert.Equal(t, 40.0, fpm.CPUPercent)
+	assert.Equal(t, 30.0, fpm.MaxCPUPercent)
+	assert.Equal(t, int64(400), fpm.MemoryRSSBytes)
+	assert.Equal(t, int64(300), fpm.MaxMemoryRSSBytes)
+	assert.Equal(t, int32(30), *fpm.FdCount)
+	assert.Equal(t, int32(20), *fpm.MaxFdCount)
+	assert.Equal(t, 5.0, *fpm.IOReadBytesPerSecond)
+	assert.Nil(t, fpm.IOWriteBytesPerSecond)
+
+	master := results[1].(*ProcessGroupSample)
+	assert.Equal(t, "root", master.User)
+	assert.Equal(t, 1, master.ProcessCount)
+	assert.Nil(t, master.FdCount)
+
+	assert.Equal(t, "mysqld", results[2].(*ProcessGroupSample).CommandName)
+
+	assert.Equal(t, int32(2), results[3].(*types.ProcessSample).ProcessID, "top by CPU")
+	assert.Equal(t, int32(4), results[4].(*types.ProcessSample).ProcessID, "top by memory")
+}
+
+func TestAggregator_CommandLineGroup(t *testing.T) {
+	a := newAggregator(&config.Config{
+		EnableProcessAggregation:           true,
+		ProcessAggregationKeys:             []string{config.ProcessAggregationKeyCommandLine},
+		ProcessAggregationCommandLineRegex: `celery .*-Q (\S+)`,
+		ProcessAggregationTopN:             -1,
+	})
+
+	samples := []*types.ProcessSample{
+		processSample(1, "python", "celery", "/usr/bin/celery worker -Q emails", 1, 1, nil, nil),
+		processSample(2, "python", "celery", "/usr/bin/celery worker -Q reports", 1, 1, nil, nil),
+		processSample(3, "python", "celery", "/usr/bin/celery worker -Q emails", 1, 1, nil, nil),
+		processSample(4, "python", "app", "/usr/bin/python app.py", 1, 1, nil, nil),
+	}
+	results := a.aggregate(samples, identity)
+	require.Len(t, results, 3, "no top processes are reported")
+
+	emails := results[0].(*ProcessGroupSample)
+	assert.Equal(t, "emails", emails.CommandLineGroup)
+	assert.Empty(t, emails.CommandName, "only the configured keys are reported")
+	assert.Equal(t, 2, emails.ProcessCount)
+	assert.Equal(t, "reports", results[1].(*ProcessGroupSample).CommandLineGroup)
+	assert.Empty(t, results[2].(*ProcessGroupSample).CommandLineGroup, "not matching processes are grouped together")
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/acquire"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/process/snapshot"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// extraAttributes are the groups of extra attributes enabled in the process_sample_attributes option.
+type extraAttributes map[string]bool
+
+func newExtraAttributes(groups []string) extraAttributes {
+	attributes := make(extraAttributes, len(groups))
+	for _, group := range groups {
+		attributes[group] = true
+	}
+	return attributes
+}
+
+// populateExtraAttributes fills the sample with the enabled groups of extra attributes. They are optional, so the
+// values that can't be read (e.g. because of the agent privileges) are just omitted, as the start time when the boot
+// time of the host, in seconds since the epoch, is unknown.
+func (ps *linuxHarvester) populateExtraAttributes(sample, lastSample *types.ProcessSample, source *snapshot.LinuxProcess, elapsedSeconds float64, bootTime uint64) {
+	if len(ps.extraAttributes) == 0 {
+		return
+	}
+	pid := strconv.Itoa(int(source.Pid()))
+
+	if ps.extraAttributes[config.ProcessAttributesCgroup] {
+		sample.CgroupPath, sample.SystemdUnit = readProcCgroup(pid)
+	}
+
+	if ps.extraAttributes[config.ProcessAttributesNamespaces] {
+		sample.PidNamespace = readNamespace(pid, "pid")
+		sample.NetNamespace = readNamespace(pid, "net")
+		sample.MountNamespace = readNamespace(pid, "mnt")
+	}
+
+	if ps.extraAttributes[config.ProcessAttributesOOM] {
+		sample.OOMScore = readProcInt(pid, "oom_score")
+		sample.OOMScoreAdj = readProcInt(pid, "oom_score_adj")
+	}
+
+	if ps.extraAttributes[config.ProcessAttributesContextSwitches] || ps.extraAttributes[config.ProcessAttributesMemory] ||
+		ps.extraAttributes[config.ProcessAttributesSwap] {
+		status := readProcStatus(pid)
+		if ps.extraAttributes[config.ProcessAttributesContextSwitches] {
+			populateContextSwitches(sample, lastSample, status, elapsedSeconds)
+		}
+		if ps.extraAttributes[config.ProcessAttributesMemory] {
+			sample.MemoryAnonymousBytes = status.bytes("RssAnon")
+			sample.MemoryFileBytes = status.bytes("RssFile")
+			sample.MemorySharedBytes = status.bytes("RssShmem")
+		}
+		if ps.extraAttributes[config.ProcessAttributesSwap] {
+			sample.MemorySwapBytes = status.bytes("VmSwap")
+		}
+	}
+
+	if ps.extraAttributes[config.ProcessAttributesFdLimit] {
+		sample.FdLimit = readFdLimit(pid)
+		// the file descriptors are only counted in privileged mode
+		if sample.FdLimit != nil && *sample.FdLimit > 0 && sample.FdCount != nil {
+			percent := float64(*sample.FdCount) / float64(*sample.FdLimit) * 100
+			sample.FdUsedPercent = &percent
+		}
+	}
+
+	if ps.extraAttributes[config.ProcessAttributesStartTime] && bootTime > 0 {
+		startTime := source.StartTime(bootTime).Unix()
+		sample.StartTime = &startTime
+	}
+
+	if ps.extraAttributes[config.ProcessAttributesScheduling] {
+		nice, priority := source.Nice(), source.Priority()
+		sample.Nice = &nice
+		sample.Priority = &priority
+	}
+}
+
+// readProcCgroup returns the cgroup path of the process, and its systemd unit: the innermost service or scope of the
+// path. With cgroup v1, the path of the systemd hierarchy is returned.
+func readProcCgroup(pid string) (cgroupPath, unit string) {
+	content, err := ioutil.ReadFile(helpers.HostProc(pid, "cgroup"))
+	if err != nil {
+		return "", ""
+	}
+	// 0::/system.slice/nginx.service
+	// 1:name=systemd:/system.slice/nginx.service
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == "name=systemd" || (fields[0] == "0" && fields[1] == "" && cgroupPath == "") {
+			cgroupPath = fields[2]
+		}
+	}
+	for _, element := range strings.Split(cgroupPath, "/") {
+		if strings.HasSuffix(element, ".service") || strings.HasSuffix(element, ".scope") {
+			unit = element
+		}
+	}
+	return cgroupPath, unit
+}
+
+// readNamespace returns the inode of a namespace of the process, from its link, e.g. pid:[4026531836]
+func readNamespace(pid, namespace string) *uint64 {
+	link, err := os.Readlink(helpers.HostProc(pid, "ns", namespace))
+	if err != nil {
+		return nil
+	}
+	start, end := strings.Index(link, "["), strings.Index(link, "]")
+	if start < 0 || end < start {
+		return nil
+	}
+	inode, err := strconv.ParseUint(link[start+1:end], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &inode
+}
+
+func readProcInt(pid, file string) *int64 {
+	content, err := ioutil.ReadFile(helpers.HostProc(pid, file))
+	if err != nil {
+		return nil
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// procStatus holds the numeric fields of /proc/<pid>/status, whose lines look like:
+// RssAnon:	    2412 kB
+// voluntary_ctxt_switches:	150
+type procStatus map[string]int64
+
+func readProcStatus(pid string) procStatus {
+	status := make(procStatus)
+	content, err := ioutil.ReadFile(helpers.HostProc(pid, "status"))
+	if err != nil {
+		return status
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			status[strings.TrimSuffix(fields[0], ":")] = value
+		}
+	}
+	return status
+}
+
+// bytes returns a memory field of the status, which is reported in kB.
+func (s procStatus) bytes(field string) *int64 {
+	value, ok := s[field]
+	if !ok {
+		return nil
+	}
+	value *= 1024
+	return &value
+}
+
+// populateContextSwitches fills the rates of the context switches since the last sample of the process.
+func populateContextSwitches(sample, lastSample *types.ProcessSample, status procStatus, elapsedSeconds float64) {
+	voluntary, ok := status["voluntary_ctxt_switches"]
+	if !ok {
+		return
+	}
+	involuntary := status["nonvoluntary_ctxt_switches"]
+	if lastSample != nil && lastSample.LastNumCtxSwitches != nil {
+		last := lastSample.LastNumCtxSwitches
+		voluntaryPerSecond := acquire.CalculateSafeDelta(uint64(voluntary), uint64(last.Voluntary), elapsedSeconds)
+		involuntaryPerSecond := acquire.CalculateSafeDelta(uint64(involuntary), uint64(last.Involuntary), elapsedSeconds)
+		sample.VoluntaryContextSwitchesPerSecond = &voluntaryPerSecond
+		sample.InvoluntaryContextSwitchesPerSecond = &involuntaryPerSecond
+	}
+	sample.LastNumCtxSwitches = &process.NumCtxSwitchesStat{Voluntary: voluntary, Involuntary: involuntary}
+}
+
+// readFdLimit returns the soft limit of open files of the process, or nil if it's unlimited. The line of the limit
+// in /proc/<pid>/limits looks like:
+// Max open files            1024                 524288               files
+func readFdLimit(pid string) *int64 {
+	content, err := ioutil.ReadFile(helpers.HostProc(pid, "limits"))
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 {
+			return nil
+		}
+		limit, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil
+		}
+		return &limit
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ package process
 
 import (
 	"github.com/newrelic/infrastructure-agent/pkg/helpers/lru"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/process/snapshot"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
 )
 
@@ -16,7 +17,7 @@ type cache struct {
 }
 
 type cacheEntry struct {
-	process    *linuxProcess
+	process    *snapshot.LinuxProcess
 	lastSample *types.ProcessSample // The last event we generated for this process, so we can re-use metadata which doesn't change
 }
 
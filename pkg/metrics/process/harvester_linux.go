@@ -12,8 +12,10 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/acquire"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/process/snapshot"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
 	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/process"
 	"github.com/sirupsen/logrus"
 )
@@ -24,13 +26,19 @@ func newHarvester(ctx agent.AgentContext, cache *cache) *linuxHarvester {
 	privileged := cfg == nil || cfg.RunMode == config.ModeRoot || cfg.RunMode == config.ModePrivileged
 	disableZeroRSSFilter := cfg != nil && cfg.DisableZeroRSSFilter
 	stripCommandLine := (cfg != nil && cfg.StripCommandLine) || (cfg == nil && config.DefaultStripCommandLine)
+	var extraAttributes extraAttributes
+	if cfg != nil {
+		extraAttributes = newExtraAttributes(cfg.ProcessSampleAttributes)
+	}
 
 	return &linuxHarvester{
 		privileged:           privileged,
 		disableZeroRSSFilter: disableZeroRSSFilter,
 		stripCommandLine:     stripCommandLine,
+		extraAttributes:      extraAttributes,
 		serviceForPid:        ctx.GetServiceForPid,
 		cache:                cache,
+		readBootTime:         host.BootTime,
 	}
 }
 
@@ -39,14 +47,26 @@ type linuxHarvester struct {
 	privileged           bool
 	disableZeroRSSFilter bool
 	stripCommandLine     bool
+	extraAttributes      extraAttributes
 	cache                *cache
 	serviceForPid        func(int) (string, bool)
+	readBootTime         func() (uint64, error)
+	// bootTime is read once per harvest, to calculate the start time of the processes
+	bootTime uint64
 }
 
 var _ Harvester = (*linuxHarvester)(nil) // static interface assertion
 
-// Pids returns a slice of process IDs that are running now
-func (*linuxHarvester) Pids() ([]int32, error) {
+// Pids returns a slice of process IDs that are running now. As it starts each harvest, the boot time of the host is
+// refreshed here when the start time of the processes is reported.
+func (ps *linuxHarvester) Pids() ([]int32, error) {
+	if ps.extraAttributes[config.ProcessAttributesStartTime] {
+		bootTime, err := ps.readBootTime()
+		if err != nil {
+			mplog.WithError(err).Debug("Can't read the boot time, the process start time won't be reported.")
+		}
+		ps.bootTime = bootTime
+	}
 	return process.Pids()
 }
 
@@ -62,7 +82,7 @@ func (ps *linuxHarvester) Do(pid int32, elapsedSeconds float64) (*types.ProcessS
 		cached = &cacheEntry{}
 	}
 	var err error
-	cached.process, err = getLinuxProcess(pid, cached.process, ps.privileged)
+	cached.process, err = snapshot.NewLinuxProcess(pid, cached.process, ps.privileged)
 	if err != nil {
 		return nil, errors.Wrap(err, "can't create process")
 	}
@@ -92,6 +112,8 @@ func (ps *linuxHarvester) Do(pid int32, elapsedSeconds float64) (*types.ProcessS
 		return nil, errors.Wrap(err, "can't fetch deltas")
 	}
 
+	ps.populateExtraAttributes(sample, cached.lastSample, cached.process, elapsedSeconds, ps.bootTime)
+
 	// This must happen every time, even if we already had a cached sample for the process, because
 	// the available process name metadata may have changed underneath us (if we pick up a new
 	// service/PID association, etc)
@@ -14,6 +14,8 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
 	"github.com/newrelic/infrastructure-agent/internal/testhelpers"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/process/snapshot"
+	"github.com/shirou/gopsutil/v3/host"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -194,9 +196,11 @@ func TestLinuxHarvester_Do_InvalidateCache_DifferentCmd(t *testing.T) {
 	ctx.On("Config").Return(&config.Config{})
 	ctx.On("GetServiceForPid", mock.Anything).Return("", false)
 
-	// That has cached an old process sharing the PID with a new process
+	// That has cached an old process sharing the PID with a new process (the parent, which has another command)
+	old, err := snapshot.NewLinuxProcess(int32(os.Getppid()), nil, false)
+	require.NoError(t, err)
 	cache := newCache()
-	cache.Add(currentPid, &cacheEntry{process: &linuxProcess{cmdLine: "something old"}})
+	cache.Add(currentPid, &cacheEntry{process: old})
 	h := newHarvester(ctx, &cache)
 
 	// When the process is harvested
@@ -205,7 +209,7 @@ func TestLinuxHarvester_Do_InvalidateCache_DifferentCmd(t *testing.T) {
 
 	// The sample is updated
 	assert.NotEmpty(t, sample.CommandName)
-	assert.NotEqual(t, "something old", sample.CommandName)
+	assert.NotEqual(t, old.Command(), sample.CommandName)
 }
 
 func TestLinuxHarvester_Do_InvalidateCache_DifferentPid(t *testing.T) {
@@ -217,8 +221,10 @@ func TestLinuxHarvester_Do_InvalidateCache_DifferentPid(t *testing.T) {
 	ctx.On("GetServiceForPid", mock.Anything).Return("", false)
 
 	// That has cached an old process sharing the PID with a new process
+	old, err := snapshot.NewLinuxProcess(int32(os.Getppid()), nil, false)
+	require.NoError(t, err)
 	cache := newCache()
-	cache.Add(currentPid, &cacheEntry{process: &linuxProcess{stats: procStats{ppid: -1}}})
+	cache.Add(currentPid, &cacheEntry{process: old})
 	h := newHarvester(ctx, &cache)
 
 	// When the process is harvested
@@ -226,7 +232,7 @@ func TestLinuxHarvester_Do_InvalidateCache_DifferentPid(t *testing.T) {
 	require.NoError(t, err)
 
 	// The sample is updated
-	assert.NotEqual(t, -1, sample.ParentProcessID)
+	assert.NotEqual(t, old.Ppid(), sample.ParentProcessID)
 }
 
 func TestLinuxHarvester_GetServiceForPid(t *testing.T) {
@@ -269,3 +275,91 @@ func TestLinuxHarvester_GetServiceForPid_OnEmptyUseCommandName(t *testing.T) {
 	assert.Equal(t, "process.test", sample.CommandName)
 	assert.Contains(t, sample.CmdLine, os.Args[0])
 }
+
+func TestLinuxHarvester_Do_ExtraAttributes(t *testing.T) {
+	// Given a process harvester with all the extra attributes enabled
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{
+		RunMode: config.ModeRoot,
+		ProcessSampleAttributes: []string{
+			config.ProcessAttributesCgroup, config.ProcessAttributesNamespaces, config.ProcessAttributesOOM,
+			config.ProcessAttributesContextSwitches, config.ProcessAttributesMemory, config.ProcessAttributesSwap,
+			config.ProcessAttributesFdLimit, config.ProcessAttributesStartTime, config.ProcessAttributesScheduling,
+		},
+	})
+	ctx.On("GetServiceForPid", mock.Anything).Return("", false)
+	cache := newCache()
+	h := newHarvester(ctx, &cache)
+
+	// When retrieving the process sample of the current testing executable twice
+	_, err := h.Pids()
+	require.NoError(t, err)
+	sample, err := h.Do(int32(os.Getpid()), 0)
+	require.NoError(t, err)
+	assert.Nil(t, sample.VoluntaryContextSwitchesPerSecond, "rates aren't reported in the first sample")
+	sample, err = h.Do(int32(os.Getpid()), 1)
+	require.NoError(t, err)
+
+	// The extra attributes are reported
+	assert.NotEmpty(t, sample.CgroupPath)
+	assert.NotNil(t, sample.PidNamespace)
+	assert.NotNil(t, sample.NetNamespace)
+	assert.NotNil(t, sample.MountNamespace)
+	assert.NotNil(t, sample.OOMScore)
+	assert.NotNil(t, sample.OOMScoreAdj)
+	assert.NotNil(t, sample.VoluntaryContextSwitchesPerSecond)
+	assert.NotNil(t, sample.InvoluntaryContextSwitchesPerSecond)
+	require.NotNil(t, sample.MemoryAnonymousBytes)
+	assert.True(t, *sample.MemoryAnonymousBytes > 0)
+	assert.NotNil(t, sample.MemoryFileBytes)
+	assert.NotNil(t, sample.MemorySwapBytes)
+	require.NotNil(t, sample.FdLimit)
+	require.NotNil(t, sample.FdUsedPercent)
+	assert.True(t, *sample.FdUsedPercent > 0)
+	require.NotNil(t, sample.StartTime)
+	assert.InDelta(t, time.Now().Unix(), *sample.StartTime, 600)
+	assert.NotNil(t, sample.Nice)
+	assert.NotNil(t, sample.Priority)
+}
+
+func TestLinuxHarvester_BootTimeReadOncePerHarvest(t *testing.T) {
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{
+		RunMode:                 config.ModeRoot,
+		ProcessSampleAttributes: []string{config.ProcessAttributesStartTime},
+	})
+	ctx.On("GetServiceForPid", mock.Anything).Return("", false)
+	cache := newCache()
+	h := newHarvester(ctx, &cache)
+	reads := 0
+	h.readBootTime = func() (uint64, error) {
+		reads++
+		return host.BootTime()
+	}
+
+	for harvest := 1; harvest <= 2; harvest++ {
+		_, err := h.Pids()
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			sample, err := h.Do(int32(os.Getpid()), 0)
+			require.NoError(t, err)
+			assert.NotNil(t, sample.StartTime)
+		}
+		assert.Equal(t, harvest, reads)
+	}
+}
+
+func TestLinuxHarvester_Do_NoExtraAttributes(t *testing.T) {
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{})
+	ctx.On("GetServiceForPid", mock.Anything).Return("", false)
+	cache := newCache()
+	h := newHarvester(ctx, &cache)
+
+	sample, err := h.Do(int32(os.Getpid()), 0)
+	require.NoError(t, err)
+	assert.Empty(t, sample.CgroupPath)
+	assert.Nil(t, sample.OOMScore)
+	assert.Nil(t, sample.StartTime)
+	assert.Nil(t, sample.Nice)
+}
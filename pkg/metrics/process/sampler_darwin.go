@@ -24,6 +24,7 @@ type processSampler struct {
 	lastRun          time.Time
 	hasAlreadyRun    bool
 	interval         time.Duration
+	aggregator       *aggregator
 }
 
 var (
@@ -38,11 +39,13 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 	ttlSecs := config.DefaultContainerCacheMetadataLimit
 	apiVersion := ""
 	interval := config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS
+	var aggregator *aggregator
 	if hasConfig {
 		cfg := ctx.Config()
 		ttlSecs = cfg.ContainerMetadataCacheLimit
 		apiVersion = cfg.DockerApiVersion
 		interval = cfg.MetricsProcessSampleRate
+		aggregator = newAggregator(cfg)
 	}
 	harvester := newHarvester(ctx)
 	dockerSampler := metrics.NewDockerSampler(time.Duration(ttlSecs)*time.Second, apiVersion)
@@ -51,6 +54,7 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 		harvest:          harvester,
 		containerSampler: dockerSampler,
 		interval:         time.Second * time.Duration(interval),
+		aggregator:       aggregator,
 	}
 
 }
@@ -103,6 +107,7 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 		}
 	}
 
+	var processSamples []*types.ProcessSample
 	for _, pid := range pids {
 		var processSample *types.ProcessSample
 		var err error
@@ -117,7 +122,14 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 			dockerDecorator.Decorate(processSample)
 		}
 
-		results = append(results, ps.normalizeSample(processSample))
+		if ps.aggregator != nil {
+			processSamples = append(processSamples, processSample)
+		} else {
+			results = append(results, ps.normalizeSample(processSample))
+		}
+	}
+	if ps.aggregator != nil {
+		results = ps.aggregator.aggregate(processSamples, ps.normalizeSample)
 	}
 
 	ps.hasAlreadyRun = true
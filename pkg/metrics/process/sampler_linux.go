@@ -25,6 +25,7 @@ type processSampler struct {
 	hasAlreadyRun    bool
 	interval         time.Duration
 	cache            *cache
+	aggregator       *aggregator
 }
 
 var (
@@ -39,11 +40,13 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 	ttlSecs := config.DefaultContainerCacheMetadataLimit
 	apiVersion := ""
 	interval := config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS
+	var aggregator *aggregator
 	if hasConfig {
 		cfg := ctx.Config()
 		ttlSecs = cfg.ContainerMetadataCacheLimit
 		apiVersion = cfg.DockerApiVersion
 		interval = cfg.MetricsProcessSampleRate
+		aggregator = newAggregator(cfg)
 	}
 	cache := newCache()
 	harvest := newHarvester(ctx, &cache)
@@ -54,6 +57,7 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 		containerSampler: dockerSampler,
 		cache:            &cache,
 		interval:         time.Second * time.Duration(interval),
+		aggregator:       aggregator,
 	}
 
 }
@@ -106,6 +110,7 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 		}
 	}
 
+	var processSamples []*types.ProcessSample
 	for _, pid := range pids {
 		var processSample *types.ProcessSample
 		var err error
@@ -120,7 +125,14 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 			dockerDecorator.Decorate(processSample)
 		}
 
-		results = append(results, ps.normalizeSample(processSample))
+		if ps.aggregator != nil {
+			processSamples = append(processSamples, processSample)
+		} else {
+			results = append(results, ps.normalizeSample(processSample))
+		}
+	}
+	if ps.aggregator != nil {
+		results = ps.aggregator.aggregate(processSamples, ps.normalizeSample)
 	}
 
 	ps.cache.items.RemoveUntilLen(len(pids))
@@ -115,6 +115,8 @@ func (*dummyAgentContext) ActiveEntitiesChannel() chan string {
 	return nil
 }
 
+func (*dummyAgentContext) InstanceGone(string) {}
+
 func (*dummyAgentContext) AddReconnecting(agent.Plugin) {}
 
 func (*dummyAgentContext) EntityKey() string {
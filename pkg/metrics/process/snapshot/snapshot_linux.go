@@ -1,6 +1,6 @@
 // Copyright 2020 New Relic Corporation. All rights reserved.
 // SPDX-License-Identifier: Apache-2.0
-package process
+package snapshot
 
 import (
 	"fmt"
@@ -17,9 +17,9 @@ import (
 	"github.com/shirou/gopsutil/v3/process"
 )
 
-// linuxProcess is an implementation of the process.Snapshot interface for linux hosts. It is designed to be highly
+// LinuxProcess is an implementation of the Snapshot interface for linux hosts. It is designed to be highly
 // optimized and avoid unnecessary/duplicated system calls
-type linuxProcess struct {
+type LinuxProcess struct {
 	// if privileged == false, some operations will be avoided: FD and IO count
 	privileged bool
 
@@ -52,11 +52,11 @@ func init() {
 	}
 }
 
-var _ Snapshot = (*linuxProcess)(nil) // static interface assertion
+var _ Snapshot = (*LinuxProcess)(nil) // static interface assertion
 
-// getLinuxProcess returns a linux process snapshot, trying to reuse the data from a previous snapshot of the same
+// NewLinuxProcess returns a linux process snapshot, trying to reuse the data from a previous snapshot of the same
 // process.
-func getLinuxProcess(pid int32, previous *linuxProcess, privileged bool) (*linuxProcess, error) {
+func NewLinuxProcess(pid int32, previous *LinuxProcess, privileged bool) (*LinuxProcess, error) {
 	var gops *process.Process
 	var err error
 
@@ -75,7 +75,7 @@ func getLinuxProcess(pid int32, previous *linuxProcess, privileged bool) (*linux
 		if err != nil {
 			return nil, err
 		}
-		return &linuxProcess{
+		return &LinuxProcess{
 			privileged: privileged,
 			pid:        pid,
 			process:    gops,
@@ -89,11 +89,11 @@ func getLinuxProcess(pid int32, previous *linuxProcess, privileged bool) (*linux
 	return previous, nil
 }
 
-func (pw *linuxProcess) Pid() int32 {
+func (pw *LinuxProcess) Pid() int32 {
 	return pw.pid
 }
 
-func (pw *linuxProcess) Username() (string, error) {
+func (pw *LinuxProcess) Username() (string, error) {
 	var err error
 	if pw.user == "" { // caching user
 		pw.user, err = pw.process.Username()
@@ -104,7 +104,7 @@ func (pw *linuxProcess) Username() (string, error) {
 	return pw.user, nil
 }
 
-func (pw *linuxProcess) IOCounters() (*process.IOCountersStat, error) {
+func (pw *LinuxProcess) IOCounters() (*process.IOCountersStat, error) {
 	if !pw.privileged {
 		return nil, nil
 	}
@@ -113,7 +113,7 @@ func (pw *linuxProcess) IOCounters() (*process.IOCountersStat, error) {
 
 // NumFDs returns the number of file descriptors. It returns -1 (and nil error) if the Agent does not have privileges to
 // access this information.
-func (pw *linuxProcess) NumFDs() (int32, error) {
+func (pw *LinuxProcess) NumFDs() (int32, error) {
 	if !pw.privileged {
 		return -1, nil
 	}
@@ -140,6 +140,10 @@ type procStats struct {
 	vmRSS      int64
 	vmSize     int64
 	cpu        CPUInfo
+	priority   int32
+	nice       int32
+	// startTime is the time the process started after system boot, in clock ticks
+	startTime uint64
 }
 
 // /proc/<pid>/stat standard field indices according to: http://man7.org/linux/man-pages/man5/proc.5.html
@@ -149,7 +153,10 @@ const (
 	statPPID       = 1
 	statUtime      = 11
 	statStime      = 12
+	statPriority   = 15
+	statNice       = 16
 	statNumThreads = 17
+	statStartTime  = 19
 	statVsize      = 20
 	statRss        = 21
 )
@@ -218,6 +225,24 @@ func parseProcStat(content string) (procStats, error) {
 	}
 	stats.numThreads = int32(nthreads)
 
+	// Scheduling priority and nice value
+	priority, err := strconv.ParseInt(fields[statPriority], 10, 32)
+	if err != nil {
+		return stats, errors.Wrapf(err, "for stats: %s", string(content))
+	}
+	stats.priority = int32(priority)
+	nice, err := strconv.ParseInt(fields[statNice], 10, 32)
+	if err != nil {
+		return stats, errors.Wrapf(err, "for stats: %s", string(content))
+	}
+	stats.nice = int32(nice)
+
+	// Start time
+	stats.startTime, err = strconv.ParseUint(fields[statStartTime], 10, 64)
+	if err != nil {
+		return stats, errors.Wrapf(err, "for stats: %s", string(content))
+	}
+
 	// VM Memory size
 	stats.vmSize, err = strconv.ParseInt(fields[statVsize], 10, 64)
 	if err != nil {
@@ -234,7 +259,7 @@ func parseProcStat(content string) (procStats, error) {
 	return stats, nil
 }
 
-func (pw *linuxProcess) CPUTimes() (CPUInfo, error) {
+func (pw *LinuxProcess) CPUTimes() (CPUInfo, error) {
 	now := time.Now()
 
 	if pw.lastTime.IsZero() {
@@ -263,27 +288,43 @@ func calculatePercent(t1, t2 CPUInfo, delta float64, numcpu int) float64 {
 	return overallPercent
 }
 
-func (pw *linuxProcess) Ppid() int32 {
+// StartTime returns the time the process started, from the boot time of the host in seconds since the epoch.
+func (pw *LinuxProcess) StartTime(bootTime uint64) time.Time {
+	ticks := int64(pw.stats.startTime)
+	return time.Unix(int64(bootTime)+ticks/clockTicks, ticks%clockTicks*int64(time.Second)/clockTicks)
+}
+
+// Priority returns the scheduling priority of the process.
+func (pw *LinuxProcess) Priority() int32 {
+	return pw.stats.priority
+}
+
+// Nice returns the nice value of the process.
+func (pw *LinuxProcess) Nice() int32 {
+	return pw.stats.nice
+}
+
+func (pw *LinuxProcess) Ppid() int32 {
 	return pw.stats.ppid
 }
 
-func (pw *linuxProcess) NumThreads() int32 {
+func (pw *LinuxProcess) NumThreads() int32 {
 	return pw.stats.numThreads
 }
 
-func (pw *linuxProcess) Status() string {
+func (pw *LinuxProcess) Status() string {
 	return pw.stats.state
 }
 
-func (pw *linuxProcess) VmRSS() int64 {
+func (pw *LinuxProcess) VmRSS() int64 {
 	return pw.stats.vmRSS
 }
 
-func (pw *linuxProcess) VmSize() int64 {
+func (pw *LinuxProcess) VmSize() int64 {
 	return pw.stats.vmSize
 }
 
-func (pw *linuxProcess) Command() string {
+func (pw *LinuxProcess) Command() string {
 	return pw.stats.command
 }
 
@@ -291,7 +332,7 @@ func (pw *linuxProcess) Command() string {
 // Data to be derived from /proc/<pid>/cmdline: command line, and command line without arguments
 //////////////////////////
 
-func (pw *linuxProcess) CmdLine(withArgs bool) (string, error) {
+func (pw *LinuxProcess) CmdLine(withArgs bool) (string, error) {
 	if pw.cmdLine != "" {
 		return pw.cmdLine, nil
 	}
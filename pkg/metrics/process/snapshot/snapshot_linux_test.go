@@ -1,6 +1,6 @@
 // Copyright 2020 New Relic Corporation. All rights reserved.
 // SPDX-License-Identifier: Apache-2.0
-package process
+package snapshot
 
 import (
 	"fmt"
@@ -36,7 +36,7 @@ func TestLinuxProcess_CmdLine(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		require.NoError(t, ioutil.WriteFile(path.Join(processDir, "cmdline"), tc.rawProcCmdline, 0666))
-		lp := linuxProcess{pid: 12345}
+		lp := LinuxProcess{pid: 12345}
 		actual, err := lp.CmdLine(true)
 		assert.NoError(t, err)
 		assert.Equal(t, tc.expected, actual)
@@ -66,7 +66,7 @@ func TestLinuxProcess_CmdLine_NoArgs(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		require.NoError(t, ioutil.WriteFile(path.Join(processDir, "cmdline"), tc.rawProcCmdline, 0666))
-		lp := linuxProcess{pid: 12345}
+		lp := LinuxProcess{pid: 12345}
 		actual, err := lp.CmdLine(false)
 		assert.NoError(t, err)
 		assert.Equal(t, tc.expected, actual)
@@ -95,7 +95,7 @@ func TestLinuxProcess_CmdLine_NotStandard(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		require.NoError(t, ioutil.WriteFile(path.Join(processDir, "cmdline"), tc.rawProcCmdline, 0666))
-		lp := linuxProcess{pid: 12345}
+		lp := LinuxProcess{pid: 12345}
 
 		// Testing both the cases with and without command line stripping
 		actual, err := lp.CmdLine(true)
@@ -109,14 +109,14 @@ func TestLinuxProcess_CmdLine_NotStandard(t *testing.T) {
 }
 
 func TestLinuxProcess_CmdLine_ProcessNotExist(t *testing.T) {
-	lp := linuxProcess{pid: 999999999}
+	lp := LinuxProcess{pid: 999999999}
 	actual, err := lp.CmdLine(true)
 	assert.NoError(t, err)
 	assert.Equal(t, "", actual)
 }
 
 func TestLinuxProcess_CmdLine_ProcessNotExist_NoStrip(t *testing.T) {
-	lp := linuxProcess{pid: 999999999}
+	lp := LinuxProcess{pid: 999999999}
 	actual, err := lp.CmdLine(false)
 	assert.NoError(t, err)
 	assert.Equal(t, "", actual)
@@ -132,6 +132,9 @@ func TestParseProcStatMultipleWordsProcess(t *testing.T) {
 		state:      "S",
 		vmRSS:      87003136,
 		vmSize:     1005015040,
+		priority:   20,
+		nice:       0,
+		startTime:  6384148,
 		cpu: CPUInfo{
 			Percent: 0,
 			User:    3.78,
@@ -154,6 +157,9 @@ func TestParseProcStatSingleWordProcess(t *testing.T) {
 		state:      "S",
 		vmRSS:      18391040,
 		vmSize:     464912384,
+		priority:   20,
+		nice:       0,
+		startTime:  1071,
 		cpu: CPUInfo{
 			Percent: 0,
 
@@ -173,13 +179,13 @@ func TestParseProcStatUntrimmedCommand(t *testing.T) {
 		expected procStats
 	}{{
 		input:    "11155 (/usr/bin/spamd ) S 1 11155 11155 0 -1 1077944640 19696 1028 0 0 250 32 0 0 20 0 1 0 6285571 300249088 18439 18446744073709551615 4194304 4198572 140721992060048 140721992059288 139789215727443 0 0 4224 92163 18446744072271262725 0 0 17 1 0 0 0 0 0 6298944 6299796 18743296 140721992060730 140721992060807 140721992060807 140721992060905 0\n",
-		expected: procStats{command: "/usr/bin/spamd ", state: "S", ppid: 1, cpu: CPUInfo{User: 2.50, System: 0.32}, numThreads: 1, vmSize: 300249088, vmRSS: 18439 * pageSize},
+		expected: procStats{command: "/usr/bin/spamd ", state: "S", ppid: 1, cpu: CPUInfo{User: 2.50, System: 0.32}, numThreads: 1, vmSize: 300249088, vmRSS: 18439 * pageSize, priority: 20, startTime: 6285571},
 	}, {
 		input:    "11159 (spamd child) S 11155 11155 11155 0 -1 1077944384 459 0 0 0 1 0 0 0 20 0 1 0 6285738 300249088 17599 18446744073709551615 4194304 4198572 140721992060048 140721992059288 139789215727443 0 0 4224 2048 18446744072271262725 0 0 17 0 0 0 0 0 0 6298944 6299796 18743296 140721992060730 140721992060807 140721992060807 140721992060905 0\n",
-		expected: procStats{command: "spamd child", state: "S", ppid: 11155, cpu: CPUInfo{User: 0.01, System: 0}, numThreads: 1, vmSize: 300249088, vmRSS: 17599 * pageSize},
+		expected: procStats{command: "spamd child", state: "S", ppid: 11155, cpu: CPUInfo{User: 0.01, System: 0}, numThreads: 1, vmSize: 300249088, vmRSS: 17599 * pageSize, priority: 20, startTime: 6285738},
 	}, {
 		input:    "11160 ( spamd child) S 11155 11155 11155 0 -1 1077944384 459 0 0 0 0 0 0 0 20 0 1 0 6285738 300249088 17599 18446744073709551615 4194304 4198572 140721992060048 140721992059288 139789215727443 0 0 4224 2048 18446744072271262725 0 0 17 0 0 0 0 0 0 6298944 6299796 18743296 140721992060730 140721992060807 140721992060807 140721992060905 0\n",
-		expected: procStats{command: " spamd child", state: "S", ppid: 11155, cpu: CPUInfo{User: 0, System: 0}, numThreads: 1, vmSize: 300249088, vmRSS: 17599 * pageSize},
+		expected: procStats{command: " spamd child", state: "S", ppid: 11155, cpu: CPUInfo{User: 0, System: 0}, numThreads: 1, vmSize: 300249088, vmRSS: 17599 * pageSize, priority: 20, startTime: 6285738},
 	}}
 
 	for n, c := range cases {
@@ -190,3 +196,8 @@ func TestParseProcStatUntrimmedCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestLinuxProcess_StartTime(t *testing.T) {
+	lp := LinuxProcess{stats: procStats{startTime: uint64(125 * clockTicks)}}
+	assert.Equal(t, int64(1600000125), lp.StartTime(1600000000).Unix())
+}
@@ -0,0 +1,30 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sdk
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// Event is an event whose attributes aren't known at compile time, e.g. the entries of a stats file.
+type Event map[string]interface{}
+
+var _ sample.Event = &Event{} // Event implements sample.Event
+
+// NewEvent returns an empty event of the event type.
+func NewEvent(eventType string) Event {
+	return Event{"eventType": eventType}
+}
+
+func (e *Event) Type(eventType string) {
+	(*e)["eventType"] = eventType
+}
+
+func (e *Event) Entity(key entity.Key) {
+	(*e)["entityKey"] = key
+}
+
+func (e *Event) Timestamp(timestamp int64) {
+	(*e)["timestamp"] = timestamp
+}
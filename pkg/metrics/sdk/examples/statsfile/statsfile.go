@@ -0,0 +1,114 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package statsfile is an example sampler of the sdk package that reports the entries of a stats file, e.g. the
+// stats an application exposes in /proc or in a file of /dev/shm. Each line of the file is an entry with a key and a
+// value separated by spaces, a colon or an equals sign:
+//
+//	connections_active 12
+//	requests_total: 4096
+//	state=running
+//
+// The numeric values are reported as numbers and the rest as strings. A custom build of the agent includes the
+// sampler with a blank import of this package, and runs it with the configuration:
+//
+//	samplers:
+//	  statsfile:
+//	    interval: 1
+//	    config:
+//	      path: /dev/shm/myapp.stats
+//	      event_type: MyAppSample
+package statsfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// Name of the sampler in the samplers section of the configuration.
+const Name = "statsfile"
+
+const defaultEventType = "StatsFileSample"
+
+func init() {
+	sdk.Register(Name, New)
+}
+
+// Config is the config section of the sampler.
+type Config struct {
+	// Path of the stats file.
+	Path string `yaml:"path"`
+	// EventType of the samples. Default: StatsFileSample
+	EventType string `yaml:"event_type"`
+	// Prefix is added to the name of the attributes.
+	Prefix string `yaml:"prefix"`
+}
+
+// Sampler reports the entries of a stats file.
+type Sampler struct {
+	cfg Config
+}
+
+// New returns the sampler for the configuration.
+func New(cfg sdk.Config) (sdk.Sampler, error) {
+	c := Config{}
+	if err := cfg.Decode(&c); err != nil {
+		return nil, err
+	}
+	if c.Path == "" {
+		return nil, errors.New("missing path of the stats file")
+	}
+	if c.EventType == "" {
+		c.EventType = defaultEventType
+	}
+	return &Sampler{cfg: c}, nil
+}
+
+func (s *Sampler) Sample() (sample.EventBatch, error) {
+	file, err := os.Open(s.cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	event := sdk.NewEvent(s.cfg.EventType)
+	event["statsFile"] = s.cfg.Path
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := parseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			event[s.cfg.Prefix+key] = number
+		} else {
+			event[s.cfg.Prefix+key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read %s: %w", s.cfg.Path, err)
+	}
+	return sample.EventBatch{&event}, nil
+}
+
+// parseLine returns the key and value of an entry. Empty lines and comments are skipped.
+func parseLine(line string) (key, value string, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	idx := strings.IndexAny(line, ":= \t")
+	if idx <= 0 {
+		return "", "", false
+	}
+	key = line[:idx]
+	value = strings.TrimSpace(strings.TrimLeft(line[idx:], ":= \t"))
+	return key, value, value != ""
+}
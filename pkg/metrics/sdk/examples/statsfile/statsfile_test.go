@@ -0,0 +1,58 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package statsfile
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk/sdktest"
+)
+
+func TestSampler(t *testing.T) {
+	dir := sdktest.WriteFiles(t, map[string]string{
+		"myapp.stats": "# myapp stats\nconnections_active 12\nrequests_total: 4096\n\nstate=running\nbroken\n",
+	})
+	path := filepath.Join(dir, "myapp.stats")
+	s, err := New(sdk.NewConfig(Name, time.Second, map[string]interface{}{
+		"path":       path,
+		"event_type": "MyAppSample",
+		"prefix":     "myapp.",
+	}))
+	require.NoError(t, err)
+
+	batches := sdktest.Run(t, s, 1)
+	require.Len(t, batches[0], 1)
+	assert.Equal(t, map[string]interface{}{
+		"eventType":                "MyAppSample",
+		"statsFile":                path,
+		"myapp.connections_active": float64(12),
+		"myapp.requests_total":     float64(4096),
+		"myapp.state":              "running",
+	}, sdktest.Attributes(t, batches[0][0]))
+}
+
+func TestNew_Config(t *testing.T) {
+	_, err := New(sdk.NewConfig(Name, time.Second, nil))
+	assert.Error(t, err, "the path is required")
+
+	_, err = New(sdk.NewConfig(Name, time.Second, map[string]interface{}{"path": "/tmp/stats", "unknown": 1}))
+	assert.Error(t, err, "unknown entries are rejected")
+
+	s, err := New(sdk.NewConfig(Name, time.Second, map[string]interface{}{"path": "/tmp/stats"}))
+	require.NoError(t, err)
+	assert.Equal(t, defaultEventType, s.(*Sampler).cfg.EventType)
+}
+
+func TestSampler_MissingFile(t *testing.T) {
+	s, err := New(sdk.NewConfig(Name, time.Second, map[string]interface{}{"path": "/nonexistent/stats"}))
+	require.NoError(t, err)
+
+	_, err = s.Sample()
+	assert.Error(t, err)
+}
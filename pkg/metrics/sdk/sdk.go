@@ -0,0 +1,203 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sdk is the stable API to add in-process samplers to custom builds of the agent. A sampler registers a
+// factory with its name, usually from the init function of its package:
+//
+//	func init() {
+//		sdk.Register("myapp", func(cfg sdk.Config) (sdk.Sampler, error) {
+//			return &myAppSampler{}, nil
+//		})
+//	}
+//
+// The custom build includes the sampler with a blank import of its package, and the sampler is run when it's
+// configured in the samplers section of the agent configuration:
+//
+//	samplers:
+//	  myapp:
+//	    interval: 1
+//	    config:
+//	      path: /proc/myapp/stats
+//
+// The samplers run in the agent process, so they must be cheap and must not block: use external integrations for
+// anything that needs to spawn processes.
+package sdk
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// DefaultInterval is used by the samplers whose interval isn't configured.
+const DefaultInterval = 10 * time.Second
+
+var slog = log.WithComponent("SamplerSDK")
+
+// Sampler returns the events of a sample. The events must implement sample.Event, e.g. embedding sample.BaseEvent
+// or using the Event map, and must set their event type.
+type Sampler interface {
+	Sample() (sample.EventBatch, error)
+}
+
+// Starter is implemented by the samplers that need to be initialized when the agent starts, before the first sample.
+type Starter interface {
+	OnStartup()
+}
+
+// SamplerFunc adapts a function to the Sampler interface.
+type SamplerFunc func() (sample.EventBatch, error)
+
+// Sample calls f.
+func (f SamplerFunc) Sample() (sample.EventBatch, error) {
+	return f()
+}
+
+// Config is the configuration of a sampler from the samplers section of the agent configuration.
+type Config struct {
+	// Name of the sampler.
+	Name string
+	// Interval between samples.
+	Interval time.Duration
+
+	values map[string]interface{}
+}
+
+// NewConfig returns the configuration of a sampler with the entries of its config section.
+func NewConfig(name string, interval time.Duration, values map[string]interface{}) Config {
+	return Config{Name: name, Interval: interval, values: values}
+}
+
+// Decode stores the entries of the config section into target, which is usually a pointer to a struct whose fields
+// have yaml tags.
+func (c Config) Decode(target interface{}) error {
+	if len(c.values) == 0 {
+		return nil
+	}
+	out, err := yaml.Marshal(c.values)
+	if err != nil {
+		return fmt.Errorf("can't read the config of sampler %s: %w", c.Name, err)
+	}
+	if err := yaml.UnmarshalStrict(out, target); err != nil {
+		return fmt.Errorf("invalid config of sampler %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+// Factory returns the sampler for a configuration. Returning an error disables the sampler.
+type Factory func(cfg Config) (Sampler, error)
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Factory{}
+)
+
+// Register makes a sampler available by name. It panics if the name is already registered or the factory is nil,
+// as it's meant to be called from init functions.
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if factory == nil {
+		panic("sdk: Register factory is nil for sampler " + name)
+	}
+	if _, ok := registry[name]; ok {
+		panic("sdk: Register called twice for sampler " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the sorted names of the registered samplers.
+func Registered() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Samplers returns the registered samplers configured in the samplers section, ready to be registered in the
+// metrics sender. The samplers that aren't registered or fail to be created are logged and skipped.
+func Samplers(cfg config.SamplersConfig) (samplers []sampler.Sampler) {
+	names := make([]string, 0, len(cfg))
+	for name := range cfg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	for _, name := range names {
+		samplerCfg := cfg[name]
+		factory, ok := registry[name]
+		if !ok {
+			slog.WithField("sampler", name).Warn("Sampler is configured but not included in this build of the agent")
+			continue
+		}
+		if samplerCfg.Interval <= config.FREQ_DISABLE_SAMPLING {
+			slog.WithField("sampler", name).Debug("Sampler is disabled.")
+			continue
+		}
+		interval := DefaultInterval
+		if samplerCfg.Interval != config.FREQ_DEFAULT_SAMPLING {
+			interval = time.Duration(samplerCfg.Interval) * time.Second
+		}
+
+		s, err := factory(NewConfig(name, interval, samplerCfg.Config))
+		if err != nil {
+			slog.WithError(err).WithField("sampler", name).Error("Can't create sampler.")
+			continue
+		}
+		samplers = append(samplers, &registeredSampler{name: name, interval: interval, sampler: s})
+	}
+	return samplers
+}
+
+// registeredSampler adapts a Sampler of the SDK to the sampler.Sampler interface of the metrics sender.
+type registeredSampler struct {
+	name     string
+	interval time.Duration
+	sampler  Sampler
+}
+
+func (r *registeredSampler) Name() string {
+	return r.name
+}
+
+func (r *registeredSampler) Interval() time.Duration {
+	return r.interval
+}
+
+func (r *registeredSampler) Disabled() bool {
+	return false
+}
+
+func (r *registeredSampler) OnStartup() {
+	if starter, ok := r.sampler.(Starter); ok {
+		starter.OnStartup()
+	}
+}
+
+// Sample recovers from the panics of the sampler, so a faulty sampler doesn't crash the agent.
+func (r *registeredSampler) Sample() (batch sample.EventBatch, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("panic in sampler %s: %v", r.name, panicErr)
+			batch = nil
+		}
+	}()
+	return r.sampler.Sample()
+}
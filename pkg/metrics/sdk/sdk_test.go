@@ -0,0 +1,118 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sdk
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+type startedSampler struct {
+	started bool
+}
+
+func (s *startedSampler) OnStartup() { s.started = true }
+
+func (s *startedSampler) Sample() (sample.EventBatch, error) {
+	event := NewEvent("StartedSample")
+	event["started"] = s.started
+	return sample.EventBatch{&event}, nil
+}
+
+func withRegistry(t *testing.T, factories map[string]Factory) {
+	previous := registry
+	registry = map[string]Factory{}
+	t.Cleanup(func() { registry = previous })
+	for name, factory := range factories {
+		Register(name, factory)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(Config) (Sampler, error) { return &startedSampler{}, nil }
+	withRegistry(t, map[string]Factory{"b": factory, "a": factory})
+
+	assert.Equal(t, []string{"a", "b"}, Registered())
+	assert.Panics(t, func() { Register("a", factory) })
+	assert.Panics(t, func() { Register("c", nil) })
+}
+
+func TestSamplers(t *testing.T) {
+	var configs []Config
+	withRegistry(t, map[string]Factory{
+		"fast": func(cfg Config) (Sampler, error) {
+			configs = append(configs, cfg)
+			return &startedSampler{}, nil
+		},
+		"default": func(cfg Config) (Sampler, error) {
+			configs = append(configs, cfg)
+			return &startedSampler{}, nil
+		},
+		"disabled": func(Config) (Sampler, error) {
+			t.Error("disabled samplers aren't created")
+			return nil, nil
+		},
+		"failing": func(Config) (Sampler, error) {
+			return nil, errors.New("invalid config")
+		},
+		"unconfigured": func(Config) (Sampler, error) {
+			t.Error("unconfigured samplers aren't created")
+			return nil, nil
+		},
+	})
+
+	samplers := Samplers(config.SamplersConfig{
+		"fast":     {Interval: 1, Config: map[string]interface{}{"path": "/proc/myapp"}},
+		"default":  {},
+		"disabled": {Interval: -1},
+		"failing":  {Interval: 5},
+		"unknown":  {Interval: 5},
+	})
+
+	require.Len(t, samplers, 2)
+	assert.Equal(t, "default", samplers[0].Name())
+	assert.Equal(t, DefaultInterval, samplers[0].Interval())
+	assert.Equal(t, "fast", samplers[1].Name())
+	assert.Equal(t, time.Second, samplers[1].Interval())
+	assert.False(t, samplers[1].Disabled())
+
+	require.Len(t, configs, 2)
+	assert.Equal(t, "fast", configs[1].Name)
+	assert.Equal(t, time.Second, configs[1].Interval)
+	var c struct {
+		Path string `yaml:"path"`
+	}
+	require.NoError(t, configs[1].Decode(&c))
+	assert.Equal(t, "/proc/myapp", c.Path)
+
+	samplers[1].OnStartup()
+	batch, err := samplers[1].Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, true, (*batch[0].(*Event))["started"])
+	assert.Equal(t, "StartedSample", (*batch[0].(*Event))["eventType"])
+}
+
+func TestSamplers_RecoversPanics(t *testing.T) {
+	withRegistry(t, map[string]Factory{
+		"panicking": func(Config) (Sampler, error) {
+			return SamplerFunc(func() (sample.EventBatch, error) {
+				panic("boom")
+			}), nil
+		},
+	})
+
+	samplers := Samplers(config.SamplersConfig{"panicking": {}})
+	require.Len(t, samplers, 1)
+	samplers[0].OnStartup()
+	batch, err := samplers[0].Sample()
+	assert.Nil(t, batch)
+	assert.EqualError(t, err, "panic in sampler panicking: boom")
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sdktest provides helpers to test the samplers of the sdk package.
+package sdktest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// Run starts the sampler as the agent does and returns the batches of n samples. It fails the test if a sample
+// returns an error.
+func Run(t *testing.T, s sdk.Sampler, n int) []sample.EventBatch {
+	t.Helper()
+
+	if starter, ok := s.(sdk.Starter); ok {
+		starter.OnStartup()
+	}
+	batches := make([]sample.EventBatch, 0, n)
+	for i := 0; i < n; i++ {
+		batch, err := s.Sample()
+		require.NoError(t, err)
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// Attributes returns the attributes of an event as they are sent by the agent.
+func Attributes(t *testing.T, event sample.Event) map[string]interface{} {
+	t.Helper()
+
+	out, err := json.Marshal(event)
+	require.NoError(t, err)
+	attributes := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(out, &attributes))
+	return attributes
+}
+
+// WriteFiles creates the files, by path relative to a temporary directory, and returns the directory.
+func WriteFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+// FakeProc creates the files in a temporary /proc, by path relative to it, which is used by the helpers.HostProc
+// function until the end of the test.
+func FakeProc(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := WriteFiles(t, files)
+	t.Setenv("HOST_PROC", dir)
+	return dir
+}
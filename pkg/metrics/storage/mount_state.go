@@ -0,0 +1,140 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package storage
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// Values of the mountState attribute of the storage and NFS samples
+const (
+	MountStateOK       = "ok"
+	MountStateReadOnly = "readonly"
+	MountStateStale    = "stale"
+	MountStateTimeout  = "timeout"
+)
+
+// defaultUsageTimeout is used when the storage_mount_timeout isn't set, e.g. in tests
+const defaultUsageTimeout = 5 * time.Second
+
+var ErrUsageTimeout = errors.New("timeout reading the filesystem usage")
+
+// FilesystemStateChange is reported when the mountState of a filesystem changes between two samples, e.g. when ext4
+// remounts it as read-only after an error (errors=remount-ro) or the NFS server stops responding.
+type FilesystemStateChange struct {
+	sample.BaseEvent
+
+	MountPoint         string `json:"mountPoint"`
+	Device             string `json:"device"`
+	FileSystemType     string `json:"filesystemType"`
+	PreviousMountState string `json:"previousMountState"`
+	MountState         string `json:"mountState"`
+}
+
+// MountStatus is the state of a mounted filesystem in a sample.
+type MountStatus struct {
+	MountPoint     string
+	Device         string
+	FileSystemType string
+	State          string
+}
+
+// MountState returns the state of a filesystem from the error of its usage. The known states are returned with
+// known=true, while the rest of errors are unexpected and the filesystem shouldn't be reported.
+func MountState(usageErr error, readOnly bool) (state string, known bool) {
+	switch {
+	case errors.Is(usageErr, ErrUsageTimeout):
+		return MountStateTimeout, true
+	case errors.Is(usageErr, syscall.ESTALE):
+		return MountStateStale, true
+	case usageErr != nil:
+		return "", false
+	case readOnly:
+		return MountStateReadOnly, true
+	default:
+		return MountStateOK, true
+	}
+}
+
+// UsageProbe reads the usage (statfs) of the mounted filesystems with a timeout, so a hung mount (e.g. a hard NFS
+// mount whose server is unreachable) can't stall the sampler. The calls can't be cancelled, so while the call of a
+// mount point is hung no new calls are started for it and its usage times out immediately.
+type UsageProbe struct {
+	timeout time.Duration
+	lock    sync.Mutex
+	pending map[string]bool
+}
+
+func NewUsageProbe(timeout time.Duration) *UsageProbe {
+	if timeout <= 0 {
+		timeout = defaultUsageTimeout
+	}
+	return &UsageProbe{timeout: timeout, pending: map[string]bool{}}
+}
+
+// Usage returns the result of the usage function for the path, or ErrUsageTimeout if it doesn't return in time.
+func (p *UsageProbe) Usage(path string, usage func(path string) (*disk.UsageStat, error)) (*disk.UsageStat, error) {
+	p.lock.Lock()
+	if p.pending[path] {
+		p.lock.Unlock()
+		return nil, ErrUsageTimeout
+	}
+	p.pending[path] = true
+	p.lock.Unlock()
+
+	type result struct {
+		usage *disk.UsageStat
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		u, err := usage(path)
+		p.lock.Lock()
+		delete(p.pending, path)
+		p.lock.Unlock()
+		done <- result{usage: u, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.usage, r.err
+	case <-time.After(p.timeout):
+		return nil, ErrUsageTimeout
+	}
+}
+
+// MountStateTracker remembers the state of the filesystems to report their changes.
+type MountStateTracker struct {
+	last map[string]string
+}
+
+// Changes returns a FilesystemStateChange for each filesystem whose state differs from the previous call. The
+// filesystems that weren't mounted in the previous call are just recorded.
+func (t *MountStateTracker) Changes(current []MountStatus) (changes sample.EventBatch) {
+	states := make(map[string]string, len(current))
+	for _, m := range current {
+		states[m.MountPoint] = m.State
+		previous, ok := t.last[m.MountPoint]
+		if !ok || previous == m.State {
+			continue
+		}
+		change := &FilesystemStateChange{
+			MountPoint:         m.MountPoint,
+			Device:             m.Device,
+			FileSystemType:     m.FileSystemType,
+			PreviousMountState: previous,
+			MountState:         m.State,
+		}
+		change.Type("FilesystemStateChange")
+		changes = append(changes, change)
+	}
+	t.last = states
+	return changes
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStorage struct {
+	partitions []PartitionStat
+	usage      map[string]error
+}
+
+func (f *fakeStorage) Partitions() ([]PartitionStat, error) { return f.partitions, nil }
+
+func (f *fakeStorage) Usage(path string) (*disk.UsageStat, error) {
+	if err := f.usage[path]; err != nil {
+		return nil, err
+	}
+	return &disk.UsageStat{Path: path, Total: 100, Used: 40, Free: 60}, nil
+}
+
+func (f *fakeStorage) IOCounters() (map[string]IOCountersStat, error) {
+	return nil, errors.New("not supported")
+}
+
+func (f *fakeStorage) CalculateSampleValues(_, _ IOCountersStat, _ int64) *Sample { return nil }
+
+func TestMountState(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		readOnly bool
+		state    string
+		known    bool
+	}{
+		{"ok", nil, false, MountStateOK, true},
+		{"read only", nil, true, MountStateReadOnly, true},
+		{"stale", fmt.Errorf("statfs: %w", syscall.ESTALE), true, MountStateStale, true},
+		{"timeout", ErrUsageTimeout, false, MountStateTimeout, true},
+		{"unexpected error", syscall.EACCES, false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, known := MountState(tt.err, tt.readOnly)
+			assert.Equal(t, tt.state, state)
+			assert.Equal(t, tt.known, known)
+		})
+	}
+}
+
+func TestUsageProbe(t *testing.T) {
+	p := NewUsageProbe(50 * time.Millisecond)
+	var calls int32
+	release := make(chan struct{})
+	hung := func(path string) (*disk.UsageStat, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &disk.UsageStat{Path: path}, nil
+	}
+
+	_, err := p.Usage("/mnt/nfs", hung)
+	assert.Equal(t, ErrUsageTimeout, err)
+
+	// while the call is hung, no new calls are started
+	_, err = p.Usage("/mnt/nfs", hung)
+	assert.Equal(t, ErrUsageTimeout, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	close(release)
+	require.Eventually(t, func() bool {
+		u, err := p.Usage("/mnt/nfs", hung)
+		return err == nil && u.Path == "/mnt/nfs"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSampler_MountStateChanges(t *testing.T) {
+	storage := &fakeStorage{
+		partitions: []PartitionStat{
+			{Device: "/dev/sdb1", Mountpoint: "/var/lib/mysql", Fstype: "ext4", Opts: "rw,relatime"},
+			{Device: "/dev/sdc1", Mountpoint: "/data", Fstype: "xfs", Opts: "rw"},
+		},
+		usage: map[string]error{},
+	}
+	ss := &Sampler{storageUtilities: storage, usageProbe: NewUsageProbe(time.Second)}
+
+	samples, err := ss.Sample()
+	require.NoError(t, err)
+	require.Len(t, samples, 2, "no changes in the first sample")
+	for _, s := range samples {
+		assert.Equal(t, MountStateOK, s.(*Sample).MountState)
+	}
+
+	storage.partitions[0].Opts = "ro,relatime"
+	storage.usage["/data"] = syscall.ESTALE
+	samples, err = ss.Sample()
+	require.NoError(t, err)
+	require.Len(t, samples, 4)
+
+	var changes []*FilesystemStateChange
+	for _, s := range samples {
+		switch event := s.(type) {
+		case *Sample:
+			if event.MountPoint == "/data" {
+				assert.Equal(t, MountStateStale, event.MountState)
+				assert.Nil(t, event.TotalBytes, "stale filesystems are reported without usage")
+			} else {
+				assert.Equal(t, MountStateReadOnly, event.MountState)
+				assert.NotNil(t, event.TotalBytes)
+			}
+		case *FilesystemStateChange:
+			changes = append(changes, event)
+		}
+	}
+	require.Len(t, changes, 2)
+	assert.Equal(t, "FilesystemStateChange", changes[0].EventType)
+	assert.Equal(t, "/var/lib/mysql", changes[0].MountPoint)
+	assert.Equal(t, "/dev/sdb1", changes[0].Device)
+	assert.Equal(t, MountStateOK, changes[0].PreviousMountState)
+	assert.Equal(t, MountStateReadOnly, changes[0].MountState)
+	assert.Equal(t, "/data", changes[1].MountPoint)
+	assert.Equal(t, MountStateStale, changes[1].MountState)
+	assert.Len(t, ss.Samples(), 2, "the changes aren't storage samples")
+
+	samples, err = ss.Sample()
+	require.NoError(t, err)
+	assert.Len(t, samples, 2, "the changes are reported once")
+}
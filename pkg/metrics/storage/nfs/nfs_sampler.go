@@ -11,8 +11,10 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
 	"github.com/newrelic/infrastructure-agent/pkg/sample"
 	"github.com/prometheus/procfs"
+	"github.com/shirou/gopsutil/v3/disk"
 )
 
 var sslog = log.WithComponent("NFSSampler")
@@ -25,8 +27,13 @@ type Sampler struct {
 	lastSamples map[string]statsCache
 	sampleRate  time.Duration
 	detailed    bool
+	usageProbe  *storage.UsageProbe
+	mountStates storage.MountStateTracker
 }
 
+// usageFunc returns the usage of the filesystem mounted in the path.
+type usageFunc func(path string) (*disk.UsageStat, error)
+
 type statsCache struct {
 	last    *procfs.MountStatsNFS
 	lastRun time.Time
@@ -67,6 +74,8 @@ type Sample struct {
 	Mountpoint *string `json:"mountPoint,omitempty"`
 	// Filesystem type; used for filtering out non-NFS filesystems
 	FilesystemType *string `json:"filesystemType,omitempty"`
+	// State of the mount: ok, readonly, or stale or timeout when the server isn't reachable
+	MountState *string `json:"mountState,omitempty"`
 
 	DetailedSample
 }
@@ -180,28 +189,50 @@ func (s *Sampler) Sample() (eventBatch sample.EventBatch, err error) {
 			err = fmt.Errorf("Panic in nfs.Sampler: %v\nStack: %s", panicErr, debug.Stack())
 		}
 	}()
-	samples, err := populateNFS(s.lastSamples, s.detailed)
+	samples, err := populateNFS(s.lastSamples, s.detailed, s.usage)
 	if err != nil {
 		if errors.Is(err, ErrNFSNotFound) {
 			sslog.WithError(err).Debug("Unable to retrieve NFS stats.")
 		} else {
 			sslog.WithError(err).Warn("Unable to retrieve NFS stats.")
 		}
+		s.mountStates.Changes(nil)
 		return nil, nil
 	}
+	var mountStatuses []storage.MountStatus
 	for _, ss := range samples {
 		ss.Type("NFSSample")
 		eventBatch = append(eventBatch, ss)
+		if ss.MountState != nil {
+			mountStatuses = append(mountStatuses, storage.MountStatus{
+				MountPoint:     *ss.Mountpoint,
+				Device:         *ss.Device,
+				FileSystemType: *ss.FilesystemType,
+				State:          *ss.MountState,
+			})
+		}
+	}
+	for _, change := range s.mountStates.Changes(mountStatuses) {
+		sslog.WithField("mountPoint", change.(*storage.FilesystemStateChange).MountPoint).Warn("NFS mount state changed.")
+		eventBatch = append(eventBatch, change)
 	}
 	return eventBatch, err
 }
 
+// usage reads the usage of the NFS mounts with the storage_mount_timeout, since it blocks while the server isn't
+// reachable.
+func (s *Sampler) usage(path string) (*disk.UsageStat, error) {
+	return s.usageProbe.Usage(path, disk.Usage)
+}
+
 func NewSampler(context agent.AgentContext) *Sampler {
 	sampleRateSec := config.DefaultMetricsNFSSampleRate
 	detailed := false
+	var usageTimeout time.Duration
 	if context != nil {
 		sampleRateSec = context.Config().MetricsNFSSampleRate
 		detailed = context.Config().DetailedNFS
+		usageTimeout, _ = time.ParseDuration(context.Config().StorageMountTimeout)
 	}
 
 	return &Sampler{
@@ -209,5 +240,6 @@ func NewSampler(context agent.AgentContext) *Sampler {
 		lastSamples: map[string]statsCache{},
 		sampleRate:  time.Second * time.Duration(sampleRateSec),
 		detailed:    detailed,
+		usageProbe:  storage.NewUsageProbe(usageTimeout),
 	}
 }
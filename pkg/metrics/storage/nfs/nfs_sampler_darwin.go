@@ -5,6 +5,6 @@
 
 package nfs
 
-func populateNFS(cache map[string]statsCache, detailed bool) ([]*Sample, error) {
+func populateNFS(cache map[string]statsCache, detailed bool, usage usageFunc) ([]*Sample, error) {
 	return nil, nil
 }
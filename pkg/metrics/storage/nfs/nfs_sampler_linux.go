@@ -10,11 +10,11 @@ import (
 	"math"
 	"time"
 
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
 	"github.com/prometheus/procfs"
-	"github.com/shirou/gopsutil/v3/disk"
 )
 
-func populateNFS(cache map[string]statsCache, detailed bool) ([]*Sample, error) {
+func populateNFS(cache map[string]statsCache, detailed bool, usage usageFunc) ([]*Sample, error) {
 	mounts, err := getMounts()
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving mounts for NFS: %s", err)
@@ -24,9 +24,10 @@ func populateNFS(cache map[string]statsCache, detailed bool) ([]*Sample, error)
 	samples := []*Sample{}
 	for _, m := range mounts {
 		if m.Type == "nfs" || m.Type == "nfs4" {
-			sample, err := parseNFSMount(cache, m, checkTime)
+			sample, err := parseNFSMount(cache, m, checkTime, usage)
 			if err != nil {
-				return nil, err
+				sslog.WithError(err).WithField("mountPoint", m.Mount).Warn("Can't get NFS disk usage. Ignoring it.")
+				continue
 			}
 			if detailed {
 				parseDetailedNFSStats(sample, m.Stats.(*procfs.MountStatsNFS))
@@ -49,25 +50,32 @@ func getMounts() ([]*procfs.Mount, error) {
 	return proc.MountStats()
 }
 
-func parseNFSMount(cache map[string]statsCache, mount *procfs.Mount, checkTime time.Time) (*Sample, error) {
+// parseNFSMount returns the sample of the NFS mount. The mounts whose server isn't reachable are reported with the
+// stale or timeout mountState and without disk usage.
+func parseNFSMount(cache map[string]statsCache, mount *procfs.Mount, checkTime time.Time, usage usageFunc) (*Sample, error) {
 	ms := mount.Stats.(*procfs.MountStatsNFS)
 
-	diskStats, err := disk.Usage(mount.Mount)
-	if err != nil {
+	diskStats, err := usage(mount.Mount)
+	_, readOnly := ms.Opts["ro"]
+	mountState, known := storage.MountState(err, readOnly)
+	if !known {
 		return nil, err
 	}
-	diskFreePercent := (float64(diskStats.Free) / float64(diskStats.Total)) * 100
 	s := &Sample{
-		DiskTotalBytes:  &diskStats.Total,
-		DiskUsedBytes:   &diskStats.Used,
-		DiskUsedPercent: parseFloat(diskStats.UsedPercent),
-		DiskFreeBytes:   &diskStats.Free,
-		DiskFreePercent: parseFloat(diskFreePercent),
 		TotalReadBytes:  &ms.Bytes.ReadTotal,
 		TotalWriteBytes: &ms.Bytes.WriteTotal,
 		Device:          &mount.Device,
 		Mountpoint:      &mount.Mount,
 		FilesystemType:  &mount.Type,
+		MountState:      &mountState,
+	}
+	if diskStats != nil {
+		diskFreePercent := (float64(diskStats.Free) / float64(diskStats.Total)) * 100
+		s.DiskTotalBytes = &diskStats.Total
+		s.DiskUsedBytes = &diskStats.Used
+		s.DiskUsedPercent = parseFloat(diskStats.UsedPercent)
+		s.DiskFreeBytes = &diskStats.Free
+		s.DiskFreePercent = parseFloat(diskFreePercent)
 	}
 	if v, ok := ms.Opts["vers"]; ok {
 		s.Version = &v
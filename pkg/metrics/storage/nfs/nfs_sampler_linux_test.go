@@ -6,12 +6,18 @@
 package nfs
 
 import (
+	"errors"
 	"math"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/prometheus/procfs"
+	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
 )
 
 func Test_compareNFSOps(t *testing.T) {
@@ -182,3 +188,41 @@ func Test_parseFloat(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseNFSMount_MountState(t *testing.T) {
+	mount := &procfs.Mount{
+		Device: "nas:/export",
+		Mount:  "/mnt/nas",
+		Type:   "nfs4",
+		Stats:  &procfs.MountStatsNFS{Opts: map[string]string{"rw": "", "vers": "4.1"}},
+	}
+	usage := func(path string) (*disk.UsageStat, error) {
+		return &disk.UsageStat{Path: path, Total: 100, Used: 25, Free: 75, UsedPercent: 25}, nil
+	}
+
+	s, err := parseNFSMount(map[string]statsCache{}, mount, time.Now(), usage)
+	require.NoError(t, err)
+	assert.Equal(t, storage.MountStateOK, *s.MountState)
+	assert.Equal(t, uint64(100), *s.DiskTotalBytes)
+	assert.Equal(t, 75.0, *s.DiskFreePercent)
+
+	mount.Stats.(*procfs.MountStatsNFS).Opts = map[string]string{"ro": ""}
+	s, err = parseNFSMount(map[string]statsCache{}, mount, time.Now(), usage)
+	require.NoError(t, err)
+	assert.Equal(t, storage.MountStateReadOnly, *s.MountState)
+
+	for err, state := range map[error]string{syscall.ESTALE: storage.MountStateStale, storage.ErrUsageTimeout: storage.MountStateTimeout} {
+		s, parseErr := parseNFSMount(map[string]statsCache{}, mount, time.Now(), func(string) (*disk.UsageStat, error) {
+			return nil, err
+		})
+		require.NoError(t, parseErr)
+		assert.Equal(t, state, *s.MountState)
+		assert.Nil(t, s.DiskTotalBytes, "unreachable mounts are reported without usage")
+		assert.Equal(t, "/mnt/nas", *s.Mountpoint)
+	}
+
+	_, err = parseNFSMount(map[string]statsCache{}, mount, time.Now(), func(string) (*disk.UsageStat, error) {
+		return nil, errors.New("permission denied")
+	})
+	assert.Error(t, err)
+}
@@ -36,6 +36,7 @@ type BaseSample struct {
 	MountPoint     string `json:"mountPoint"`
 	Device         string `json:"device"`
 	IsReadOnly     string `json:"isReadOnly"`
+	MountState     string `json:"mountState,omitempty"`
 	FileSystemType string `json:"filesystemType"`
 	CountersSource string `json:"countersSource,omitempty"` // Source for the IOCounters: wmi, pdh, diskstats
 
@@ -84,6 +85,8 @@ type Sampler struct {
 	waitForCleanup   *sync.WaitGroup
 	storageUtilities SampleWrapper
 	sampleRate       time.Duration
+	usageProbe       *UsageProbe
+	mountStates      MountStateTracker
 }
 
 type SampleWrapper interface {
@@ -99,11 +102,15 @@ func NewSampler(context agent.AgentContext) *Sampler {
 		sampleRateSec = context.Config().MetricsStorageSampleRate
 	}
 
+	// the default is used in tests with an unset timeout
+	usageTimeout, _ := time.ParseDuration(context.Config().StorageMountTimeout)
+
 	return &Sampler{
 		context:          context,
 		waitForCleanup:   &sync.WaitGroup{},
 		storageUtilities: NewStorageSampleWrapper(context.Config()),
 		sampleRate:       time.Second * time.Duration(sampleRateSec),
+		usageProbe:       NewUsageProbe(usageTimeout),
 	}
 }
 
@@ -205,23 +212,9 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 
 	// key: sample deviceKey
 	dev2Samples := map[string][]*Sample{}
+	var mountStatuses []MountStatus
 	for _, p := range partitions {
 		helpers.LogStructureDetails(sslog, p, "Partition", "raw", logrus.Fields{"supported": true})
-		// If there is a mountPointPrefix, this means we're most likely running inside a container.
-		// Mount points are reported from the perspective of the host. e.g. "/", "/data1"
-		//
-		// If the host has bind mounted its root to "/host" with associated OverrideHostRoot config,
-		// to collect the disk usage we need to resolve the mount points with the host root prefix.
-		// e.g. "/" -> "/host" and "/data1" -> "/host/data1"
-		mountPoint := filepath.Join(mountPointPrefix, p.Mountpoint)
-
-		var fsUsage *disk.UsageStat
-		if fsUsage, err = ss.storageUtilities.Usage(mountPoint); err != nil {
-			sslog.WithError(err).WithField("mountPoint", mountPoint).Warn("can't get disk usage. Ignoring it")
-			continue
-		}
-
-		helpers.LogStructureDetails(sslog, fsUsage, "PartitionUsage", "raw", nil)
 
 		if cfg != nil && len(cfg.FileDevicesIgnored) > 0 {
 			found := false
@@ -244,6 +237,27 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 			}
 		}
 
+		// If there is a mountPointPrefix, this means we're most likely running inside a container.
+		// Mount points are reported from the perspective of the host. e.g. "/", "/data1"
+		//
+		// If the host has bind mounted its root to "/host" with associated OverrideHostRoot config,
+		// to collect the disk usage we need to resolve the mount points with the host root prefix.
+		// e.g. "/" -> "/host" and "/data1" -> "/host/data1"
+		mountPoint := filepath.Join(mountPointPrefix, p.Mountpoint)
+
+		fsUsage, usageErr := ss.usageProbe.Usage(mountPoint, ss.storageUtilities.Usage)
+		mountState, known := MountState(usageErr, p.IsReadOnly())
+		if !known {
+			sslog.WithError(usageErr).WithField("mountPoint", mountPoint).Warn("can't get disk usage. Ignoring it")
+			continue
+		}
+		if usageErr != nil {
+			// stale and hung filesystems are reported without usage
+			sslog.WithError(usageErr).WithField("mountPoint", mountPoint).Debug("Filesystem not responding.")
+		} else {
+			helpers.LogStructureDetails(sslog, fsUsage, "PartitionUsage", "raw", nil)
+		}
+
 		s := &Sample{}
 		s.Type("StorageSample")
 		s.ElapsedSampleDeltaMs = elapsedMs
@@ -251,7 +265,16 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 		s.MountPoint = p.Mountpoint // Ensure we use the reported mount point, not the prefixed one
 		s.Device = p.Device
 		s.IsReadOnly = strconv.FormatBool(p.IsReadOnly())
-		populateUsage(fsUsage, s)
+		s.MountState = mountState
+		if fsUsage != nil {
+			populateUsage(fsUsage, s)
+		}
+		mountStatuses = append(mountStatuses, MountStatus{
+			MountPoint:     p.Mountpoint,
+			Device:         p.Device,
+			FileSystemType: p.Fstype,
+			State:          mountState,
+		})
 
 		// we can have multiple mountpoints for the same device
 		dev2Samples[p.Device] = append(dev2Samples[p.Device], s)
@@ -328,6 +351,14 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 		helpers.LogStructureDetails(sslog, s.(*Sample), "StorageSample", "final", nil)
 	}
 
+	for _, change := range ss.mountStates.Changes(mountStatuses) {
+		sslog.WithFieldsF(func() logrus.Fields {
+			c := change.(*FilesystemStateChange)
+			return logrus.Fields{"mountPoint": c.MountPoint, "previous": c.PreviousMountState, "state": c.MountState}
+		}).Warn("Filesystem state changed.")
+		samples = append(samples, change)
+	}
+
 	return samples, nil
 }
 
@@ -30,6 +30,8 @@ type SystemSample struct {
 	*LoadSample
 	*MemorySample
 	*DiskSample
+	*PressureSample
+	*KernelSample
 }
 
 type SystemSampler struct {
@@ -37,6 +39,7 @@ type SystemSampler struct {
 	DiskMonitor    *DiskMonitor
 	LoadMonitor    *LoadMonitor
 	MemoryMonitor  *MemoryMonitor
+	KernelMonitor  *KernelMonitor // nil unless the kernel metrics are enabled
 	context        agent.AgentContext
 	stopChannel    chan bool
 	waitForCleanup *sync.WaitGroup
@@ -44,7 +47,7 @@ type SystemSampler struct {
 
 func NewSystemSampler(context agent.AgentContext, storageSampler *storage.Sampler) *SystemSampler {
 	cfg := context.Config()
-	return &SystemSampler{
+	s := &SystemSampler{
 		CpuMonitor:     NewCPUMonitor(context),
 		DiskMonitor:    NewDiskMonitor(storageSampler),
 		LoadMonitor:    NewLoadMonitor(),
@@ -52,6 +55,10 @@ func NewSystemSampler(context agent.AgentContext, storageSampler *storage.Sample
 		context:        context,
 		waitForCleanup: &sync.WaitGroup{},
 	}
+	if cfg.EnableKernelMetrics {
+		s.KernelMonitor = NewKernelMonitor()
+	}
+	return s
 }
 
 func (s *SystemSampler) sampleInterval() int {
@@ -121,6 +128,18 @@ func (s *SystemSampler) Sample() (results sample.EventBatch, err error) {
 	}
 	seg.End()
 
+	if s.KernelMonitor != nil {
+		ctx, seg = trx.StartSegment(ctx, "kernel sample")
+		// the kernel metrics are optional, so they don't prevent reporting the sample
+		if pressureSample, kernelSample, err := s.KernelMonitor.Sample(); err != nil {
+			syslog.WithError(err).Debug("Cannot sample kernel metrics.")
+		} else {
+			sample.PressureSample = pressureSample
+			sample.KernelSample = kernelSample
+		}
+		seg.End()
+	}
+
 	helpers.LogStructureDetails(syslog, sample, "SystemSample", "final", nil)
 	results = append(results, sample)
 
@@ -41,9 +41,29 @@ type ProcessSample struct {
 	IOTotalWriteCount     *uint64  `json:"ioTotalWriteCount,omitempty"`
 	IOTotalReadBytes      *uint64  `json:"ioTotalReadBytes,omitempty"`
 	IOTotalWriteBytes     *uint64  `json:"ioTotalWriteBytes,omitempty"`
+	// Extra attributes, reported on Linux when enabled in the process_sample_attributes option
+	CgroupPath                          string   `json:"cgroupPath,omitempty"`
+	SystemdUnit                         string   `json:"systemdUnit,omitempty"`
+	PidNamespace                        *uint64  `json:"pidNamespace,omitempty"`
+	NetNamespace                        *uint64  `json:"netNamespace,omitempty"`
+	MountNamespace                      *uint64  `json:"mountNamespace,omitempty"`
+	OOMScore                            *int64   `json:"oomScore,omitempty"`
+	OOMScoreAdj                         *int64   `json:"oomScoreAdj,omitempty"`
+	VoluntaryContextSwitchesPerSecond   *float64 `json:"voluntaryContextSwitchesPerSecond,omitempty"`
+	InvoluntaryContextSwitchesPerSecond *float64 `json:"involuntaryContextSwitchesPerSecond,omitempty"`
+	MemoryAnonymousBytes                *int64   `json:"memoryAnonymousBytes,omitempty"`
+	MemoryFileBytes                     *int64   `json:"memoryFileBytes,omitempty"`
+	MemorySharedBytes                   *int64   `json:"memorySharedBytes,omitempty"`
+	MemorySwapBytes                     *int64   `json:"memorySwapBytes,omitempty"`
+	FdLimit                             *int64   `json:"fileDescriptorLimit,omitempty"`
+	FdUsedPercent                       *float64 `json:"fileDescriptorUsedPercent,omitempty"`
+	StartTime                           *int64   `json:"startTime,omitempty"`
+	Nice                                *int32   `json:"nice,omitempty"`
+	Priority                            *int32   `json:"priority,omitempty"`
 	// Auxiliary values, not to be reported
-	LastIOCounters  *process.IOCountersStat `json:"-"`
-	ContainerLabels map[string]string       `json:"-"`
+	LastIOCounters     *process.IOCountersStat     `json:"-"`
+	LastNumCtxSwitches *process.NumCtxSwitchesStat `json:"-"`
+	ContainerLabels    map[string]string           `json:"-"`
 }
 
 // FlatProcessSample stores the process sampling information as a map
@@ -3,14 +3,19 @@
 package plugins
 
 import (
+	"sync"
+
 	"github.com/newrelic/infrastructure-agent/internal/agent"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 )
 
 type CustomAttrsPlugin struct {
 	agent.PluginCommon
 	customAttributes map[string]interface{}
+	cloudTags        *cloud.TagsAttributes // nil unless cloud_tags_as_attributes is enabled.
+	refreshOnce      sync.Once
 }
 
 type CustomAttrs map[string]interface{}
@@ -19,24 +24,58 @@ func (self CustomAttrs) SortKey() string {
 	return "customAttributes"
 }
 
-func NewCustomAttrsPlugin(ctx agent.AgentContext) agent.Plugin {
+func NewCustomAttrsPlugin(ctx agent.AgentContext, cloudTags *cloud.TagsAttributes) agent.Plugin {
 	return &CustomAttrsPlugin{
 		PluginCommon: agent.PluginCommon{
 			ID:      ids.CustomAttrsID,
 			Context: ctx,
 		},
 		customAttributes: ctx.Config().CustomAttributes,
+		cloudTags:        cloudTags,
 	}
 }
 
-// This plugin is pretty simple - it simply returns once with the object containing current custom attributes.
+// This plugin is pretty simple - it returns the object containing current custom attributes. When the cloud tags are
+// reported as attributes, they are refreshed periodically and the attributes are returned again when they change.
 func (self *CustomAttrsPlugin) Run() {
 	self.Context.AddReconnecting(self)
 
-	data := agent.PluginInventoryDataset{CustomAttrs(self.customAttributes)}
+	if self.cloudTags != nil {
+		self.refreshOnce.Do(func() {
+			self.cloudTags.OnChange(self.emit)
+			self.cloudTags.Start(self.Context.Context())
+		})
+	}
+
+	self.emit()
+}
+
+func (self *CustomAttrsPlugin) emit() {
+	attributes := self.attributes()
+	data := agent.PluginInventoryDataset{CustomAttrs(attributes)}
 	entityKey := self.Context.EntityKey()
 
-	aclog.Tracef("run, entity: %s, data: %+v", entityKey, self.customAttributes)
+	aclog.Tracef("run, entity: %s, data: %+v", entityKey, attributes)
 
 	self.EmitInventory(data, entity.NewFromNameWithoutID(entityKey))
 }
+
+// attributes returns the cloud tags merged with the custom attributes, which take precedence.
+func (self *CustomAttrsPlugin) attributes() map[string]interface{} {
+	if self.cloudTags == nil {
+		return self.customAttributes
+	}
+	cloudTags := self.cloudTags.Get()
+	if len(cloudTags) == 0 {
+		return self.customAttributes
+	}
+
+	attributes := make(map[string]interface{}, len(cloudTags)+len(self.customAttributes))
+	for key, value := range cloudTags {
+		attributes[key] = value
+	}
+	for key, value := range self.customAttributes {
+		attributes[key] = value
+	}
+	return attributes
+}
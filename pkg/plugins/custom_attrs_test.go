@@ -0,0 +1,75 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/mocks"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakeTagsHarvester struct {
+	cloud.Harvester
+	tags map[string]string
+}
+
+func (f *fakeTagsHarvester) GetCloudType() cloud.Type {
+	return cloud.TypeAWS
+}
+
+func (f *fakeTagsHarvester) GetInstanceTags() (map[string]string, error) {
+	return f.tags, nil
+}
+
+func TestCustomAttrsPlugin_CloudTags(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.CustomAttributes = config.CustomAttributeMap{"team": "from-config"}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(cfg)
+	ctx.On("Context").Return(runCtx)
+	ctx.On("EntityKey").Return("my-host")
+	ctx.On("AddReconnecting", mock.Anything)
+	ch := make(chan agent.PluginOutput, 2)
+	ctx.On("SendData", mock.Anything).Run(func(args mock.Arguments) {
+		ch <- args[0].(agent.PluginOutput)
+	})
+	ctx.SendDataWg.Add(2)
+
+	harvester := &fakeTagsHarvester{tags: map[string]string{"team": "infra", "env": "prod", "secret": "x"}}
+	tags := cloud.NewTagsAttributes(harvester, nil, []string{"secret"}, "", time.Hour)
+	NewCustomAttrsPlugin(ctx, tags).Run()
+
+	// the attributes are emitted again once the cloud tags are fetched, the custom attributes taking precedence
+	expected := agent.PluginInventoryDataset{CustomAttrs{"team": "from-config", "env": "prod"}}
+	for {
+		select {
+		case output := <-ch:
+			if assert.ObjectsAreEqual(expected, output.Data) {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the cloud tags aren't emitted as attributes")
+		}
+	}
+}
+
+func TestCustomAttrsPlugin_CloudTagsDisabled(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.CustomAttributes = config.CustomAttributeMap{"team": "from-config"}
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(cfg)
+
+	plugin := NewCustomAttrsPlugin(ctx, nil).(*CustomAttrsPlugin)
+
+	assert.Equal(t, map[string]interface{}{"team": "from-config"}, plugin.attributes())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/metrics"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/network"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/process"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk"
 	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
@@ -22,7 +23,7 @@ func RegisterPlugins(a *agent.Agent) error {
 	if config.ProxyConfigPlugin {
 		a.RegisterPlugin(proxy.ConfigPlugin(a.Context))
 	}
-	a.RegisterPlugin(NewCustomAttrsPlugin(a.Context))
+	a.RegisterPlugin(NewCustomAttrsPlugin(a.Context, a.GetCloudTags()))
 	a.RegisterPlugin(NewAgentConfigPlugin(*ids.NewPluginID("metadata", "agent_config"), a.Context))
 
 	if config.FilesConfigOn {
@@ -41,6 +42,10 @@ func RegisterPlugins(a *agent.Agent) error {
 	//sender.RegisterSampler(nfsSampler)
 	sender.RegisterSampler(networkSampler)
 	sender.RegisterSampler(procSampler)
+	// in-process samplers of custom builds, see pkg/metrics/sdk
+	for _, s := range sdk.Samplers(config.Samplers) {
+		sender.RegisterSampler(s)
+	}
 
 	a.RegisterMetricsSender(sender)
 
@@ -3,13 +3,21 @@
 package plugins
 
 import (
+	"os"
+
 	agnt "github.com/newrelic/infrastructure-agent/internal/agent"
 	pluginsLinux "github.com/newrelic/infrastructure-agent/internal/plugins/linux"
 	config2 "github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/blockdevice"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/cgroup"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/cpucore"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/hardware"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/network"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/numa"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/process"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk"
 	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage/nfs"
@@ -43,7 +51,7 @@ func RegisterPlugins(agent *agnt.Agent) error {
 		return nil
 	}
 
-	agent.RegisterPlugin(NewCustomAttrsPlugin(agent.Context))
+	agent.RegisterPlugin(NewCustomAttrsPlugin(agent.Context, agent.GetCloudTags()))
 
 	// Enabling the hostinfo plugin will make the host appear in the UI
 	agent.RegisterPlugin(pluginsLinux.NewHostinfoPlugin(agent.Context, agent.GetCloudHarvester()))
@@ -63,6 +71,7 @@ func RegisterPlugins(agent *agnt.Agent) error {
 		// register our plugins
 		agent.RegisterPlugin(pluginsLinux.NewUpstartPlugin(ids.PluginID{"services", "upstart"}, agent.Context))
 		agent.RegisterPlugin(pluginsLinux.NewSystemdPlugin(agent.Context))
+		agent.RegisterPlugin(pluginsLinux.NewSystemdTimersPlugin(agent.Context))
 		agent.RegisterPlugin(pluginsLinux.NewFacterPlugin(agent.Context))
 		if config.FilesConfigOn {
 			agent.RegisterPlugin(NewConfigFilePlugin(ids.PluginID{"files", "config"}, agent.Context))
@@ -87,6 +96,15 @@ func RegisterPlugins(agent *agnt.Agent) error {
 			agent.RegisterPlugin(pluginsLinux.NewKernelModulesPlugin(ids.PluginID{"kernel", "modules"}, agent.Context))
 			agent.RegisterPlugin(pluginsLinux.NewSysvInitPlugin(ids.PluginID{"services", "pidfile"}, agent.Context))
 			agent.RegisterPlugin(pluginsLinux.NewSshdConfigPlugin(ids.PluginID{"config", "sshd"}, agent.Context))
+			agent.RegisterPlugin(pluginsLinux.NewListeningPortsPlugin(agent.Context))
+			agent.RegisterPlugin(pluginsLinux.NewCronPlugin(agent.Context))
+			if config.EnableSecurityInventory {
+				agent.RegisterPlugin(pluginsLinux.NewAccountsPlugin(agent.Context))
+				agent.RegisterPlugin(pluginsLinux.NewGroupsPlugin(agent.Context))
+				agent.RegisterPlugin(pluginsLinux.NewAuthorizedKeysPlugin(agent.Context))
+				agent.RegisterPlugin(pluginsLinux.NewSudoersPlugin(agent.Context))
+				agent.RegisterPlugin(pluginsLinux.NewPamPlugin(agent.Context))
+			}
 
 			// platform specific plugins
 			switch helpers.GetLinuxDistro() {
@@ -97,11 +115,33 @@ func RegisterPlugins(agent *agnt.Agent) error {
 			case helpers.LINUX_REDHAT, helpers.LINUX_AWS_REDHAT, helpers.LINUX_SUSE:
 				slog.Debug("Registering RPM plugins.")
 				agent.RegisterPlugin(pluginsLinux.NewRpmPlugin(agent.Context))
+
+			case helpers.LINUX_ALPINE:
+				slog.Debug("Registering Alpine plugins.")
+				agent.RegisterPlugin(pluginsLinux.NewApkPlugin(agent.Context))
+
+			case helpers.LINUX_ARCH:
+				slog.Debug("Registering Arch plugins.")
+				agent.RegisterPlugin(pluginsLinux.NewPacmanPlugin(agent.Context))
+			}
+
+			// distro agnostic package managers
+			if _, err := os.Stat(pluginsLinux.SnapPath); err == nil {
+				agent.RegisterPlugin(pluginsLinux.NewSnapPlugin(agent.Context))
+			}
+			if _, err := os.Stat(pluginsLinux.FlatpakPath); err == nil {
+				agent.RegisterPlugin(pluginsLinux.NewFlatpakPlugin(agent.Context))
+			}
+			if config.EnableLanguagePackages {
+				agent.RegisterPlugin(pluginsLinux.NewLanguagePackagesPlugin(agent.Context, pluginsLinux.PythonPackagesEcosystem))
+				agent.RegisterPlugin(pluginsLinux.NewLanguagePackagesPlugin(agent.Context, pluginsLinux.NodePackagesEcosystem))
+				agent.RegisterPlugin(pluginsLinux.NewLanguagePackagesPlugin(agent.Context, pluginsLinux.RubyPackagesEcosystem))
 			}
 		}
 
 		if config.RunMode == config2.ModeRoot {
 			agent.RegisterPlugin(pluginsLinux.NewSELinuxPlugin(ids.PluginID{"config", "selinux"}, agent.Context))
+			agent.RegisterPlugin(pluginsLinux.NewFirewallPlugin(agent.Context))
 		}
 
 		if agent.GetCloudHarvester().GetCloudType() == cloud.TypeAWS {
@@ -137,6 +177,29 @@ func RegisterPlugins(agent *agnt.Agent) error {
 	sender.RegisterSampler(nfsSampler)
 	sender.RegisterSampler(networkSampler)
 	sender.RegisterSampler(procSampler)
+	// opt-in samplers, registered only when enabled to avoid warning about them
+	if networkProtocolSampler := network.NewNetworkProtocolSampler(agent.Context); !networkProtocolSampler.Disabled() {
+		sender.RegisterSampler(networkProtocolSampler)
+	}
+	if cpuCoreSampler := cpucore.NewCpuCoreSampler(agent.Context); !cpuCoreSampler.Disabled() {
+		sender.RegisterSampler(cpuCoreSampler)
+	}
+	if numaNodeSampler := numa.NewNumaNodeSampler(agent.Context); !numaNodeSampler.Disabled() {
+		sender.RegisterSampler(numaNodeSampler)
+	}
+	if containerSampler := cgroup.NewContainerSampler(agent.Context); !containerSampler.Disabled() {
+		sender.RegisterSampler(containerSampler)
+	}
+	if blockDeviceSampler := blockdevice.NewBlockDeviceSampler(agent.Context); !blockDeviceSampler.Disabled() {
+		sender.RegisterSampler(blockDeviceSampler)
+	}
+	if hardwareSampler := hardware.NewHardwareSampler(agent.Context); !hardwareSampler.Disabled() {
+		sender.RegisterSampler(hardwareSampler)
+	}
+	// in-process samplers of custom builds, see pkg/metrics/sdk
+	for _, s := range sdk.Samplers(config.Samplers) {
+		sender.RegisterSampler(s)
+	}
 
 	agent.RegisterMetricsSender(sender)
 
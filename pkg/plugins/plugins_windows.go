@@ -3,7 +3,9 @@
 package plugins
 
 import (
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/cpucore"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/network"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sdk"
 	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
@@ -29,7 +31,7 @@ func RegisterPlugins(a *agent.Agent) error {
 		a.RegisterPlugin(proxy.ConfigPlugin(a.Context))
 	}
 
-	a.RegisterPlugin(NewCustomAttrsPlugin(a.Context))
+	a.RegisterPlugin(NewCustomAttrsPlugin(a.Context, a.GetCloudTags()))
 
 	if config.IsSecureForwardOnly {
 		// We need heartbeat samples.
@@ -71,6 +73,14 @@ func RegisterPlugins(a *agent.Agent) error {
 	sender.RegisterSampler(storageSampler)
 	sender.RegisterSampler(networkSampler)
 	sender.RegisterSampler(procSampler)
+	// opt-in sampler, registered only when enabled to avoid warning about it
+	if cpuCoreSampler := cpucore.NewCpuCoreSampler(a.Context); !cpuCoreSampler.Disabled() {
+		sender.RegisterSampler(cpuCoreSampler)
+	}
+	// in-process samplers of custom builds, see pkg/metrics/sdk
+	for _, s := range sdk.Samplers(config.Samplers) {
+		sender.RegisterSampler(s)
+	}
 	a.RegisterMetricsSender(sender)
 
 	return nil
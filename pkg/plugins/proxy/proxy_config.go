@@ -6,8 +6,10 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"net/url"
 	"os"
+	"sort"
 
 	"github.com/newrelic/infrastructure-agent/internal/agent"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
 )
@@ -44,6 +46,21 @@ type boolEntry struct {
 	Value bool `json:"value"`
 }
 
+// stringEntry represents a textual proxy configuration entry
+type stringEntry struct {
+	entry
+	Value string `json:"value"`
+}
+
+// routeEntry shows how the agent connects to a New Relic endpoint: directly or through a proxy with the scheme
+type routeEntry struct {
+	entry
+	Route  string `json:"route"` // direct or proxy
+	Scheme string `json:"scheme,omitempty"`
+	Source string `json:"source,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
 func (e *entry) SortKey() string {
 	return e.Id
 }
@@ -82,6 +99,16 @@ func ConfigPlugin(ctx agent.AgentContext) agent.Plugin {
 		e.Id = "ca_bundle_file"
 		proxyConfig = append(proxyConfig, e)
 	}
+	if e := pathEntry(cfg.ClientCertFile); e != nil {
+		e.Id = "client_cert_file"
+		proxyConfig = append(proxyConfig, e)
+	}
+	if cfg.ProxyAuth != "" {
+		proxyConfig = append(proxyConfig, &stringEntry{
+			entry: entry{Id: "proxy_auth"},
+			Value: cfg.ProxyAuth,
+		})
+	}
 	proxyConfig = append(proxyConfig, &boolEntry{
 		entry: entry{Id: "ignore_system_proxy"},
 		Value: cfg.IgnoreSystemProxy,
@@ -91,6 +118,8 @@ func ConfigPlugin(ctx agent.AgentContext) agent.Plugin {
 		Value: cfg.ProxyValidateCerts,
 	})
 
+	proxyConfig = append(proxyConfig, routeEntries(cfg)...)
+
 	return &configPlugin{
 		agent.PluginCommon{ID: proxyConfigID, Context: ctx},
 		proxyConfig,
@@ -115,6 +144,32 @@ func urlEntry(rawUrl string) *proxyEntry {
 	}
 }
 
+// routeEntries returns the effective route of each New Relic endpoint, as "route.<endpoint>" entries
+func routeEntries(cfg *config.Config) (entries []agent.Sortable) {
+	routes := backendhttp.EffectiveRoutes(cfg)
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		r := routes[name]
+		e := &routeEntry{
+			entry:  entry{Id: "route." + name},
+			Route:  "proxy",
+			Scheme: r.Scheme,
+			Source: r.Source,
+			Error:  r.Error,
+		}
+		if r.Direct {
+			e.Route = "direct"
+		}
+		entries = append(entries, e)
+	}
+	return
+}
+
 func pathEntry(path string) *fileEntry {
 	if path == "" {
 		return nil
@@ -4,8 +4,11 @@ package cloud
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,14 +20,32 @@ import (
 type Type string
 
 const (
-	TypeNoCloud    Type = "no_cloud"    // No cloud type has been detected.
-	TypeInProgress Type = "in_progress" // Cloud detection is in progress.
-	TypeAWS        Type = "aws"         // This instance is running in aws.
-	TypeAzure      Type = "azure"       // This instance is running in Azure.
-	TypeGCP        Type = "gcp"         // This instance is running in gcp.
-	TypeAlibaba    Type = "alibaba"     // This instance is running in alibaba.
+	TypeNoCloud    Type = "no_cloud"     // No cloud type has been detected.
+	TypeInProgress Type = "in_progress"  // Cloud detection is in progress.
+	TypeAWS        Type = "aws"          // This instance is running in aws.
+	TypeAzure      Type = "azure"        // This instance is running in Azure.
+	TypeGCP        Type = "gcp"          // This instance is running in gcp.
+	TypeAlibaba    Type = "alibaba"      // This instance is running in alibaba.
+	TypeOCI        Type = "oci"          // This instance is running in Oracle Cloud Infrastructure.
+	TypeDO         Type = "digitalocean" // This instance is running in DigitalOcean.
+	TypeHetzner    Type = "hetzner"      // This instance is running in Hetzner Cloud.
+	TypeOpenStack  Type = "openstack"    // This instance is running in OpenStack.
+	TypeVMware     Type = "vmware"       // This instance is running in a VMware virtual machine.
 )
 
+// DefaultDetectionOrder is the order the clouds are probed by default.
+var DefaultDetectionOrder = []Type{
+	TypeAWS,
+	TypeAzure,
+	TypeGCP,
+	TypeAlibaba,
+	TypeOCI,
+	TypeDO,
+	TypeHetzner,
+	TypeOpenStack,
+	TypeVMware,
+}
+
 var dlog = log.WithComponent("CloudDetector")
 
 // ShouldCollect returns true if we should collect data for this cloud type.
@@ -67,6 +88,12 @@ type Harvester interface {
 	GetHarvester() (Harvester, error)
 }
 
+// TagsHarvester is implemented by the harvesters able to fetch the tags (or labels) of the instance.
+type TagsHarvester interface {
+	// GetInstanceTags returns the tags of the cloud instance, by key.
+	GetInstanceTags() (map[string]string, error)
+}
+
 // Detector is used to detect the cloud type on which the instance is running
 // and can be queried in order to get the information needed.
 type Detector struct {
@@ -79,6 +106,9 @@ type Detector struct {
 	initialized          bool          // Flag to determine when the Detector is initialized.
 	inProgress           bool          // Flag to determine when Detector initialization is in progress.
 	disableKeepAlive     bool          // Disables HTTP keep-alives and will only use the connection to the server for a single HTTP request.
+	detectionOrder       []Type        // The clouds to probe, in order.
+	explicitOrder        bool          // Whether the detection order is set explicitly, so it's always probed.
+	readDMI              func() dmiInfo
 }
 
 // NewDetector returns a new Detector instance.
@@ -89,27 +119,103 @@ func NewDetector(disableCloudMetadata bool, maxRetriesNumber, retryBackOffSec, e
 		expiryInSec:          expiryInSec,
 		disableCloudMetadata: disableCloudMetadata,
 		disableKeepAlive:     disableKeepAlive,
+		detectionOrder:       DefaultDetectionOrder,
+		readDMI:              readDMI,
+	}
+}
+
+// SetDetectionOrder sets the clouds to probe, in order. Unknown cloud types are ignored, and the
+// current order is kept if none is known. It must be called before Initialize.
+func (d *Detector) SetDetectionOrder(order []string) {
+	var detectionOrder []Type
+	for _, name := range order {
+		t := Type(strings.ToLower(strings.TrimSpace(name)))
+		if d.newHarvester(t) == nil {
+			dlog.WithField("cloudType", name).Warn("Unknown cloud type in the detection order, ignoring it.")
+			continue
+		}
+		detectionOrder = append(detectionOrder, t)
+	}
+	if len(detectionOrder) == 0 {
+		if len(order) > 0 {
+			dlog.WithField("detectionOrder", d.detectionOrder).Warn("No known cloud type in the detection order, keeping the current one.")
+		}
+		return
 	}
+	d.detectionOrder = detectionOrder
+	d.explicitOrder = true
 }
 
 // Initialize should be called in order to Detect the cloud harvester.
 func (d *Detector) Initialize() {
-	harvesters := []Harvester{
-		NewAWSHarvester(d.disableKeepAlive),
-		NewAzureHarvester(d.disableKeepAlive),
-		NewGCPHarvester(d.disableKeepAlive),
-		NewAlibabaHarvester(d.disableKeepAlive),
+	harvesters, retry := d.harvesters()
+	if !retry {
+		d.maxRetriesNumber = 0
 	}
 	d.initialize(harvesters...)
 }
 
+// harvesters returns the harvesters to probe, and whether the probes are retried when no cloud is
+// detected. When the DMI information reveals the cloud of the instance, only its harvester is probed,
+// avoiding the network timeouts of the other ones. When it reveals a physical machine, which may still
+// be provisioned by a cloud (e.g. OpenStack Ironic), the probes aren't retried, unless the detection
+// order is set explicitly.
+func (d *Detector) harvesters() (harvesters []Harvester, retry bool) {
+	order := d.detectionOrder
+	retry = true
+	dmi := d.readDMI()
+	if hint := dmi.cloudType(); hint != "" {
+		for _, t := range order {
+			if t == hint {
+				dlog.WithField("cloudType", hint).Debug("Cloud type hinted by DMI information.")
+				order = []Type{hint}
+				break
+			}
+		}
+	} else if dmi.bareMetal() && !d.explicitOrder {
+		dlog.WithField("sysVendor", dmi.sysVendor).Debug("Physical machine revealed by DMI information, probing the clouds without retrying.")
+		retry = false
+	}
+
+	harvesters = make([]Harvester, 0, len(order))
+	for _, t := range order {
+		harvesters = append(harvesters, d.newHarvester(t))
+	}
+	return harvesters, retry
+}
+
+// newHarvester returns the harvester of a cloud type, or nil if unknown.
+func (d *Detector) newHarvester(t Type) Harvester {
+	switch t {
+	case TypeAWS:
+		return NewAWSHarvester(d.disableKeepAlive)
+	case TypeAzure:
+		return NewAzureHarvester(d.disableKeepAlive)
+	case TypeGCP:
+		return NewGCPHarvester(d.disableKeepAlive)
+	case TypeAlibaba:
+		return NewAlibabaHarvester(d.disableKeepAlive)
+	case TypeOCI:
+		return NewOCIHarvester(d.disableKeepAlive)
+	case TypeDO:
+		return NewDOHarvester(d.disableKeepAlive)
+	case TypeHetzner:
+		return NewHetznerHarvester(d.disableKeepAlive)
+	case TypeOpenStack:
+		return NewOpenStackHarvester(d.disableKeepAlive)
+	case TypeVMware:
+		return NewVMwareHarvester()
+	}
+	return nil
+}
+
 // initialize should be called in order to Detect the cloud harvester.
 func (d *Detector) initialize(harvesters ...Harvester) {
 	if d.isInitialized() || d.isInProgress() {
 		return
 	}
 
-	if d.disableCloudMetadata {
+	if d.disableCloudMetadata || len(harvesters) == 0 {
 		d.finishInit()
 		return
 	}
@@ -198,6 +304,20 @@ func (d *Detector) GetZone() (string, error) {
 	return cloudHarvester.GetZone()
 }
 
+// GetInstanceTags will return the tags of the cloud instance, or ErrMethodNotImplemented when the
+// detected cloud doesn't provide them.
+func (d *Detector) GetInstanceTags() (map[string]string, error) {
+	cloudHarvester, err := d.GetHarvester()
+	if err != nil {
+		return nil, err
+	}
+	tagsHarvester, ok := cloudHarvester.(TagsHarvester)
+	if !ok {
+		return nil, ErrMethodNotImplemented
+	}
+	return tagsHarvester.GetInstanceTags()
+}
+
 // GetCloudSource Returns a string key which will be used as a HostSource (see host_aliases plugin).
 func (d *Detector) GetCloudSource() string {
 	cloudHarvester, err := d.GetHarvester()
@@ -267,24 +387,39 @@ func (d *Detector) detectRetrying(harvesters ...Harvester) {
 }
 
 // detect will check which cloud harvester is able to successfully request data from API in order to detect the cloud type.
+// All the harvesters are probed at once, so the detection takes as long as the slowest probe, and the first one in order
+// that succeeds is used.
 func (d *Detector) detect(harvesters ...Harvester) error {
-
-	for _, harvester := range harvesters {
+	instanceIDs := make([]string, len(harvesters))
+	detected := make([]bool, len(harvesters))
+	var wg sync.WaitGroup
+	for i, harvester := range harvesters {
 		if harvester == nil {
 			continue
 		}
+		wg.Add(1)
+		go func(i int, harvester Harvester) {
+			defer wg.Done()
+			var err error
+			instanceIDs[i], err = harvester.GetInstanceID()
+			detected[i] = err == nil
+		}(i, harvester)
+	}
+	wg.Wait()
 
-		if instanceID, err := harvester.GetInstanceID(); err == nil {
+	for i, harvester := range harvesters {
+		if !detected[i] {
+			continue
+		}
 
-			dlog.WithFields(logrus.Fields{
-				"instanceId": instanceID,
-				"cloudType":  harvester.GetCloudType(),
-			}).Debug("Detected cloud type and retrieved instance ID")
+		dlog.WithFields(logrus.Fields{
+			"instanceId": instanceIDs[i],
+			"cloudType":  harvester.GetCloudType(),
+		}).Debug("Detected cloud type and retrieved instance ID")
 
-			d.setHarvester(harvester)
-			d.finishInit()
-			return nil
-		}
+		d.setHarvester(harvester)
+		d.finishInit()
+		return nil
 	}
 	return ErrCouldNotDetect
 }
@@ -302,6 +437,32 @@ func clientWithFastTimeout(disableKeepAlive bool) *http.Client {
 	}
 }
 
+// fetchMetadata requests a cloud metadata endpoint, returning the body of OK responses.
+func fetchMetadata(url string, headers map[string]string, disableKeepAlive bool) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare cloud metadata request: %v", err)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := clientWithFastTimeout(disableKeepAlive).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch cloud metadata: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cloud metadata request returned non-OK response: %d %s", response.StatusCode, response.Status)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cloud metadata response body: %v", err)
+	}
+	return body, nil
+}
+
 // Timeout is used to check if a period of time has passed.
 type Timeout struct {
 	expiry   time.Time
@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -34,6 +35,8 @@ const (
 	// awsMetaDataPath is the path of the URL used for requesting AWS metadata.
 	awsMetaDataPath             = "/latest/meta-data/"
 	instanceIdentityDocumentURL = "/latest/dynamic/instance-identity/document"
+	// instanceTagsPath lists the instance tags keys, only available when the instance metadata tags are enabled.
+	instanceTagsPath = "tags/instance"
 	defaultTimeout              = 600
 )
 
@@ -140,6 +143,29 @@ func (a *AWSHarvester) GetInstanceImageID() (string, error) {
 	return icc.ImageID, nil
 }
 
+// GetInstanceTags returns the instance tags, which requires enabling the access to the tags in the
+// instance metadata options.
+func (a *AWSHarvester) GetInstanceTags() (map[string]string, error) {
+	keys, err := a.GetAWSMetadataValue(instanceTagsPath, a.disableKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for _, key := range strings.Split(keys, "\n") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		value, err := a.GetAWSMetadataValue(instanceTagsPath+"/"+url.PathEscape(key), a.disableKeepAlive)
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
 // GetCloudType returns the type of the cloud.
 func (a *AWSHarvester) GetCloudType() Type {
 	return TypeAWS
@@ -16,6 +16,8 @@ import (
 const (
 	// azureEndpoint is the URL used for requesting Azure metadata.
 	azureEndpoint = "http://169.254.169.254/metadata/instance?api-version=2017-04-02"
+	// azureTagsEndpoint is the URL used for requesting the Azure instance tags.
+	azureTagsEndpoint = "http://169.254.169.254/metadata/instance/compute/tagsList?api-version=2019-06-04"
 )
 
 // AzureHarvester is used to fetch data from Azure api.
@@ -28,6 +30,7 @@ type AzureHarvester struct {
 	zone             string
 	subscriptionID   string
 	imageID          string
+	tagsEndpoint     string
 }
 
 // AzureHarvester returns a new instance of AzureHarvester.
@@ -35,6 +38,7 @@ func NewAzureHarvester(disableKeepAlive bool) *AzureHarvester {
 	return &AzureHarvester{
 		timeout:          NewTimeout(600),
 		disableKeepAlive: disableKeepAlive,
+		tagsEndpoint:     azureTagsEndpoint,
 	}
 }
 
@@ -130,6 +134,28 @@ func (a *AzureHarvester) GetInstanceImageID() (string, error) {
 	return a.imageID, nil
 }
 
+// GetInstanceTags returns the Azure instance tags.
+func (a *AzureHarvester) GetInstanceTags() (map[string]string, error) {
+	body, err := fetchMetadata(a.tagsEndpoint, map[string]string{"Metadata": "true"}, a.disableKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagsList []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	if err = json.Unmarshal(body, &tagsList); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal Azure tags response body: %v", err)
+	}
+
+	tags := make(map[string]string, len(tagsList))
+	for _, tag := range tagsList {
+		tags[tag.Name] = tag.Value
+	}
+	return tags, nil
+}
+
 // Captures the fields we care about from the Azure metadata API
 type azureMetadata struct {
 	Compute struct {
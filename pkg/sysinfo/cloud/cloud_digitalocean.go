@@ -0,0 +1,132 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+)
+
+const (
+	// doEndpoint is the URL used for requesting DigitalOcean metadata.
+	doEndpoint = "http://169.254.169.254/metadata/v1.json"
+)
+
+//
+// https://docs.digitalocean.com/reference/api/metadata-api/
+//
+// Example response (trimmed):
+//
+// {
+//     "droplet_id": 2756294,
+//     "hostname": "sample-droplet",
+//     "region": "nyc3"
+// }
+//
+
+// DOHarvester is used to fetch data from DigitalOcean api. The metadata doesn't provide the droplet
+// size, account nor image.
+type DOHarvester struct {
+	timeout          *Timeout
+	disableKeepAlive bool
+	endpoint         string
+	metadata         *DOMetadata // Cache the droplet metadata.
+}
+
+// NewDOHarvester returns a new instance of DOHarvester.
+func NewDOHarvester(disableKeepAlive bool) *DOHarvester {
+	return &DOHarvester{
+		timeout:          NewTimeout(600),
+		disableKeepAlive: disableKeepAlive,
+		endpoint:         doEndpoint,
+	}
+}
+
+// GetHarvester returns instance of the Harvester detected (or instance of themselves)
+func (d *DOHarvester) GetHarvester() (Harvester, error) {
+	return d, nil
+}
+
+// GetInstanceID returns the droplet ID.
+func (d *DOHarvester) GetInstanceID() (string, error) {
+	m, err := d.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(m.DropletID, 10), nil
+}
+
+// GetHostType is not provided by the DigitalOcean metadata.
+func (d *DOHarvester) GetHostType() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetCloudType returns the type of the cloud.
+func (d *DOHarvester) GetCloudType() Type {
+	return TypeDO
+}
+
+// GetCloudSource returns a string key which will be used as a HostSource (see host_aliases plugin).
+func (d *DOHarvester) GetCloudSource() string {
+	return sysinfo.HOST_SOURCE_DO_DROPLET_ID
+}
+
+// GetRegion will return the droplet region.
+func (d *DOHarvester) GetRegion() (string, error) {
+	m, err := d.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.Region, nil
+}
+
+// GetAccountID is not provided by the DigitalOcean metadata.
+func (d *DOHarvester) GetAccountID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetZone returns the droplet region, as DigitalOcean regions have a single zone.
+func (d *DOHarvester) GetZone() (string, error) {
+	return d.GetRegion()
+}
+
+// GetInstanceImageID is not provided by the DigitalOcean metadata.
+func (d *DOHarvester) GetInstanceImageID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+func (d *DOHarvester) getMetadata() (*DOMetadata, error) {
+	if d.metadata == nil || d.timeout.HasExpired() {
+		body, err := fetchMetadata(d.endpoint, nil, d.disableKeepAlive)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := parseDOMetadata(body)
+		if err != nil {
+			return nil, err
+		}
+		d.metadata = metadata
+	}
+	return d.metadata, nil
+}
+
+// DOMetadata captures the fields we care about from the DigitalOcean metadata API.
+type DOMetadata struct {
+	DropletID int64  `json:"droplet_id"`
+	Region    string `json:"region"`
+}
+
+func parseDOMetadata(body []byte) (*DOMetadata, error) {
+	var metadata DOMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal DigitalOcean metadata response body: %v", err)
+	}
+	if metadata.DropletID == 0 {
+		return nil, errors.New("missing droplet ID in DigitalOcean metadata")
+	}
+	return &metadata, nil
+}
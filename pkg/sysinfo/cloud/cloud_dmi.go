@@ -0,0 +1,80 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// azureAssetTag is the chassis asset tag of the Azure virtual machines.
+const azureAssetTag = "7783-7084-3265-9085-8269-3286-77"
+
+// hardwareVendors are the DMI system vendors of physical machines, that don't run in any cloud.
+var hardwareVendors = []string{
+	"acer", "apple", "asus", "cisco", "dell", "fujitsu", "gigabyte", "hewlett-packard", "hp", "hpe",
+	"ibm", "inspur", "intel", "lenovo", "micro-star", "quanta", "supermicro",
+}
+
+// dmiInfo holds the DMI fields identifying the platform of the instance.
+type dmiInfo struct {
+	sysVendor       string
+	productName     string
+	chassisAssetTag string
+}
+
+// readDMI reads the DMI information exposed by sysfs, empty when not available.
+func readDMI() dmiInfo {
+	read := func(name string) string {
+		content, err := ioutil.ReadFile(helpers.HostSys("/devices/virtual/dmi/id/" + name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(content))
+	}
+	return dmiInfo{
+		sysVendor:       read("sys_vendor"),
+		productName:     read("product_name"),
+		chassisAssetTag: read("chassis_asset_tag"),
+	}
+}
+
+// cloudType returns the cloud type revealed by the DMI information, or empty if unknown.
+func (d dmiInfo) cloudType() Type {
+	vendor := strings.ToLower(d.sysVendor)
+	product := strings.ToLower(d.productName)
+	switch {
+	case d.chassisAssetTag == "OracleCloud.com":
+		return TypeOCI
+	case d.chassisAssetTag == azureAssetTag:
+		return TypeAzure
+	case vendor == "amazon ec2" || strings.HasPrefix(product, "amazon ec2"):
+		return TypeAWS
+	case vendor == "google" || product == "google compute engine":
+		return TypeGCP
+	case strings.HasPrefix(vendor, "alibaba"):
+		return TypeAlibaba
+	case vendor == "digitalocean":
+		return TypeDO
+	case vendor == "hetzner":
+		return TypeHetzner
+	case strings.HasPrefix(vendor, "openstack") || strings.HasPrefix(product, "openstack"):
+		return TypeOpenStack
+	case strings.HasPrefix(vendor, "vmware"):
+		return TypeVMware
+	}
+	return ""
+}
+
+// bareMetal returns true if the DMI information names the vendor of a physical machine.
+func (d dmiInfo) bareMetal() bool {
+	vendor := strings.ToLower(d.sysVendor)
+	for _, hw := range hardwareVendors {
+		if vendor == hw || strings.HasPrefix(vendor, hw+" ") || strings.HasPrefix(vendor, hw+",") {
+			return true
+		}
+	}
+	return false
+}
@@ -15,6 +15,8 @@ import (
 const (
 	// gcpEndpoint is the URL used for requesting GCP metadata.
 	gcpEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/?recursive=true"
+	// gcpAttributesEndpoint is the URL used for requesting the GCP instance custom metadata.
+	gcpAttributesEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/attributes/?recursive=true"
 )
 
 // GCPHarvester is used to fetch data from GCP API.
@@ -24,6 +26,28 @@ type GCPHarvester struct {
 	instanceID       string // Cache the gcp instance ID.
 	hostType         string // Cache the gcp instance Type.
 	zone             string
+	tagsEndpoint     string
+}
+
+// gcpMaxAttributeValueSize is the maximum size of the instance custom metadata values reported as
+// tags. Larger values are usually scripts, keys or configuration files rather than labels.
+const gcpMaxAttributeValueSize = 255
+
+// gcpReservedAttributes are the instance metadata keys used by GCP to configure the instance,
+// which aren't reported as tags.
+var gcpReservedAttributes = map[string]bool{
+	"ssh-keys":                   true,
+	"sshKeys":                    true,
+	"block-project-ssh-keys":     true,
+	"enable-oslogin":             true,
+	"startup-script":             true,
+	"startup-script-url":         true,
+	"shutdown-script":            true,
+	"shutdown-script-url":        true,
+	"windows-keys":               true,
+	"windows-startup-script-ps1": true,
+	"user-data":                  true,
+	"kube-env":                   true,
 }
 
 // NewGCPHarvester return a new GCPHarvester instance.
@@ -31,6 +55,7 @@ func NewGCPHarvester(disableKeepAlive bool) *GCPHarvester {
 	return &GCPHarvester{
 		timeout:          NewTimeout(600),
 		disableKeepAlive: disableKeepAlive,
+		tagsEndpoint:     gcpAttributesEndpoint,
 	}
 }
 
@@ -79,6 +104,28 @@ func (gcp *GCPHarvester) GetInstanceImageID() (string, error) {
 	return "", ErrMethodNotImplemented
 }
 
+// GetInstanceTags returns the GCP instance custom metadata, excluding the keys reserved by GCP and
+// the values larger than gcpMaxAttributeValueSize.
+func (gcp *GCPHarvester) GetInstanceTags() (map[string]string, error) {
+	body, err := fetchMetadata(gcp.tagsEndpoint, map[string]string{"Metadata-Flavor": "Google"}, gcp.disableKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	var attributes map[string]string
+	if err = json.Unmarshal(body, &attributes); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal GCP attributes response body: %v", err)
+	}
+
+	tags := make(map[string]string, len(attributes))
+	for key, value := range attributes {
+		if !gcpReservedAttributes[key] && len(value) <= gcpMaxAttributeValueSize {
+			tags[key] = value
+		}
+	}
+	return tags, nil
+}
+
 // GetCloudType returns the type of the cloud.
 func (gcp *GCPHarvester) GetCloudType() Type {
 	return TypeGCP
@@ -0,0 +1,136 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+)
+
+const (
+	// hetznerEndpoint is the URL used for requesting Hetzner Cloud metadata.
+	hetznerEndpoint = "http://169.254.169.254/hetzner/v1/metadata"
+)
+
+//
+// https://docs.hetzner.cloud/#server-metadata
+//
+// This response is YAML. Example response (trimmed):
+//
+// availability-zone: fsn1-dc14
+// hostname: my-server
+// instance-id: 42
+// region: eu-central
+//
+
+// HetznerHarvester is used to fetch data from Hetzner Cloud api. The metadata doesn't provide the
+// server type, account nor image.
+type HetznerHarvester struct {
+	timeout          *Timeout
+	disableKeepAlive bool
+	endpoint         string
+	metadata         *HetznerMetadata // Cache the server metadata.
+}
+
+// NewHetznerHarvester returns a new instance of HetznerHarvester.
+func NewHetznerHarvester(disableKeepAlive bool) *HetznerHarvester {
+	return &HetznerHarvester{
+		timeout:          NewTimeout(600),
+		disableKeepAlive: disableKeepAlive,
+		endpoint:         hetznerEndpoint,
+	}
+}
+
+// GetHarvester returns instance of the Harvester detected (or instance of themselves)
+func (h *HetznerHarvester) GetHarvester() (Harvester, error) {
+	return h, nil
+}
+
+// GetInstanceID returns the server ID.
+func (h *HetznerHarvester) GetInstanceID() (string, error) {
+	m, err := h.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.InstanceID, nil
+}
+
+// GetHostType is not provided by the Hetzner Cloud metadata.
+func (h *HetznerHarvester) GetHostType() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetCloudType returns the type of the cloud.
+func (h *HetznerHarvester) GetCloudType() Type {
+	return TypeHetzner
+}
+
+// GetCloudSource returns a string key which will be used as a HostSource (see host_aliases plugin).
+func (h *HetznerHarvester) GetCloudSource() string {
+	return sysinfo.HOST_SOURCE_HETZNER_SERVER_ID
+}
+
+// GetRegion will return the network zone of the server, like eu-central.
+func (h *HetznerHarvester) GetRegion() (string, error) {
+	m, err := h.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.Region, nil
+}
+
+// GetAccountID is not provided by the Hetzner Cloud metadata.
+func (h *HetznerHarvester) GetAccountID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetZone will return the datacenter of the server, like fsn1-dc14.
+func (h *HetznerHarvester) GetZone() (string, error) {
+	m, err := h.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.AvailabilityZone, nil
+}
+
+// GetInstanceImageID is not provided by the Hetzner Cloud metadata.
+func (h *HetznerHarvester) GetInstanceImageID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+func (h *HetznerHarvester) getMetadata() (*HetznerMetadata, error) {
+	if h.metadata == nil || h.timeout.HasExpired() {
+		body, err := fetchMetadata(h.endpoint, nil, h.disableKeepAlive)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := parseHetznerMetadata(body)
+		if err != nil {
+			return nil, err
+		}
+		h.metadata = metadata
+	}
+	return h.metadata, nil
+}
+
+// HetznerMetadata captures the fields we care about from the Hetzner Cloud metadata API.
+type HetznerMetadata struct {
+	InstanceID       string `yaml:"instance-id"`
+	Region           string `yaml:"region"`
+	AvailabilityZone string `yaml:"availability-zone"`
+}
+
+func parseHetznerMetadata(body []byte) (*HetznerMetadata, error) {
+	var metadata HetznerMetadata
+	if err := yaml.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal Hetzner metadata response body: %v", err)
+	}
+	if metadata.InstanceID == "" {
+		return nil, errors.New("missing instance ID in Hetzner metadata")
+	}
+	return &metadata, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+)
+
+const (
+	// ociEndpoint is the URL used for requesting OCI metadata (IMDS v2).
+	ociEndpoint = "http://169.254.169.254/opc/v2/instance/"
+)
+
+// ociHeaders are required by the IMDS v2, which rejects the requests without them.
+var ociHeaders = map[string]string{"Authorization": "Bearer Oracle"}
+
+//
+// https://docs.oracle.com/en-us/iaas/Content/Compute/Tasks/gettingmetadata.htm
+//
+// Example response (trimmed):
+//
+// {
+//     "availabilityDomain": "EMIr:US-ASHBURN-AD-1",
+//     "canonicalRegionName": "us-ashburn-1",
+//     "compartmentId": "ocid1.compartment.oc1..aaaa",
+//     "id": "ocid1.instance.oc1.iad.anuwc",
+//     "image": "ocid1.image.oc1.iad.aaaa",
+//     "region": "iad",
+//     "shape": "VM.Standard.E4.Flex"
+// }
+//
+
+// OCIHarvester is used to fetch data from Oracle Cloud Infrastructure api.
+type OCIHarvester struct {
+	timeout          *Timeout
+	disableKeepAlive bool
+	endpoint         string
+	metadata         *OCIMetadata // Cache the OCI instance metadata.
+}
+
+// NewOCIHarvester returns a new instance of OCIHarvester.
+func NewOCIHarvester(disableKeepAlive bool) *OCIHarvester {
+	return &OCIHarvester{
+		timeout:          NewTimeout(600),
+		disableKeepAlive: disableKeepAlive,
+		endpoint:         ociEndpoint,
+	}
+}
+
+// GetHarvester returns instance of the Harvester detected (or instance of themselves)
+func (o *OCIHarvester) GetHarvester() (Harvester, error) {
+	return o, nil
+}
+
+// GetInstanceID returns the OCI instance OCID.
+func (o *OCIHarvester) GetInstanceID() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.ID, nil
+}
+
+// GetHostType will return the instance shape.
+func (o *OCIHarvester) GetHostType() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.Shape, nil
+}
+
+// GetCloudType returns the type of the cloud.
+func (o *OCIHarvester) GetCloudType() Type {
+	return TypeOCI
+}
+
+// GetCloudSource returns a string key which will be used as a HostSource (see host_aliases plugin).
+func (o *OCIHarvester) GetCloudSource() string {
+	return sysinfo.HOST_SOURCE_OCI_INSTANCE_ID
+}
+
+// GetRegion will return the canonical region name.
+func (o *OCIHarvester) GetRegion() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.CanonicalRegionName, nil
+}
+
+// GetAccountID will return the compartment OCID of the instance.
+func (o *OCIHarvester) GetAccountID() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.CompartmentID, nil
+}
+
+// GetZone will return the availability domain of the instance.
+func (o *OCIHarvester) GetZone() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.AvailabilityDomain, nil
+}
+
+// GetInstanceImageID will return the image OCID of the instance.
+func (o *OCIHarvester) GetInstanceImageID() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.Image, nil
+}
+
+func (o *OCIHarvester) getMetadata() (*OCIMetadata, error) {
+	if o.metadata == nil || o.timeout.HasExpired() {
+		body, err := fetchMetadata(o.endpoint, ociHeaders, o.disableKeepAlive)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := parseOCIMetadata(body)
+		if err != nil {
+			return nil, err
+		}
+		o.metadata = metadata
+	}
+	return o.metadata, nil
+}
+
+// OCIMetadata captures the fields we care about from the OCI metadata API.
+type OCIMetadata struct {
+	ID                  string `json:"id"`
+	Shape               string `json:"shape"`
+	CanonicalRegionName string `json:"canonicalRegionName"`
+	AvailabilityDomain  string `json:"availabilityDomain"`
+	CompartmentID       string `json:"compartmentId"`
+	Image               string `json:"image"`
+}
+
+func parseOCIMetadata(body []byte) (*OCIMetadata, error) {
+	var metadata OCIMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal OCI metadata response body: %v", err)
+	}
+	if metadata.ID == "" {
+		return nil, errors.New("missing instance ID in OCI metadata")
+	}
+	return &metadata, nil
+}
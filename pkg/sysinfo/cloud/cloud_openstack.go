@@ -0,0 +1,162 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+)
+
+const (
+	// openStackEndpoint is the URL used for requesting OpenStack metadata.
+	openStackEndpoint = "http://169.254.169.254/openstack/latest/meta_data.json"
+	// openStackConfigDriveFile is the metadata file, relative to the mounted config drive.
+	openStackConfigDriveFile = "openstack/latest/meta_data.json"
+)
+
+// openStackConfigDrives are the usual mount points of the config drive (the "config-2" volume).
+var openStackConfigDrives = []string{
+	"/mnt/config",
+	"/media/configdrive",
+	"/config-drive",
+}
+
+//
+// https://docs.openstack.org/nova/latest/user/metadata.html
+//
+// Example meta_data.json (trimmed):
+//
+// {
+//     "uuid": "d8e02d56-2648-49a3-bf97-6be8f1204f38",
+//     "availability_zone": "nova",
+//     "hostname": "test.novalocal",
+//     "project_id": "f7ac731cc11f40efbc03a9f9e1d1d21f"
+// }
+//
+
+// OpenStackHarvester is used to fetch data from the OpenStack config drive, when mounted, or from
+// the metadata service. The metadata doesn't provide the flavor, region nor image.
+type OpenStackHarvester struct {
+	timeout          *Timeout
+	disableKeepAlive bool
+	endpoint         string
+	configDrives     []string
+	metadata         *OpenStackMetadata // Cache the instance metadata.
+}
+
+// NewOpenStackHarvester returns a new instance of OpenStackHarvester.
+func NewOpenStackHarvester(disableKeepAlive bool) *OpenStackHarvester {
+	return &OpenStackHarvester{
+		timeout:          NewTimeout(600),
+		disableKeepAlive: disableKeepAlive,
+		endpoint:         openStackEndpoint,
+		configDrives:     openStackConfigDrives,
+	}
+}
+
+// GetHarvester returns instance of the Harvester detected (or instance of themselves)
+func (o *OpenStackHarvester) GetHarvester() (Harvester, error) {
+	return o, nil
+}
+
+// GetInstanceID returns the instance UUID.
+func (o *OpenStackHarvester) GetInstanceID() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.UUID, nil
+}
+
+// GetHostType is not provided by the OpenStack metadata.
+func (o *OpenStackHarvester) GetHostType() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetCloudType returns the type of the cloud.
+func (o *OpenStackHarvester) GetCloudType() Type {
+	return TypeOpenStack
+}
+
+// GetCloudSource returns a string key which will be used as a HostSource (see host_aliases plugin).
+func (o *OpenStackHarvester) GetCloudSource() string {
+	return sysinfo.HOST_SOURCE_OPENSTACK_INSTANCE_ID
+}
+
+// GetRegion is not provided by the OpenStack metadata.
+func (o *OpenStackHarvester) GetRegion() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetAccountID will return the project ID of the instance.
+func (o *OpenStackHarvester) GetAccountID() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.ProjectID, nil
+}
+
+// GetZone will return the availability zone of the instance.
+func (o *OpenStackHarvester) GetZone() (string, error) {
+	m, err := o.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.AvailabilityZone, nil
+}
+
+// GetInstanceImageID is not provided by the OpenStack metadata.
+func (o *OpenStackHarvester) GetInstanceImageID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+func (o *OpenStackHarvester) getMetadata() (*OpenStackMetadata, error) {
+	if o.metadata == nil || o.timeout.HasExpired() {
+		body, err := o.readConfigDrive()
+		if err != nil {
+			if body, err = fetchMetadata(o.endpoint, nil, o.disableKeepAlive); err != nil {
+				return nil, err
+			}
+		}
+		metadata, err := parseOpenStackMetadata(body)
+		if err != nil {
+			return nil, err
+		}
+		o.metadata = metadata
+	}
+	return o.metadata, nil
+}
+
+// readConfigDrive reads the metadata from the first mounted config drive found.
+func (o *OpenStackHarvester) readConfigDrive() ([]byte, error) {
+	for _, dir := range o.configDrives {
+		if body, err := ioutil.ReadFile(filepath.Join(dir, openStackConfigDriveFile)); err == nil {
+			return body, nil
+		}
+	}
+	return nil, errors.New("no OpenStack config drive found")
+}
+
+// OpenStackMetadata captures the fields we care about from the OpenStack metadata.
+type OpenStackMetadata struct {
+	UUID             string `json:"uuid"`
+	AvailabilityZone string `json:"availability_zone"`
+	ProjectID        string `json:"project_id"`
+}
+
+func parseOpenStackMetadata(body []byte) (*OpenStackMetadata, error) {
+	var metadata OpenStackMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal OpenStack metadata: %v", err)
+	}
+	if metadata.UUID == "" {
+		return nil, errors.New("missing instance UUID in OpenStack metadata")
+	}
+	return &metadata, nil
+}
@@ -0,0 +1,274 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metadataServer serves a metadata response on the path, only if the required headers are set.
+func metadataServer(t *testing.T, path, body string, headers map[string]string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range headers {
+			if r.Header.Get(name) != value {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestOCIHarvester(t *testing.T) {
+	ts := metadataServer(t, "/opc/v2/instance/", `{
+		"availabilityDomain": "EMIr:US-ASHBURN-AD-1",
+		"canonicalRegionName": "us-ashburn-1",
+		"compartmentId": "ocid1.compartment.oc1..aaaa",
+		"id": "ocid1.instance.oc1.iad.anuwc",
+		"image": "ocid1.image.oc1.iad.aaaa",
+		"region": "iad",
+		"shape": "VM.Standard.E4.Flex"
+	}`, map[string]string{"Authorization": "Bearer Oracle"})
+
+	h := NewOCIHarvester(true)
+	h.endpoint = ts.URL + "/opc/v2/instance/"
+
+	assertHarvested(t, h, "ocid1.instance.oc1.iad.anuwc", "VM.Standard.E4.Flex", "us-ashburn-1", "EMIr:US-ASHBURN-AD-1")
+	account, err := h.GetAccountID()
+	require.NoError(t, err)
+	assert.Equal(t, "ocid1.compartment.oc1..aaaa", account)
+	image, err := h.GetInstanceImageID()
+	require.NoError(t, err)
+	assert.Equal(t, "ocid1.image.oc1.iad.aaaa", image)
+}
+
+func TestOCIHarvester_RequiresAuthorizationHeader(t *testing.T) {
+	ts := metadataServer(t, "/opc/v2/instance/", `{"id": "ocid1.instance"}`, map[string]string{"Authorization": "Bearer Other"})
+
+	h := NewOCIHarvester(true)
+	h.endpoint = ts.URL + "/opc/v2/instance/"
+
+	_, err := h.GetInstanceID()
+	assert.Error(t, err)
+}
+
+func TestDOHarvester(t *testing.T) {
+	ts := metadataServer(t, "/metadata/v1.json", `{"droplet_id": 2756294, "hostname": "sample-droplet", "region": "nyc3"}`, nil)
+
+	h := NewDOHarvester(true)
+	h.endpoint = ts.URL + "/metadata/v1.json"
+
+	id, err := h.GetInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "2756294", id)
+	region, err := h.GetRegion()
+	require.NoError(t, err)
+	assert.Equal(t, "nyc3", region)
+	_, err = h.GetHostType()
+	assert.Equal(t, ErrMethodNotImplemented, err)
+}
+
+func TestHetznerHarvester(t *testing.T) {
+	ts := metadataServer(t, "/hetzner/v1/metadata", `availability-zone: fsn1-dc14
+hostname: my-server
+instance-id: 42
+public-ipv4: 1.2.3.4
+region: eu-central
+`, nil)
+
+	h := NewHetznerHarvester(true)
+	h.endpoint = ts.URL + "/hetzner/v1/metadata"
+
+	id, err := h.GetInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "42", id)
+	region, err := h.GetRegion()
+	require.NoError(t, err)
+	assert.Equal(t, "eu-central", region)
+	zone, err := h.GetZone()
+	require.NoError(t, err)
+	assert.Equal(t, "fsn1-dc14", zone)
+}
+
+func TestOpenStackHarvester_MetadataService(t *testing.T) {
+	ts := metadataServer(t, "/openstack/latest/meta_data.json", `{
+		"uuid": "d8e02d56-2648-49a3-bf97-6be8f1204f38",
+		"availability_zone": "nova",
+		"project_id": "f7ac731cc11f40efbc03a9f9e1d1d21f"
+	}`, nil)
+
+	h := NewOpenStackHarvester(true)
+	h.endpoint = ts.URL + "/openstack/latest/meta_data.json"
+	h.configDrives = []string{filepath.Join(t.TempDir(), "missing")}
+
+	id, err := h.GetInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "d8e02d56-2648-49a3-bf97-6be8f1204f38", id)
+	zone, err := h.GetZone()
+	require.NoError(t, err)
+	assert.Equal(t, "nova", zone)
+	account, err := h.GetAccountID()
+	require.NoError(t, err)
+	assert.Equal(t, "f7ac731cc11f40efbc03a9f9e1d1d21f", account)
+}
+
+func TestOpenStackHarvester_ConfigDrive(t *testing.T) {
+	drive := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(drive, "openstack", "latest"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(drive, openStackConfigDriveFile),
+		[]byte(`{"uuid": "from-config-drive", "availability_zone": "az1"}`), 0644))
+
+	h := NewOpenStackHarvester(true)
+	h.endpoint = "http://127.0.0.1:1/unreachable"
+	h.configDrives = []string{drive}
+
+	id, err := h.GetInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "from-config-drive", id)
+}
+
+func TestVMwareHarvester_GuestInfoMetadata(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte("instance-id: vm-1234\nregion: dc-1\navailability-zone: cluster-a\n"))
+	require.NoError(t, gz.Close())
+
+	h := NewVMwareHarvester()
+	h.guestInfo = func(key string) (string, error) {
+		switch key {
+		case "metadata":
+			return base64.StdEncoding.EncodeToString(compressed.Bytes()), nil
+		case "metadata.encoding":
+			return "gzip+base64", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	assertHarvested(t, h, "vm-1234", "", "dc-1", "cluster-a")
+}
+
+func TestVMwareHarvester_UUIDFallback(t *testing.T) {
+	h := NewVMwareHarvester()
+	h.guestInfo = func(string) (string, error) { return "", errors.New("no vmware tools") }
+	h.vmUUID = func() (string, error) { return "4203a1c2-0000", nil }
+
+	h.readDMI = func() dmiInfo { return dmiInfo{sysVendor: "Dell Inc."} }
+	_, err := h.GetInstanceID()
+	assert.Error(t, err, "not a VMware VM")
+
+	h.readDMI = func() dmiInfo { return dmiInfo{sysVendor: "VMware, Inc."} }
+	id, err := h.GetInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "4203a1c2-0000", id)
+}
+
+// assertHarvested checks the harvested values, skipping the empty expectations.
+func assertHarvested(t *testing.T, h Harvester, instanceID, hostType, region, zone string) {
+	t.Helper()
+	for expected, get := range map[string]func() (string, error){
+		instanceID: h.GetInstanceID,
+		hostType:   h.GetHostType,
+		region:     h.GetRegion,
+		zone:       h.GetZone,
+	} {
+		if expected == "" {
+			continue
+		}
+		value, err := get()
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+}
+
+func TestDMIInfo_CloudType(t *testing.T) {
+	for expected, dmi := range map[Type]dmiInfo{
+		TypeAWS:       {sysVendor: "Amazon EC2"},
+		TypeAzure:     {sysVendor: "Microsoft Corporation", chassisAssetTag: azureAssetTag},
+		TypeGCP:       {sysVendor: "Google", productName: "Google Compute Engine"},
+		TypeAlibaba:   {sysVendor: "Alibaba Cloud"},
+		TypeOCI:       {sysVendor: "QEMU", chassisAssetTag: "OracleCloud.com"},
+		TypeDO:        {sysVendor: "DigitalOcean"},
+		TypeHetzner:   {sysVendor: "Hetzner"},
+		TypeOpenStack: {sysVendor: "RDO", productName: "OpenStack Compute"},
+		TypeVMware:    {sysVendor: "VMware, Inc."},
+		"":            {sysVendor: "Microsoft Corporation", productName: "Virtual Machine"},
+	} {
+		assert.Equal(t, expected, dmi.cloudType(), dmi)
+	}
+}
+
+func TestDetector_Harvesters(t *testing.T) {
+	d := NewDetector(false, 0, 0, 0, true)
+	d.readDMI = func() dmiInfo { return dmiInfo{} }
+
+	d.SetDetectionOrder([]string{"openstack", "unknown", " AWS "})
+	harvesters, _ := d.harvesters()
+	require.Len(t, harvesters, 2)
+	assert.Equal(t, TypeOpenStack, harvesters[0].GetCloudType())
+	assert.Equal(t, TypeAWS, harvesters[1].GetCloudType())
+
+	// only the hinted cloud is probed
+	d.readDMI = func() dmiInfo { return dmiInfo{sysVendor: "Amazon EC2"} }
+	harvesters, _ = d.harvesters()
+	require.Len(t, harvesters, 1)
+	assert.Equal(t, TypeAWS, harvesters[0].GetCloudType())
+
+	// unless it isn't in the detection order
+	d.readDMI = func() dmiInfo { return dmiInfo{sysVendor: "Hetzner"} }
+	harvesters, _ = d.harvesters()
+	assert.Len(t, harvesters, 2)
+
+	d = NewDetector(false, 0, 0, 0, true)
+	d.readDMI = func() dmiInfo { return dmiInfo{} }
+	harvesters, _ = d.harvesters()
+	assert.Len(t, harvesters, len(DefaultDetectionOrder))
+
+	// an order without any known cloud keeps the default one
+	d.SetDetectionOrder([]string{"unknown"})
+	harvesters, _ = d.harvesters()
+	assert.Len(t, harvesters, len(DefaultDetectionOrder))
+}
+
+func TestDetector_HarvestersOnPhysicalMachine(t *testing.T) {
+	d := NewDetector(false, 3, 60, 0, true)
+	d.readDMI = func() dmiInfo { return dmiInfo{sysVendor: "Dell Inc.", productName: "PowerEdge R740"} }
+
+	// the clouds are probed, as physical machines may be provisioned by OpenStack Ironic, but not retried
+	harvesters, retry := d.harvesters()
+	assert.Len(t, harvesters, len(DefaultDetectionOrder))
+	assert.False(t, retry)
+
+	// an explicit detection order is always probed as in any other machine
+	d.SetDetectionOrder([]string{"openstack"})
+	harvesters, retry = d.harvesters()
+	require.Len(t, harvesters, 1)
+	assert.Equal(t, TypeOpenStack, harvesters[0].GetCloudType())
+	assert.True(t, retry)
+}
+
+func TestDMIInfo_BareMetal(t *testing.T) {
+	for _, vendor := range []string{"Dell Inc.", "HP", "HPE", "Hewlett-Packard", "LENOVO", "Supermicro", "Apple Inc."} {
+		assert.True(t, dmiInfo{sysVendor: vendor}.bareMetal(), vendor)
+	}
+	for _, vendor := range []string{"", "QEMU", "Xen", "Microsoft Corporation", "HPC Cloud", "innotek GmbH"} {
+		assert.False(t, dmiInfo{sysVendor: vendor}.bareMetal(), vendor)
+	}
+}
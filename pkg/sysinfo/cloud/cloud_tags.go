@@ -0,0 +1,148 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// tagsRetryInterval is the time to wait before fetching again the cloud tags after a failure.
+const tagsRetryInterval = 30 * time.Second
+
+// TagsAttributes keeps the tags of the cloud instance that are reported as attributes, filtered and prefixed as
+// configured. They are shared by the custom attributes inventory and the integrations samples.
+type TagsAttributes struct {
+	harvester TagsHarvester
+	include   []string
+	exclude   []string
+	prefix    string
+	interval  time.Duration
+	startOnce sync.Once
+	lock      sync.Mutex
+	tags      map[string]string
+	listeners []func()
+}
+
+// NewTagsAttributes returns the attributes for the tags of the given harvester, refreshed every interval.
+func NewTagsAttributes(harvester TagsHarvester, include, exclude []string, prefix string, interval time.Duration) *TagsAttributes {
+	if interval <= 0 {
+		interval = tagsRetryInterval
+	}
+	return &TagsAttributes{
+		harvester: harvester,
+		include:   include,
+		exclude:   exclude,
+		prefix:    prefix,
+		interval:  interval,
+	}
+}
+
+// Start refreshes the tags in background until the context is done. Only the first invocation has effect.
+func (t *TagsAttributes) Start(ctx context.Context) {
+	t.startOnce.Do(func() {
+		go t.refresh(ctx)
+	})
+}
+
+// OnChange registers a function that is invoked every time the tags change.
+func (t *TagsAttributes) OnChange(listener func()) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.listeners = append(t.listeners, listener)
+}
+
+// Get returns the filtered and prefixed tags fetched the last time.
+func (t *TagsAttributes) Get() map[string]string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.tags
+}
+
+// refresh fetches the tags every interval. It returns when the context is done, or when the cloud doesn't provide
+// tags.
+func (t *TagsAttributes) refresh(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		wait := t.interval
+		if err := t.update(); err == ErrMethodNotImplemented {
+			dlog.Debug("The cloud doesn't provide instance tags, stop fetching them.")
+			return
+		} else if err != nil {
+			dlog.WithError(err).Debug("Cannot fetch the cloud tags, retrying later.")
+			wait = tagsRetryInterval
+		}
+		timer.Reset(wait)
+	}
+}
+
+// update fetches the tags, invoking the listeners if they changed since the previous fetch.
+func (t *TagsAttributes) update() error {
+	// The GCP custom metadata is free-form and commonly holds configuration and credentials, so none of it is
+	// reported unless the keys to include are explicitly set.
+	if len(t.include) == 0 && t.cloudType() == TypeGCP {
+		dlog.Warn("GCP custom metadata isn't reported as attributes unless cloud_tags_include is set.")
+		return ErrMethodNotImplemented
+	}
+
+	tags, err := t.harvester.GetInstanceTags()
+	if err != nil {
+		return err
+	}
+	tags = filterTags(tags, t.include, t.exclude, t.prefix)
+
+	t.lock.Lock()
+	changed := !reflect.DeepEqual(tags, t.tags)
+	t.tags = tags
+	listeners := t.listeners
+	t.lock.Unlock()
+
+	if changed {
+		for _, listener := range listeners {
+			listener()
+		}
+	}
+	return nil
+}
+
+func (t *TagsAttributes) cloudType() Type {
+	if harvester, ok := t.harvester.(Harvester); ok {
+		return harvester.GetCloudType()
+	}
+	return TypeNoCloud
+}
+
+// filterTags returns the tags with a key matching any of the include patterns (all of them when there are no
+// include patterns) and none of the exclude patterns, with the prefix added to their keys.
+func filterTags(tags map[string]string, include, exclude []string, prefix string) map[string]string {
+	filtered := make(map[string]string, len(tags))
+	for key, value := range tags {
+		if len(include) > 0 && !matchesAny(include, key) {
+			continue
+		}
+		if matchesAny(exclude, key) {
+			continue
+		}
+		filtered[prefix+key] = value
+	}
+	return filtered
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSHarvester_GetInstanceTags(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "token")
+	})
+	tags := map[string]string{
+		"":             "team\nCost Center",
+		"/team":        "infra",
+		"/Cost Center": "42",
+	}
+	mux.HandleFunc("/latest/meta-data/tags/instance/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-aws-ec2-metadata-token"))
+		value, ok := tags[r.URL.Path[len("/latest/meta-data/tags/instance"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-type", "text/plain")
+		_, _ = fmt.Fprint(w, value)
+	})
+	mux.HandleFunc("/latest/meta-data/tags/instance", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-type", "text/plain")
+		_, _ = fmt.Fprint(w, tags[""])
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	h := NewAWSHarvester(true)
+	h.awsEC2MetadataHostname = ts.URL
+
+	actual, err := h.GetInstanceTags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "infra", "Cost Center": "42"}, actual)
+}
+
+func TestAzureHarvester_GetInstanceTags(t *testing.T) {
+	ts := metadataServer(t, "/metadata/instance/compute/tagsList",
+		`[{"name": "team", "value": "infra"}, {"name": "env", "value": "prod"}]`,
+		map[string]string{"Metadata": "true"})
+
+	h := NewAzureHarvester(true)
+	h.tagsEndpoint = ts.URL + "/metadata/instance/compute/tagsList"
+
+	actual, err := h.GetInstanceTags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "infra", "env": "prod"}, actual)
+}
+
+func TestGCPHarvester_GetInstanceTags(t *testing.T) {
+	ts := metadataServer(t, "/computeMetadata/v1/instance/attributes/",
+		fmt.Sprintf(`{"team": "infra", "ssh-keys": "user:ssh-rsa AAAA", "startup-script": "#!/bin/sh", "config": %q}`,
+			strings.Repeat("x", gcpMaxAttributeValueSize+1)),
+		map[string]string{"Metadata-Flavor": "Google"})
+
+	h := NewGCPHarvester(true)
+	h.tagsEndpoint = ts.URL + "/computeMetadata/v1/instance/attributes/"
+
+	actual, err := h.GetInstanceTags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "infra"}, actual)
+}
+
+func TestDetector_GetInstanceTags_NotImplemented(t *testing.T) {
+	d := NewDetector(false, 0, 0, 0, true)
+	d.setHarvester(NewHetznerHarvester(true))
+
+	_, err := d.GetInstanceTags()
+	assert.Equal(t, ErrMethodNotImplemented, err)
+}
+
+type fakeTagsHarvester struct {
+	Harvester
+	cloudType Type
+	tags      map[string]string
+	err       error
+	calls     int
+}
+
+func (f *fakeTagsHarvester) GetCloudType() Type {
+	return f.cloudType
+}
+
+func (f *fakeTagsHarvester) GetInstanceTags() (map[string]string, error) {
+	f.calls++
+	return f.tags, f.err
+}
+
+func TestFilterTags(t *testing.T) {
+	tags := map[string]string{"team": "infra", "cost-center": "42", "cost-owner": "jane", "env": "prod"}
+
+	assert.Equal(t, map[string]string{"cloud.team": "infra", "cloud.cost-center": "42", "cloud.cost-owner": "jane", "cloud.env": "prod"},
+		filterTags(tags, nil, nil, "cloud."))
+	assert.Equal(t, map[string]string{"team": "infra", "cost-center": "42"},
+		filterTags(tags, []string{"team", "cost-*"}, []string{"*-owner"}, ""))
+}
+
+func TestTagsAttributes_Update(t *testing.T) {
+	harvester := &fakeTagsHarvester{cloudType: TypeAWS, tags: map[string]string{"team": "infra", "secret": "x"}}
+	tags := NewTagsAttributes(harvester, nil, []string{"secret"}, "", time.Minute)
+	changes := 0
+	tags.OnChange(func() { changes++ })
+
+	require.NoError(t, tags.update())
+	assert.Equal(t, map[string]string{"team": "infra"}, tags.Get())
+	assert.Equal(t, 1, changes)
+
+	// unchanged tags don't notify the listeners
+	require.NoError(t, tags.update())
+	assert.Equal(t, 1, changes)
+
+	harvester.tags = map[string]string{"team": "core"}
+	require.NoError(t, tags.update())
+	assert.Equal(t, map[string]string{"team": "core"}, tags.Get())
+	assert.Equal(t, 2, changes)
+}
+
+func TestTagsAttributes_GCPRequiresInclude(t *testing.T) {
+	harvester := &fakeTagsHarvester{cloudType: TypeGCP, tags: map[string]string{"team": "infra", "db-password": "x"}}
+
+	tags := NewTagsAttributes(harvester, nil, nil, "", time.Minute)
+	assert.Equal(t, ErrMethodNotImplemented, tags.update())
+	assert.Empty(t, tags.Get())
+	assert.Zero(t, harvester.calls)
+
+	tags = NewTagsAttributes(harvester, []string{"team"}, nil, "", time.Minute)
+	require.NoError(t, tags.update())
+	assert.Equal(t, map[string]string{"team": "infra"}, tags.Get())
+}
+
+func TestTagsAttributes_RefreshStops(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err    error
+		cancel bool
+	}{
+		"tags not implemented": {err: ErrMethodNotImplemented},
+		"agent shutdown":       {err: errors.New("unavailable"), cancel: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			harvester := &fakeTagsHarvester{cloudType: TypeAWS, err: tc.err}
+			tags := NewTagsAttributes(harvester, nil, nil, "", time.Minute)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan struct{})
+			go func() {
+				tags.refresh(ctx)
+				close(done)
+			}()
+			if tc.cancel {
+				cancel()
+			}
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("cloud tags are still refreshed")
+			}
+			assert.LessOrEqual(t, harvester.calls, 1)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+)
+
+const vmwareRPCTimeout = 2 * time.Second
+
+//
+// The VMware guestinfo variables are read through the VMware tools. The instance metadata follows
+// the cloud-init VMware datasource format, in the guestinfo.metadata variable, optionally encoded as
+// set by guestinfo.metadata.encoding ("base64" or "gzip+base64"). Example metadata:
+//
+// instance-id: vm-1234
+// local-hostname: my-vm
+// region: datacenter-1
+// availability-zone: cluster-a
+//
+// When there is no metadata, the virtual machine UUID is used as instance ID.
+//
+
+// VMwareHarvester is used to fetch data from the VMware guestinfo.
+type VMwareHarvester struct {
+	timeout   *Timeout
+	guestInfo func(key string) (string, error)
+	readDMI   func() dmiInfo
+	vmUUID    func() (string, error)
+	metadata  *VMwareMetadata // Cache the VM metadata.
+}
+
+// NewVMwareHarvester returns a new instance of VMwareHarvester.
+func NewVMwareHarvester() *VMwareHarvester {
+	return &VMwareHarvester{
+		timeout:   NewTimeout(600),
+		guestInfo: vmwareGuestInfo,
+		readDMI:   readDMI,
+		vmUUID:    dmiProductUUID,
+	}
+}
+
+// GetHarvester returns instance of the Harvester detected (or instance of themselves)
+func (v *VMwareHarvester) GetHarvester() (Harvester, error) {
+	return v, nil
+}
+
+// GetInstanceID returns the instance ID of the metadata, or the VM UUID.
+func (v *VMwareHarvester) GetInstanceID() (string, error) {
+	m, err := v.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	return m.InstanceID, nil
+}
+
+// GetHostType is not provided by the VMware guestinfo.
+func (v *VMwareHarvester) GetHostType() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetCloudType returns the type of the cloud.
+func (v *VMwareHarvester) GetCloudType() Type {
+	return TypeVMware
+}
+
+// GetCloudSource returns a string key which will be used as a HostSource (see host_aliases plugin).
+func (v *VMwareHarvester) GetCloudSource() string {
+	return sysinfo.HOST_SOURCE_VMWARE_VM_ID
+}
+
+// GetRegion will return the region of the metadata, if any.
+func (v *VMwareHarvester) GetRegion() (string, error) {
+	m, err := v.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	if m.Region == "" {
+		return "", ErrMethodNotImplemented
+	}
+	return m.Region, nil
+}
+
+// GetAccountID is not provided by the VMware guestinfo.
+func (v *VMwareHarvester) GetAccountID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetZone will return the availability zone of the metadata, if any.
+func (v *VMwareHarvester) GetZone() (string, error) {
+	m, err := v.getMetadata()
+	if err != nil {
+		return "", err
+	}
+	if m.AvailabilityZone == "" {
+		return "", ErrMethodNotImplemented
+	}
+	return m.AvailabilityZone, nil
+}
+
+// GetInstanceImageID is not provided by the VMware guestinfo.
+func (v *VMwareHarvester) GetInstanceImageID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+func (v *VMwareHarvester) getMetadata() (*VMwareMetadata, error) {
+	if v.metadata == nil || v.timeout.HasExpired() {
+		metadata, err := v.fetchMetadata()
+		if err != nil {
+			return nil, err
+		}
+		v.metadata = metadata
+	}
+	return v.metadata, nil
+}
+
+func (v *VMwareHarvester) fetchMetadata() (*VMwareMetadata, error) {
+	raw, err := v.guestInfo("metadata")
+	if err == nil && raw != "" {
+		encoding, _ := v.guestInfo("metadata.encoding")
+		return parseVMwareMetadata(raw, encoding)
+	}
+
+	// any machine has an UUID, so it only identifies VMware virtual machines
+	if v.readDMI().cloudType() != TypeVMware {
+		return nil, errors.New("not a VMware virtual machine")
+	}
+	uuid, err := v.vmUUID()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the VMware VM UUID: %v", err)
+	}
+	return &VMwareMetadata{InstanceID: uuid}, nil
+}
+
+// VMwareMetadata captures the fields we care about from the VMware guestinfo metadata.
+type VMwareMetadata struct {
+	InstanceID       string `yaml:"instance-id"`
+	Region           string `yaml:"region"`
+	AvailabilityZone string `yaml:"availability-zone"`
+}
+
+// parseVMwareMetadata decodes the guestinfo metadata, which is YAML or JSON.
+func parseVMwareMetadata(raw, encoding string) (*VMwareMetadata, error) {
+	content := []byte(raw)
+	switch strings.ToLower(encoding) {
+	case "":
+	case "base64", "b64", "gzip+base64", "gz+b64":
+		decoded, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode VMware metadata: %v", err)
+		}
+		content = decoded
+		if strings.HasPrefix(strings.ToLower(encoding), "gz") {
+			reader, err := gzip.NewReader(bytes.NewReader(decoded))
+			if err != nil {
+				return nil, fmt.Errorf("unable to decompress VMware metadata: %v", err)
+			}
+			if content, err = ioutil.ReadAll(reader); err != nil {
+				return nil, fmt.Errorf("unable to decompress VMware metadata: %v", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported VMware metadata encoding: %s", encoding)
+	}
+
+	var metadata VMwareMetadata
+	if err := yaml.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal VMware metadata: %v", err)
+	}
+	if metadata.InstanceID == "" {
+		return nil, errors.New("missing instance ID in VMware metadata")
+	}
+	return &metadata, nil
+}
+
+// vmwareGuestInfo reads a guestinfo variable through the VMware tools.
+func vmwareGuestInfo(key string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), vmwareRPCTimeout)
+	defer cancel()
+
+	query := "info-get guestinfo." + key
+	out, err := exec.CommandContext(ctx, "vmware-rpctool", query).Output()
+	if err != nil {
+		out, err = exec.CommandContext(ctx, "vmtoolsd", "--cmd", query).Output()
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read guestinfo.%s: %v", key, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// dmiProductUUID reads the VM UUID, which requires root privileges.
+func dmiProductUUID() (string, error) {
+	content, err := ioutil.ReadFile(helpers.HostSys("/devices/virtual/dmi/id/product_uuid"))
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(string(content))), nil
+}
@@ -15,6 +15,14 @@ const (
 	HOST_SOURCE_HOSTNAME       = "hostname"
 	HOST_SOURCE_HOSTNAME_SHORT = "hostname_short"
 
+	// The instance IDs of these clouds are reported as host aliases, but they aren't part of HOST_ID_TYPES
+	// so the agent identifier of the existing hosts doesn't change.
+	HOST_SOURCE_OCI_INSTANCE_ID       = "oci_instance_id"
+	HOST_SOURCE_DO_DROPLET_ID         = "digitalocean_droplet_id"
+	HOST_SOURCE_HETZNER_SERVER_ID     = "hetzner_server_id"
+	HOST_SOURCE_OPENSTACK_INSTANCE_ID = "openstack_instance_id"
+	HOST_SOURCE_VMWARE_VM_ID          = "vmware_vm_id"
+
 	PROCESS_NAME_SOURCE_DAEMONTOOLS = "daemontools"
 	PROCESS_NAME_SOURCE_SUPERVISOR  = "supervisor"
 	PROCESS_NAME_SOURCE_SYSTEMD     = "systemd"
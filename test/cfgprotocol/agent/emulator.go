@@ -109,7 +109,7 @@ func (ae *Emulator) RunAgent() error {
 	// queues config entries requests
 	configEntryQ := make(chan configrequest.Entry, 100)
 
-	dmEmitter := dm.NewEmitter(ae.agent.GetContext(), dmSender, nil, instrumentation.NoopMeasure)
+	dmEmitter := dm.NewEmitter(ae.agent.GetContext(), dmSender, nil, nil, instrumentation.NoopMeasure)
 
 	// track stoppable integrations
 	tracker := track.NewTracker(dmEmitter)
@@ -352,7 +352,7 @@ func TestDeltas_HarvestAfterStoreCleanup(t *testing.T) {
 	plugin := newDummyPlugin("hi", a.Context)
 	a.RegisterPlugin(plugin)
 	// That runs a re-connectable plugin (e.g. Custom Attributes plugin)
-	a.RegisterPlugin(plugins.NewCustomAttrsPlugin(a.Context))
+	a.RegisterPlugin(plugins.NewCustomAttrsPlugin(a.Context, a.GetCloudTags()))
 	go a.Run()
 
 	plugin.harvest()
@@ -0,0 +1,62 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux && slow
+// +build linux,slow
+
+package process_test
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type procTemplate struct {
+	Pid     string
+	Cmdline string
+	User    string
+	Cwd     string
+}
+
+func TestProcessFetch(t *testing.T) {
+	// GIVEN a running process
+	cmd := exec.Command("sleep", "31337")
+	cmd.Dir = os.TempDir()
+	require.NoError(t, cmd.Start())
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	// WHEN the data is fetched
+	cfg, err := databind.LoadYAML([]byte(`
+discovery:
+  process:
+    match:
+      name: sleep
+      cmdline: /31337/
+`))
+	require.NoError(t, err)
+	ctx, err := databind.Fetch(cfg)
+	require.NoError(t, err)
+
+	// THEN the process is found
+	matches, err := databind.Replace(&ctx, procTemplate{
+		Pid:     "${discovery.pid}",
+		Cmdline: "${discovery.cmdline}",
+		User:    "${discovery.user}",
+		Cwd:     "${discovery.cwd}",
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	match := matches[0].Variables.(procTemplate)
+	assert.Equal(t, strconv.Itoa(cmd.Process.Pid), match.Pid)
+	assert.Equal(t, "sleep 31337", match.Cmdline)
+	assert.NotEmpty(t, match.User)
+	assert.Equal(t, os.TempDir(), match.Cwd)
+}
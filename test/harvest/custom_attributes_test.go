@@ -30,7 +30,7 @@ func TestPluginCustomAttributes(t *testing.T) {
 		}
 	})
 	a.Context.SetAgentIdentity(entity.Identity{10, "abcdef"})
-	a.RegisterPlugin(plugins.NewCustomAttrsPlugin(a.Context))
+	a.RegisterPlugin(plugins.NewCustomAttrsPlugin(a.Context, a.GetCloudHarvester()))
 	go a.Run()
 	defer a.Terminate()
 
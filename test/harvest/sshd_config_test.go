@@ -65,6 +65,7 @@ func TestSshdConfig(t *testing.T) {
 				"PermitRootLogin": map[string]interface{}{
 					"id":    "PermitRootLogin",
 					"value": "yes",
+					"risky": true,
 				},
 				"PermitEmptyPasswords": map[string]interface{}{
 					"id":    "PermitEmptyPasswords",